@@ -28,6 +28,7 @@ var runCmd = &cobra.Command{
 		extraBind, _ := cmd.Flags().GetString("bind")
 		xDisplay, _ := cmd.Flags().GetString("display")
 		extraHost, _ := cmd.Flags().GetString("extrahosts")
+		extraEnv, _ := cmd.Flags().GetString("env")
 		pulseServer, _ := cmd.Flags().GetString("pulseserver")
 		dockerName, _ := cmd.Flags().GetString("name")
 		netMode, _ := cmd.Flags().GetString("network")
@@ -41,6 +42,8 @@ var runCmd = &cobra.Command{
 		noX11, _ := cmd.Flags().GetBool("no-x11")
 		recordSession, _ := cmd.Flags().GetBool("record")
 		recordOutput, _ := cmd.Flags().GetString("record-output")
+		recordInput, _ := cmd.Flags().GetBool("record-input")
+		recordRedact, _ := cmd.Flags().GetStringArray("record-redact")
 		realtime, _ := cmd.Flags().GetBool("realtime")
 		ulimits, _ := cmd.Flags().GetString("ulimits")
 		desktop, _ := cmd.Flags().GetBool("desktop")
@@ -53,8 +56,11 @@ var runCmd = &cobra.Command{
 		workspacePath, _ := cmd.Flags().GetString("workspace")
 		noWorkspace, _ := cmd.Flags().GetBool("no-workspace")
 		cwdWorkspace, _ := cmd.Flags().GetBool("cwd")
+		usbDevices, _ := cmd.Flags().GetStringSlice("usb")
+		resources := runResourceFlags(cmd)
 
 		// Resolve workspace config
+		workspaceSet := noWorkspace || cwdWorkspace || workspacePath != ""
 		if noWorkspace {
 			rfdock.ContainerSetWorkspace("none")
 		} else if cwdWorkspace {
@@ -64,6 +70,45 @@ var runCmd = &cobra.Command{
 			rfdock.ContainerSetWorkspace(workspacePath)
 		}
 
+		// applyProfileRunOptions fills the run options a profile sets and the
+		// command line left empty
+		applyProfileRunOptions := func(prof *rfdock.Profile) {
+			if ulimits == "" && prof.Ulimits != "" {
+				ulimits = prof.Ulimits
+			}
+			if extraEnv == "" && prof.ExtraEnv != "" {
+				extraEnv = prof.ExtraEnv
+			}
+			if extraHost == "" && prof.ExtraHosts != "" {
+				extraHost = prof.ExtraHosts
+			}
+			if seccomp == "" && prof.Seccomp != "" {
+				seccomp = prof.Seccomp
+			}
+			if execCommand == "" && prof.Shell != "" {
+				execCommand = prof.Shell
+			}
+			if desktopConfig == "" && prof.DesktopConfig() != "" {
+				desktopConfig = prof.DesktopConfig()
+			}
+			if desktopPass == "" && prof.DesktopPass != "" {
+				desktopPass = prof.DesktopPass
+			}
+			// display and pulseserver have non-empty defaults: only an
+			// explicit flag wins over the profile
+			if !cmd.Flags().Changed("pulseserver") && prof.PulseServer != "" {
+				pulseServer = prof.PulseServer
+			}
+			if !cmd.Flags().Changed("display") && prof.Display != "" {
+				xDisplay = prof.Display
+			}
+			if !workspaceSet && prof.Workspace != "" {
+				workspacePath = prof.WorkspacePath()
+				rfdock.ContainerSetWorkspace(workspacePath)
+			}
+			resources = resources.WithDefaults(prof.Resources())
+		}
+
 		// Apply profile if specified (profile values are used as defaults, CLI flags override)
 		if profileName != "" {
 			prof, err := rfdock.GetProfileByName(profileName)
@@ -113,6 +158,7 @@ var runCmd = &cobra.Command{
 			if vpnConfig == "" && prof.VPN != "" {
 				vpnConfig = prof.VPN
 			}
+			applyProfileRunOptions(prof)
 			if gpus == "" && prof.GPUs != "" {
 				// A profile asking for a GPU must not make the run fail on a
 				// host that has none: the daemon refuses DeviceRequests it
@@ -188,6 +234,11 @@ var runCmd = &cobra.Command{
 			}
 			image = wizResult.Image
 			dockerName = wizResult.Name
+			if wizResult.Profile != "" {
+				if prof, err := rfdock.GetProfileByName(wizResult.Profile); err == nil {
+					applyProfileRunOptions(prof)
+				}
+			}
 			if wizResult.Bindings != "" {
 				extraBind = wizResult.Bindings
 			}
@@ -253,115 +304,55 @@ var runCmd = &cobra.Command{
 		// older Ubuntu base than the current one.
 		rfutils.NotifyIfOutdatedImage(image)
 
+		if err := resources.Validate(); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+
 		// On macOS with Lima engine, offer to attach USB devices before container creation
 		if runtime.GOOS == "darwin" && rfdock.GetEngine().Type() == rfdock.EngineLima && tui.IsInteractive() {
 			MacUSBWizardStep(limaInstance)
 		}
 
-		if recordSession {
-			// Build extra args map for recording subprocess
-			extraArgs := map[string]string{}
-			if extraBind != "" {
-				extraArgs["-b"] = extraBind
-			}
-			if extraHost != "" {
-				extraArgs["-x"] = extraHost
-			}
-			if xDisplay != "" && xDisplay != rfutils.GetDisplayEnv() {
-				extraArgs["-d"] = xDisplay
-			}
-			if execCommand != "" {
-				extraArgs["-e"] = execCommand
-			}
-			if pulseServer != "tcp:127.0.0.1:34567" {
-				extraArgs["-p"] = pulseServer
-			}
-			if netMode != "" {
-				extraArgs["-t"] = netMode
-			}
-			if exposedPorts != "" {
-				extraArgs["-z"] = exposedPorts
-			}
-			if bindedPorts != "" {
-				extraArgs["-w"] = bindedPorts
-			}
-			if devices != "" {
-				extraArgs["-s"] = devices
-			}
-			if privileged != 0 {
-				extraArgs["-u"] = fmt.Sprintf("%d", privileged)
-			}
-			if caps != "" {
-				extraArgs["-a"] = caps
-			}
-			if cgroups != "" {
-				extraArgs["-g"] = cgroups
-			}
-			if seccomp != "" {
-				extraArgs["-m"] = seccomp
-			}
-			if noX11 {
-				extraArgs["--no-x11"] = ""
-			}
-			if desktop {
-				extraArgs["--desktop"] = ""
-			}
-			if desktopConfig != "" {
-				extraArgs["--desktop-config"] = desktopConfig
-			}
+		setupX11(noX11, xDisplay, true)
+		rfdock.ContainerSetShell(execCommand)
+		rfdock.ContainerAddBinding(extraBind)
+		rfdock.ContainerSetImage(image)
+		rfdock.ContainerSetExtraHosts(extraHost)
+		rfdock.ContainerSetEnv(extraEnv)
+		rfdock.ContainerSetPulse(pulseServer)
+		rfdock.ContainerSetNetworkMode(netMode)
+		rfdock.ContainerSetExposedPorts(exposedPorts)
+		rfdock.ContainerSetBindedPorts(bindedPorts)
+		rfdock.ContainerAddDevices(devices)
+		rfdock.ContainerAddCaps(caps)
+		rfdock.ContainerAddCgroups(cgroups)
+		if err := rfdock.ContainerSetUSB(usbDevices); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		rfdock.ContainerSetPrivileges(privileged)
+		rfdock.ContainerSetSeccomp(seccomp)
+		rfdock.ContainerSetRealtime(realtime)
+		rfdock.ContainerSetUlimits(ulimits)
+		rfdock.ContainerSetResources(resources)
+		if desktop {
+			parseAndSetDesktop(desktopConfig)
 			if desktopPass != "" {
-				extraArgs["--desktop-pass"] = desktopPass
-			}
-			if desktopSSL {
-				extraArgs["--desktop-ssl"] = ""
-			}
-			if vpnConfig != "" {
-				extraArgs["--vpn"] = vpnConfig
-			}
-			if gpus != "" {
-				extraArgs["--gpus"] = gpus
-			}
-
-			if err := rfdock.ContainerRunWithRecording(dockerName, recordOutput, image, extraArgs); err != nil {
-				common.PrintErrorMessage(err)
-				os.Exit(1)
-			}
-
-			if realtime {
-				extraArgs["--realtime"] = ""
-			}
-			if ulimits != "" {
-				extraArgs["--ulimits"] = ulimits
+				rfdock.ContainerSetDesktopPassword(desktopPass)
 			}
+			rfdock.ContainerSetDesktopSSL(desktopSSL)
+		}
+		rfdock.ContainerSetVPN(vpnConfig)
+		rfdock.ContainerSetGPUs(gpus)
+		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+			rfutils.SetPulseCTL(pulseServer)
+		}
+		rfdock.ContainerSetRecordInput(recordInput)
+		rfdock.ContainerSetRecordRedact(recordRedact)
+		if recordSession {
+			rfdock.ContainerRunWithRecording(dockerName, recordOutput)
 		} else {
-			setupX11(noX11, xDisplay, true)
-			rfdock.ContainerSetShell(execCommand)
-			rfdock.ContainerAddBinding(extraBind)
-			rfdock.ContainerSetImage(image)
-			rfdock.ContainerSetExtraHosts(extraHost)
-			rfdock.ContainerSetPulse(pulseServer)
-			rfdock.ContainerSetNetworkMode(netMode)
-			rfdock.ContainerSetExposedPorts(exposedPorts)
-			rfdock.ContainerSetBindedPorts(bindedPorts)
-			rfdock.ContainerAddDevices(devices)
-			rfdock.ContainerAddCaps(caps)
-			rfdock.ContainerAddCgroups(cgroups)
-			rfdock.ContainerSetPrivileges(privileged)
-			rfdock.ContainerSetSeccomp(seccomp)
-			rfdock.ContainerSetRealtime(realtime)
-			rfdock.ContainerSetUlimits(ulimits)
-			if desktop {
-				parseAndSetDesktop(desktopConfig)
-				if desktopPass != "" {
-					rfdock.ContainerSetDesktopPassword(desktopPass)
-				}
-				rfdock.ContainerSetDesktopSSL(desktopSSL)
-			}
-			rfdock.ContainerSetVPN(vpnConfig)
-			rfdock.ContainerSetGPUs(gpus)
-			if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
-				rfutils.SetPulseCTL(pulseServer)
-			}
 			rfdock.ContainerRun(dockerName)
 		}
 	},
@@ -379,6 +370,8 @@ var execCmd = &cobra.Command{
 		noX11, _ := cmd.Flags().GetBool("no-x11")
 		recordSession, _ := cmd.Flags().GetBool("record")
 		recordOutput, _ := cmd.Flags().GetString("record-output")
+		recordInput, _ := cmd.Flags().GetBool("record-input")
+		recordRedact, _ := cmd.Flags().GetStringArray("record-redact")
 		desktop, _ := cmd.Flags().GetBool("desktop")
 		desktopConfig, _ := cmd.Flags().GetString("desktop-config")
 		desktopPass, _ := cmd.Flags().GetString("desktop-pass")
@@ -437,7 +430,9 @@ var execCmd = &cobra.Command{
 		}
 		rfdock.ContainerSetVPN(vpnConfig)
 		if recordSession {
-			if err := rfdock.ContainerExecWithRecording(contID, workingDir, recordOutput, execCommand); err != nil {
+			rfdock.ContainerSetRecordInput(recordInput)
+			rfdock.ContainerSetRecordRedact(recordRedact)
+			if err := rfdock.ContainerExecWithRecording(contID, workingDir, recordOutput); err != nil {
 				common.PrintErrorMessage(err)
 				os.Exit(1)
 			}
@@ -693,6 +688,7 @@ func registerContainerCommands() {
 	rootCmd.AddCommand(removeCmd)
 
 	runCmd.Flags().StringP("extrahosts", "x", "", "set extra hosts (default: 'pluto.local:192.168.1.2', and separate them with commas)")
+	runCmd.Flags().String("env", "", "extra environment variables as KEY=VALUE (separate them with commas)")
 	runCmd.Flags().StringP("display", "d", rfutils.GetDisplayEnv(), "set X Display (duplicates hosts's env by default)")
 	runCmd.Flags().StringP("command", "e", "", "command to exec (by default: '/bin/bash')")
 	runCmd.Flags().StringP("bind", "b", "", "extra bindings (separate them with commas)")
@@ -701,6 +697,7 @@ func registerContainerCommands() {
 	runCmd.Flags().StringP("name", "n", "", "A docker name")
 	runCmd.Flags().StringP("network", "t", "", "Network mode (default: 'host')")
 	runCmd.Flags().StringP("devices", "s", "", "extra devices mapping (separate them with commas)")
+	runCmd.Flags().StringSlice("usb", nil, "pass only these USB devices, as VID:PID[:serial] (repeatable or comma-separated; replaces the /dev/bus/usb tree)")
 	runCmd.Flags().IntP("privileged", "u", 0, "Set privilege level (1: privileged, 0: unprivileged)")
 	runCmd.Flags().StringP("capabilities", "a", "", "extra capabilities (separate them with commas)")
 	runCmd.Flags().StringP("cgroups", "g", "", "extra cgroup rules (separate them with commas)")
@@ -710,8 +707,11 @@ func registerContainerCommands() {
 	runCmd.Flags().StringP("bindedports", "w", "", "Exposed ports")
 	runCmd.Flags().Bool("record", false, "Record the container session")
 	runCmd.Flags().String("record-output", "", "Output file for recording (default: auto-generated)")
+	runCmd.Flags().Bool("record-input", false, "Also record typed keys, including passwords (with --record)")
+	runCmd.Flags().StringArray("record-redact", nil, "Extra regex to redact from the recording, on top of built-in secret patterns (repeatable)")
 	runCmd.Flags().Bool("realtime", false, "Enable realtime mode (SYS_NICE + rtprio=95 + memlock=unlimited)")
 	runCmd.Flags().String("ulimits", "", "Set ulimits (e.g., 'rtprio=95,memlock=-1,nofile=1024:65536')")
+	addResourceFlags(runCmd)
 	runCmd.Flags().Bool("desktop", false, "Enable remote desktop via VNC/noVNC (access GUI tools from a browser)")
 	runCmd.Flags().String("desktop-config", "", "Desktop config as proto:host:port (e.g., 'http:0.0.0.0:6080' or 'vnc::5900')")
 	runCmd.Flags().String("desktop-pass", "", "Set VNC password for desktop access (recommended when exposing on 0.0.0.0)")
@@ -730,6 +730,8 @@ func registerContainerCommands() {
 	execCmd.Flags().Bool("no-x11", false, "Disable X11 forwarding")
 	execCmd.Flags().Bool("record", false, "Record the container session")
 	execCmd.Flags().String("record-output", "", "Output file for recording (default: auto-generated)")
+	execCmd.Flags().Bool("record-input", false, "Also record typed keys, including passwords (with --record)")
+	execCmd.Flags().StringArray("record-redact", nil, "Extra regex to redact from the recording, on top of built-in secret patterns (repeatable)")
 	execCmd.Flags().Bool("desktop", false, "Enable remote desktop via VNC/noVNC (access GUI tools from a browser)")
 	execCmd.Flags().String("desktop-config", "", "Desktop config as proto:host:port (e.g., 'http:0.0.0.0:6080' or 'vnc::5900')")
 	execCmd.Flags().String("desktop-pass", "", "Set VNC password for desktop access (recommended when exposing on 0.0.0.0)")
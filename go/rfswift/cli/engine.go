@@ -11,6 +11,7 @@ import (
 	"runtime"
 
 	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
 	rfdock "penthertz/rfswift/dock"
 	rfutils "penthertz/rfswift/rfutils"
 	"penthertz/rfswift/tui"
@@ -140,6 +141,11 @@ var engineLimaStatusCmd = &cobra.Command{
 			return
 		}
 
+		if common.MachineOutput() {
+			printLimaStatusStructured(instance)
+			return
+		}
+
 		items := []tui.PropertyItem{
 			{Key: "Instance", Value: instance, ValueColor: tui.ColorPrimary},
 		}
@@ -270,6 +276,39 @@ Examples:
 	},
 }
 
+// limaStatus is the structured form of "engine lima status".
+type limaStatus struct {
+	Instance     string `json:"instance" yaml:"instance"`
+	Running      bool   `json:"running" yaml:"running"`
+	Config       string `json:"config" yaml:"config"`
+	Template     string `json:"template" yaml:"template"`
+	QMPSocket    string `json:"qmp_socket" yaml:"qmp_socket"`
+	DockerSocket string `json:"docker_socket" yaml:"docker_socket"`
+}
+
+// printLimaStatusStructured emits the Lima VM status as a json/yaml document.
+// Missing template or sockets are reported as empty strings.
+//
+//	in(1): string instance Lima instance name
+//	out: none
+func printLimaStatusStructured(instance string) {
+	lima := &rfdock.LimaEngine{}
+	status := limaStatus{
+		Instance:     instance,
+		Running:      rfutils.IsLimaInstanceRunning(instance),
+		Config:       rfutils.GetLimaInstanceConfigPath(instance),
+		Template:     lima.FindTemplate(),
+		DockerSocket: lima.GetSocketPath(),
+	}
+	if sockPath, err := rfutils.FindLimaQMPSocket(instance); err == nil {
+		status.QMPSocket = sockPath
+	}
+	if err := common.PrintStructured("lima", status); err != nil {
+		common.PrintErrorMessage(err)
+		os.Exit(1)
+	}
+}
+
 func registerEngineCommands() {
 	rootCmd.AddCommand(engineCmd)
 
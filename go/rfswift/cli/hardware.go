@@ -0,0 +1,45 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * CLI commands for the hardware inventory
+ */
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+)
+
+var hardwareCmd = &cobra.Command{
+	Use:   "hardware",
+	Short: "Inspect the hardware attached to the host",
+}
+
+var hardwareListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List USB, serial and GPU devices",
+	Long: `List the USB devices (from sysfs), serial ports (/dev/ttyUSB*, /dev/ttyACM*)
+and DRI nodes of the host. Known RF and hardware tools (HackRF, RTL-SDR, USRP,
+bladeRF, LimeSDR, PlutoSDR, Ubertooth, Proxmark3, Flipper Zero, JTAGulator...)
+are identified, with the running RF Swift containers that can access them and
+the profile that fits them.
+
+Use --output json or yaml for a structured document.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		hubs, _ := cmd.Flags().GetBool("all")
+		if err := rfdock.DisplayHardware(hubs); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func registerHardwareCommands() {
+	rootCmd.AddCommand(hardwareCmd)
+	hardwareCmd.AddCommand(hardwareListCmd)
+	hardwareListCmd.Flags().BoolP("all", "a", false, "include USB hubs")
+}
@@ -37,7 +37,13 @@ var ImagesLocalCmd = &cobra.Command{
 var ImagesRemoteCmd = &cobra.Command{
 	Use:   "remote",
 	Short: "List remote images",
-	Long:  `Lists RF Swift images from official repository`,
+	Long: `Lists RF Swift images from official repository.
+
+The repository comes from the configured repotag. When it carries a registry
+host (e.g. harbor.lab/rf/rfswift_resolute) the listing, version lookups and
+pulls use that registry through the OCI Distribution API, with credentials
+from 'docker login'. Loopback registries and hosts listed in
+RFSWIFT_INSECURE_REGISTRIES (comma-separated) are reached over plain HTTP.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		showVersions, _ := cmd.Flags().GetBool("show-versions")
 		filterImage, _ := cmd.Flags().GetString("filter")
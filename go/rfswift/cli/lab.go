@@ -0,0 +1,104 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * CLI commands for multi-container labs
+ */
+
+package cli
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+	rfutils "penthertz/rfswift/rfutils"
+)
+
+var labCmd = &cobra.Command{
+	Use:   "lab",
+	Short: "Manage multi-container labs",
+	Long: `Bring up and tear down a set of coordinated containers described in a
+YAML lab manifest. All containers join one shared NAT network and are started
+in dependency order.
+
+Example manifest:
+  name: 5glab
+  network:
+    subnet: 172.30.50.0/24     # optional, auto-allocated otherwise
+  containers:
+    - name: core
+      profile: telecom-5g
+    - name: gnb
+      profile: telecom-5g
+      depends_on: [core]
+    - name: capture
+      image: penthertz/rfswift_resolute:network
+      caps: NET_ADMIN,NET_RAW
+      depends_on: [core]
+
+Each container accepts a 'profile' and/or the same options as a profile
+(image, devices, bindings, caps, cgroups, desktop, vpn, gpus ...) plus
+'command', 'ulimits', 'extraenv', 'extrahosts' and 'workspace'.
+Inline values override the profile.`,
+}
+
+var labUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Create and start a lab",
+	Long:  `Create the lab NAT network and start every container of the manifest in dependency order`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		pulseServer, _ := cmd.Flags().GetString("pulseserver")
+
+		setupX11(false, rfutils.GetDisplayEnv(), true)
+		rfdock.ContainerSetPulse(pulseServer)
+		if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+			rfutils.SetPulseCTL(pulseServer)
+		}
+
+		if err := rfdock.LabUp(file); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Stop and remove a lab",
+	Long:  `Remove every container of the manifest in reverse dependency order, then the lab NAT network`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		if err := rfdock.LabDown(file); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var labStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show lab containers",
+	Long:  `Show the state of every container declared in a lab manifest`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		if err := rfdock.LabStatus(file); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func registerLabCommands() {
+	rootCmd.AddCommand(labCmd)
+	labCmd.AddCommand(labUpCmd)
+	labCmd.AddCommand(labDownCmd)
+	labCmd.AddCommand(labStatusCmd)
+
+	for _, c := range []*cobra.Command{labUpCmd, labDownCmd, labStatusCmd} {
+		c.Flags().StringP("file", "f", "lab.yaml", "Lab manifest file")
+	}
+	labUpCmd.Flags().StringP("pulseserver", "p", "tcp:127.0.0.1:34567", "PULSE SERVER TCP address (by default: tcp:127.0.0.1:34567)")
+}
@@ -17,7 +17,10 @@ import (
 var LogCmd = &cobra.Command{
 	Use:   "log",
 	Short: "Record and replay terminal sessions",
-	Long:  `Record RF Swift operations using asciinema or script command`,
+	Long: `Record RF Swift operations and replay, search or export the recordings.
+
+'run --record' and 'exec --record' write asciicast v2 files natively;
+'log start' records the host terminal with asciinema or script.`,
 }
 
 var LogStartCmd = &cobra.Command{
@@ -50,9 +53,19 @@ var LogStopCmd = &cobra.Command{
 var LogReplayCmd = &cobra.Command{
 	Use:   "replay [file]",
 	Short: "Replay a recorded session",
-	Long:  `Replay a previously recorded session. If no file is specified, pick from available recordings.`,
+	Long: `Replay a previously recorded session with the built-in player. If no file is
+specified, pick from available recordings.
+
+Controls: space pause/resume, ←/→ (or h/l) seek 5s, +/- change speed,
+'.' step one frame while paused, q quit.
+
+Examples:
+  rfswift log replay session.cast --speed 2 --idle-limit 1
+  rfswift log replay session.cast --start 12:30`,
 	Run: func(cmd *cobra.Command, args []string) {
 		speed, _ := cmd.Flags().GetFloat64("speed")
+		idleLimit, _ := cmd.Flags().GetFloat64("idle-limit")
+		start, _ := cmd.Flags().GetString("start")
 		inputFile, _ := cmd.Flags().GetString("input")
 
 		// Accept positional argument as well
@@ -94,7 +107,8 @@ var LogReplayCmd = &cobra.Command{
 			}
 		}
 
-		if err := rfdock.ReplayLog(inputFile, speed); err != nil {
+		opts := rfdock.ReplayOptions{Speed: speed, IdleLimit: idleLimit, Start: start}
+		if err := rfdock.ReplayLog(inputFile, opts); err != nil {
 			common.PrintErrorMessage(err)
 			os.Exit(1)
 		}
@@ -114,6 +128,78 @@ var LogListCmd = &cobra.Command{
 	},
 }
 
+var LogSearchCmd = &cobra.Command{
+	Use:   "search <pattern>",
+	Short: "Search the text of recorded sessions",
+	Long: `Search all recordings found by 'log list' for lines matching a regular
+expression, with escape codes stripped. Each match shows its offset in the
+recording, to replay from there with 'log replay --start'.
+
+Examples:
+  rfswift log search 'IMSI'
+  rfswift log search -i --fixed 'Found device' --dir ./workspace`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logDir, _ := cmd.Flags().GetString("dir")
+		ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+		fixed, _ := cmd.Flags().GetBool("fixed")
+		if err := rfdock.SearchLogs(logDir, args[0], ignoreCase, fixed); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var LogExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export a recording excerpt as text or cast",
+	Long: `Export a recording, or the part between --from and --to, as plain text
+without escape codes or as a trimmed asciicast file, e.g. to attach evidence
+excerpts to reports. The format follows the output extension unless --format
+is given.
+
+Examples:
+  rfswift log export session.cast --from 1:30 --to 2:45 -o excerpt.txt
+  rfswift log export session.cast --from 90 --to 165 -o excerpt.cast`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFile, _ := cmd.Flags().GetString("output")
+		format, _ := cmd.Flags().GetString("format")
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		if err := rfdock.ExportLog(args[0], outputFile, format, from, to); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var LogRedactCmd = &cobra.Command{
+	Use:   "redact <file>",
+	Short: "Redact secrets from a recording",
+	Long: `Replace secrets in a recording with [REDACTED], keeping a valid cast with the
+original timing. Built-in patterns cover Tailscale and NetBird keys given to
+--vpn, desktop passwords, Authorization headers and API tokens; add your own
+regexes with --pattern (a (?P<secret>...) group limits the redaction to it).
+
+Each pass is noted, without the secrets, in <file>.redactions.json. Sessions
+recorded with --record are redacted the same way while they are recorded.
+
+Examples:
+  rfswift log redact session.cast
+  rfswift log redact session.cast --dest shared.cast --pattern 'IMSI: (?P<secret>[0-9]+)'
+  rfswift log redact session.cast -o json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFile, _ := cmd.Flags().GetString("dest")
+		patterns, _ := cmd.Flags().GetStringArray("pattern")
+		if err := rfdock.RedactLog(args[0], outputFile, patterns); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
 func registerLoggingCommands() {
 	rootCmd.AddCommand(LogCmd)
 
@@ -121,13 +207,30 @@ func registerLoggingCommands() {
 	LogCmd.AddCommand(LogStopCmd)
 	LogCmd.AddCommand(LogReplayCmd)
 	LogCmd.AddCommand(LogListCmd)
+	LogCmd.AddCommand(LogSearchCmd)
+	LogCmd.AddCommand(LogExportCmd)
+	LogCmd.AddCommand(LogRedactCmd)
 
 	LogStartCmd.Flags().StringP("output", "o", "", "output file (default: rfswift-session-YYYYMMDD-HHMMSS.cast)")
 	LogStartCmd.Flags().Bool("use-script", false, "force use of 'script' command instead of asciinema")
 
 	LogReplayCmd.Flags().StringP("input", "i", "", "recording file to replay (interactive picker if omitted)")
 	LogReplayCmd.Flags().Float64P("speed", "s", 1.0, "playback speed (e.g., 2.0 for 2x)")
+	LogReplayCmd.Flags().Float64("idle-limit", 0, "cap pauses between events to this many seconds (default: the recording's own limit)")
+	LogReplayCmd.Flags().String("start", "", "start position (e.g., '90', '1:30')")
 	LogReplayCmd.Flags().String("dir", "", "directory to search for recordings (default: current directory)")
 
 	LogListCmd.Flags().String("dir", "", "directory to search (default: current directory)")
+
+	LogSearchCmd.Flags().String("dir", "", "directory to search (default: current directory)")
+	LogSearchCmd.Flags().BoolP("ignore-case", "i", false, "case-insensitive match")
+	LogSearchCmd.Flags().BoolP("fixed", "F", false, "match the pattern as literal text")
+
+	LogExportCmd.Flags().StringP("output", "o", "", "output file (default: stdout)")
+	LogExportCmd.Flags().String("format", "", "text or cast (default: from the output extension, else text)")
+	LogExportCmd.Flags().String("from", "", "start of the excerpt (e.g., '90', '1:30')")
+	LogExportCmd.Flags().String("to", "", "end of the excerpt (default: end of the recording)")
+
+	LogRedactCmd.Flags().StringP("dest", "d", "", "write the redacted recording here (default: redact in place)")
+	LogRedactCmd.Flags().StringArrayP("pattern", "p", nil, "extra regex to redact (repeatable)")
 }
@@ -7,7 +7,9 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -22,43 +24,83 @@ var profileCmd = &cobra.Command{
 	Long: `Manage RF Swift profiles — YAML presets for quick container creation.
 
 Profiles bundle image, network mode, features (desktop, realtime, privileged),
-and device mappings into a single named preset.
+and device mappings into a single named preset. A profile can build on others
+with 'extends: <name>' or 'extends: [a, b]': list fields (ports, caps, cgroups,
+devices, bindings) are appended, other fields are overridden.
 
 Profiles are stored as YAML files in:
   Linux:   ~/.config/rfswift/profiles/
   macOS:   ~/Library/Application Support/rfswift/profiles/
   Windows: %APPDATA%\rfswift\profiles\
 
+Shared directories (e.g. a team git checkout) can be added as read-only
+catalogs with 'rfswift profile catalog add <dir>' or RFSWIFT_PROFILE_CATALOGS.
+A personal profile shadows a catalog profile of the same name.
+
 Use 'rfswift profile init' to generate default profiles.
-Use 'rfswift profile create' to create a new profile interactively.`,
+Use 'rfswift profile create' to create a new profile interactively.
+Use 'rfswift profile export' / 'import' to share profiles as YAML bundles.
+Use 'rfswift profile lint' to validate profiles and rate their privilege risk.`,
 }
 
 var profileListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available profiles",
-	Long:  `List all profiles from the profiles directory`,
+	Long:  `List all profiles from the profiles directory and the configured catalogs`,
 	Run: func(cmd *cobra.Command, args []string) {
 		profiles := rfdock.GetAllProfiles()
+		entries, collisions := rfdock.LoadProfileCatalog()
+		for _, c := range collisions {
+			common.PrintWarningMessage(fmt.Sprintf("Profile '%s' is defined more than once: using %s, ignoring %s", c.Name, c.Used, strings.Join(c.Shadowed, ", ")))
+		}
+		if common.MachineOutput() {
+			masked := []rfdock.Profile{}
+			for _, p := range profiles {
+				masked = append(masked, p.Masked())
+			}
+			if err := common.PrintStructured("profiles", masked); err != nil {
+				common.PrintErrorMessage(err)
+			}
+			return
+		}
 		if len(profiles) == 0 {
 			common.PrintInfoMessage("No profiles found. Run 'rfswift profile init' to generate defaults or 'rfswift profile create' to create one.")
 			return
 		}
 
+		catalogs := make(map[string]string, len(entries))
+		for _, e := range entries {
+			catalogs[strings.ToLower(e.Profile.Name)] = e.Catalog
+		}
+		withCatalogs := len(rfdock.ProfileCatalogDirs()) > 0
+
 		var rows [][]string
 		for _, p := range profiles {
 			features := profileFeatures(p)
-			rows = append(rows, []string{
+			row := []string{
 				p.Name,
 				p.Description,
 				p.Image,
 				networkLabel(p.Network),
 				features,
-			})
+			}
+			if withCatalogs {
+				source := "personal"
+				if c := catalogs[strings.ToLower(p.Name)]; c != "" {
+					source = c + " (read-only)"
+				}
+				row = append(row, source)
+			}
+			rows = append(rows, row)
 		}
 
+		headers := []string{"Name", "Description", "Image", "Network", "Features"}
+		if withCatalogs {
+			headers = append(headers, "Source")
+		}
 		tui.RenderTable(tui.TableConfig{
 			Title:   fmt.Sprintf("Profiles (%s)", rfdock.ProfilesDirByPlatform()),
-			Headers: []string{"Name", "Description", "Image", "Network", "Features"},
+			Headers: headers,
 			Rows:    rows,
 		})
 	},
@@ -67,7 +109,12 @@ var profileListCmd = &cobra.Command{
 var profileShowCmd = &cobra.Command{
 	Use:   "show [name]",
 	Short: "Show profile details",
-	Long:  `Display detailed configuration for a specific profile`,
+	Long: `Display detailed configuration for a specific profile, as written in its file.
+
+With --resolved, the effective profile is shown instead: the profiles it
+extends are merged (list fields such as caps, devices and bindings are
+appended, other fields are overridden) and each value names the profile(s)
+it comes from.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		name, _ := cmd.Flags().GetString("name")
 
@@ -93,12 +140,39 @@ var profileShowCmd = &cobra.Command{
 			return
 		}
 
-		p, err := rfdock.GetProfileByName(name)
+		resolve, _ := cmd.Flags().GetBool("resolved")
+		var (
+			p        *rfdock.Profile
+			resolved *rfdock.ResolvedProfile
+			err      error
+		)
+		if resolve {
+			resolved, err = rfdock.ResolveProfile(name)
+			if resolved != nil {
+				p = &resolved.Profile
+			}
+		} else {
+			p, err = rfdock.GetUnresolvedProfile(name)
+		}
 		if err != nil {
 			common.PrintErrorMessage(err)
 			return
 		}
 
+		if common.MachineOutput() {
+			if resolved != nil {
+				masked := *resolved
+				masked.Profile = resolved.Profile.Masked()
+				err = common.PrintStructured("resolved_profile", masked)
+			} else {
+				err = common.PrintStructured("profile", p.Masked())
+			}
+			if err != nil {
+				common.PrintErrorMessage(err)
+			}
+			return
+		}
+
 		network := p.Network
 		if network == "" {
 			network = "host"
@@ -106,6 +180,7 @@ var profileShowCmd = &cobra.Command{
 
 		items := map[string]string{
 			"Name":         p.Name,
+			"Extends":      valueOrDash(strings.Join(p.Extends, ", ")),
 			"Description":  p.Description,
 			"Image":        p.Image,
 			"Network":      networkLabel(network),
@@ -122,16 +197,117 @@ var profileShowCmd = &cobra.Command{
 			"GPUs":         valueOrDash(p.GPUs),
 			"VPN":          valueOrDash(p.VPN),
 		}
-		keys := []string{"Name", "Description", "Image", "Network", "Ports", "Capabilities", "Cgroups", "GPUs", "Desktop", "Desktop SSL", "X11", "Privileged", "Realtime", "Devices", "Bindings", "VPN"}
+		keys := []string{"Name", "Extends", "Description", "Image", "Network", "Ports", "Capabilities", "Cgroups", "GPUs", "Desktop", "Desktop SSL", "X11", "Privileged", "Realtime", "Devices", "Bindings", "VPN"}
+
+		// Run options most profiles leave alone are only listed when set
+		optional := []struct{ label, value string }{
+			{"Desktop config", p.DesktopConfig()},
+			{"Desktop password", maskedValue(p.DesktopPass)},
+			{"Ulimits", p.Ulimits},
+			{"Environment", p.ExtraEnv},
+			{"Extra hosts", p.ExtraHosts},
+			{"Seccomp", p.Seccomp},
+			{"Shell", p.Shell},
+			{"Workspace", p.Workspace},
+			{"Pulse server", p.PulseServer},
+			{"Display", p.Display},
+			{"CPUs", p.CPUs},
+			{"Memory", p.Memory},
+			{"CPU pinning", p.CpusetCpus},
+			{"Shared memory", p.ShmSize},
+			{"Processes", p.PidsLimit},
+		}
+		for _, o := range optional {
+			if o.value != "" {
+				items[o.label] = o.value
+				keys = append(keys, o.label)
+			}
+		}
 
-		tui.PrintRecap(fmt.Sprintf("Profile: %s", p.Name), items, keys)
+		title := fmt.Sprintf("Profile: %s", p.Name)
+		if resolved != nil {
+			// Annotate each value with the profiles it comes from
+			for label, yamlKeys := range profileShowKeys {
+				var from []string
+				for _, key := range yamlKeys {
+					for _, src := range resolved.Sources[key] {
+						if !containsName(from, src) {
+							from = append(from, src)
+						}
+					}
+				}
+				if _, shown := items[label]; shown && len(from) > 0 {
+					items[label] += "  ← " + strings.Join(from, ", ")
+				}
+			}
+			title = fmt.Sprintf("Profile: %s (resolved: %s)", p.Name, strings.Join(resolved.Chain, " → "))
+		}
+		tui.PrintRecap(title, items, keys)
 
-		// Show equivalent run command
+		// Show equivalent run command, which applies the resolved profile
+		if resolved == nil && len(p.Extends) > 0 {
+			if p, err = rfdock.GetProfileByName(name); err != nil {
+				common.PrintErrorMessage(err)
+				return
+			}
+		}
 		cmdStr := profileToCLICommand(p)
 		tui.PrintCLIEquivalent(cmdStr)
 	},
 }
 
+// profileShowKeys maps the labels of 'profile show' to the YAML keys of the
+// values they display.
+var profileShowKeys = map[string][]string{
+	"Description":      {"description"},
+	"Image":            {"image"},
+	"Network":          {"network"},
+	"Ports":            {"port_bindings"},
+	"Capabilities":     {"caps"},
+	"Cgroups":          {"cgroups"},
+	"GPUs":             {"gpus"},
+	"Desktop":          {"desktop"},
+	"Desktop SSL":      {"desktop_ssl"},
+	"X11":              {"no_x11"},
+	"Privileged":       {"privileged"},
+	"Realtime":         {"realtime"},
+	"Devices":          {"devices"},
+	"Bindings":         {"bindings"},
+	"VPN":              {"vpn"},
+	"Desktop config":   {"desktop_proto", "desktop_host", "desktop_port"},
+	"Desktop password": {"desktop_pass"},
+	"Ulimits":          {"ulimits"},
+	"Environment":      {"extraenv"},
+	"Extra hosts":      {"extrahosts"},
+	"Seccomp":          {"seccomp"},
+	"Shell":            {"shell"},
+	"Workspace":        {"workspace"},
+	"Pulse server":     {"pulse_server"},
+	"Display":          {"display"},
+	"CPUs":             {"cpus"},
+	"Memory":           {"memory"},
+	"CPU pinning":      {"cpuset_cpus"},
+	"Shared memory":    {"shm_size"},
+	"Processes":        {"pids_limit"},
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedValue hides a secret while still showing that it is set.
+func maskedValue(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
+
 var profileCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new profile interactively",
@@ -171,12 +347,27 @@ var profileCreateCmd = &cobra.Command{
 			VPN:          p.VPN,
 		}
 
+		lint := rfdock.CheckProfile(*profile)
+		for _, f := range lint.Findings {
+			msg := fmt.Sprintf("%s: %s", f.Field, f.Message)
+			if f.Severity == rfdock.ProfileError {
+				common.PrintErrorMessage(fmt.Errorf("%s", msg))
+			} else {
+				common.PrintWarningMessage(msg)
+			}
+		}
+		if !lint.Valid {
+			common.PrintErrorMessage(fmt.Errorf("profile '%s' not saved: fix the errors above", profile.Name))
+			return
+		}
+
 		if err := rfdock.SaveProfile(profile); err != nil {
 			common.PrintErrorMessage(err)
 			return
 		}
 
 		common.PrintSuccessMessage(fmt.Sprintf("Profile '%s' saved to %s", profile.Name, rfdock.ProfilesDirByPlatform()))
+		common.PrintInfoMessage(fmt.Sprintf("Risk: %d/100 (%s): %s", lint.Risk.Score, lint.Risk.Level, lint.Risk.Explain()))
 	},
 }
 
@@ -206,6 +397,193 @@ Existing profiles are not overwritten unless --force is used.`,
 	},
 }
 
+var profileExportCmd = &cobra.Command{
+	Use:   "export <name...>",
+	Short: "Export profiles to a YAML bundle",
+	Long: `Write one or more profiles, as written in their files, to a multi-document
+YAML bundle. The profiles they extend are included so the bundle can be
+imported on its own. Without -o the bundle is printed to stdout.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+
+		var buf bytes.Buffer
+		exported, err := rfdock.ExportProfiles(args, &buf)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if output == "" {
+			// Banners and notices went to stderr (see common.RawOutputCommands)
+			common.DocumentWriter().Write(buf.Bytes())
+			return
+		}
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			common.PrintErrorMessage(fmt.Errorf("failed to write bundle: %w", err))
+			os.Exit(1)
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("%d profile(s) exported to %s: %s", len(exported), output, strings.Join(exported, ", ")))
+	},
+}
+
+var profileImportCmd = &cobra.Command{
+	Use:   "import <file|dir|url>",
+	Short: "Import profiles from a bundle, a directory or a URL",
+	Long: `Import profiles into the profiles directory from a YAML bundle (as written by
+'rfswift profile export'), a single profile file, a directory of profile files
+or an http(s) URL.
+
+--on-conflict decides what happens when a profile name is already in use:
+  skip       keep the existing profile (default)
+  overwrite  replace it
+  rename     import it as <name>-2, <name>-3 ... (profiles of the same import
+             extending it follow the new name)`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, _ := cmd.Flags().GetString("on-conflict")
+
+		results, err := rfdock.ImportProfiles(args[0], mode)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if common.MachineOutput() {
+			if err := common.PrintStructured("profile_import", results); err != nil {
+				common.PrintErrorMessage(err)
+			}
+			return
+		}
+
+		var rows [][]string
+		for _, r := range results {
+			name := r.Name
+			if r.NewName != "" {
+				name += " → " + r.NewName
+			}
+			rows = append(rows, []string{name, r.Action, valueOrDash(r.Path)})
+		}
+		tui.RenderTable(tui.TableConfig{
+			Title:   fmt.Sprintf("Import from %s", args[0]),
+			Headers: []string{"Profile", "Action", "File"},
+			Rows:    rows,
+		})
+	},
+}
+
+var profileLintCmd = &cobra.Command{
+	Use:   "lint [name...]",
+	Short: "Validate profiles and rate their privilege risk",
+	Long: `Check profiles (all of them when no name is given) after inheritance:
+image references, device and binding paths, capability names, cgroup rule
+syntax, port bindings, GPU values, ulimits and environment entries.
+
+Each profile also gets a risk score from 0 to 100 with the settings behind
+it, e.g. "privileged + host network + SYS_ADMIN". Warnings flag departures
+from least privilege: privileged mode, ALL capabilities and cgroup rules
+covering every device.
+
+Exits with status 1 when a profile is invalid, when --strict is set and a
+profile has warnings, or when a score exceeds --max-risk.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		strict, _ := cmd.Flags().GetBool("strict")
+		maxRisk, _ := cmd.Flags().GetInt("max-risk")
+
+		results, err := rfdock.LintProfiles(args)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if len(results) == 0 {
+			common.PrintInfoMessage("No profiles found. Run 'rfswift profile init' to generate defaults.")
+			return
+		}
+		if err := rfdock.DisplayProfileLint(results); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, r := range results {
+			if !r.Valid || (strict && r.Warnings() > 0) || r.Risk.Score > maxRisk {
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var profileCatalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Manage read-only profile catalogs",
+	Long: `Manage the shared profile directories scanned, read-only, after the personal
+profiles directory. Catalogs are listed in the 'profile-catalogs' file of the
+RF Swift config directory; RFSWIFT_PROFILE_CATALOGS (separated like PATH) adds
+more, with a higher priority.`,
+}
+
+var profileCatalogListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profile catalogs",
+	Run: func(cmd *cobra.Command, args []string) {
+		dirs := rfdock.ProfileCatalogDirs()
+		entries, _ := rfdock.LoadProfileCatalog()
+		counts := make(map[string]int)
+		for _, e := range entries {
+			counts[e.Catalog]++
+		}
+		if common.MachineOutput() {
+			if dirs == nil {
+				dirs = []string{}
+			}
+			if err := common.PrintStructured("profile_catalogs", dirs); err != nil {
+				common.PrintErrorMessage(err)
+			}
+			return
+		}
+		if len(dirs) == 0 {
+			common.PrintInfoMessage("No profile catalog configured. Add one with 'rfswift profile catalog add <dir>'.")
+			return
+		}
+		var rows [][]string
+		for _, d := range dirs {
+			rows = append(rows, []string{d, fmt.Sprintf("%d", counts[d])})
+		}
+		tui.RenderTable(tui.TableConfig{
+			Title:   "Profile catalogs",
+			Headers: []string{"Directory", "Profiles"},
+			Rows:    rows,
+		})
+	},
+}
+
+var profileCatalogAddCmd = &cobra.Command{
+	Use:   "add <dir>",
+	Short: "Add a read-only profile catalog",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rfdock.AddProfileCatalog(args[0]); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("Profile catalog %s added", args[0]))
+	},
+}
+
+var profileCatalogRmCmd = &cobra.Command{
+	Use:   "rm <dir>",
+	Short: "Remove a profile catalog",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rfdock.RemoveProfileCatalog(args[0]); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("Profile catalog %s removed", args[0]))
+	},
+}
+
 var profileDeleteCmd = &cobra.Command{
 	Use:   "delete [name]",
 	Short: "Delete a profile",
@@ -257,10 +635,22 @@ func registerProfileCommands() {
 	profileCmd.AddCommand(profileCreateCmd)
 	profileCmd.AddCommand(profileInitCmd)
 	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileExportCmd)
+	profileCmd.AddCommand(profileImportCmd)
+	profileCmd.AddCommand(profileCatalogCmd)
+	profileCmd.AddCommand(profileLintCmd)
+	profileCatalogCmd.AddCommand(profileCatalogListCmd)
+	profileCatalogCmd.AddCommand(profileCatalogAddCmd)
+	profileCatalogCmd.AddCommand(profileCatalogRmCmd)
 
 	profileShowCmd.Flags().StringP("name", "n", "", "Profile name")
+	profileShowCmd.Flags().Bool("resolved", false, "Show the effective profile after inheritance, with the source of each value")
 	profileDeleteCmd.Flags().StringP("name", "n", "", "Profile name")
 	profileInitCmd.Flags().Bool("force", false, "Overwrite existing profile files")
+	profileExportCmd.Flags().StringP("output", "o", "", "Bundle file to write (default: stdout)")
+	profileLintCmd.Flags().Bool("strict", false, "Fail on warnings as well as errors")
+	profileLintCmd.Flags().Int("max-risk", 100, "Fail when a profile's risk score is above this value")
+	profileImportCmd.Flags().String("on-conflict", rfdock.ImportSkip, "What to do when a profile name is in use: skip, overwrite or rename")
 }
 
 // profileFeatures returns a comma-separated list of enabled features.
@@ -377,5 +767,56 @@ func profileToCLICommand(p *rfdock.Profile) string {
 	if p.VPN != "" {
 		parts = append(parts, fmt.Sprintf("--vpn %s", p.VPN))
 	}
+	if p.DesktopConfig() != "" {
+		parts = append(parts, fmt.Sprintf("--desktop-config %s", p.DesktopConfig()))
+	}
+	if p.DesktopPass != "" {
+		parts = append(parts, "--desktop-pass <password>")
+	}
+	if p.Ulimits != "" {
+		parts = append(parts, fmt.Sprintf("--ulimits %s", p.Ulimits))
+	}
+	if p.ExtraEnv != "" {
+		parts = append(parts, fmt.Sprintf("--env %s", p.ExtraEnv))
+	}
+	if p.ExtraHosts != "" {
+		parts = append(parts, fmt.Sprintf("-x %s", p.ExtraHosts))
+	}
+	if p.Seccomp != "" {
+		parts = append(parts, fmt.Sprintf("-m %s", p.Seccomp))
+	}
+	if p.Shell != "" {
+		parts = append(parts, fmt.Sprintf("-e %s", p.Shell))
+	}
+	switch p.Workspace {
+	case "":
+	case "none":
+		parts = append(parts, "--no-workspace")
+	case "cwd":
+		parts = append(parts, "--cwd")
+	default:
+		parts = append(parts, fmt.Sprintf("--workspace %s", p.Workspace))
+	}
+	if p.PulseServer != "" {
+		parts = append(parts, fmt.Sprintf("-p %s", p.PulseServer))
+	}
+	if p.Display != "" {
+		parts = append(parts, fmt.Sprintf("-d %s", p.Display))
+	}
+	if p.CPUs != "" {
+		parts = append(parts, fmt.Sprintf("--cpus %s", p.CPUs))
+	}
+	if p.Memory != "" {
+		parts = append(parts, fmt.Sprintf("--memory %s", p.Memory))
+	}
+	if p.CpusetCpus != "" {
+		parts = append(parts, fmt.Sprintf("--cpuset-cpus %s", p.CpusetCpus))
+	}
+	if p.ShmSize != "" {
+		parts = append(parts, fmt.Sprintf("--shm-size %s", p.ShmSize))
+	}
+	if p.PidsLimit != "" {
+		parts = append(parts, fmt.Sprintf("--pids-limit %s", p.PidsLimit))
+	}
 	return strings.Join(parts, " ")
 }
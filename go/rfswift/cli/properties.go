@@ -5,9 +5,14 @@
 package cli
 
 import (
+	"context"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
 	rfdock "penthertz/rfswift/dock"
 )
 
@@ -115,6 +120,83 @@ var CgroupsRmCmd = &cobra.Command{
 	},
 }
 
+var USBCmd = &cobra.Command{
+	Use:   "usb",
+	Short: "Manage per-device USB passthrough",
+	Long:  `Grant or revoke single USB devices, selected by VID:PID[:serial], instead of the whole /dev/bus/usb tree`,
+}
+
+var USBAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Grant USB devices",
+	Long:  `Map the USB devices matching VID:PID[:serial] into a container with exact cgroup rules (e.g., '1d50:6089')`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		devices, _ := cmd.Flags().GetStringSlice("device")
+		if err := rfdock.UpdateUSBDevices(contID, devices, true); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var USBRmCmd = &cobra.Command{
+	Use:   "rm",
+	Short: "Revoke USB devices",
+	Long:  `Remove the USB devices matching VID:PID[:serial] and their cgroup rules from a container. Devices are matched against those recorded on the container, so unplugged devices can be revoked`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		devices, _ := cmd.Flags().GetStringSlice("device")
+		if err := rfdock.UpdateUSBDevices(contID, devices, false); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var USBWatchCmd = &cobra.Command{
+	Use:   "watch <container>",
+	Short: "Follow USB hotplug into a running container",
+	Long: `Listen to kernel uevents and mirror the matching USB devices into a running
+container: a plugged device gets its node created inside the container (exec
+mknod) and is allowed in the container's devices cgroup, an unplugged one is
+removed. This keeps containers created with explicit device nodes (--usb)
+working when a dongle is replugged and gets a new bus/device number.
+
+Without --device, the container's USB selectors (from --usb and 'usb add',
+less 'usb rm') are followed. Every change is logged until Ctrl+C.
+
+On cgroup v2 hosts the device rules of a running container cannot change, so
+a replugged device with a new minor number cannot be allowed. Following is
+refused there unless the container already allows every USB device, e.g.
+  rfswift cgroups add -c <container> -r "c 189:* rwm"
+Otherwise re-grant a replugged device with 'rfswift usb add' (cgroup v1 and
+privileged containers need nothing).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		devices, _ := cmd.Flags().GetStringSlice("device")
+		logFile, _ := cmd.Flags().GetString("log")
+
+		var log io.Writer = os.Stdout
+		if logFile != "" {
+			f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				common.PrintErrorMessage(err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			log = io.MultiWriter(os.Stdout, f)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := rfdock.WatchUSBDevices(ctx, args[0], devices, log); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
 var PortsCmd = &cobra.Command{
 	Use:   "ports",
 	Short: "Manage container ports",
@@ -211,6 +293,7 @@ func registerPropertyCommands() {
 	rootCmd.AddCommand(CgroupsCmd)
 	rootCmd.AddCommand(GPUsCmd)
 	rootCmd.AddCommand(PortsCmd)
+	rootCmd.AddCommand(USBCmd)
 
 	// Bindings
 	BindingsCmd.AddCommand(BindingsAddCmd)
@@ -282,4 +365,19 @@ func registerPropertyCommands() {
 	PortsUnbindCmd.Flags().StringP("binding", "b", "", "port binding to remove")
 	PortsUnbindCmd.MarkFlagRequired("container")
 	PortsUnbindCmd.MarkFlagRequired("binding")
+
+	// USB devices
+	USBCmd.AddCommand(USBAddCmd)
+	USBCmd.AddCommand(USBRmCmd)
+	USBCmd.AddCommand(USBWatchCmd)
+	USBAddCmd.Flags().StringP("container", "c", "", "container ID or name")
+	USBAddCmd.Flags().StringSliceP("device", "d", nil, "USB device as VID:PID[:serial] (e.g., '1d50:6089'; repeatable)")
+	USBAddCmd.MarkFlagRequired("container")
+	USBAddCmd.MarkFlagRequired("device")
+	USBRmCmd.Flags().StringP("container", "c", "", "container ID or name")
+	USBRmCmd.Flags().StringSliceP("device", "d", nil, "USB device to remove as VID:PID[:serial]")
+	USBRmCmd.MarkFlagRequired("container")
+	USBRmCmd.MarkFlagRequired("device")
+	USBWatchCmd.Flags().StringSliceP("device", "d", nil, "USB device to follow as VID:PID[:serial] (default: the container's --usb devices)")
+	USBWatchCmd.Flags().String("log", "", "also append the change log to this file")
 }
@@ -24,7 +24,7 @@ Reports combine container metadata, session recordings, shell history,
 and workspace artifacts into a single document for documentation,
 client deliverables, or research papers.
 
-Supported formats: markdown (default), html, pdf (requires pandoc)`,
+Supported formats: markdown (default), html, pdf (built in, no external tools)`,
 }
 
 var reportGenerateCmd = &cobra.Command{
@@ -34,9 +34,11 @@ var reportGenerateCmd = &cobra.Command{
 structured report. The report includes:
 
   - Container configuration and metadata
+  - Timeline of commands, recording sessions and file changes
   - Session recordings inventory
   - Shell command history
-  - Workspace file artifacts (captures, configs, logs, scripts)
+  - Workspace file artifacts (captures, configs, logs, scripts),
+    with image thumbnails in PDF reports
   - Editable notes section
 
 Examples:
@@ -0,0 +1,92 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+)
+
+var ResourcesCmd = &cobra.Command{
+	Use:   "resources",
+	Short: "Manage container CPU, memory and process limits",
+	Long: `Show or change the engine resource limits of a container.
+
+CPU, memory, CPU pinning and process limits are applied live through the
+engine update API, without restarting the container. The shared memory size
+(/dev/shm, used by GNU Radio flowgraphs) and removing a CPU, memory or pinning
+limit need the container configuration to be updated, which stops it.
+
+Use 0 or 'none' to remove a limit ('none' only for --cpuset-cpus).`,
+}
+
+var ResourcesSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Change resource limits",
+	Long: `Change the resource limits of a container. Options left out are kept.
+
+Examples:
+  rfswift resources set -c mycontainer --cpus 2 --memory 4g
+  rfswift resources set -c mycontainer --cpuset-cpus 2-3 --pids-limit 1024
+  rfswift resources set -c mycontainer --shm-size 1g
+  rfswift resources set -c mycontainer --memory none`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		if err := rfdock.UpdateResources(contID, runResourceFlags(cmd)); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var ResourcesShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show resource limits",
+	Long:  `Display the CPU, memory, CPU pinning, shared memory and process limits of a container`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		if err := rfdock.ShowContainerResources(contID); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// addResourceFlags declares the resource limit flags shared by run and
+// resources set.
+func addResourceFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cpus", "", "Number of CPUs (e.g., '2' or '1.5')")
+	cmd.Flags().String("memory", "", "Memory limit (e.g., '512m', '4g')")
+	cmd.Flags().String("cpuset-cpus", "", "CPUs the container may run on (e.g., '0-3' or '0,2')")
+	cmd.Flags().String("shm-size", "", "Size of /dev/shm (e.g., '1g'; engine default: 64m)")
+	cmd.Flags().String("pids-limit", "", "Maximum number of processes ('unlimited' to remove the limit)")
+}
+
+// runResourceFlags reads the flags declared by addResourceFlags.
+func runResourceFlags(cmd *cobra.Command) rfdock.ResourceLimits {
+	cpus, _ := cmd.Flags().GetString("cpus")
+	memory, _ := cmd.Flags().GetString("memory")
+	cpuset, _ := cmd.Flags().GetString("cpuset-cpus")
+	shmSize, _ := cmd.Flags().GetString("shm-size")
+	pidsLimit, _ := cmd.Flags().GetString("pids-limit")
+	return rfdock.ResourceLimits{CPUs: cpus, Memory: memory, CpusetCpus: cpuset, ShmSize: shmSize, PidsLimit: pidsLimit}
+}
+
+func registerResourcesCommands() {
+	rootCmd.AddCommand(ResourcesCmd)
+
+	ResourcesCmd.AddCommand(ResourcesSetCmd)
+	ResourcesCmd.AddCommand(ResourcesShowCmd)
+
+	ResourcesSetCmd.Flags().StringP("container", "c", "", "container ID or name")
+	addResourceFlags(ResourcesSetCmd)
+	ResourcesSetCmd.MarkFlagRequired("container")
+
+	ResourcesShowCmd.Flags().StringP("container", "c", "", "container ID or name")
+	ResourcesShowCmd.MarkFlagRequired("container")
+}
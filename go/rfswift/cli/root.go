@@ -110,9 +110,18 @@ func init() {
 	rootCmd.PersistentFlags().Bool("gpu", false,
 		"Use the GPU-accelerated Lima VM on macOS Apple Silicon (krunkit/Vulkan). Implies --engine lima; provides GPU compute but NOT USB passthrough")
 	rootCmd.PersistentFlags().BoolVarP(&common.Disconnected, "disconnect", "q", false, "Don't query updates (disconnected mode)")
+	rootCmd.PersistentFlags().StringVarP(&common.OutputFormat, "output", "o", common.OutputTable,
+		"Output format for list and status commands: table, json, yaml")
 
 	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		isCompletion := len(os.Args) > 1 && (os.Args[1] == "completion" || os.Args[1] == "__complete")
+		if err := common.ApplyOutputFormat(); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if common.RawOutputRequested(os.Args[1:]) {
+			common.RouteHumanOutput()
+		}
 		if !isCompletion {
 			// Initialize container engine BEFORE anything else
 			engineType, _ := cmd.Flags().GetString("engine")
@@ -131,6 +140,11 @@ func init() {
 			// Trigger detection (sets DOCKER_HOST for Podman)
 			rfdock.GetEngine()
 
+			// Keep structured output free of version banners and notices
+			if common.MachineOutput() {
+				return
+			}
+
 			rfutils.DisplayVersion()
 
 			// Nudge the user when the configured repository still points at an
@@ -150,6 +164,7 @@ func init() {
 	registerCleanupCommands()
 	registerLoggingCommands()
 	registerUlimitsCommands()
+	registerResourcesCommands()
 	registerCompletionCommands()
 	registerHostCommands()
 	if runtime.GOOS == "windows" {
@@ -160,9 +175,30 @@ func init() {
 	}
 	registerEngineCommands()
 	registerNetworkCommands()
+	registerLabCommands()
+	registerSnapshotCommands()
 	registerProfileCommands()
 	registerReportCommands()
 	registerDoctorCommands()
+	registerHardwareCommands()
+	registerTopCommands()
+	registerStatusCommands()
+}
+
+// MachineOutputRequested reports whether json or yaml output was requested on
+// the command line, before cobra parses it. Commands with their own
+// -o/--output flag (an output file) are left out: their local flag shadows
+// the global one.
+//
+//	in(1): []string args command-line arguments without the program name
+//	out: bool true if json or yaml output was requested
+func MachineOutputRequested(args []string) bool {
+	if cmd, _, err := rootCmd.Find(args); err == nil {
+		if f := cmd.Flags().Lookup("output"); f != nil && f != rootCmd.PersistentFlags().Lookup("output") {
+			return false
+		}
+	}
+	return common.MachineOutputRequested(args)
 }
 
 // Execute runs the root cobra command, invoking the appropriate subcommand based on
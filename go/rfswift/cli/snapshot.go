@@ -0,0 +1,141 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * CLI commands for container snapshots
+ */
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+	"penthertz/rfswift/tui"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage container snapshots",
+	Long: `Take named snapshots of a container and restore them later.
+
+A snapshot commits the container filesystem to a local image and records the
+container's run properties and host configuration (bindings, devices, cgroup
+rules, capabilities, network...), so 'snapshot restore' recreates the container
+exactly as it was configured when the snapshot was taken.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Snapshot a container",
+	Long:  `Commit the current state of a container to a named snapshot (a timestamp is used when no name is given)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		name, _ := cmd.Flags().GetString("name")
+
+		if contID == "" && tui.IsInteractive() {
+			contID = pickContainer("Select a container to snapshot")
+		}
+		if contID == "" {
+			common.PrintErrorMessage(fmt.Errorf("container is required (use -c flag)"))
+			os.Exit(1)
+		}
+
+		if err := rfdock.SnapshotCreate(contID, name); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots",
+	Long:  `List stored snapshots, optionally only those of one container`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		rfdock.DisplaySnapshots(contID)
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Recreate a container from a snapshot",
+	Long: `Recreate a container from a snapshot with the host configuration it had when
+the snapshot was taken. An existing container with the same name is replaced
+(after confirmation, or directly with --force). The restored container is not
+started; use 'rfswift exec -c <container>' to enter it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		force, _ := cmd.Flags().GetBool("force")
+
+		if err := rfdock.SnapshotRestore(contID, args[0], force); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot",
+	Long:  `Remove a stored snapshot image`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+
+		if err := rfdock.SnapshotDelete(contID, args[0]); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show filesystem changes of a container",
+	Long: `Show files added (A), changed (C) and deleted (D) in a container since it was
+created from its image, or from the snapshot it was restored from`,
+	Run: func(cmd *cobra.Command, args []string) {
+		contID, _ := cmd.Flags().GetString("container")
+		path, _ := cmd.Flags().GetString("path")
+
+		if contID == "" && tui.IsInteractive() {
+			contID = pickContainer("Select a container to diff")
+		}
+		if contID == "" {
+			common.PrintErrorMessage(fmt.Errorf("container is required (use -c flag)"))
+			os.Exit(1)
+		}
+
+		if err := rfdock.SnapshotDiff(contID, path); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func registerSnapshotCommands() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+
+	snapshotCreateCmd.Flags().StringP("container", "c", "", "container to snapshot")
+	snapshotCreateCmd.Flags().StringP("name", "n", "", "snapshot name (default: current timestamp)")
+
+	snapshotListCmd.Flags().StringP("container", "c", "", "only list snapshots of this container")
+
+	snapshotRestoreCmd.Flags().StringP("container", "c", "", "container the snapshot belongs to (needed when several containers have a snapshot with that name)")
+	snapshotRestoreCmd.Flags().BoolP("force", "f", false, "replace an existing container without asking")
+
+	snapshotDeleteCmd.Flags().StringP("container", "c", "", "container the snapshot belongs to")
+
+	snapshotDiffCmd.Flags().StringP("container", "c", "", "container to inspect")
+	snapshotDiffCmd.Flags().String("path", "", "only show changes under this path")
+}
@@ -0,0 +1,46 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * CLI command for the container subsystem health checks
+ */
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status <container>",
+	Short: "Check the desktop, VPN, audio and X11 subsystems of a container",
+	Long: `Actively probe the subsystems of a running container and report each as
+passed, warning or failed, with a hint on how to fix it:
+
+  Desktop  the VNC/noVNC port listens in the container and is reachable from the host
+  VPN      WireGuard/OpenVPN interface up with an address and a route,
+           Tailscale/NetBird connected with an address
+  Audio    the PULSE_SERVER of the container is reachable from inside it
+  X11      the X socket of DISPLAY exists and the X server accepts the container
+
+The command exits with status 1 when a check fails, e.g. for scripts:
+  rfswift status mycontainer --output json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, err := rfdock.RunContainerStatus(args[0])
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func registerStatusCommands() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,56 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * CLI command for the live container resource monitor
+ */
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	common "penthertz/rfswift/common"
+	rfdock "penthertz/rfswift/dock"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top [container...]",
+	Short: "Monitor the resource usage of running containers",
+	Long: `Stream the engine stats of the running RF Swift containers (or of the
+containers given) and refresh a table of their CPU, memory, network I/O,
+block I/O and process count until Ctrl+C.
+
+CPU % follows 'docker stats': 100% is one full CPU. Throttled counts the
+scheduler periods in which the container hit its CPU limit; a growing value
+while an SDR tool reports overruns points at CPU contention (see
+'rfswift resources').
+
+Use --once to print a single sample, e.g. 'rfswift top --once --output json'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		once, _ := cmd.Flags().GetBool("once")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval < time.Second {
+			common.PrintErrorMessage(fmt.Errorf("--interval must be at least 1s"))
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := rfdock.TopContainers(ctx, args, once, interval); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func registerTopCommands() {
+	rootCmd.AddCommand(topCmd)
+	topCmd.Flags().Bool("once", false, "print a single sample and exit")
+	topCmd.Flags().Duration("interval", 2*time.Second, "refresh interval")
+}
@@ -52,22 +52,120 @@ Examples:
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build an image from a recipe",
-	Long:  `Build a Docker image from a simplified YAML recipe file`,
+	Long: `Build a Docker image from a simplified YAML recipe file.
+
+Recipes may define named builder stages under 'stages:' (copy their artifacts
+with a copy step using 'from: <stage>'), and use arg, env, user and entrypoint
+steps. ${VAR} references are replaced with values from the recipe's 'vars:'
+block or from --set KEY=VALUE, which takes precedence. Unknown references are
+left for the build (ARG/ENV/shell variables); write $${VAR} for a literal ${VAR}.
+
+A recipe can inherit another one with 'extends: <file>' and splice shared step
+fragments with '- include: <file>' steps; both paths are relative to the recipe
+that references them. Use --render to print the fully expanded Dockerfile
+without building.
+
+--platforms linux/amd64,linux/arm64 builds one image per architecture (through
+docker buildx when available) tagged like the official images: <tag>_amd64,
+<tag>_arm64, <tag>_riscv64. Add --manifest to group them under a <tag>
+manifest list (Docker only accepts images already pushed to a registry).
+
+Each step is hashed with the files it copies; the hashes and the image built
+after each step are kept next to the recipe (.<recipe>.rfswift-cache.json), so
+a rebuild starts from the last unchanged step and only sends the files the
+remaining steps need. --cache-from adds images as layer cache sources. A
+summary of reused, cached and built steps with their durations ends the build.
+
+The build output is saved to a log file (--log, or a timestamped file in the
+build-logs directory next to the profiles). When a step fails, the recipe step,
+its source line, the script function that failed and the last --tail lines of
+its output are printed.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		recipeFile, _ := cmd.Flags().GetString("recipe")
 		tagName, _ := cmd.Flags().GetString("tag")
 		noCache, _ := cmd.Flags().GetBool("no-cache")
+		sets, _ := cmd.Flags().GetStringArray("set")
+
+		render, _ := cmd.Flags().GetBool("render")
+		platformsSpec, _ := cmd.Flags().GetString("platforms")
+		manifest, _ := cmd.Flags().GetBool("manifest")
+		cacheFrom, _ := cmd.Flags().GetStringSlice("cache-from")
+		logFile, _ := cmd.Flags().GetString("log")
+		tailLines, _ := cmd.Flags().GetInt("tail")
+
+		vars, err := rfdock.ParseRecipeVars(sets)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+
+		if render {
+			dockerfile, err := rfdock.RenderRecipe(recipeFile, tagName, vars)
+			if err != nil {
+				common.PrintErrorMessage(err)
+				os.Exit(1)
+			}
+			fmt.Fprint(common.DocumentWriter(), dockerfile)
+			return
+		}
 
-		if err := rfdock.BuildFromRecipe(recipeFile, tagName, noCache); err != nil {
+		opts := rfdock.RecipeBuildOptions{Tag: tagName, NoCache: noCache, Vars: vars, CacheFrom: cacheFrom, LogFile: logFile, TailLines: tailLines}
+		if platformsSpec != "" {
+			opts.Platforms, err = rfdock.ParseBuildPlatforms(platformsSpec)
+			if err != nil {
+				common.PrintErrorMessage(err)
+				os.Exit(1)
+			}
+			opts.Manifest = manifest
+		} else if manifest {
+			common.PrintErrorMessage(fmt.Errorf("--manifest requires --platforms"))
+			os.Exit(1)
+		}
+
+		if err := rfdock.BuildFromRecipe(recipeFile, opts); err != nil {
 			common.PrintErrorMessage(err)
 			os.Exit(1)
 		}
 	},
 }
 
+var buildValidateCmd = &cobra.Command{
+	Use:   "validate <recipe>",
+	Short: "Check a recipe without building it",
+	Long: `Check a recipe without building it and report every problem at once, with
+its file and line: unknown keys (typos), unknown step types, missing required
+fields, copy sources missing from the build context, and script functions not
+defined in the referenced script.
+
+When a script step runs a script that comes from the base image rather than the
+build context, point --scripts at a local copy of the scripts directory to
+check the functions it calls.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sets, _ := cmd.Flags().GetStringArray("set")
+		scriptsDir, _ := cmd.Flags().GetString("scripts")
+
+		vars, err := rfdock.ParseRecipeVars(sets)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+
+		result := rfdock.ValidateRecipeFile(args[0], vars, scriptsDir)
+		if err := rfdock.DisplayRecipeValidation(result); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		if !result.Valid {
+			os.Exit(1)
+		}
+	},
+}
+
 func registerUpgradeBuildCommands() {
 	rootCmd.AddCommand(upgradeCmd)
 	rootCmd.AddCommand(buildCmd)
+	buildCmd.AddCommand(buildValidateCmd)
 
 	upgradeCmd.Flags().StringP("container", "c", "", "Container name or ID to upgrade (required)")
 	upgradeCmd.Flags().StringP("repositories", "r", "", "Comma-separated list of container directories to preserve (e.g., /root/share,/opt/tools). These directories will be copied from old container to new container")
@@ -76,5 +174,15 @@ func registerUpgradeBuildCommands() {
 
 	buildCmd.Flags().StringP("recipe", "r", "rfswift-recipe.yaml", "Path to the recipe file")
 	buildCmd.Flags().StringP("tag", "t", "", "Override the tag name from recipe")
-	buildCmd.Flags().Bool("no-cache", false, "Build without using cache")
+	buildCmd.Flags().Bool("no-cache", false, "Build without the layer cache or step checkpoints")
+	buildCmd.Flags().Bool("render", false, "Print the expanded Dockerfile without building")
+	buildCmd.Flags().StringArray("set", nil, "Set a recipe variable as KEY=VALUE (repeatable, overrides the recipe's vars)")
+	buildCmd.Flags().String("platforms", "", "Build for these platforms, comma-separated (e.g. linux/amd64,linux/arm64)")
+	buildCmd.Flags().StringSlice("cache-from", nil, "Images to use as layer cache sources (repeatable or comma-separated)")
+	buildCmd.Flags().String("log", "", "Write the build log to this file (default: timestamped file in the build-logs directory)")
+	buildCmd.Flags().Int("tail", 30, "Output lines of the failed step to show when the build fails")
+	buildCmd.Flags().Bool("manifest", false, "Group the per-platform images under a manifest list (requires --platforms)")
+
+	buildValidateCmd.Flags().StringArray("set", nil, "Set a recipe variable as KEY=VALUE (repeatable, overrides the recipe's vars)")
+	buildValidateCmd.Flags().String("scripts", "", "Local scripts directory used to check script functions provided by the base image")
 }
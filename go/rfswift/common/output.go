@@ -0,0 +1,221 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Machine-readable output for list and status commands
+ */
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputSchema identifies the layout of structured documents. Bump the
+// version suffix whenever a field is renamed or removed; adding fields is a
+// compatible change and keeps the current version.
+const OutputSchema = "rfswift/v1"
+
+// Output formats accepted by the global --output flag.
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+var OutputFormat string = OutputTable // selected by the global --output flag
+
+// structuredOut is where json/yaml documents are written. It keeps the real
+// stdout once ApplyOutputFormat has routed human-oriented messages to stderr.
+var structuredOut io.Writer = os.Stdout
+
+var humanOutputRouted bool // set once os.Stdout points to stderr
+
+// RawOutputFlags are flags whose command prints a document other than json or
+// yaml (e.g. a rendered Dockerfile) that must stay alone on stdout.
+var RawOutputFlags = []string{"--render"}
+
+// RawOutputCommand is a command that prints a document (e.g. a profile
+// bundle) to stdout unless one of its ToFile flags writes it to a file.
+type RawOutputCommand struct {
+	Path   []string // command words, e.g. {"profile", "export"}
+	ToFile []string // flags sending the document to a file instead
+}
+
+// RawOutputCommands are the commands writing a document to stdout by default.
+var RawOutputCommands = []RawOutputCommand{
+	{[]string{"profile", "export"}, []string{"-o", "--output"}},
+	{[]string{"log", "export"}, []string{"-o", "--output"}},
+}
+
+// Document is the envelope wrapping every structured result so scripts can
+// check the schema and the kind of data before decoding it.
+type Document struct {
+	Schema string      `json:"schema" yaml:"schema"`
+	Kind   string      `json:"kind" yaml:"kind"`
+	Data   interface{} `json:"data" yaml:"data"`
+}
+
+// ApplyOutputFormat validates OutputFormat and, for json and yaml, redirects
+// os.Stdout to stderr so banners, progress and info boxes never end up mixed
+// with the document on stdout.
+//
+//	out: error if the format is not one of table, json or yaml
+func ApplyOutputFormat() error {
+	OutputFormat = strings.ToLower(strings.TrimSpace(OutputFormat))
+	switch OutputFormat {
+	case "", OutputTable:
+		OutputFormat = OutputTable
+		return nil
+	case OutputJSON, OutputYAML:
+		RouteHumanOutput()
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q (expected table, json or yaml)", OutputFormat)
+}
+
+// RouteHumanOutput redirects os.Stdout to stderr so banners, progress and
+// info boxes stay out of the document written with DocumentWriter.
+func RouteHumanOutput() {
+	if humanOutputRouted {
+		return
+	}
+	humanOutputRouted = true
+	structuredOut = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// DocumentWriter returns the real stdout, where documents are written.
+//
+//	out: io.Writer stdout, even after RouteHumanOutput
+func DocumentWriter() io.Writer {
+	return structuredOut
+}
+
+// MachineOutput reports whether results must be emitted as a structured
+// document instead of a table.
+//
+//	out: bool true for json and yaml output
+func MachineOutput() bool {
+	return OutputFormat == OutputJSON || OutputFormat == OutputYAML
+}
+
+// MachineOutputRequested scans raw command-line arguments for a json or yaml
+// --output flag. It is used before cobra parses the command line, e.g. to
+// keep the ASCII banner out of structured output; callers must first rule out
+// commands whose own -o/--output flag shadows the global one.
+//
+//	in(1): []string args command-line arguments without the program name
+//	out: bool true if json or yaml output was requested
+func MachineOutputRequested(args []string) bool {
+	for i, arg := range args {
+		var value string
+		switch {
+		case arg == "--output" || arg == "-o":
+			if i+1 < len(args) {
+				value = args[i+1]
+			}
+		case strings.HasPrefix(arg, "--output="):
+			value = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o") && !strings.HasPrefix(arg, "--"):
+			value = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		}
+		value = strings.ToLower(value)
+		if value == OutputJSON || value == OutputYAML {
+			return true
+		}
+	}
+	return false
+}
+
+// RawOutputRequested scans raw command-line arguments for one of the
+// RawOutputFlags, or one of the RawOutputCommands without a flag writing
+// to a file, before cobra parses the command line.
+//
+//	in(1): []string args command-line arguments without the program name
+//	out: bool true if a raw document was requested
+func RawOutputRequested(args []string) bool {
+	for _, arg := range args {
+		for _, flag := range RawOutputFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") && arg != flag+"=false" {
+				return true
+			}
+		}
+	}
+	for _, c := range RawOutputCommands {
+		if hasCommandPath(args, c.Path) && !hasFlag(args, c.ToFile) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommandPath reports whether the words of path follow each other in args.
+func hasCommandPath(args, path []string) bool {
+	for i := 0; i+len(path) <= len(args); i++ {
+		match := true
+		for j, word := range path {
+			if args[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFlag reports whether one of flags is set in args, as "--flag",
+// "--flag=value" or, for a shorthand, "-fvalue".
+func hasFlag(args, flags []string) bool {
+	for _, arg := range args {
+		for _, flag := range flags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") ||
+				!strings.HasPrefix(flag, "--") && strings.HasPrefix(arg, flag) && !strings.HasPrefix(arg, "--") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrintStructured writes data wrapped in a versioned Document using the
+// selected output format.
+//
+//	in(1): string kind name of the data set (e.g. "images", "networks")
+//	in(2): interface{} data value to encode
+//	out: error if encoding fails
+func PrintStructured(kind string, data interface{}) error {
+	return WriteStructured(structuredOut, OutputFormat, kind, data)
+}
+
+// WriteStructured encodes data wrapped in a versioned Document to w.
+//
+//	in(1): io.Writer w destination
+//	in(2): string format json or yaml
+//	in(3): string kind name of the data set
+//	in(4): interface{} data value to encode
+//	out: error if the format is not structured or encoding fails
+func WriteStructured(w io.Writer, format string, kind string, data interface{}) error {
+	doc := Document{Schema: OutputSchema, Kind: kind, Data: data}
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("output format %q is not a structured format", format)
+}
@@ -0,0 +1,108 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type outputItem struct {
+	Name  string `json:"name" yaml:"name"`
+	Count int    `json:"count" yaml:"count"`
+}
+
+func TestMachineOutputRequested(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"images", "local"}, false},
+		{[]string{"-o", "json", "images", "local"}, true},
+		{[]string{"images", "local", "--output", "yaml"}, true},
+		{[]string{"--output=JSON", "doctor"}, true},
+		{[]string{"-ojson", "doctor"}, true},
+		{[]string{"--output=table", "doctor"}, false},
+		{[]string{"images", "download", "-o", "sdr.tar.gz"}, false},
+		{[]string{"-o"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := MachineOutputRequested(tt.args); got != tt.want {
+			t.Errorf("MachineOutputRequested(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestWriteStructuredEnvelope(t *testing.T) {
+	items := []outputItem{{Name: "sdr_full", Count: 2}}
+
+	var buf bytes.Buffer
+	if err := WriteStructured(&buf, OutputJSON, "items", items); err != nil {
+		t.Fatalf("WriteStructured(json) error: %v", err)
+	}
+	var fromJSON struct {
+		Schema string       `json:"schema"`
+		Kind   string       `json:"kind"`
+		Data   []outputItem `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fromJSON); err != nil {
+		t.Fatalf("json output does not decode: %v\n%s", err, buf.String())
+	}
+	if fromJSON.Schema != OutputSchema || fromJSON.Kind != "items" || len(fromJSON.Data) != 1 || fromJSON.Data[0] != items[0] {
+		t.Errorf("json document = %+v, want schema %q kind %q data %+v", fromJSON, OutputSchema, "items", items)
+	}
+
+	buf.Reset()
+	if err := WriteStructured(&buf, OutputYAML, "items", items); err != nil {
+		t.Fatalf("WriteStructured(yaml) error: %v", err)
+	}
+	var fromYAML struct {
+		Schema string       `yaml:"schema"`
+		Kind   string       `yaml:"kind"`
+		Data   []outputItem `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("yaml output does not decode: %v\n%s", err, buf.String())
+	}
+	if fromYAML.Schema != OutputSchema || fromYAML.Kind != "items" || len(fromYAML.Data) != 1 || fromYAML.Data[0] != items[0] {
+		t.Errorf("yaml document = %+v, want schema %q kind %q data %+v", fromYAML, OutputSchema, "items", items)
+	}
+
+	if err := WriteStructured(&buf, OutputTable, "items", items); err == nil || !strings.Contains(err.Error(), "not a structured format") {
+		t.Errorf("WriteStructured(table) error = %v, want not a structured format", err)
+	}
+}
+
+func TestRawOutputRequested(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"build", "-r", "recipe.yaml"}, false},
+		{[]string{"build", "--render", "-r", "recipe.yaml"}, true},
+		{[]string{"build", "--render=true"}, true},
+		{[]string{"build", "--render=false"}, false},
+		{[]string{"profile", "export", "sdr"}, true},
+		{[]string{"--engine", "docker", "profile", "export", "sdr", "usb"}, true},
+		{[]string{"profile", "export", "sdr", "-o", "bundle.yaml"}, false},
+		{[]string{"profile", "export", "sdr", "--output=bundle.yaml"}, false},
+		{[]string{"profile", "export", "sdr", "-obundle.yaml"}, false},
+		{[]string{"profile", "import", "bundle.yaml"}, false},
+		{[]string{"export", "profile"}, false},
+		{[]string{"log", "export", "s.cast", "--format", "cast"}, true},
+		{[]string{"log", "export", "s.cast", "-o", "excerpt.cast"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := RawOutputRequested(tt.args); got != tt.want {
+			t.Errorf("RawOutputRequested(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Native asciicast v2 recorder for interactive container sessions
+ */
+
+package dock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicast v2 event codes.
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// castEvent is one event line of an asciicast v2 file: [time, code, data].
+type castEvent struct {
+	Time float64
+	Code string
+	Data string
+}
+
+func (e castEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{math.Round(e.Time*1e6) / 1e6, e.Code, e.Data})
+}
+
+func (e *castEvent) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("event has %d fields, want 3", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[1], &e.Code); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[2], &e.Data)
+}
+
+// castRecorder writes the output, input and resize events of a terminal
+// session as asciicast v2, the format played by 'rfswift log replay' and
+// asciinema. Methods are safe on a nil recorder, which records nothing.
+//
+// With redaction rules, events are held until the line they belong to is
+// complete and written with its secrets redacted, so no secret ever reaches
+// the file, even if the session is killed.
+type castRecorder struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	start   time.Time
+	now     func() time.Time
+	pending map[string][]byte // incomplete UTF-8 sequence per stream
+	width   int
+	height  int
+	closed  bool
+	err     error
+	rules   []redactRule // secrets redacted before events are written
+	held    []castEvent  // events of lines not complete yet
+	heldLen int          // bytes of data in held
+	notes   []Redaction  // redactions applied so far
+}
+
+// maxHeldRecording bounds the data held while waiting for a line break;
+// beyond it, held events are redacted and written as they are.
+const maxHeldRecording = 64 << 10
+
+// newCastRecorder writes the asciicast header and returns a recorder whose
+// event times are relative to now.
+//
+//	in(1): io.WriteCloser w  recording destination
+//	in(2): int width  terminal columns
+//	in(3): int height  terminal rows
+//	in(4): string title
+//	in(5): map[string]string env  TERM and SHELL of the session
+//	out: (*castRecorder, error)
+func newCastRecorder(w io.WriteCloser, width, height int, title string, env map[string]string) (*castRecorder, error) {
+	return newCastRecorderAt(w, width, height, title, env, time.Now)
+}
+
+// newCastRecorderAt is newCastRecorder with the clock injected.
+func newCastRecorderAt(w io.WriteCloser, width, height int, title string, env map[string]string, now func() time.Time) (*castRecorder, error) {
+	r := &castRecorder{
+		w:       w,
+		start:   now(),
+		now:     now,
+		pending: make(map[string][]byte),
+		width:   width,
+		height:  height,
+	}
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       env,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(header, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write recording header: %v", err)
+	}
+	return r, nil
+}
+
+// createCastRecording creates the recording file and writes its header.
+//
+//	in(1): string path
+//	in(2): int width  terminal columns
+//	in(3): int height  terminal rows
+//	in(4): string title
+//	in(5): map[string]string env
+//	in(6): []redactRule rules  secrets redacted while recording
+//	out: (*castRecorder, error)
+func createCastRecording(path string, width, height int, title string, env map[string]string, rules []redactRule) (*castRecorder, error) {
+	// Recordings hold whatever the session printed, keep them private
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %v", err)
+	}
+	r, err := newCastRecorder(f, width, height, title, env)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.rules = rules
+	return r, nil
+}
+
+// splitUTF8Tail splits off a trailing incomplete UTF-8 sequence, which a
+// read may cut in the middle of a multi-byte character.
+//
+//	in(1): []byte p
+//	out: (complete, tail []byte)
+func splitUTF8Tail(p []byte) ([]byte, []byte) {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return p[:i], p[i:]
+		}
+		break
+	}
+	return p, nil
+}
+
+// event appends one event line; the first write error stops the recording
+// without interrupting the session.
+func (r *castRecorder) event(code string, data []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil || r.closed {
+		return
+	}
+
+	buf := append(r.pending[code], data...)
+	complete, tail := splitUTF8Tail(buf)
+	r.pending[code] = append([]byte(nil), tail...)
+	if len(complete) == 0 {
+		return
+	}
+	r.record(code, string(complete))
+}
+
+// record writes an event now, or holds it back while the redaction rules may
+// still match across it and the events to come.
+func (r *castRecorder) record(code, data string) {
+	e := castEvent{r.now().Sub(r.start).Seconds(), code, data}
+	if len(r.rules) == 0 {
+		r.writeEvent(e)
+		return
+	}
+	r.held = append(r.held, splitAtLineBreak(e)...)
+	r.heldLen += len(data)
+	r.flushHeld(r.heldLen > maxHeldRecording)
+}
+
+// flushHeld redacts and writes the held events of complete lines, or all of
+// them.
+func (r *castRecorder) flushHeld(all bool) {
+	n := len(r.held)
+	if !all {
+		n = completeLines(r.held)
+	}
+	if n == 0 {
+		return
+	}
+	events, notes := redactEvents(r.held[:n], r.rules)
+	for _, e := range events {
+		if r.err == nil {
+			r.writeEvent(e)
+		}
+	}
+	r.notes = append(r.notes, notes...)
+	for _, e := range r.held[:n] {
+		r.heldLen -= len(e.Data)
+	}
+	r.held = append(r.held[:0], r.held[n:]...)
+}
+
+func (r *castRecorder) writeEvent(e castEvent) {
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.err = fmt.Errorf("recording stopped: %v", err)
+	}
+}
+
+// Resize records a terminal size change.
+//
+//	in(1): int width  terminal columns
+//	in(2): int height  terminal rows
+func (r *castRecorder) Resize(width, height int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil || r.closed || (width == r.width && height == r.height) {
+		return
+	}
+	r.width, r.height = width, height
+	r.record(castResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+// castStream is the io.Writer of one event stream of a recorder.
+type castStream struct {
+	r    *castRecorder
+	code string
+}
+
+func (s castStream) Write(p []byte) (int, error) {
+	s.r.event(s.code, p)
+	return len(p), nil
+}
+
+// Output returns a writer recording the session output.
+func (r *castRecorder) Output() io.Writer {
+	return castStream{r, castOutput}
+}
+
+// Input returns a writer recording the keys typed in the session.
+func (r *castRecorder) Input() io.Writer {
+	return castStream{r, castInput}
+}
+
+// Redactions returns the redactions applied to the recording so far.
+func (r *castRecorder) Redactions() []Redaction {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Redaction(nil), r.notes...)
+}
+
+// Close flushes pending bytes and held events and closes the recording.
+//
+//	out: error  the first write error of the recording, if any
+func (r *castRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return r.err
+	}
+	r.closed = true
+	for _, code := range []string{castOutput, castInput} {
+		if len(r.pending[code]) > 0 && r.err == nil {
+			r.record(code, string(r.pending[code]))
+		}
+		delete(r.pending, code)
+	}
+	r.flushHeld(true)
+	if err := r.w.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// castFile is a parsed asciicast v2 recording.
+type castFile struct {
+	Header castHeader
+	Events []castEvent
+}
+
+// readCast parses an asciicast v2 recording.
+//
+//	in(1): io.Reader r
+//	out: (*castFile, error)
+func readCast(r io.Reader) (*castFile, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty recording")
+	}
+	cast := &castFile{}
+	if err := json.Unmarshal(scanner.Bytes(), &cast.Header); err != nil {
+		return nil, fmt.Errorf("invalid asciicast header: %v", err)
+	}
+	if cast.Header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d (only v2 is supported)", cast.Header.Version)
+	}
+
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e castEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: invalid event: %v", line, err)
+		}
+		cast.Events = append(cast.Events, e)
+	}
+	return cast, scanner.Err()
+}
+
+// loadCast reads an asciicast v2 recording from disk.
+func loadCast(path string) (*castFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cast, err := readCast(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cast, nil
+}
+
+// write encodes the recording as asciicast v2.
+func (c *castFile) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(c.Header); err != nil {
+		return err
+	}
+	for _, e := range c.Events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Duration returns the time of the last event in seconds.
+func (c *castFile) Duration() float64 {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return c.Events[len(c.Events)-1].Time
+}
+
+// trim returns the events between from and to (to <= 0 means the end),
+// shifted to start at zero, with the terminal size in effect at from.
+//
+//	in(1): float64 from  seconds
+//	in(2): float64 to  seconds
+//	out: *castFile
+func (c *castFile) trim(from, to float64) *castFile {
+	out := &castFile{Header: c.Header}
+	if c.Header.Timestamp != 0 {
+		out.Header.Timestamp = c.Header.Timestamp + int64(from)
+	}
+	for _, e := range c.Events {
+		if to > 0 && e.Time > to {
+			break
+		}
+		if e.Time < from {
+			if e.Code == castResize {
+				fmt.Sscanf(e.Data, "%dx%d", &out.Header.Width, &out.Header.Height)
+			}
+			continue
+		}
+		e.Time -= from
+		out.Events = append(out.Events, e)
+	}
+	return out
+}
+
+// castLine is a line of terminal output with the time it started at.
+type castLine struct {
+	Time float64
+	Text string
+}
+
+// Escape sequence states of castLines.
+const (
+	ansiText = iota
+	ansiEsc
+	ansiCSI
+	ansiString // OSC, DCS, PM, APC: until BEL or ST
+	ansiStringEsc
+	ansiCharset
+)
+
+// castLines renders the output events of a recording as plain text lines:
+// escape sequences are dropped, a carriage return not followed by a newline
+// overwrites the line (progress bars) and backspaces erase.
+//
+//	in(1): []castEvent events
+//	out: []castLine
+func castLines(events []castEvent) []castLine {
+	var lines []castLine
+	var line []rune
+	start := -1.0
+	state := ansiText
+	cr := false
+
+	for _, e := range events {
+		if e.Code != castOutput {
+			continue
+		}
+		for _, c := range e.Data {
+			switch state {
+			case ansiEsc:
+				switch c {
+				case '[':
+					state = ansiCSI
+				case ']', 'P', 'X', '^', '_':
+					state = ansiString
+				case '(', ')', '*', '+':
+					state = ansiCharset
+				default:
+					state = ansiText
+				}
+				continue
+			case ansiCSI:
+				if c >= 0x40 && c <= 0x7e {
+					state = ansiText
+				}
+				continue
+			case ansiString:
+				if c == 0x07 {
+					state = ansiText
+				} else if c == 0x1b {
+					state = ansiStringEsc
+				}
+				continue
+			case ansiStringEsc:
+				state = ansiString
+				if c == '\\' {
+					state = ansiText
+				}
+				continue
+			case ansiCharset:
+				state = ansiText
+				continue
+			}
+
+			switch {
+			case c == 0x1b:
+				state = ansiEsc
+			case c == '\n':
+				if start < 0 {
+					start = e.Time
+				}
+				lines = append(lines, castLine{start, string(line)})
+				line, start, cr = line[:0], -1, false
+			case c == '\r':
+				cr = true
+			case c == '\b':
+				if len(line) > 0 {
+					line = line[:len(line)-1]
+				}
+			case c == '\t' || c >= 0x20 && c != 0x7f && !(c >= 0x80 && c < 0xa0):
+				if cr {
+					line, cr = line[:0], false
+				}
+				if start < 0 {
+					start = e.Time
+				}
+				line = append(line, c)
+			}
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, castLine{start, string(line)})
+	}
+	return lines
+}
+
+// stripANSI returns text with terminal escape sequences and control
+// characters removed, as castLines renders it.
+func stripANSI(text string) string {
+	lines := castLines([]castEvent{{0, castOutput, text}})
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = l.Text
+	}
+	return strings.Join(out, "\n")
+}
+
+// formatCastTime formats a position in a recording as [h:]mm:ss.d.
+func formatCastTime(seconds float64) string {
+	tenths := int64(math.Round(seconds * 10))
+	h, m := tenths/36000, tenths/600%60
+	s := float64(tenths%600) / 10
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%04.1f", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%04.1f", m, s)
+}
+
+// parseCastTime parses a position in a recording: seconds ("90.5"),
+// [h:]mm:ss[.d] ("1:30", "01:02:03") or a duration ("1m30s").
+//
+//	in(1): string s
+//	out: (float64 seconds, error)
+func parseCastTime(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return d.Seconds(), nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time '%s' (use seconds, mm:ss or h:mm:ss)", s)
+	}
+	var total float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i > 0 && v >= 60) || (i < len(parts)-1 && v != math.Trunc(v)) {
+			return 0, fmt.Errorf("invalid time '%s' (use seconds, mm:ss or h:mm:ss)", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
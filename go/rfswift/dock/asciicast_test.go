@@ -0,0 +1,239 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestCastRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Unix(1700000000, 0)
+	now := func() time.Time { return clock }
+
+	rec, err := newCastRecorderAt(nopWriteCloser{&buf}, 120, 40, "RF Swift: sdr", map[string]string{"SHELL": "/bin/bash"}, now)
+	if err != nil {
+		t.Fatalf("newCastRecorderAt() error = %v", err)
+	}
+	clock = clock.Add(500 * time.Millisecond)
+	rec.Output().Write([]byte("$ ls\r\n"))
+	clock = clock.Add(250 * time.Millisecond)
+	rec.Input().Write([]byte("q"))
+	rec.Resize(120, 40) // unchanged, not recorded
+	rec.Resize(100, 30)
+	// "é" split across two reads is recorded once complete
+	rec.Output().Write([]byte{'c', 0xc3})
+	rec.Output().Write([]byte{0xa9})
+	rec.Output().Write([]byte{0xe2, 0x82})
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	rec.Output().Write([]byte("after close"))
+
+	want := []string{
+		`{"version":2,"width":120,"height":40,"timestamp":1700000000,"title":"RF Swift: sdr","env":{"SHELL":"/bin/bash"}}`,
+		`[0.5,"o","$ ls\r\n"]`,
+		`[0.75,"i","q"]`,
+		`[0.75,"r","100x30"]`,
+		`[0.75,"o","c"]`,
+		`[0.75,"o","é"]`,
+		`[0.75,"o","��"]`, // truncated sequence flushed on close
+	}
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("recording =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var none *castRecorder
+	none.Resize(80, 24)
+	none.Output().Write([]byte("x"))
+	if err := none.Close(); err != nil {
+		t.Errorf("nil Close() error = %v", err)
+	}
+}
+
+func TestCastRecorderRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Unix(1700000000, 0)
+	now := func() time.Time { return clock }
+	rec, err := newCastRecorderAt(nopWriteCloser{&buf}, 80, 24, "", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.rules = builtinRedactRules
+
+	// The key is typed and echoed one character per event
+	rec.Output().Write([]byte("$ "))
+	rec.Input().Write([]byte("tailscale up --authkey "))
+	for _, c := range "tskey-abc12345" {
+		clock = clock.Add(100 * time.Millisecond)
+		rec.Input().Write([]byte(string(c)))
+		rec.Output().Write([]byte(string(c)))
+	}
+	if strings.Contains(buf.String(), "tskey") {
+		t.Fatalf("recording holds part of the key before the line ends:\n%s", buf.String())
+	}
+
+	clock = clock.Add(time.Second)
+	rec.Input().Write([]byte("\r"))
+	rec.Output().Write([]byte("\r\nSuccess.\r\n$ "))
+	written := buf.String()
+	if strings.Contains(written, "tskey") || strings.Count(written, redactionMark) != 2 {
+		t.Errorf("recording after the line ended =\n%s\nwant the key redacted in both streams", written)
+	}
+	if !strings.HasSuffix(written, `[2.4,"o","\r\nSuccess.\r\n"]`+"\n") {
+		t.Errorf("recording after the line ended =\n%s\nwant complete lines written and the new prompt held", written)
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(buf.String(), `[2.4,"o","$ "]`+"\n") {
+		t.Errorf("recording after Close =\n%s\nwant the held prompt written", buf.String())
+	}
+	if notes := rec.Redactions(); len(notes) != 2 || notes[0].Rule != "tailscale-key" {
+		t.Errorf("Redactions() = %+v, want the key in both streams", notes)
+	}
+}
+
+func TestCompleteLines(t *testing.T) {
+	events := splitAtLineBreak(castEvent{1, castOutput, "ok\r\n$ "})
+	events = append(events, castEvent{2, castInput, "ls"}, castEvent{2, castResize, "80x24"}, castEvent{3, castInput, "\r"})
+	if len(events) != 5 || events[0].Data != "ok\r\n" || events[1].Data != "$ " || events[1].Time != 1 {
+		t.Fatalf("splitAtLineBreak() = %+v, want the line and the prompt", events[:2])
+	}
+	if got := completeLines(events); got != 1 {
+		t.Errorf("completeLines() = %d, want 1 (the prompt line is open)", got)
+	}
+	events = append(events, castEvent{4, castOutput, "ls\r\n"})
+	if got := completeLines(events); got != len(events) {
+		t.Errorf("completeLines() = %d, want %d", got, len(events))
+	}
+}
+
+func TestSplitUTF8Tail(t *testing.T) {
+	tests := []struct {
+		in, complete, tail string
+	}{
+		{"abc", "abc", ""},
+		{"a\xc3", "a", "\xc3"},
+		{"a\xe2\x82", "a", "\xe2\x82"},
+		{"a\xe2\x82\xac", "a\xe2\x82\xac", ""},
+		{"\xf0\x9f\x93", "", "\xf0\x9f\x93"},
+		{"a\xff", "a\xff", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		complete, tail := splitUTF8Tail([]byte(tt.in))
+		if string(complete) != tt.complete || string(tail) != tt.tail {
+			t.Errorf("splitUTF8Tail(%q) = %q, %q, want %q, %q", tt.in, complete, tail, tt.complete, tt.tail)
+		}
+	}
+}
+
+const sampleCast = `{"version":2,"width":80,"height":24,"timestamp":1700000000,"title":"RF Swift: sdr"}
+[0.1,"o","\u001b]0;root@sdr\u0007\u001b[01;32mroot@sdr\u001b[00m# "]
+[1.0,"i","grgsm_scanner\r"]
+[1.2,"o","grgsm_scanner\r\n"]
+[3.5,"r","100x30"]
+[10.0,"o","Scanning: 20% \rScanning: 100%\r\n"]
+[42.0,"o","ARFCN:  975, Freq:  925.2M, CID: 1234\r\n"]
+[42.5,"o","typo\b\bpe\r\n"]
+`
+
+func TestReadCastAndTrim(t *testing.T) {
+	cast, err := readCast(strings.NewReader(sampleCast))
+	if err != nil {
+		t.Fatalf("readCast() error = %v", err)
+	}
+	if cast.Header.Width != 80 || len(cast.Events) != 7 || cast.Duration() != 42.5 {
+		t.Fatalf("readCast() = %+v, %d events", cast.Header, len(cast.Events))
+	}
+
+	excerpt := cast.trim(5, 42)
+	if excerpt.Header.Timestamp != 1700000005 || excerpt.Header.Width != 100 || excerpt.Header.Height != 30 {
+		t.Errorf("trim() header = %+v, want timestamp 1700000005 and size 100x30", excerpt.Header)
+	}
+	if len(excerpt.Events) != 2 || excerpt.Events[0].Time != 5 || excerpt.Events[1].Time != 37 {
+		t.Errorf("trim() events = %+v", excerpt.Events)
+	}
+
+	var buf bytes.Buffer
+	if err := excerpt.write(&buf); err != nil {
+		t.Fatalf("write() error = %v", err)
+	}
+	again, err := readCast(&buf)
+	if err != nil || len(again.Events) != 2 || again.Events[1].Data != cast.Events[5].Data {
+		t.Errorf("readCast(write()) = %+v, %v", again, err)
+	}
+
+	for _, bad := range []string{"", `{"version":1}`, "{\"version\":2}\n[1.0,\"o\"]\n"} {
+		if _, err := readCast(strings.NewReader(bad)); err == nil {
+			t.Errorf("readCast(%q) error = nil, want an error", bad)
+		}
+	}
+}
+
+func TestCastLines(t *testing.T) {
+	cast, _ := readCast(strings.NewReader(sampleCast))
+	want := []castLine{
+		{0.1, "root@sdr# grgsm_scanner"},
+		{10.0, "Scanning: 100%"},
+		{42.0, "ARFCN:  975, Freq:  925.2M, CID: 1234"},
+		{42.5, "type"},
+	}
+	got := castLines(cast.Events)
+	if len(got) != len(want) {
+		t.Fatalf("castLines() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("castLines()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := stripANSI("\x1b[1;31mred\x1b[0m\x1b(B text \x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\\n"); got != "red text link" {
+		t.Errorf("stripANSI() = %q, want %q", got, "red text link")
+	}
+}
+
+func TestCastTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"", 0, true},
+		{"90", 90, true},
+		{"90.5", 90.5, true},
+		{"1:30", 90, true},
+		{"01:02:03.5", 3723.5, true},
+		{"1m30s", 90, true},
+		{"1:75", 0, false},
+		{"1.5:30", 0, false},
+		{"1:2:3:4", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseCastTime(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseCastTime(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for in, want := range map[float64]string{0: "00:00.0", 90.04: "01:30.0", 3723.5: "1:02:03.5", 59.96: "01:00.0"} {
+		if got := formatCastTime(in); got != want {
+			t.Errorf("formatCastTime(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Native asciicast v2 player with pause, seek, speed and idle-time cap
+ */
+
+package dock
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/moby/term"
+)
+
+// ReplayOptions tunes the native session player.
+type ReplayOptions struct {
+	Speed     float64 // playback speed multiplier
+	IdleLimit float64 // longest pause between events in seconds (0 = the recording's own limit, if any)
+	Start     string  // position to start from ("90", "1:30"), as shown by 'rfswift log search'
+}
+
+// Player controls; seekStep is the jump of the arrow keys.
+const (
+	seekStep = 5.0
+	maxSpeed = 16.0
+	minSpeed = 0.25
+)
+
+type playerKey int
+
+const (
+	keyPause playerKey = iota
+	keyForward
+	keyBackward
+	keyFaster
+	keySlower
+	keyStep
+	keyQuit
+)
+
+// parsePlayerKeys maps bytes read from a raw terminal to player controls.
+//
+//	in(1): []byte b
+//	out: []playerKey
+func parsePlayerKeys(b []byte) []playerKey {
+	var keys []playerKey
+	for i := 0; i < len(b); i++ {
+		// Arrow keys: ESC [ C / ESC O C (application mode)
+		if b[i] == 0x1b && i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+			switch b[i+2] {
+			case 'C':
+				keys = append(keys, keyForward)
+			case 'D':
+				keys = append(keys, keyBackward)
+			}
+			i += 2
+			continue
+		}
+		switch b[i] {
+		case ' ':
+			keys = append(keys, keyPause)
+		case 'l':
+			keys = append(keys, keyForward)
+		case 'h':
+			keys = append(keys, keyBackward)
+		case '+', '=':
+			keys = append(keys, keyFaster)
+		case '-':
+			keys = append(keys, keySlower)
+		case '.':
+			keys = append(keys, keyStep)
+		case 'q', 0x03, 0x04:
+			keys = append(keys, keyQuit)
+		}
+	}
+	return keys
+}
+
+// playbackEvents returns the output events of a recording with pauses longer
+// than idleLimit shortened to it, and maps start, a position in the original
+// recording, onto the shortened timeline.
+//
+//	in(1): []castEvent events
+//	in(2): float64 idleLimit  seconds, 0 for no cap
+//	in(3): float64 start  seconds
+//	out: ([]castEvent, float64 start)
+func playbackEvents(events []castEvent, idleLimit, start float64) ([]castEvent, float64) {
+	var out []castEvent
+	var last, shift float64
+	mapped := start
+	for _, e := range events {
+		if idleLimit > 0 && e.Time-last > idleLimit {
+			shift += e.Time - last - idleLimit
+		}
+		last = e.Time
+		if e.Time <= start {
+			mapped = start - shift
+		}
+		if e.Code != castOutput {
+			continue
+		}
+		out = append(out, castEvent{e.Time - shift, e.Code, e.Data})
+	}
+	if mapped < 0 {
+		mapped = 0
+	}
+	return out, mapped
+}
+
+// castPlayer replays output events to a terminal.
+type castPlayer struct {
+	events []castEvent
+	out    io.Writer
+	speed  float64
+	next   int     // index of the next event to print
+	pos    float64 // playback position in seconds
+	paused bool
+}
+
+func (p *castPlayer) duration() float64 {
+	if len(p.events) == 0 {
+		return 0
+	}
+	return p.events[len(p.events)-1].Time
+}
+
+// seek moves to t, printing the events up to it at once. Seeking backwards
+// resets the terminal and renders again from the start.
+func (p *castPlayer) seek(t float64) {
+	if t < 0 {
+		t = 0
+	}
+	if end := p.duration(); t > end {
+		t = end
+	}
+	if t < p.pos {
+		io.WriteString(p.out, "\033c")
+		p.next = 0
+	}
+	for p.next < len(p.events) && p.events[p.next].Time <= t {
+		io.WriteString(p.out, p.events[p.next].Data)
+		p.next++
+	}
+	p.pos = t
+}
+
+// status shows the player state in the terminal title, leaving the replayed
+// screen untouched.
+func (p *castPlayer) status() {
+	icon := "▶"
+	if p.paused {
+		icon = "⏸"
+	}
+	fmt.Fprintf(p.out, "\033]0;%s %s / %s  x%g | RF Swift replay\007",
+		icon, formatCastTime(p.pos), formatCastTime(p.duration()), p.speed)
+}
+
+// run plays the events from the current position until the end, or until
+// the quit key. keys may be nil when there is no interactive terminal.
+func (p *castPlayer) run(keys <-chan playerKey) {
+	resumed := time.Now()
+	for p.next < len(p.events) {
+		var tick <-chan time.Time
+		if !p.paused {
+			wait := time.Duration((p.events[p.next].Time - p.pos) / p.speed * float64(time.Second))
+			tick = time.After(wait)
+			resumed = time.Now()
+		}
+
+		select {
+		case <-tick:
+			p.pos = p.events[p.next].Time
+			io.WriteString(p.out, p.events[p.next].Data)
+			p.next++
+
+		case k, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			if !p.paused {
+				p.pos += time.Since(resumed).Seconds() * p.speed
+			}
+			switch k {
+			case keyQuit:
+				return
+			case keyPause:
+				p.paused = !p.paused
+			case keyForward:
+				p.seek(p.pos + seekStep)
+			case keyBackward:
+				p.seek(p.pos - seekStep)
+			case keyFaster:
+				p.speed = min(p.speed*2, maxSpeed)
+			case keySlower:
+				p.speed = max(p.speed/2, minSpeed)
+			case keyStep:
+				if p.paused && p.next < len(p.events) {
+					p.seek(p.events[p.next].Time)
+				}
+			}
+			p.status()
+		}
+	}
+}
+
+// playCast replays an asciicast v2 recording in the terminal. When stdin is
+// a terminal, space pauses, ←/→ (or h/l) seek 5 seconds, +/- change the
+// speed, '.' steps one frame while paused and q quits.
+//
+//	in(1): string inputFile
+//	in(2): ReplayOptions opts
+//	out: error
+func playCast(inputFile string, opts ReplayOptions) error {
+	cast, err := loadCast(inputFile)
+	if err != nil {
+		return err
+	}
+	start, err := parseCastTime(opts.Start)
+	if err != nil {
+		return err
+	}
+	idle := opts.IdleLimit
+	if idle == 0 {
+		idle = cast.Header.IdleTimeLimit
+	}
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	events, startPos := playbackEvents(cast.Events, idle, start)
+	player := &castPlayer{events: events, out: os.Stdout, speed: speed}
+
+	var keys chan playerKey
+	inFd, inIsTerminal := term.GetFdInfo(os.Stdin)
+	if inIsTerminal {
+		fmt.Printf("Controls: space pause · ←/→ seek %gs · +/- speed · . step · q quit\n", seekStep)
+		state, err := term.SetRawTerminal(inFd)
+		if err != nil {
+			return fmt.Errorf("failed to set raw terminal: %v", err)
+		}
+		defer term.RestoreTerminal(inFd, state)
+
+		keys = make(chan playerKey, 16)
+		go func() {
+			buf := make([]byte, 32)
+			for {
+				n, err := os.Stdin.Read(buf)
+				for _, k := range parsePlayerKeys(buf[:n]) {
+					keys <- k
+				}
+				if err != nil {
+					close(keys)
+					return
+				}
+			}
+		}()
+	}
+
+	player.seek(startPos)
+	player.run(keys)
+
+	// Reset terminal title and leave the cursor on a fresh line
+	fmt.Print("\033]0;RF Swift\007\033[0m\r\n")
+	return nil
+}
@@ -0,0 +1,102 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParsePlayerKeys(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []playerKey
+	}{
+		{" ", []playerKey{keyPause}},
+		{"\x1b[C\x1b[D", []playerKey{keyForward, keyBackward}},
+		{"\x1bOC", []playerKey{keyForward}},
+		{"+-.q", []playerKey{keyFaster, keySlower, keyStep, keyQuit}},
+		{"\x03", []playerKey{keyQuit}},
+		{"xyz", nil},
+	}
+	for _, tt := range tests {
+		if got := parsePlayerKeys([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePlayerKeys(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlaybackEvents(t *testing.T) {
+	events := []castEvent{
+		{1, castOutput, "a"},
+		{2, castInput, "x"},
+		{30, castOutput, "b"},
+		{31, castOutput, "c"},
+	}
+	got, start := playbackEvents(events, 2, 30.5)
+	want := []castEvent{{1, castOutput, "a"}, {4, castOutput, "b"}, {5, castOutput, "c"}}
+	if !reflect.DeepEqual(got, want) || start != 4.5 {
+		t.Errorf("playbackEvents() = %v, %v, want %v, 4.5", got, start, want)
+	}
+	if got, _ := playbackEvents(events, 0, 0); got[1].Time != 30 {
+		t.Errorf("playbackEvents(no limit) = %v, want times kept", got)
+	}
+}
+
+func TestCastPlayerSeek(t *testing.T) {
+	var out bytes.Buffer
+	p := &castPlayer{events: []castEvent{{1, castOutput, "a"}, {2, castOutput, "b"}, {3, castOutput, "c"}}, out: &out, speed: 1}
+
+	p.seek(2.5)
+	if out.String() != "ab" || p.next != 2 {
+		t.Errorf("seek(2.5) printed %q, next %d", out.String(), p.next)
+	}
+	p.seek(1)
+	if out.String() != "ab\033ca" || p.next != 1 {
+		t.Errorf("seek(1) printed %q, next %d, want a reset and a", out.String(), p.next)
+	}
+	p.seek(99)
+	if p.pos != 3 || p.next != 3 {
+		t.Errorf("seek(99) pos %v, next %d, want clamped to the end", p.pos, p.next)
+	}
+
+	// Without a terminal, run plays the remaining events to the end
+	out.Reset()
+	p = &castPlayer{events: []castEvent{{0.01, castOutput, "x"}, {0.02, castOutput, "y"}}, out: &out, speed: 4}
+	p.run(nil)
+	if out.String() != "xy" {
+		t.Errorf("run() printed %q, want %q", out.String(), "xy")
+	}
+}
+
+func TestSearchRecording(t *testing.T) {
+	dir := t.TempDir()
+	cast := filepath.Join(dir, "rfswift-exec-sdr.cast")
+	script := filepath.Join(dir, "rfswift-session.log")
+	if err := os.WriteFile(cast, []byte(sampleCast), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, []byte("Script started\n\x1b[32mCID: 99\x1b[0m\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	re := regexp.MustCompile("CID: [0-9]+")
+	matches, err := searchRecording(cast, re)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("searchRecording(cast) = %+v, %v", matches, err)
+	}
+	if m := matches[0]; m.Offset != "00:42.0" || m.Seconds != 42 || m.Time == "" || m.Line != 3 {
+		t.Errorf("searchRecording(cast) = %+v", m)
+	}
+
+	matches, err = searchRecording(script, re)
+	if err != nil || len(matches) != 1 || matches[0].Line != 2 || matches[0].Offset != "" || matches[0].Text != "CID: 99" {
+		t.Errorf("searchRecording(script) = %+v, %v", matches, err)
+	}
+}
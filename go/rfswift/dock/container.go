@@ -8,6 +8,7 @@ package dock
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -16,9 +17,11 @@ import (
 	"os/signal"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/moby/moby/api/pkg/stdcopy"
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/api/types/network"
 	"github.com/moby/moby/client"
@@ -69,8 +72,19 @@ func startDesktopInContainer(ctx context.Context, cli *client.Client, containerI
 		return fmt.Errorf("failed to start desktop: %v", err)
 	}
 
-	// Give the VNC server a moment to start
-	time.Sleep(2 * time.Second)
+	// Wait for the VNC/noVNC listener rather than a fixed delay
+	name := containerID
+	if inspectErr == nil {
+		name = strings.TrimPrefix(containerJSON.Name, "/")
+	}
+	port, err := strconv.Atoi(containerCfg.desktopPort)
+	if err != nil {
+		time.Sleep(2 * time.Second)
+		return nil
+	}
+	if !waitForDesktop(ctx, cli, containerID, port, 15*time.Second) {
+		common.PrintWarningMessage(fmt.Sprintf("Desktop is not listening on port %d yet (check with: rfswift status %s)", port, name))
+	}
 	return nil
 }
 
@@ -178,6 +192,7 @@ func ContainerLast(ifilter string, labelKey string, labelValue string) {
 
 	//rfutils.ClearScreen()
 	tableData := [][]string{}
+	infos := []ContainerInfo{}
 
 	// Filter containers by image, name or ID (if ifilter is provided)
 	filteredContainers := []container.Summary{}
@@ -298,6 +313,14 @@ func ContainerLast(ifilter string, labelKey string, labelValue string) {
 		}
 		containerID := container.ID[:12]
 		command := container.Command
+		infos = append(infos, ContainerInfo{
+			ID:      containerID,
+			Name:    containerName,
+			Image:   imageTag,
+			State:   string(container.State),
+			Created: created,
+			Command: command,
+		})
 
 		// Truncate command if too long
 		if len(command) > 30 {
@@ -313,6 +336,13 @@ func ContainerLast(ifilter string, labelKey string, labelValue string) {
 		})
 	}
 
+	if common.MachineOutput() {
+		if err := common.PrintStructured("containers", infos); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	tui.RenderTable(tui.TableConfig{
 		Title:      "🤖 Last Run Containers",
 		TitleColor: tui.ColorPink,
@@ -358,12 +388,15 @@ func latestDockerID(labelKey string, labelValue string) string {
 	return ""
 }
 
-// ContainerInfo holds basic container metadata for interactive selection.
+// ContainerInfo holds basic container metadata for interactive selection
+// and structured output.
 type ContainerInfo struct {
-	ID    string
-	Name  string
-	Image string
-	State string
+	ID      string `json:"id" yaml:"id"`
+	Name    string `json:"name" yaml:"name"`
+	Image   string `json:"image" yaml:"image"`
+	State   string `json:"state" yaml:"state"`
+	Created string `json:"created,omitempty" yaml:"created,omitempty"`
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
 }
 
 // ListContainers returns RF Swift containers with their name, image, and state.
@@ -523,13 +556,113 @@ func ContainerRun(containerName string) {
 	}
 	defer cli.Close()
 
+	containerID, err := createContainerFromConfig(ctx, cli, containerName)
+	if err != nil {
+		common.PrintErrorMessage(err)
+		return
+	}
+
+	// ── Podman or recording: use exec-style attach ──
+	// Podman's compat API rejects attach-before-start.
+	// Recording mode uses exec so RFSWIFT_RECORDING is session-scoped
+	// (not baked into the container env, which would persist forever).
+	if GetEngine().Type() == EnginePodman || os.Getenv("RFSWIFT_RECORDING") == "1" || containerCfg.recordFile != "" {
+		if _, err := cli.ContainerStart(ctx, containerID, client.ContainerStartOptions{}); err != nil {
+			common.PrintErrorMessage(err)
+			return
+		}
+
+		props, err := getContainerProperties(ctx, cli, containerID)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			return
+		}
+		size := props["Size"]
+		printContainerProperties(ctx, cli, containerName, props, size)
+		common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started successfully", containerName))
+		printDesktopURL()
+
+		// Start VPN if configured
+		if containerCfg.vpn != "" {
+			if err := startVPNInContainer(ctx, cli, containerID); err != nil {
+				common.PrintErrorMessage(err)
+			}
+			printVPNInfo()
+		}
+
+		// Attach via exec (same as ContainerExec)
+		if err := execInteractiveSession(ctx, cli, containerID, containerCfg.shell, ""); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		return
+	}
+
+	waiter, err := cli.ContainerAttach(ctx, containerID, client.ContainerAttachOptions{
+		Stderr: true,
+		Stdout: true,
+		Stdin:  true,
+		Stream: true,
+	})
+	if err != nil {
+		common.PrintErrorMessage(err)
+		return
+	}
+	defer waiter.Close()
+
+	if _, err := cli.ContainerStart(ctx, containerID, client.ContainerStartOptions{}); err != nil {
+		common.PrintErrorMessage(err)
+		return
+	}
+
+	props, err := getContainerProperties(ctx, cli, containerID)
+	if err != nil {
+		common.PrintErrorMessage(err)
+		return
+	}
+	size := props["Size"]
+	printContainerProperties(ctx, cli, containerName, props, size)
+	common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started successfully", containerName))
+	printDesktopURL()
+
+	// Start VPN if configured
+	if containerCfg.vpn != "" {
+		if err := startVPNInContainer(ctx, cli, containerID); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		printVPNInfo()
+	}
+
+	handleIOStreams(waiter.HijackedResponse)
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		oldState, err := terminal.MakeRaw(fd)
+		if err != nil {
+			common.PrintErrorMessage(err)
+			return
+		}
+		defer terminal.Restore(fd, oldState)
+		go resizeTty(ctx, cli, containerID, fd)
+		go readAndWriteInput(waiter.HijackedResponse)
+	}
+
+	waitForContainer(ctx, cli, containerID)
+}
+
+// createContainerFromConfig resolves the global container configuration (VPN,
+// workspace, desktop, devices, cgroup rules, GPUs, NAT network) into engine
+// configs and creates the container without starting it.
+//
+//	in(1): context.Context ctx
+//	in(2): *client.Client cli
+//	in(3): string containerName container name
+//	out: string created container ID, error
+func createContainerFromConfig(ctx context.Context, cli *client.Client, containerName string) (string, error) {
 	containerCfg.imagename = normalizeImageName(containerCfg.imagename)
 
 	// VPN: adjust caps, devices, bindings, env before container creation
 	if containerCfg.vpn != "" {
 		if err := applyVPNConfig(); err != nil {
-			common.PrintErrorMessage(err)
-			return
+			return "", err
 		}
 	}
 
@@ -601,6 +734,11 @@ func ContainerRun(containerName string) {
 		hostConfig.Resources.Ulimits = ulimits
 	}
 
+	// CPU, memory, cpuset, shm and pids limits
+	if err := containerCfg.resources.applyToHostConfig(hostConfig); err != nil {
+		return "", err
+	}
+
 	// If not in privileged mode, add device permissions
 	if !containerCfg.privileged {
 		devices := getDeviceMappingsFromString(containerCfg.devices)
@@ -702,10 +840,11 @@ func ContainerRun(containerName string) {
 			}
 		}
 
-		// Also inject cgroup rules for remaining individual device entries
+		// Also inject cgroup rules for remaining individual device entries,
+		// unless exact rules for that major were given (--usb)
 		for _, dev := range filteredDevices {
 			for prefix, rule := range devMajorRules {
-				if strings.HasPrefix(dev.PathOnHost, prefix) && !existingRules[rule] {
+				if strings.HasPrefix(dev.PathOnHost, prefix) && !existingRules[rule] && !hasMajorRule(hostConfig.DeviceCgroupRules, rule) {
 					hostConfig.DeviceCgroupRules = append(hostConfig.DeviceCgroupRules, rule)
 					existingRules[rule] = true
 				}
@@ -744,7 +883,7 @@ func ContainerRun(containerName string) {
 		"org.container.project": "rfswift",
 	}
 	if containerCfg.desktopProto != "" {
-		containerLabels["org.rfswift.desktop"] = fmt.Sprintf("%s://%s:%s", containerCfg.desktopProto, containerCfg.desktopHost, containerCfg.desktopPort)
+		containerLabels[desktopLabel] = fmt.Sprintf("%s://%s:%s", containerCfg.desktopProto, containerCfg.desktopHost, containerCfg.desktopPort)
 	}
 	if len(hostConfig.DeviceCgroupRules) > 0 {
 		containerLabels["org.rfswift.cgroup_rules"] = strings.Join(hostConfig.DeviceCgroupRules, ",")
@@ -752,6 +891,18 @@ func ContainerRun(containerName string) {
 	if containerCfg.gpus != "" {
 		containerLabels["org.rfswift.gpus"] = containerCfg.gpus
 	}
+	if containerCfg.lab != "" {
+		containerLabels[LabLabel] = containerCfg.lab
+	}
+	if containerCfg.usb != "" {
+		containerLabels[USBLabel] = containerCfg.usb
+		containerLabels[USBDevicesLabel] = containerCfg.usbDevices
+	}
+	if containerCfg.vpn != "" {
+		if vpnType, _, err := parseVPN(containerCfg.vpn); err == nil {
+			containerLabels[VPNLabel] = vpnType
+		}
+	}
 	if containerCfg.exposedPorts == "" {
 		containerLabels["org.rfswift.exposedPorts"] = "none"
 	} else {
@@ -767,8 +918,7 @@ func ContainerRun(containerName string) {
 			common.PrintInfoMessage("Device hotplug (USB, SDR dongles) may not work without cgroup rules.")
 			common.PrintInfoMessage("To use cgroup rules, run RF Swift with sudo.")
 			if !tui.Confirm("Continue without cgroup rules?") {
-				common.PrintInfoMessage("Aborted. Re-run with: sudo ./rfswift run ...")
-				return
+				return "", fmt.Errorf("aborted, re-run with: sudo ./rfswift run ...")
 			}
 			hostConfig.DeviceCgroupRules = nil
 			delete(containerLabels, "org.rfswift.cgroup_rules")
@@ -814,10 +964,8 @@ func ContainerRun(containerName string) {
 	}
 
 	// Verify the image exists locally before attempting to create container
-	_, err = ImageInspectCompat(ctx, cli, containerCfg.imagename)
-	if err != nil {
-		common.PrintErrorMessage(fmt.Errorf("image '%s' not found locally. Pull it first with: rfswift pull -i %s", containerCfg.imagename, containerCfg.imagename))
-		return
+	if _, err := ImageInspectCompat(ctx, cli, containerCfg.imagename); err != nil {
+		return "", fmt.Errorf("image '%s' not found locally. Pull it first with: rfswift pull -i %s", containerCfg.imagename, containerCfg.imagename)
 	}
 
 	// Build container config
@@ -845,8 +993,7 @@ func ContainerRun(containerName string) {
 	if isNAT, natTarget, natUserSubnet := parseNATMode(); isNAT {
 		natNetName, natSubnet, natErr := createOrJoinNATNetwork(ctx, cli, containerName, natTarget, natUserSubnet)
 		if natErr != nil {
-			common.PrintErrorMessage(natErr)
-			return
+			return "", natErr
 		}
 		hostConfig.NetworkMode = container.NetworkMode(natNetName)
 		containerLabels["org.rfswift.nat_network"] = natNetName
@@ -864,97 +1011,12 @@ func ContainerRun(containerName string) {
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), "already in use") || strings.Contains(err.Error(), "already exists") {
-			common.PrintErrorMessage(fmt.Errorf("container name '%s' is already in use. Use a different name with -n, or exec into the existing container with: rfswift exec -c %s", containerName, containerName))
-		} else {
-			common.PrintErrorMessage(err)
-		}
-		return
-	}
-
-	// ── Podman or recording: use exec-style attach ──
-	// Podman's compat API rejects attach-before-start.
-	// Recording mode uses exec so RFSWIFT_RECORDING is session-scoped
-	// (not baked into the container env, which would persist forever).
-	if GetEngine().Type() == EnginePodman || os.Getenv("RFSWIFT_RECORDING") == "1" {
-		if _, err := cli.ContainerStart(ctx, resp.ID, client.ContainerStartOptions{}); err != nil {
-			common.PrintErrorMessage(err)
-			return
+			return "", fmt.Errorf("container name '%s' is already in use. Use a different name with -n, or exec into the existing container with: rfswift exec -c %s", containerName, containerName)
 		}
-
-		props, err := getContainerProperties(ctx, cli, resp.ID)
-		if err != nil {
-			common.PrintErrorMessage(err)
-			return
-		}
-		size := props["Size"]
-		printContainerProperties(ctx, cli, containerName, props, size)
-		common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started successfully", containerName))
-		printDesktopURL()
-
-		// Start VPN if configured
-		if containerCfg.vpn != "" {
-			if err := startVPNInContainer(ctx, cli, resp.ID); err != nil {
-				common.PrintErrorMessage(err)
-			}
-			printVPNInfo()
-		}
-
-		// Attach via exec (same as ContainerExec)
-		if err := execInteractiveSession(ctx, cli, resp.ID, containerCfg.shell, ""); err != nil {
-			common.PrintErrorMessage(err)
-		}
-		return
-	}
-
-	waiter, err := cli.ContainerAttach(ctx, resp.ID, client.ContainerAttachOptions{
-		Stderr: true,
-		Stdout: true,
-		Stdin:  true,
-		Stream: true,
-	})
-	if err != nil {
-		common.PrintErrorMessage(err)
-		return
-	}
-	defer waiter.Close()
-
-	if _, err := cli.ContainerStart(ctx, resp.ID, client.ContainerStartOptions{}); err != nil {
-		common.PrintErrorMessage(err)
-		return
-	}
-
-	props, err := getContainerProperties(ctx, cli, resp.ID)
-	if err != nil {
-		common.PrintErrorMessage(err)
-		return
-	}
-	size := props["Size"]
-	printContainerProperties(ctx, cli, containerName, props, size)
-	common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started successfully", containerName))
-	printDesktopURL()
-
-	// Start VPN if configured
-	if containerCfg.vpn != "" {
-		if err := startVPNInContainer(ctx, cli, resp.ID); err != nil {
-			common.PrintErrorMessage(err)
-		}
-		printVPNInfo()
-	}
-
-	handleIOStreams(waiter.HijackedResponse)
-	fd := int(os.Stdin.Fd())
-	if terminal.IsTerminal(fd) {
-		oldState, err := terminal.MakeRaw(fd)
-		if err != nil {
-			common.PrintErrorMessage(err)
-			return
-		}
-		defer terminal.Restore(fd, oldState)
-		go resizeTty(ctx, cli, resp.ID, fd)
-		go readAndWriteInput(waiter.HijackedResponse)
+		return "", err
 	}
 
-	waitForContainer(ctx, cli, resp.ID)
+	return resp.ID, nil
 }
 
 // execInteractiveSession creates an exec instance in the container and runs an interactive
@@ -977,7 +1039,7 @@ func execInteractiveSession(ctx context.Context, cli *client.Client, containerID
 	}
 
 	// Propagate recording indicator into the container shell
-	if os.Getenv("RFSWIFT_RECORDING") == "1" {
+	if os.Getenv("RFSWIFT_RECORDING") == "1" || containerCfg.recordFile != "" {
 		execConfig.Env = []string{"RFSWIFT_RECORDING=1"}
 	}
 
@@ -1003,6 +1065,28 @@ func execInteractiveSession(ctx context.Context, cli *client.Client, containerID
 		defer term.RestoreTerminal(inFd, state)
 	}
 
+	var rec *castRecorder
+	if containerCfg.recordFile != "" {
+		width, height := 80, 24
+		if outIsTerminal {
+			if size, err := term.GetWinsize(outFd); err == nil {
+				width, height = int(size.Width), int(size.Height)
+			}
+		}
+		rules := sessionRedactRules()
+		rec, err = createCastRecording(containerCfg.recordFile, width, height,
+			sessionTitle(ctx, cli, containerID), map[string]string{"TERM": os.Getenv("TERM"), "SHELL": shell}, rules)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := rec.Close(); err != nil {
+				common.PrintWarningMessage(err.Error())
+			}
+			noteSessionRedactions(containerCfg.recordFile, rules, rec.Redactions())
+		}()
+	}
+
 	// NOTE: Podman's compat API implicitly starts the exec session during Attach,
 	// so calling ExecStart again causes "exec session state improper". Skip it.
 	if GetEngine().Type() != EnginePodman {
@@ -1026,6 +1110,7 @@ func execInteractiveSession(ctx context.Context, cli *client.Client, containerID
 							Height: uint(size.Height),
 							Width:  uint(size.Width),
 						})
+						rec.Resize(int(size.Width), int(size.Height))
 					}
 				}
 			}
@@ -1042,6 +1127,7 @@ func execInteractiveSession(ctx context.Context, cli *client.Client, containerID
 								Height: uint(size.Height),
 								Width:  uint(size.Width),
 							})
+							rec.Resize(int(size.Width), int(size.Height))
 							lastHeight = size.Height
 							lastWidth = size.Width
 						}
@@ -1061,15 +1147,24 @@ func execInteractiveSession(ctx context.Context, cli *client.Client, containerID
 		}
 	}
 
-	// Handle I/O
+	// Handle I/O, teeing both directions into the recording when enabled
+	var stdout io.Writer = os.Stdout
+	var stdin io.Reader = os.Stdin
+	if rec != nil {
+		stdout = io.MultiWriter(os.Stdout, rec.Output())
+		if containerCfg.recordInput {
+			stdin = io.TeeReader(os.Stdin, rec.Input())
+		}
+	}
+
 	outputDone := make(chan error)
 	go func() {
-		_, err := io.Copy(os.Stdout, attachResp.Reader)
+		_, err := io.Copy(stdout, attachResp.Reader)
 		outputDone <- err
 	}()
 
 	go func() {
-		io.Copy(attachResp.Conn, os.Stdin)
+		io.Copy(attachResp.Conn, stdin)
 		attachResp.CloseWrite()
 	}()
 
@@ -1469,6 +1564,39 @@ func execCommandWithOutput(ctx context.Context, cli *client.Client, containerID
 	return string(output), nil
 }
 
+// execScriptAsRoot runs a shell script inside a container as root and returns
+// its combined output and exit code.
+//
+//	in(1): context.Context ctx
+//	in(2): *client.Client cli
+//	in(3): string containerID
+//	in(4): string script  POSIX shell script
+//	out: (string output, int exitCode, error)
+func execScriptAsRoot(ctx context.Context, cli *client.Client, containerID, script string) (string, int, error) {
+	execID, err := cli.ExecCreate(ctx, containerID, client.ExecCreateOptions{
+		User:         "root",
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          []string{"sh", "-c", script},
+	})
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to create exec instance: %v", err)
+	}
+	resp, err := cli.ExecAttach(ctx, execID.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return "", -1, fmt.Errorf("failed to attach to exec instance: %v", err)
+	}
+	var output bytes.Buffer
+	_, _ = stdcopy.StdCopy(&output, &output, resp.Reader)
+	resp.Close()
+
+	inspect, err := cli.ExecInspect(ctx, execID.ID, client.ExecInspectOptions{})
+	if err != nil {
+		return output.String(), -1, err
+	}
+	return output.String(), inspect.ExitCode, nil
+}
+
 // ContainerStop stops a running container, using the latest RF Swift container if none is specified.
 //
 //	in(1): string containerIdentifier container ID or name
@@ -3,9 +3,7 @@ package dock
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"regexp"
 	"runtime"
 	"sort"
@@ -53,9 +51,9 @@ type DockerHubResponse struct {
 
 // VersionInfo holds version information for an image
 type VersionInfo struct {
-	Version string
-	Digest  string
-	Date    time.Time
+	Version string    `json:"version" yaml:"version"`
+	Digest  string    `json:"digest" yaml:"digest"`
+	Date    time.Time `json:"date" yaml:"date"`
 }
 
 // ImageVersionMap maps base image names to their versions
@@ -234,7 +232,7 @@ func ListAvailableVersions(filterImage string) {
 
 	remoteVersions := GetAllRemoteVersions(architecture)
 
-	if len(remoteVersions) == 0 {
+	if len(remoteVersions) == 0 && !common.MachineOutput() {
 		common.PrintInfoMessage("No version information available")
 		return
 	}
@@ -250,6 +248,17 @@ func ListAvailableVersions(filterImage string) {
 	}
 	sort.Strings(imageNames)
 
+	if common.MachineOutput() {
+		filtered := ImageVersionMap{}
+		for _, name := range imageNames {
+			filtered[name] = remoteVersions[name]
+		}
+		if err := common.PrintStructured("versions", filtered); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		return
+	}
+
 	if len(imageNames) == 0 {
 		common.PrintInfoMessage("No images found matching filter")
 		return
@@ -337,7 +346,7 @@ func determineArchitectureFromTag(tagName, requestedArch string) string {
 	return requestedArch
 }
 
-// OfficialRepos returns the list of official RF-Swift Docker Hub repository names
+// OfficialRepos returns the list of official RF-Swift repository names
 // to query for remote images. It honours the configured repository (config
 // [general] repotag, surfaced as containerCfg.repotag) so that `images remote`
 // lists the repo the user is actually pointed at.
@@ -347,6 +356,9 @@ func determineArchitectureFromTag(tagName, requestedArch string) string {
 // images, while penthertz/rfswift_resolute may not be published yet) got an empty
 // listing. Falls back to penthertz/rfswift_<current-codename> when repotag is unset.
 //
+// A repotag carrying a registry host (e.g. harbor.lab/rf/rfswift_resolute)
+// points every remote lookup at that registry instead of Docker Hub.
+//
 //	out: []string slice of fully-qualified repository names (e.g. "penthertz/rfswift_noble")
 func OfficialRepos() []string {
 	if repo := strings.TrimSpace(containerCfg.repotag); repo != "" {
@@ -524,75 +536,33 @@ func FormatVersionsString(versions []VersionInfo, maxVersions int) string {
 }
 
 // GetRemoteImageDigest fetches the content-addressable digest for a specific tag
-// from Docker Hub, normalising the tag name with an architecture suffix before querying.
+// from the remote registry, normalising the tag name with an architecture suffix
+// before querying.
 //
-//	in(1): string repo Docker Hub repository (e.g. "penthertz/rfswift_resolute")
+//	in(1): string repo repository, optionally prefixed with a registry host (e.g. "penthertz/rfswift_resolute")
 //	in(2): string tag image tag name, with or without architecture suffix
 //	in(3): string architecture target architecture used to normalise the tag
 //	out: string digest of the matching tag, or "" when not found
-//	out: error non-nil if the HTTP request fails or the tag cannot be located
+//	out: error non-nil if the registry query fails or the tag cannot be located
 func GetRemoteImageDigest(repo, tag, architecture string) (string, error) {
-	var digest string
-
-	// Normalize tag to include architecture suffix
-	normalizedTag := normalizeTagForRemote(tag, architecture)
-
-	err := showLoadingIndicatorWithReturn(func() error {
-		url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/?page_size=100", repo)
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Get(url)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("tag not found")
-		} else if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to get tags: %s", resp.Status)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		var response DockerHubResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			return err
-		}
-
-		for _, hubTag := range response.Results {
-			if hubTag.Name == normalizedTag {
-				if strings.HasPrefix(hubTag.Name, "cache_") {
-					continue
-				}
-				if hubTag.MediaType != "application/vnd.oci.image.index.v1+json" {
-					continue
-				}
-
-				digest = hubTag.Digest
-				return nil
-			}
-		}
-
-		return fmt.Errorf("tag not found")
-	}, fmt.Sprintf("Checking Docker Hub for '%s' (%s)", tag, architecture))
-
-	return digest, err
+	remote, err := findRemoteTag(repo, normalizeTagForRemote(tag, architecture), architecture)
+	if err != nil || len(remote.Images) == 0 {
+		return "", err
+	}
+	return remote.Images[0].Digest, nil
 }
 
-// GetRemoteVersionsForRepo fetches and parses all image tags from a single Docker Hub
+// GetRemoteVersionsForRepo fetches and parses all image tags from a single remote
 // repository, returning a map from base image name to its sorted list of VersionInfo entries.
 //
-//	in(1): string repo Docker Hub repository to query (e.g. "penthertz/rfswift_resolute")
+//	in(1): string repo repository to query, optionally prefixed with a registry host (e.g. "penthertz/rfswift_resolute")
 //	in(2): string architecture target architecture to filter tags by
 //	out: ImageVersionMap map of base image name to sorted []VersionInfo
-//	out: error non-nil if the Docker Hub request fails
+//	out: error non-nil if the registry request fails
 func GetRemoteVersionsForRepo(repo string, architecture string) (ImageVersionMap, error) {
 	versions := make(ImageVersionMap)
 
-	tags, err := getLatestDockerHubTags(repo, architecture)
+	tags, err := getLatestRemoteTags(repo, architecture)
 	if err != nil {
 		return nil, err
 	}
@@ -669,47 +639,20 @@ func GetAllRemoteVersions(architecture string) ImageVersionMap {
 	return allVersions
 }
 
-// getRemoteImageCreationDate queries Docker Hub for the push date of the specified tag
-// in repo, filtering by architecture, and returns the parsed timestamp.
+// getRemoteImageCreationDate queries the remote registry for the push date of
+// the specified tag in repo, filtering by architecture.
 //
-//	in(1): string repo Docker Hub repository (e.g. "penthertz/rfswift_resolute")
+//	in(1): string repo repository, optionally prefixed with a registry host (e.g. "penthertz/rfswift_resolute")
 //	in(2): string tag image tag name whose creation date is requested
 //	in(3): string architecture target architecture used to match the correct tag variant
 //	out: time.Time UTC timestamp of when the tag was last pushed
-//	out: error non-nil if the HTTP request fails or the tag cannot be found
+//	out: error non-nil if the registry query fails or the tag cannot be found
 func getRemoteImageCreationDate(repo, tag, architecture string) (time.Time, error) {
-	var result time.Time
-
-	err := showLoadingIndicatorWithReturn(func() error {
-		url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/?page_size=100", repo)
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Get(url)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("tag not found")
-		} else if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to get tags: %s", resp.Status)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		date, err := getRemoteImageCreationDateFallback(body, tag, architecture)
-		if err != nil {
-			return err
-		}
-
-		result = date
-		return nil
-	}, fmt.Sprintf("Checking Docker Hub for '%s' (%s)", tag, architecture))
-
-	return result, err
+	remote, err := findRemoteTag(repo, tag, architecture)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return remote.TagLastPushed, nil
 }
 
 // getRemoteImageCreationDateFallback parses a pre-fetched Docker Hub response body
@@ -756,117 +699,68 @@ func getRemoteImageCreationDateFallback(body []byte, tag, architecture string) (
 	return time.Time{}, fmt.Errorf("tag not found")
 }
 
-// getLatestDockerHubTags fetches all pages of tags from a Docker Hub repository,
-// filters by architecture and OCI index media type, deduplicates by tag name,
-// and returns the results sorted by push date descending.
+// getLatestRemoteTags fetches the tags of repo built for architecture from
+// the registry serving it (Docker Hub or any OCI Distribution registry, see
+// registryForRepo), deduplicates them by tag name and returns them sorted by
+// push date descending.
 //
-//	in(1): string repo Docker Hub repository to paginate (e.g. "penthertz/rfswift_resolute")
+//	in(1): string repo repository, optionally prefixed with a registry host (e.g. "penthertz/rfswift_resolute")
 //	in(2): string architecture target architecture to filter tags by
 //	out: []Tag deduplicated and sorted list of matching Tag entries
-//	out: error non-nil if any HTTP request or JSON parsing step fails
-func getLatestDockerHubTags(repo string, architecture string) ([]Tag, error) {
-	var latestTags []Tag
+//	out: error non-nil if the registry cannot be queried
+func getLatestRemoteTags(repo string, architecture string) ([]Tag, error) {
+	reg, path := registryForRepo(repo)
 
+	var tags []Tag
 	err := showLoadingIndicatorWithReturn(func() error {
-		baseURL := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/", repo)
-		url := fmt.Sprintf("%s?page_size=100", baseURL)
-
-		client := &http.Client{Timeout: 10 * time.Second}
-
-		for url != "" {
-			resp, err := client.Get(url)
-			if err != nil {
-				return err
-			}
-
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close() // Close immediately after reading, not deferred in loop
-			if err != nil {
-				return err
-			}
-
-			if resp.StatusCode != http.StatusOK {
-				return fmt.Errorf("failed to get tags: %s", resp.Status)
-			}
-
-			var response DockerHubResponse
-			if err := json.Unmarshal(body, &response); err != nil {
-				return err
-			}
-
-			// Process tags from this page
-			for _, hubTag := range response.Results {
-				if strings.HasPrefix(hubTag.Name, "cache_") {
-					continue
-				}
-
-				if hubTag.MediaType != "application/vnd.oci.image.index.v1+json" {
-					continue
-				}
-
-				lastPushed, err := time.Parse(time.RFC3339, hubTag.LastUpdated)
-				if err != nil {
-					log.Printf("Warning: Could not parse date for tag %s: %v", hubTag.Name, err)
-					continue
-				}
-
-				tagArch := determineArchitectureFromTag(hubTag.Name, architecture)
-				if tagArch != architecture {
-					continue
-				}
-
-				images := []Image{
-					{
-						Architecture: tagArch,
-						Digest:       hubTag.Digest,
-					},
-				}
-
-				latestTags = append(latestTags, Tag{
-					Name:          hubTag.Name,
-					TagLastPushed: lastPushed,
-					Images:        images,
-					FullSize:      hubTag.FullSize, // ADD THIS
-				})
-			}
-
-			// Get next page URL
-			url = response.Next
-		}
-
-		return nil
-	}, "Fetching available tags")
-
+		var err error
+		tags, err = reg.ListTags(path, architecture)
+		return err
+	}, fmt.Sprintf("Fetching available tags from %s", reg.Name()))
 	if err != nil {
 		return nil, err
 	}
 
-	// Sort and deduplicate
-	sort.Slice(latestTags, func(i, j int) bool {
-		return latestTags[i].TagLastPushed.After(latestTags[j].TagLastPushed)
-	})
+	return sortAndDedupeTags(tags), nil
+}
 
-	uniqueTags := make(map[string]Tag)
-	for _, tag := range latestTags {
-		if _, exists := uniqueTags[tag.Name]; !exists {
-			uniqueTags[tag.Name] = tag
-		}
+// findRemoteTag returns the remote tag named tag (or its architecture-suffixed
+// form) for architecture, looking each candidate up directly rather than
+// listing the repository.
+//
+//	in(1): string repo repository, optionally prefixed with a registry host
+//	in(2): string tag image tag name, with or without architecture suffix
+//	in(3): string architecture target architecture
+//	out: Tag matching remote tag
+//	out: error non-nil if the registry query fails or the tag is not found
+func findRemoteTag(repo, tag, architecture string) (Tag, error) {
+	reg, path := registryForRepo(repo)
+
+	candidates := []string{tag}
+	if normalized := normalizeTagForRemote(tag, architecture); normalized != tag {
+		candidates = append(candidates, normalized)
 	}
 
-	var result []Tag
-	for _, tag := range uniqueTags {
-		result = append(result, tag)
+	for _, candidate := range candidates {
+		var found Tag
+		var ok bool
+		err := showLoadingIndicatorWithReturn(func() error {
+			var err error
+			found, ok, err = reg.GetTag(path, candidate, architecture)
+			return err
+		}, fmt.Sprintf("Looking up tag %s on %s", candidate, reg.Name()))
+		if err != nil {
+			return Tag{}, err
+		}
+		if ok {
+			return found, nil
+		}
 	}
-
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].TagLastPushed.After(result[j].TagLastPushed)
-	})
-
-	return result, nil
+	return Tag{}, fmt.Errorf("tag not found")
 }
 
 // getLatestDockerHubTagsFallback parses a pre-fetched Docker Hub tags response body,
-// applies the same architecture and media-type filters as getLatestDockerHubTags,
+// applies the same architecture and media-type filters as dockerHubRegistry.ListTags,
 // deduplicates by tag name, and returns results sorted by push date descending.
 //
 //	in(1): []byte body raw JSON body of a Docker Hub tags response
@@ -937,7 +831,19 @@ func getLatestDockerHubTagsFallback(body []byte, architecture string) ([]Tag, er
 	return latestTags, nil
 }
 
-// ListDockerImagesRepo fetches tags from all official Docker Hub repositories and
+// RemoteImageInfo describes an official image published on the registry in
+// structured output.
+type RemoteImageInfo struct {
+	Tag          string        `json:"tag" yaml:"tag"`
+	Image        string        `json:"image" yaml:"image"`
+	Pushed       time.Time     `json:"pushed" yaml:"pushed"`
+	Size         int64         `json:"size_bytes" yaml:"size_bytes"`
+	Architecture string        `json:"architecture" yaml:"architecture"`
+	Digest       string        `json:"digest" yaml:"digest"`
+	Versions     []VersionInfo `json:"versions,omitempty" yaml:"versions,omitempty"`
+}
+
+// ListDockerImagesRepo fetches tags from all official repositories and
 // renders them as a bordered table in the terminal. When showVersions is true an
 // extra column lists all available semver versions for each image. Results are
 // optionally narrowed to images whose name contains filterImage.
@@ -951,7 +857,9 @@ func ListDockerImagesRepo(showVersions bool, filterImage string) {
 		log.Fatalf("Unsupported architecture: %s", runtime.GOARCH)
 	}
 
-	rfutils.ClearScreen()
+	if !common.MachineOutput() {
+		rfutils.ClearScreen()
+	}
 
 	// Build version map for all repos
 	var allVersions ImageVersionMap
@@ -972,7 +880,7 @@ func ListDockerImagesRepo(showVersions bool, filterImage string) {
 
 	// Process each repository
 	for _, repo := range repos {
-		tags, err := getLatestDockerHubTags(repo, architecture)
+		tags, err := getLatestRemoteTags(repo, architecture)
 		if err != nil {
 			log.Printf("Warning: Error getting tags for %s: %v", repo, err)
 			continue
@@ -1100,6 +1008,7 @@ func ListDockerImagesRepo(showVersions bool, filterImage string) {
 
 	// Build table data
 	var tableData [][]string
+	infos := []RemoteImageInfo{}
 
 	// Sort by date first
 	type sortableTag struct {
@@ -1145,6 +1054,22 @@ func ListDockerImagesRepo(showVersions bool, filterImage string) {
 		}
 
 		tableData = append(tableData, row)
+		infos = append(infos, RemoteImageInfo{
+			Tag:          info.cleanName,
+			Image:        fmt.Sprintf("%s:%s", info.repo, info.cleanName),
+			Pushed:       info.pushedDate,
+			Size:         info.fullSize,
+			Architecture: info.arch,
+			Digest:       info.digest,
+			Versions:     info.versions,
+		})
+	}
+
+	if common.MachineOutput() {
+		if err := common.PrintStructured("remote_images", infos); err != nil {
+			log.Fatalf("Error encoding images: %v", err)
+		}
+		return
 	}
 
 	versionCol := len(headers) - 1
@@ -22,9 +22,9 @@ import (
 
 // CheckResult holds the outcome of a single diagnostic check.
 type CheckResult struct {
-	Name    string
-	Status  string // "ok", "warn", "fail", "skip"
-	Message string
+	Name    string `json:"name" yaml:"name"`
+	Status  string `json:"status" yaml:"status"` // "ok", "warn", "fail", "skip"
+	Message string `json:"message" yaml:"message"`
 }
 
 // DoctorReport aggregates all diagnostic results.
@@ -35,6 +35,16 @@ type DoctorReport struct {
 	fail    int
 }
 
+// doctorDocument is the structured form of a DoctorReport.
+type doctorDocument struct {
+	Results []CheckResult `json:"results" yaml:"results"`
+	Pass    int           `json:"pass" yaml:"pass"`
+	Warn    int           `json:"warn" yaml:"warn"`
+	Fail    int           `json:"fail" yaml:"fail"`
+	// Container is set for 'rfswift status' reports.
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+}
+
 func (r *DoctorReport) add(result CheckResult) {
 	r.Results = append(r.Results, result)
 	switch result.Status {
@@ -51,7 +61,9 @@ func (r *DoctorReport) add(result CheckResult) {
 func RunDoctor() {
 	report := &DoctorReport{}
 
-	tui.PrintDoctorHeader()
+	if !common.MachineOutput() {
+		tui.PrintDoctorHeader()
+	}
 
 	// Run all checks
 	checkContainerEngine(report)
@@ -72,6 +84,33 @@ func RunDoctor() {
 }
 
 func printReport(report *DoctorReport) {
+	printReportAs("doctor", "", report)
+}
+
+// printReportAs prints a report as the given structured document kind, which
+// 'rfswift status' shares with the doctor.
+//
+//	in(1): string kind  structured document kind
+//	in(2): string container  container the report is about, "" for the host
+//	in(3): *DoctorReport report
+func printReportAs(kind, container string, report *DoctorReport) {
+	if common.MachineOutput() {
+		doc := doctorDocument{
+			Results:   report.Results,
+			Pass:      report.pass,
+			Warn:      report.warn,
+			Fail:      report.fail,
+			Container: container,
+		}
+		if doc.Results == nil {
+			doc.Results = []CheckResult{}
+		}
+		if err := common.PrintStructured(kind, doc); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		return
+	}
+
 	for _, r := range report.Results {
 		tui.PrintDoctorResult(tui.DoctorResult{
 			Name:    r.Name,
@@ -308,6 +308,18 @@ func EngineSupportsDirectConfigEdit() bool {
 // Engine info display
 // ---------------------------------------------------------------------------
 
+// EngineInfo is the structured form of the "engine" CLI command.
+type EngineInfo struct {
+	Name             string   `json:"name" yaml:"name"`
+	Type             string   `json:"type" yaml:"type"`
+	Socket           string   `json:"socket" yaml:"socket"`
+	Available        bool     `json:"available" yaml:"available"`
+	ServiceRunning   bool     `json:"service_running" yaml:"service_running"`
+	DirectConfigEdit bool     `json:"direct_config_edit" yaml:"direct_config_edit"`
+	StorageRoot      string   `json:"storage_root" yaml:"storage_root"`
+	Alternatives     []string `json:"alternatives" yaml:"alternatives"`
+}
+
 // PrintEngineInfo displays the active engine status for the "engine" CLI command
 func PrintEngineInfo() {
 	engine := GetEngine()
@@ -317,12 +329,15 @@ func PrintEngineInfo() {
 	statusRunning := lipgloss.NewStyle().Foreground(tui.ColorSuccess).Render("● running")
 	statusStopped := lipgloss.NewStyle().Foreground(tui.ColorDanger).Render("● stopped")
 
+	available := engine.IsAvailable()
+	running := engine.IsServiceRunning()
+
 	avail := statusUnavail
-	if engine.IsAvailable() {
+	if available {
 		avail = statusAvail
 	}
 	svc := statusStopped
-	if engine.IsServiceRunning() {
+	if running {
 		svc = statusRunning
 	}
 
@@ -347,6 +362,28 @@ func PrintEngineInfo() {
 	if engine.Type() != EngineLima && IsLimaEngineCandidate() {
 		alternatives = append(alternatives, &LimaEngine{})
 	}
+	if common.MachineOutput() {
+		info := EngineInfo{
+			Name:             engine.Name(),
+			Type:             string(engine.Type()),
+			Socket:           engine.GetSocketPath(),
+			Available:        available,
+			ServiceRunning:   running,
+			DirectConfigEdit: engine.SupportsDirectConfigEdit(),
+			StorageRoot:      engine.GetStorageRoot(),
+			Alternatives:     []string{},
+		}
+		for _, other := range alternatives {
+			if other.IsAvailable() {
+				info.Alternatives = append(info.Alternatives, string(other.Type()))
+			}
+		}
+		if err := common.PrintStructured("engine", info); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		return
+	}
+
 	for _, other := range alternatives {
 		if other.IsAvailable() {
 			label := fmt.Sprintf("%s (use --engine %s)", other.Name(), other.Type())
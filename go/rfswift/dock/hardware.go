@@ -0,0 +1,383 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Hardware inventory: USB, serial and DRI devices with known RF tools identified
+ */
+
+package dock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// HardwareModel identifies a known tool by its USB IDs.
+type HardwareModel struct {
+	VendorID  string
+	ProductID string
+	Name      string
+	Category  string
+	Profile   string // default profile that fits the tool
+	Match     string // substring required in the manufacturer/product strings for generic IDs
+}
+
+// knownHardware is the built-in identification table. Generic bridge chips
+// (FTDI, STM32 virtual COM port) only match with their USB strings.
+var knownHardware = []HardwareModel{
+	// SDR
+	{VendorID: "1d50", ProductID: "6089", Name: "HackRF One", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "1d50", ProductID: "cc15", Name: "rad1o (HackRF)", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "1d50", ProductID: "604b", Name: "HackRF Jawbreaker", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "0bda", ProductID: "2838", Name: "RTL-SDR (RTL2838)", Category: "SDR", Profile: "sdr-light"},
+	{VendorID: "0bda", ProductID: "2832", Name: "RTL-SDR (RTL2832U)", Category: "SDR", Profile: "sdr-light"},
+	{VendorID: "2500", ProductID: "0020", Name: "USRP B200/B210", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "2500", ProductID: "0021", Name: "USRP B200mini", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "2500", ProductID: "0022", Name: "USRP B205mini", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "2cf0", ProductID: "5246", Name: "bladeRF", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "2cf0", ProductID: "5250", Name: "bladeRF 2.0 micro", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "1d50", ProductID: "6108", Name: "LimeSDR-USB", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "0403", ProductID: "601f", Name: "LimeSDR Mini", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "0456", ProductID: "b673", Name: "PlutoSDR (ADALM-PLUTO)", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "0456", ProductID: "b674", Name: "PlutoSDR (DFU mode)", Category: "SDR", Profile: "sdr-full"},
+	{VendorID: "1d50", ProductID: "60a1", Name: "Airspy", Category: "SDR", Profile: "sdr-light"},
+	{VendorID: "03eb", ProductID: "800c", Name: "Airspy HF+", Category: "SDR", Profile: "sdr-light"},
+	{VendorID: "1d50", ProductID: "605b", Name: "YARD Stick One", Category: "Sub-GHz", Profile: "sdr-light"},
+
+	// Bluetooth
+	{VendorID: "1d50", ProductID: "6002", Name: "Ubertooth One", Category: "Bluetooth", Profile: "bluetooth"},
+
+	// RFID/NFC
+	{VendorID: "9ac4", ProductID: "4b8f", Name: "Proxmark3", Category: "RFID/NFC", Profile: "rfid"},
+	{VendorID: "2d2d", ProductID: "504d", Name: "Proxmark3 (legacy firmware)", Category: "RFID/NFC", Profile: "rfid"},
+	{VendorID: "0483", ProductID: "5740", Name: "Flipper Zero", Category: "Multi-tool", Profile: "rfid", Match: "flipper"},
+
+	// Hardware hacking
+	{VendorID: "0403", ProductID: "6015", Name: "JTAGulator", Category: "Hardware", Profile: "hardware", Match: "jtagulator"},
+	{VendorID: "1d50", ProductID: "6018", Name: "Black Magic Probe", Category: "Hardware", Profile: "hardware"},
+	{VendorID: "0483", ProductID: "3748", Name: "ST-LINK/V2", Category: "Hardware", Profile: "hardware"},
+	{VendorID: "1366", ProductID: "0101", Name: "SEGGER J-Link", Category: "Hardware", Profile: "hardware"},
+}
+
+// identifyHardware returns the known model of a USB device, if any.
+//
+//	in(1): USBDevice d device read from sysfs
+//	out: *HardwareModel model, nil if the device is not in the table
+func identifyHardware(d USBDevice) *HardwareModel {
+	strs := strings.ToLower(d.Manufacturer + " " + d.Product)
+	for i, m := range knownHardware {
+		if m.VendorID != d.VendorID || m.ProductID != d.ProductID {
+			continue
+		}
+		if m.Match != "" && !strings.Contains(strs, m.Match) {
+			continue
+		}
+		return &knownHardware[i]
+	}
+	return nil
+}
+
+// HardwareDevice is one entry of the hardware inventory.
+type HardwareDevice struct {
+	Type       string   `json:"type" yaml:"type"` // usb, serial or dri
+	Path       string   `json:"path" yaml:"path"`
+	VendorID   string   `json:"vendor_id,omitempty" yaml:"vendor_id,omitempty"`
+	ProductID  string   `json:"product_id,omitempty" yaml:"product_id,omitempty"`
+	Serial     string   `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Name       string   `json:"name" yaml:"name"`
+	Category   string   `json:"category,omitempty" yaml:"category,omitempty"`
+	Known      bool     `json:"known" yaml:"known"`
+	Profile    string   `json:"profile,omitempty" yaml:"profile,omitempty"`
+	Major      int      `json:"major" yaml:"major"`
+	Minor      int      `json:"minor" yaml:"minor"`
+	Containers []string `json:"containers" yaml:"containers"`
+}
+
+// ID returns the VID:PID of the device ("" for non-USB devices).
+func (h HardwareDevice) ID() string {
+	if h.VendorID == "" {
+		return ""
+	}
+	return h.VendorID + ":" + h.ProductID
+}
+
+// hardwareFromUSB builds an inventory entry from a USB device.
+func hardwareFromUSB(typ, path string, d USBDevice) HardwareDevice {
+	h := HardwareDevice{
+		Type:       typ,
+		Path:       path,
+		VendorID:   d.VendorID,
+		ProductID:  d.ProductID,
+		Serial:     d.Serial,
+		Name:       strings.TrimSpace(d.Manufacturer + " " + d.Product),
+		Major:      d.Major,
+		Minor:      d.Minor,
+		Containers: []string{},
+	}
+	if m := identifyHardware(d); m != nil {
+		h.Name, h.Category, h.Profile, h.Known = m.Name, m.Category, m.Profile, true
+	}
+	if h.Name == "" {
+		h.Name = "unknown"
+	}
+	return h
+}
+
+// isUSBHub reports whether a device is a hub (class 09), root hubs included.
+func isUSBHub(root string, d USBDevice) bool {
+	return readSysfsAttr(filepath.Join(root, "bus", "usb", "devices", d.SysfsName), "bDeviceClass") == "09"
+}
+
+// parseDevNumbers parses a sysfs "dev" attribute ("major:minor").
+func parseDevNumbers(dev string) (int, int) {
+	major, minor, ok := strings.Cut(dev, ":")
+	if !ok {
+		return 0, 0
+	}
+	maj, _ := strconv.Atoi(major)
+	min, _ := strconv.Atoi(minor)
+	return maj, min
+}
+
+// usbParentOf walks up from a sysfs device directory to the USB device it
+// belongs to ("1-2" for a tty under "1-2:1.0").
+func usbParentOf(dir string) string {
+	for i := 0; i < 6 && dir != "/" && dir != "."; i++ {
+		if readSysfsAttr(dir, "idVendor") != "" {
+			return filepath.Base(dir)
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ""
+}
+
+// listHardware builds the inventory from a sysfs tree and a /dev tree.
+//
+//	in(1): string sysRoot sysfs mount point
+//	in(2): string devRoot device directory (/dev)
+//	in(3): bool hubs include USB hubs
+//	out: []HardwareDevice USB devices, then serial ports, then DRI nodes
+//	out: error if the USB devices cannot be enumerated
+func listHardware(sysRoot, devRoot string, hubs bool) ([]HardwareDevice, error) {
+	usbDevices, err := listUSBDevices(sysRoot)
+	if err != nil {
+		// Hosts without a USB controller (some VMs) still have serial and DRI nodes
+		if _, statErr := os.Stat(filepath.Join(sysRoot, "bus", "usb")); !os.IsNotExist(statErr) {
+			return nil, err
+		}
+	}
+
+	inventory := []HardwareDevice{}
+	bySysfsName := make(map[string]USBDevice)
+	for _, d := range usbDevices {
+		bySysfsName[d.SysfsName] = d
+		if !hubs && isUSBHub(sysRoot, d) {
+			continue
+		}
+		inventory = append(inventory, hardwareFromUSB("usb", d.DevPath(), d))
+	}
+
+	for _, pattern := range []string{"ttyUSB*", "ttyACM*"} {
+		matches, _ := filepath.Glob(filepath.Join(devRoot, pattern))
+		sort.Strings(matches)
+		for _, node := range matches {
+			name := filepath.Base(node)
+			classDir := filepath.Join(sysRoot, "class", "tty", name)
+			h := HardwareDevice{Type: "serial", Path: "/dev/" + name, Name: "serial port", Containers: []string{}}
+			if device, err := filepath.EvalSymlinks(filepath.Join(classDir, "device")); err == nil {
+				if parent, ok := bySysfsName[usbParentOf(device)]; ok {
+					h = hardwareFromUSB("serial", "/dev/"+name, parent)
+				}
+			}
+			h.Major, h.Minor = parseDevNumbers(readSysfsAttr(classDir, "dev"))
+			inventory = append(inventory, h)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(devRoot, "dri", "*"))
+	sort.Strings(matches)
+	for _, node := range matches {
+		name := filepath.Base(node)
+		if !strings.HasPrefix(name, "card") && !strings.HasPrefix(name, "renderD") {
+			continue
+		}
+		classDir := filepath.Join(sysRoot, "class", "drm", name)
+		h := HardwareDevice{Type: "dri", Path: "/dev/dri/" + name, Name: "GPU", Category: "GPU", Containers: []string{}}
+		if driver, err := filepath.EvalSymlinks(filepath.Join(classDir, "device", "driver")); err == nil {
+			h.Name = "GPU (" + filepath.Base(driver) + ")"
+		}
+		h.Major, h.Minor = parseDevNumbers(readSysfsAttr(classDir, "dev"))
+		inventory = append(inventory, h)
+	}
+	return inventory, nil
+}
+
+// deviceAccess is what a container was given to reach host devices.
+type deviceAccess struct {
+	Name       string
+	Privileged bool
+	Devices    []string // host paths of device mappings
+	Binds      []string // host sources of bind mounts
+	Rules      []string // device cgroup rules
+}
+
+// underPath reports whether p is dir or inside it.
+func underPath(p, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// hasDevice reports whether the container can open the device: it must be
+// privileged, have the node mapped as a device, or have it bind-mounted with
+// a cgroup rule granting it.
+func (a deviceAccess) hasDevice(h HardwareDevice) bool {
+	if a.Privileged {
+		return true
+	}
+	for _, d := range a.Devices {
+		if underPath(h.Path, d) {
+			return true
+		}
+	}
+	for _, b := range a.Binds {
+		if underPath(h.Path, b) && cgroupCovers(a.Rules, USBDevice{Major: h.Major, Minor: h.Minor}) {
+			return true
+		}
+	}
+	return false
+}
+
+// accessFromHostConfig extracts the device access of a container.
+func accessFromHostConfig(name string, hc *container.HostConfig) deviceAccess {
+	a := deviceAccess{Name: name}
+	if hc == nil {
+		return a
+	}
+	a.Privileged = hc.Privileged
+	a.Rules = hc.DeviceCgroupRules
+	for _, d := range hc.Devices {
+		a.Devices = append(a.Devices, d.PathOnHost)
+	}
+	for _, b := range hc.Binds {
+		source, _, _ := strings.Cut(b, ":")
+		a.Binds = append(a.Binds, source)
+	}
+	for _, m := range hc.Mounts {
+		if m.Type == "bind" {
+			a.Binds = append(a.Binds, m.Source)
+		}
+	}
+	return a
+}
+
+// runningContainerAccess returns the device access of the running RF Swift
+// containers; an unreachable engine yields none.
+func runningContainerAccess(ctx context.Context) []deviceAccess {
+	cli, err := NewEngineClient()
+	if err != nil {
+		return nil
+	}
+	defer cli.Close()
+
+	filters := make(client.Filters)
+	filters.Add("label", "org.container.project=rfswift")
+	res, err := cli.ContainerList(ctx, client.ContainerListOptions{Filters: filters})
+	if err != nil {
+		return nil
+	}
+
+	var access []deviceAccess
+	for _, c := range res.Items {
+		info, err := inspectContainer(ctx, cli, c.ID)
+		if err != nil {
+			continue
+		}
+		access = append(access, accessFromHostConfig(strings.TrimPrefix(info.Name, "/"), info.HostConfig))
+	}
+	return access
+}
+
+// ListHardware enumerates the USB, serial and DRI devices of the host,
+// identifies known tools and tells which running RF Swift containers can
+// access each of them.
+//
+//	in(1): bool hubs include USB hubs
+//	out: []HardwareDevice inventory
+//	out: error if the host devices cannot be enumerated
+func ListHardware(hubs bool) ([]HardwareDevice, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("the hardware inventory reads sysfs and needs a Linux host (on macOS use 'rfswift macusb list', on Windows 'rfswift winusb list')")
+	}
+	inventory, err := listHardware(sysfsRoot, "/dev", hubs)
+	if err != nil || len(inventory) == 0 {
+		return inventory, err
+	}
+
+	access := runningContainerAccess(context.Background())
+	for i := range inventory {
+		for _, a := range access {
+			if a.hasDevice(inventory[i]) {
+				inventory[i].Containers = append(inventory[i].Containers, a.Name)
+			}
+		}
+	}
+	return inventory, nil
+}
+
+// DisplayHardware prints the hardware inventory as a table, or as a
+// structured document with --output json|yaml.
+//
+//	in(1): bool hubs include USB hubs
+//	out: error if the inventory cannot be built
+func DisplayHardware(hubs bool) error {
+	inventory, err := ListHardware(hubs)
+	if err != nil {
+		return err
+	}
+	if common.MachineOutput() {
+		return common.PrintStructured("hardware", inventory)
+	}
+	if len(inventory) == 0 {
+		common.PrintInfoMessage("No USB, serial or DRI device found")
+		return nil
+	}
+
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	rows := make([][]string, len(inventory))
+	for i, h := range inventory {
+		rows[i] = []string{h.Type, h.Path, dash(h.ID()), h.Name, dash(h.Category), dash(strings.Join(h.Containers, ", ")), dash(h.Profile)}
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   "🔌 Hardware",
+		Headers: []string{"Type", "Device", "ID", "Name", "Category", "Containers", "Profile"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			switch {
+			case col == 3 && inventory[row].Known:
+				return tui.ColorSuccess
+			case col == 5 && content != "-":
+				return tui.ColorPrimary
+			case content == "-":
+				return tui.ColorMuted
+			}
+			return lipgloss.Color("")
+		},
+	})
+	return nil
+}
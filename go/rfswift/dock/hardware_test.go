@@ -0,0 +1,137 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentifyHardware(t *testing.T) {
+	tests := []struct {
+		device USBDevice
+		want   string
+	}{
+		{USBDevice{VendorID: "1d50", ProductID: "6089"}, "HackRF One"},
+		{USBDevice{VendorID: "0bda", ProductID: "2838"}, "RTL-SDR (RTL2838)"},
+		{USBDevice{VendorID: "2500", ProductID: "0020"}, "USRP B200/B210"},
+		{USBDevice{VendorID: "9ac4", ProductID: "4b8f"}, "Proxmark3"},
+		{USBDevice{VendorID: "0483", ProductID: "5740", Manufacturer: "Flipper Devices Inc."}, "Flipper Zero"},
+		{USBDevice{VendorID: "0483", ProductID: "5740", Manufacturer: "STMicroelectronics"}, ""},
+		{USBDevice{VendorID: "0403", ProductID: "6015", Product: "JTAGulator"}, "JTAGulator"},
+		{USBDevice{VendorID: "0403", ProductID: "6015", Product: "FT231X USB UART"}, ""},
+		{USBDevice{VendorID: "dead", ProductID: "beef"}, ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if m := identifyHardware(tt.device); m != nil {
+			got = m.Name
+		}
+		if got != tt.want {
+			t.Errorf("identifyHardware(%s %q) = %q, want %q", tt.device.ID(), tt.device.Manufacturer+tt.device.Product, got, tt.want)
+		}
+	}
+}
+
+func TestListHardware(t *testing.T) {
+	sys := fakeSysfs(t)
+	writeSysfsDevice(t, sys, "usb1", map[string]string{"bDeviceClass": "09"})
+	writeSysfsDevice(t, sys, "1-5", map[string]string{
+		"idVendor": "9ac4", "idProduct": "4b8f", "busnum": "1", "devnum": "8", "dev": "189:7",
+	})
+	writeSysfsDevice(t, sys, "1-5/1-5:1.0/tty/ttyACM0", nil)
+
+	mustMkdir := func(p string) {
+		if err := os.MkdirAll(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite := func(p, content string) {
+		mustMkdir(filepath.Dir(p))
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustLink := func(target, link string) {
+		mustMkdir(filepath.Dir(link))
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustLink(filepath.Join(sys, "bus/usb/devices/1-5/1-5:1.0/tty/ttyACM0"), filepath.Join(sys, "class/tty/ttyACM0/device"))
+	mustWrite(filepath.Join(sys, "class/tty/ttyACM0/dev"), "166:0\n")
+	mustWrite(filepath.Join(sys, "class/tty/ttyUSB3/dev"), "188:3\n")
+	mustMkdir(filepath.Join(sys, "drivers/i915"))
+	mustLink(filepath.Join(sys, "drivers/i915"), filepath.Join(sys, "class/drm/renderD128/device/driver"))
+	mustWrite(filepath.Join(sys, "class/drm/renderD128/dev"), "226:128\n")
+
+	dev := t.TempDir()
+	for _, node := range []string{"ttyACM0", "ttyUSB3", "ttyS0", "dri/renderD128", "dri/by-path"} {
+		mustWrite(filepath.Join(dev, node), "")
+	}
+
+	inventory, err := listHardware(sys, dev, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct{ typ, path, id, name, profile string }
+	var got []row
+	for _, h := range inventory {
+		got = append(got, row{h.Type, h.Path, h.ID(), h.Name, h.Profile})
+	}
+	want := []row{
+		{"usb", "/dev/bus/usb/001/005", "1d50:6089", "HackRF One", "sdr-full"},
+		{"usb", "/dev/bus/usb/001/007", "1d50:6089", "HackRF One", "sdr-full"},
+		{"usb", "/dev/bus/usb/001/008", "9ac4:4b8f", "Proxmark3", "rfid"},
+		{"usb", "/dev/bus/usb/002/003", "0bda:2838", "RTL-SDR (RTL2838)", "sdr-light"},
+		{"serial", "/dev/ttyUSB3", "", "serial port", ""},
+		{"serial", "/dev/ttyACM0", "9ac4:4b8f", "Proxmark3", "rfid"},
+		{"dri", "/dev/dri/renderD128", "", "GPU (i915)", ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("listHardware() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("listHardware()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if h := inventory[5]; h.Major != 166 || h.Minor != 0 {
+		t.Errorf("ttyACM0 dev = %d:%d, want 166:0", h.Major, h.Minor)
+	}
+
+	withHubs, _ := listHardware(sys, dev, true)
+	if len(withHubs) != len(inventory)+1 {
+		t.Errorf("listHardware(hubs) = %d entries, want %d", len(withHubs), len(inventory)+1)
+	}
+}
+
+func TestDeviceAccess(t *testing.T) {
+	hackrf := HardwareDevice{Path: "/dev/bus/usb/001/005", Major: 189, Minor: 4}
+	acm := HardwareDevice{Path: "/dev/ttyACM0", Major: 166, Minor: 0}
+
+	tests := []struct {
+		name   string
+		access deviceAccess
+		device HardwareDevice
+		want   bool
+	}{
+		{"privileged", deviceAccess{Privileged: true}, acm, true},
+		{"tree bind with rule", deviceAccess{Binds: []string{"/dev/bus/usb"}, Rules: []string{"c 189:* rwm"}}, hackrf, true},
+		{"tree bind without rule", deviceAccess{Binds: []string{"/dev/bus/usb"}}, hackrf, false},
+		{"exact device", deviceAccess{Devices: []string{"/dev/bus/usb/001/005"}}, hackrf, true},
+		{"other device", deviceAccess{Devices: []string{"/dev/bus/usb/001/007"}}, hackrf, false},
+		{"prefix is not a parent", deviceAccess{Devices: []string{"/dev/ttyACM"}}, acm, false},
+		{"serial device", deviceAccess{Devices: []string{"/dev/ttyACM0"}}, acm, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.access.hasDevice(tt.device); got != tt.want {
+			t.Errorf("%s: hasDevice(%s) = %v, want %v", tt.name, tt.device.Path, got, tt.want)
+		}
+	}
+}
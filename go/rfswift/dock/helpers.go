@@ -253,21 +253,15 @@ func normalizeImageName(imageName string) string {
 }
 
 // parseImageName splits an image reference into its repository and tag parts.
-// A leading "docker.io/" prefix is stripped before splitting. If no tag is
-// present, "latest" is used as the default.
+// A leading "docker.io/" prefix is stripped before splitting and a registry
+// port is kept in the repository. If no tag is present, "latest" is used as
+// the default.
 //
 //	in(1): string imageName - image reference, optionally prefixed with "docker.io/"
 //	out: string - repository portion of the image reference
 //	out: string - tag portion of the image reference (defaults to "latest")
 func parseImageName(imageName string) (string, string) {
-	imageName = strings.TrimPrefix(imageName, "docker.io/")
-	parts := strings.Split(imageName, ":")
-	repo := parts[0]
-	tag := "latest"
-	if len(parts) > 1 {
-		tag = parts[1]
-	}
-	return repo, tag
+	return splitImageRef(strings.TrimPrefix(imageName, "docker.io/"))
 }
 
 // bindExistsByPrefix reports whether a bind mount spec matching mount already
@@ -369,62 +363,71 @@ func ParseBindedPorts(bindedPortsStr string) network.PortMap {
 		portEntries = strings.Split(bindedPortsStr, ",")
 	}
 	for _, entry := range portEntries {
-		entry = strings.TrimSpace(entry)
-		parts := strings.Split(entry, ":")
-		if len(parts) < 2 || len(parts) > 3 {
-			fmt.Printf("Invalid port binding format: %s (expected hostPort:containerPort/proto or containerPort/proto:hostPort)\n", entry)
+		portKey, binding, err := parsePortBinding(entry)
+		if err != nil {
+			fmt.Println(err)
 			continue
 		}
+		portBindings[portKey] = append(portBindings[portKey], binding)
+	}
 
-		var containerPortProto, hostPort, hostAddress string
+	return portBindings
+}
 
-		// Detect format by checking which part contains "/proto"
-		if strings.Contains(parts[0], "/") {
-			// Internal format: containerPort/proto:hostPort or containerPort/proto:hostIP:hostPort
-			containerPortProto = strings.TrimSpace(parts[0])
-			if len(parts) == 3 {
-				hostAddress = strings.TrimSpace(parts[1])
-				hostPort = strings.TrimSpace(parts[2])
-			} else {
-				hostPort = strings.TrimSpace(parts[1])
-			}
-		} else if len(parts) == 2 && strings.Contains(parts[1], "/") {
-			// Docker-standard 2-part: hostPort:containerPort/proto
-			hostPort = strings.TrimSpace(parts[0])
-			containerPortProto = strings.TrimSpace(parts[1])
-		} else if len(parts) == 3 && strings.Contains(parts[2], "/") {
-			// Docker-standard 3-part: hostIP:hostPort:containerPort/proto
-			hostAddress = strings.TrimSpace(parts[0])
-			hostPort = strings.TrimSpace(parts[1])
-			containerPortProto = strings.TrimSpace(parts[2])
+// parsePortBinding parses one port binding entry in any of the formats
+// accepted by ParseBindedPorts.
+//
+//	in(1): string entry - single port binding spec (e.g. "127.0.0.1:8080:80/tcp")
+//	out: network.Port - container port
+//	out: network.PortBinding - host side of the binding
+//	out: error - describing why the entry is invalid
+func parsePortBinding(entry string) (network.Port, network.PortBinding, error) {
+	entry = strings.TrimSpace(entry)
+	parts := strings.Split(entry, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return network.Port{}, network.PortBinding{}, fmt.Errorf("Invalid port binding format: %s (expected hostPort:containerPort/proto or containerPort/proto:hostPort)", entry)
+	}
+
+	var containerPortProto, hostPort, hostAddress string
+
+	// Detect format by checking which part contains "/proto"
+	if strings.Contains(parts[0], "/") {
+		// Internal format: containerPort/proto:hostPort or containerPort/proto:hostIP:hostPort
+		containerPortProto = strings.TrimSpace(parts[0])
+		if len(parts) == 3 {
+			hostAddress = strings.TrimSpace(parts[1])
+			hostPort = strings.TrimSpace(parts[2])
 		} else {
-			fmt.Printf("Invalid port binding format: %s (no port/protocol found, expected e.g. 80/tcp)\n", entry)
-			continue
+			hostPort = strings.TrimSpace(parts[1])
 		}
+	} else if len(parts) == 2 && strings.Contains(parts[1], "/") {
+		// Docker-standard 2-part: hostPort:containerPort/proto
+		hostPort = strings.TrimSpace(parts[0])
+		containerPortProto = strings.TrimSpace(parts[1])
+	} else if len(parts) == 3 && strings.Contains(parts[2], "/") {
+		// Docker-standard 3-part: hostIP:hostPort:containerPort/proto
+		hostAddress = strings.TrimSpace(parts[0])
+		hostPort = strings.TrimSpace(parts[1])
+		containerPortProto = strings.TrimSpace(parts[2])
+	} else {
+		return network.Port{}, network.PortBinding{}, fmt.Errorf("Invalid port binding format: %s (no port/protocol found, expected e.g. 80/tcp)", entry)
+	}
 
-		portKey, err := network.ParsePort(containerPortProto)
-		if err != nil {
-			fmt.Printf("Invalid port binding format: %s (%v)\n", entry, err)
-			continue
-		}
+	portKey, err := network.ParsePort(containerPortProto)
+	if err != nil {
+		return network.Port{}, network.PortBinding{}, fmt.Errorf("Invalid port binding format: %s (%v)", entry, err)
+	}
 
-		var hostIP netip.Addr
-		if hostAddress != "" {
-			parsed, err := netip.ParseAddr(hostAddress)
-			if err != nil {
-				fmt.Printf("Invalid host IP in port binding: %s (%v)\n", entry, err)
-				continue
-			}
-			hostIP = parsed
+	var hostIP netip.Addr
+	if hostAddress != "" {
+		parsed, err := netip.ParseAddr(hostAddress)
+		if err != nil {
+			return network.Port{}, network.PortBinding{}, fmt.Errorf("Invalid host IP in port binding: %s (%v)", entry, err)
 		}
-
-		portBindings[portKey] = append(portBindings[portKey], network.PortBinding{
-			HostIP:   hostIP,
-			HostPort: hostPort,
-		})
+		hostIP = parsed
 	}
 
-	return portBindings
+	return portKey, network.PortBinding{HostIP: hostIP, HostPort: hostPort}, nil
 }
 
 // getDeviceMappingsFromString parses a comma-separated list of "hostPath:containerPath"
@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -27,67 +26,20 @@ import (
 	"penthertz/rfswift/tui"
 )
 
-// getRemoteImageDigest fetches the digest for a specific tag from Docker Hub,
-// normalizing the tag for the target architecture before querying the registry API.
+// getRemoteImageDigest fetches the digest for a specific tag from the remote
+// registry, normalizing the tag for the target architecture before querying.
 //
-//	in(1): string repo         Docker Hub repository path (e.g. "penthertz/rfswift_resolute")
+//	in(1): string repo         repository, optionally prefixed with a registry host
 //	in(2): string tag          image tag to look up
 //	in(3): string architecture target architecture string used to normalize the tag
 //	out:   string              digest string for the matched tag, empty on failure
 //	out:   error               non-nil if the tag was not found or the request failed
 func getRemoteImageDigest(repo, tag, architecture string) (string, error) {
-	var digest string
-
-	// Normalize tag to include architecture suffix
-	normalizedTag := normalizeTagForRemote(tag, architecture)
-
-	err := showLoadingIndicatorWithReturn(func() error {
-		url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/?page_size=100", repo)
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Get(url)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode == http.StatusNotFound {
-			return fmt.Errorf("tag not found")
-		} else if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to get tags: %s", resp.Status)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		var response DockerHubResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			return err
-		}
-
-		for _, hubTag := range response.Results {
-			if hubTag.Name == normalizedTag {
-				if strings.HasPrefix(hubTag.Name, "cache_") {
-					continue
-				}
-				if hubTag.MediaType != "application/vnd.oci.image.index.v1+json" {
-					continue
-				}
-
-				digest = hubTag.Digest
-				return nil
-			}
-		}
-
-		return fmt.Errorf("tag not found")
-	}, fmt.Sprintf("Checking Docker Hub for '%s' (%s)", tag, architecture))
-
-	return digest, err
+	return GetRemoteImageDigest(repo, tag, architecture)
 }
 
 // checkIfImageIsUpToDate reports whether the given tag is listed among the
-// latest Docker Hub tags for the current architecture of the host system.
+// latest remote tags for the current architecture of the host system.
 //
 //	in(1): string repo  Docker Hub repository path
 //	in(2): string tag   image tag to check
@@ -95,7 +47,7 @@ func getRemoteImageDigest(repo, tag, architecture string) (string, error) {
 //	out:   error        non-nil if the remote tag list could not be retrieved
 func checkIfImageIsUpToDate(repo, tag string) (bool, error) {
 	architecture := getArchitecture()
-	tags, err := getLatestDockerHubTags(repo, architecture)
+	tags, err := getLatestRemoteTags(repo, architecture)
 	if err != nil {
 		return false, err
 	}
@@ -371,12 +323,7 @@ func ContainerPull(imageref string, imagetag string) {
 	imageref = normalizeImageName(imageref)
 
 	// Parse the image reference to get repo and tag
-	parts := strings.Split(imageref, ":")
-	repo := parts[0]
-	tag := "latest"
-	if len(parts) > 1 {
-		tag = parts[1]
-	}
+	repo, tag := splitImageRef(imageref)
 
 	// Check if this is an official image that might need architecture suffix
 	isOfficial := IsOfficialImage(imageref)
@@ -418,7 +365,7 @@ func ContainerPull(imageref string, imagetag string) {
 
 	// Pull the image from remote using the architecture-specific reference
 	common.PrintInfoMessage(fmt.Sprintf("Pulling image from: %s", actualPullRef))
-	out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{})
+	out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{RegistryAuth: registryAuthFor(actualPullRef)})
 	if err != nil {
 		common.PrintErrorMessage(err)
 		return
@@ -594,6 +541,17 @@ func ListImageTags(labelKey string, labelValue string) []string {
 	return tags
 }
 
+// ImageInfo describes a local RF Swift image in structured output.
+type ImageInfo struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Tag        string `json:"tag" yaml:"tag"`
+	ID         string `json:"id" yaml:"id"`
+	Created    string `json:"created" yaml:"created"`
+	Size       int64  `json:"size_bytes" yaml:"size_bytes"`
+	Status     string `json:"status" yaml:"status"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
 // PrintImagesTable prints a formatted terminal table of RF Swift images filtered
 // by a Docker label, optionally showing resolved version strings and restricting
 // rows to tags that contain filterImage as a substring.
@@ -615,7 +573,9 @@ func PrintImagesTable(labelKey string, labelValue string, showVersions bool, fil
 		log.Fatalf("Error listing images: %v", err)
 	}
 
-	rfutils.ClearScreen()
+	if !common.MachineOutput() {
+		rfutils.ClearScreen()
+	}
 
 	// Fetch remote versions ONCE for all checks - BY REPO
 	architecture := getArchitecture()
@@ -628,17 +588,20 @@ func PrintImagesTable(labelKey string, labelValue string, showVersions bool, fil
 
 	// Prepare table data
 	tableData := [][]string{}
+	infos := []ImageInfo{}
 	maxStatusLength := 0
 	maxVersionLength := 0
 
 	for _, image := range images {
+		// Snapshots carry the container labels; they are listed by 'snapshot list'
+		if image.Labels[SnapshotLabel] == "true" {
+			continue
+		}
 		for _, repoTag := range image.RepoTags {
-			repoTagParts := strings.Split(repoTag, ":")
-			if len(repoTagParts) != 2 {
+			if !strings.Contains(repoTag, ":") {
 				continue
 			}
-			repository := repoTagParts[0]
-			tag := repoTagParts[1]
+			repository, tag := splitImageRef(repoTag)
 
 			// Apply filter if specified
 			if filterImage != "" && !strings.Contains(strings.ToLower(tag), strings.ToLower(filterImage)) {
@@ -714,9 +677,25 @@ func PrintImagesTable(labelKey string, labelValue string, showVersions bool, fil
 			}
 
 			tableData = append(tableData, row)
+			infos = append(infos, ImageInfo{
+				Repository: repository,
+				Tag:        tag,
+				ID:         strings.TrimPrefix(image.ID, "sha256:"),
+				Created:    created,
+				Size:       image.Size,
+				Status:     status,
+				Version:    versionDisplay,
+			})
 		}
 	}
 
+	if common.MachineOutput() {
+		if err := common.PrintStructured("images", infos); err != nil {
+			log.Fatalf("Error encoding images: %v", err)
+		}
+		return
+	}
+
 	// Build headers
 	headers := []string{"Repository", "Tag", "Image ID", "Created", "Size", "Status"}
 	if showVersions {
@@ -934,12 +913,7 @@ func SaveImageToFile(imageName string, outputFile string, pullFirst bool) error
 		}
 
 		// Parse image name for architecture handling
-		parts := strings.Split(imageName, ":")
-		repo := parts[0]
-		tag := "latest"
-		if len(parts) > 1 {
-			tag = parts[1]
-		}
+		repo, tag := splitImageRef(imageName)
 
 		// Check if this is an official image
 		isOfficial := IsOfficialImage(imageName)
@@ -960,7 +934,7 @@ func SaveImageToFile(imageName string, outputFile string, pullFirst bool) error
 		}
 
 		// Pull the image
-		out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{})
+		out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{RegistryAuth: registryAuthFor(actualPullRef)})
 		if err != nil {
 			return fmt.Errorf("failed to pull image: %v", err)
 		}
@@ -1083,7 +1057,7 @@ func ContainerPullVersion(imageref string, version string, imagetag string) {
 
 	common.PrintInfoMessage(fmt.Sprintf("Pulling %s...", pullRef))
 
-	out, err := cli.ImagePull(ctx, pullRef, client.ImagePullOptions{})
+	out, err := cli.ImagePull(ctx, pullRef, client.ImagePullOptions{RegistryAuth: registryAuthFor(pullRef)})
 	if err != nil {
 		common.PrintErrorMessage(fmt.Errorf("failed to pull image: %v", err))
 		return
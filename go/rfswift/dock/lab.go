@@ -0,0 +1,569 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Declarative multi-container labs (YAML manifests)
+ */
+package dock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/moby/moby/client"
+	"gopkg.in/yaml.v3"
+
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// LabLabel marks a container as part of a lab; its value is the lab name.
+const LabLabel = "org.rfswift.lab"
+
+// Lab describes a set of coordinated containers sharing one NAT network,
+// e.g. a 5G core, a gNB, a UE simulator and a capture box.
+type Lab struct {
+	Name       string         `yaml:"name"`
+	Network    LabNetwork     `yaml:"network"`
+	Containers []LabContainer `yaml:"containers"`
+}
+
+// LabNetwork configures the shared NAT network of a lab.
+type LabNetwork struct {
+	Name   string `yaml:"name,omitempty"`   // defaults to the lab name
+	Subnet string `yaml:"subnet,omitempty"` // auto-allocated when empty
+}
+
+// LabContainer is one container of a lab. It either references an existing
+// profile, sets run options inline, or both: inline values override the
+// profile, exactly like CLI flags do with `rfswift run --profile`.
+type LabContainer struct {
+	Name          string   `yaml:"name"`
+	Profile       string   `yaml:"profile,omitempty"`
+	DependsOn     []string `yaml:"depends_on,omitempty"`
+	Image         string   `yaml:"image,omitempty"`
+	Command       string   `yaml:"command,omitempty"`
+	ExposedPorts  string   `yaml:"exposed_ports,omitempty"`
+	PortBindings  string   `yaml:"port_bindings,omitempty"`
+	Desktop       bool     `yaml:"desktop,omitempty"`
+	DesktopConfig string   `yaml:"desktop_config,omitempty"`
+	DesktopPass   string   `yaml:"desktop_pass,omitempty"`
+	DesktopSSL    bool     `yaml:"desktop_ssl,omitempty"`
+	NoX11         bool     `yaml:"no_x11,omitempty"`
+	Display       string   `yaml:"display,omitempty"`
+	PulseServer   string   `yaml:"pulse_server,omitempty"`
+	Seccomp       string   `yaml:"seccomp,omitempty"`
+	Privileged    bool     `yaml:"privileged,omitempty"`
+	Realtime      bool     `yaml:"realtime,omitempty"`
+	Devices       string   `yaml:"devices,omitempty"`
+	Bindings      string   `yaml:"bindings,omitempty"`
+	Caps          string   `yaml:"caps,omitempty"`
+	Cgroups       string   `yaml:"cgroups,omitempty"`
+	Ulimits       string   `yaml:"ulimits,omitempty"`
+	ExtraEnv      string   `yaml:"extraenv,omitempty"`
+	ExtraHosts    string   `yaml:"extrahosts,omitempty"`
+	GPUs          string   `yaml:"gpus,omitempty"`
+	VPN           string   `yaml:"vpn,omitempty"`
+	Workspace     string   `yaml:"workspace,omitempty"`
+	CPUs          string   `yaml:"cpus,omitempty"`
+	Memory        string   `yaml:"memory,omitempty"`
+	CpusetCpus    string   `yaml:"cpuset_cpus,omitempty"`
+	ShmSize       string   `yaml:"shm_size,omitempty"`
+	PidsLimit     string   `yaml:"pids_limit,omitempty"`
+}
+
+// resources returns the engine resource limits of the lab container.
+func (c LabContainer) resources() ResourceLimits {
+	return ResourceLimits{CPUs: c.CPUs, Memory: c.Memory, CpusetCpus: c.CpusetCpus, ShmSize: c.ShmSize, PidsLimit: c.PidsLimit}
+}
+
+// LoadLab reads and validates a lab manifest.
+//
+//	in(1): string path manifest YAML file
+//	out: *Lab, error
+func LoadLab(path string) (*Lab, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lab manifest: %w", err)
+	}
+
+	var lab Lab
+	if err := yaml.Unmarshal(data, &lab); err != nil {
+		return nil, fmt.Errorf("failed to parse lab manifest: %w", err)
+	}
+
+	if lab.Name == "" {
+		return nil, fmt.Errorf("lab manifest %s has no 'name'", path)
+	}
+	if lab.Network.Name == "" {
+		lab.Network.Name = lab.Name
+	}
+	if len(lab.Containers) == 0 {
+		return nil, fmt.Errorf("lab '%s' defines no containers", lab.Name)
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range lab.Containers {
+		if c.Name == "" {
+			return nil, fmt.Errorf("lab '%s': container #%d has no 'name'", lab.Name, i+1)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("lab '%s': container '%s' is defined twice", lab.Name, c.Name)
+		}
+		seen[c.Name] = true
+		if c.Profile == "" && c.Image == "" {
+			return nil, fmt.Errorf("lab '%s': container '%s' needs a 'profile' or an 'image'", lab.Name, c.Name)
+		}
+		if err := c.resources().Validate(); err != nil {
+			return nil, fmt.Errorf("lab '%s': container '%s': %v", lab.Name, c.Name, err)
+		}
+	}
+
+	if _, err := lab.startOrder(); err != nil {
+		return nil, err
+	}
+	return &lab, nil
+}
+
+// startOrder returns the lab containers sorted so that every container comes
+// after the ones it depends on. Containers without a dependency between them
+// keep their manifest order.
+//
+//	out: []LabContainer ordered containers, error on unknown dependency or cycle
+func (l *Lab) startOrder() ([]LabContainer, error) {
+	index := make(map[string]int, len(l.Containers))
+	for i, c := range l.Containers {
+		index[c.Name] = i
+	}
+
+	indegree := make([]int, len(l.Containers))
+	dependents := make([][]int, len(l.Containers))
+	for i, c := range l.Containers {
+		for _, dep := range c.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("lab '%s': container '%s' depends on unknown container '%s'", l.Name, c.Name, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("lab '%s': container '%s' depends on itself", l.Name, c.Name)
+			}
+			indegree[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready []int
+	for i := range l.Containers {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var ordered []LabContainer
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, l.Containers[i])
+		for _, d := range dependents[i] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(ordered) != len(l.Containers) {
+		var cyclic []string
+		for i, c := range l.Containers {
+			if indegree[i] > 0 {
+				cyclic = append(cyclic, c.Name)
+			}
+		}
+		return nil, fmt.Errorf("lab '%s': dependency cycle between %s", l.Name, strings.Join(cyclic, ", "))
+	}
+	return ordered, nil
+}
+
+// natNetworkName returns the full engine name of the lab's shared network.
+func (l *Lab) natNetworkName() string {
+	if strings.HasPrefix(l.Network.Name, NATNetworkPrefix) {
+		return l.Network.Name
+	}
+	return NATNetworkPrefix + l.Network.Name
+}
+
+// resolveLabContainer merges a lab container with the profile it references.
+// Inline values take precedence over the profile.
+//
+//	in(1): LabContainer c lab container definition
+//	out: LabContainer resolved definition, error if the profile is missing
+func resolveLabContainer(c LabContainer) (LabContainer, error) {
+	if c.Profile == "" {
+		return c, nil
+	}
+	prof, err := GetProfileByName(c.Profile)
+	if err != nil {
+		return c, err
+	}
+
+	if c.Image == "" {
+		c.Image = prof.Image
+	}
+	if c.Devices == "" {
+		c.Devices = prof.Devices
+	}
+	if c.Bindings == "" {
+		c.Bindings = prof.Bindings
+	}
+	if c.ExposedPorts == "" {
+		c.ExposedPorts = prof.ExposedPorts
+	}
+	if c.PortBindings == "" {
+		c.PortBindings = prof.PortBindings
+	}
+	if c.Caps == "" {
+		c.Caps = prof.Caps
+	}
+	if c.Cgroups == "" {
+		c.Cgroups = prof.Cgroups
+	}
+	if c.VPN == "" {
+		c.VPN = prof.VPN
+	}
+	if c.GPUs == "" && prof.GPUs != "" {
+		// Same rule as `rfswift run --profile`: a profile GPU request is
+		// dropped on hosts without a usable GPU instead of failing creation.
+		if GPUAvailable() {
+			c.GPUs = prof.GPUs
+		} else {
+			common.PrintInfoMessage(fmt.Sprintf("Lab container '%s': profile '%s' requests GPU passthrough but no usable GPU was found, continuing without it", c.Name, prof.Name))
+		}
+	}
+	if c.Ulimits == "" {
+		c.Ulimits = prof.Ulimits
+	}
+	if c.ExtraEnv == "" {
+		c.ExtraEnv = prof.ExtraEnv
+	}
+	if c.ExtraHosts == "" {
+		c.ExtraHosts = prof.ExtraHosts
+	}
+	if c.Command == "" {
+		c.Command = prof.Shell
+	}
+	if c.DesktopConfig == "" {
+		c.DesktopConfig = prof.DesktopConfig()
+	}
+	if c.DesktopPass == "" {
+		c.DesktopPass = prof.DesktopPass
+	}
+	if c.Workspace == "" {
+		c.Workspace = prof.WorkspacePath()
+	}
+	if c.Seccomp == "" {
+		c.Seccomp = prof.Seccomp
+	}
+	if c.PulseServer == "" {
+		c.PulseServer = prof.PulseServer
+	}
+	if c.Display == "" {
+		c.Display = prof.Display
+	}
+	r := c.resources().WithDefaults(prof.Resources())
+	c.CPUs, c.Memory, c.CpusetCpus, c.ShmSize, c.PidsLimit = r.CPUs, r.Memory, r.CpusetCpus, r.ShmSize, r.PidsLimit
+	c.Desktop = c.Desktop || prof.Desktop
+	c.DesktopSSL = c.DesktopSSL || prof.DesktopSSL
+	c.NoX11 = c.NoX11 || prof.NoX11
+	c.Privileged = c.Privileged || prof.Privileged
+	c.Realtime = c.Realtime || prof.Realtime
+	return c, nil
+}
+
+// applyLabContainer loads a resolved lab container into the global container
+// configuration through the same setters `rfswift run` uses.
+//
+//	in(1): *Lab l the lab being brought up
+//	in(2): LabContainer c resolved container definition
+func applyLabContainer(l *Lab, c LabContainer) {
+	if c.NoX11 {
+		ContainerSetX11("")
+		ContainerSetXDisplay("")
+	} else if c.Display != "" {
+		ContainerSetXDisplay(c.Display)
+	}
+	ContainerSetSeccomp(c.Seccomp)
+	ContainerSetPulse(c.PulseServer)
+	ContainerSetShell(c.Command)
+	ContainerAddBinding(c.Bindings)
+	ContainerSetImage(c.Image)
+	ContainerSetExtraHosts(c.ExtraHosts)
+	ContainerSetEnv(c.ExtraEnv)
+	ContainerSetNetworkMode("nat:" + l.natNetworkName())
+	ContainerSetExposedPorts(c.ExposedPorts)
+	ContainerSetBindedPorts(c.PortBindings)
+	ContainerAddDevices(c.Devices)
+	ContainerAddCaps(c.Caps)
+	ContainerAddCgroups(c.Cgroups)
+	if c.Privileged {
+		ContainerSetPrivileges(1)
+	}
+	ContainerSetRealtime(c.Realtime)
+	ContainerSetUlimits(c.Ulimits)
+	if c.Desktop {
+		proto, host, port := parseDesktopConfig(c.DesktopConfig)
+		ContainerSetDesktop(proto, host, port)
+		if c.DesktopPass != "" {
+			ContainerSetDesktopPassword(c.DesktopPass)
+		}
+		ContainerSetDesktopSSL(c.DesktopSSL)
+	}
+	ContainerSetVPN(c.VPN)
+	ContainerSetGPUs(c.GPUs)
+	if c.Workspace != "" {
+		ContainerSetWorkspace(c.Workspace)
+	}
+	ContainerSetResources(c.resources())
+	containerCfg.lab = l.Name
+}
+
+// parseDesktopConfig splits a "proto:host:port" desktop specification, using
+// the same defaults as the --desktop-config flag (http, 127.0.0.1, 6080).
+//
+//	in(1): string config desktop specification (may be empty)
+//	out: string proto, string host, string port
+func parseDesktopConfig(config string) (string, string, string) {
+	proto, host, port := "http", "127.0.0.1", "6080"
+	parts := strings.Split(config, ":")
+	if len(parts) >= 1 && (parts[0] == "http" || parts[0] == "vnc") {
+		proto = parts[0]
+	}
+	if len(parts) >= 2 && parts[1] != "" {
+		host = parts[1]
+	}
+	if len(parts) >= 3 && parts[2] != "" {
+		port = parts[2]
+	}
+	if proto == "vnc" && port == "6080" {
+		port = "5900"
+	}
+	return proto, host, port
+}
+
+// LabUp creates the lab network and starts every container of the manifest
+// in dependency order. Containers that already exist are started instead of
+// being recreated, so running `lab up` twice is harmless.
+//
+//	in(1): string path manifest YAML file
+//	out: error
+func LabUp(path string) error {
+	lab, err := LoadLab(path)
+	if err != nil {
+		return err
+	}
+	ordered, _ := lab.startOrder()
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	netName := lab.natNetworkName()
+	if existing, err := findNATNetwork(ctx, cli, netName); err != nil || existing == "" {
+		if _, _, err := createNamedNATNetwork(ctx, cli, netName, lab.Network.Name, lab.Network.Subnet); err != nil {
+			return err
+		}
+	} else {
+		common.PrintInfoMessage(fmt.Sprintf("Reusing NAT network '%s'", netName))
+	}
+
+	// Every container starts from the same baseline; the global config is
+	// restored afterwards so the lab leaves no trace on later operations.
+	baseCfg := containerCfg
+	defer func() { containerCfg = baseCfg }()
+
+	for _, c := range ordered {
+		containerCfg = baseCfg
+
+		if existingJSON, err := inspectContainer(ctx, cli, c.Name); err == nil {
+			if existingJSON.Config == nil || existingJSON.Config.Labels[LabLabel] != lab.Name {
+				return fmt.Errorf("container '%s' already exists and does not belong to lab '%s'", c.Name, lab.Name)
+			}
+			if !existingJSON.State.Running {
+				if _, err := cli.ContainerStart(ctx, existingJSON.ID, client.ContainerStartOptions{}); err != nil {
+					return fmt.Errorf("failed to start container '%s': %v", c.Name, err)
+				}
+				common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started", c.Name))
+			} else {
+				common.PrintInfoMessage(fmt.Sprintf("Container '%s' is already running", c.Name))
+			}
+			continue
+		}
+
+		resolved, err := resolveLabContainer(c)
+		if err != nil {
+			return fmt.Errorf("container '%s': %w", c.Name, err)
+		}
+		applyLabContainer(lab, resolved)
+
+		containerID, err := createContainerFromConfig(ctx, cli, c.Name)
+		if err != nil {
+			return fmt.Errorf("container '%s': %w", c.Name, err)
+		}
+		if err := startLabContainer(ctx, cli, containerID, c.Name); err != nil {
+			return err
+		}
+	}
+
+	common.PrintSuccessMessage(fmt.Sprintf("Lab '%s' is up (%d container(s) on '%s')", lab.Name, len(ordered), netName))
+	return nil
+}
+
+// startLabContainer starts a freshly created lab container in the background
+// and brings up the VPN when configured. Desktop mode needs no extra step: the
+// entrypoint wrapper starts it with the container.
+//
+//	in(1): context.Context ctx
+//	in(2): *client.Client cli
+//	in(3): string containerID
+//	in(4): string containerName
+//	out: error
+func startLabContainer(ctx context.Context, cli *client.Client, containerID string, containerName string) error {
+	if _, err := cli.ContainerStart(ctx, containerID, client.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("failed to start container '%s': %v", containerName, err)
+	}
+
+	containerJSON, err := inspectContainer(ctx, cli, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container '%s': %v", containerName, err)
+	}
+	if !containerJSON.State.Running {
+		return fmt.Errorf("container '%s' exited right after start (exit code %d), dependent containers were not started", containerName, containerJSON.State.ExitCode)
+	}
+	common.PrintSuccessMessage(fmt.Sprintf("Container '%s' started", containerName))
+	printDesktopURL()
+
+	if containerCfg.vpn != "" {
+		if err := startVPNInContainer(ctx, cli, containerID); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		printVPNInfo()
+	}
+	return nil
+}
+
+// LabDown stops and removes the containers of a lab in reverse dependency
+// order, then removes the lab network. Containers with the same name that
+// were not created by this lab are left untouched.
+//
+//	in(1): string path manifest YAML file
+//	out: error
+func LabDown(path string) error {
+	lab, err := LoadLab(path)
+	if err != nil {
+		return err
+	}
+	ordered, _ := lab.startOrder()
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		name := ordered[i].Name
+		containerJSON, err := inspectContainer(ctx, cli, name)
+		if err != nil {
+			continue
+		}
+		if containerJSON.Config == nil || containerJSON.Config.Labels[LabLabel] != lab.Name {
+			common.PrintWarningMessage(fmt.Sprintf("Container '%s' does not belong to lab '%s', skipping it", name, lab.Name))
+			continue
+		}
+		ContainerRemove(name)
+	}
+
+	removeNATNetworkByFullName(ctx, cli, lab.natNetworkName())
+	common.PrintSuccessMessage(fmt.Sprintf("Lab '%s' is down", lab.Name))
+	return nil
+}
+
+// LabContainerStatus is the structured form of one LabStatus row.
+type LabContainerStatus struct {
+	Name      string   `json:"name" yaml:"name"`
+	Image     string   `json:"image" yaml:"image"`
+	State     string   `json:"state" yaml:"state"`
+	DependsOn []string `json:"depends_on" yaml:"depends_on"`
+}
+
+// LabStatusInfo is the structured form of LabStatus.
+type LabStatusInfo struct {
+	Lab        string               `json:"lab" yaml:"lab"`
+	Network    string               `json:"network" yaml:"network"`
+	Containers []LabContainerStatus `json:"containers" yaml:"containers"`
+}
+
+// LabStatus prints the state of every container declared by a lab manifest.
+//
+//	in(1): string path manifest YAML file
+//	out: error
+func LabStatus(path string) error {
+	lab, err := LoadLab(path)
+	if err != nil {
+		return err
+	}
+	ordered, _ := lab.startOrder()
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	var rows [][]string
+	status := LabStatusInfo{Lab: lab.Name, Network: lab.natNetworkName(), Containers: []LabContainerStatus{}}
+	for _, c := range ordered {
+		state := "absent"
+		image := c.Image
+		if containerJSON, err := inspectContainer(ctx, cli, c.Name); err == nil {
+			state = string(containerJSON.State.Status)
+			image = getDisplayImageName(containerJSON)
+		}
+		if image == "" {
+			image = "profile: " + c.Profile
+		}
+		deps := "-"
+		if len(c.DependsOn) > 0 {
+			deps = strings.Join(c.DependsOn, ", ")
+		}
+		rows = append(rows, []string{c.Name, image, state, deps})
+		dependsOn := c.DependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		status.Containers = append(status.Containers, LabContainerStatus{
+			Name:      c.Name,
+			Image:     image,
+			State:     state,
+			DependsOn: dependsOn,
+		})
+	}
+
+	if common.MachineOutput() {
+		return common.PrintStructured("lab", status)
+	}
+
+	tui.RenderTable(tui.TableConfig{
+		Title:   fmt.Sprintf("Lab: %s (network %s)", lab.Name, lab.natNetworkName()),
+		Headers: []string{"Container", "Image", "State", "Depends on"},
+		Rows:    rows,
+	})
+	return nil
+}
@@ -0,0 +1,138 @@
+/* This code is part of RF Swift by @Penthertz
+*  Tests for lab manifest parsing and container start ordering.
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeLab(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "lab.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLabStartOrder(t *testing.T) {
+	path := writeLab(t, `
+name: 5glab
+containers:
+  - name: wireshark
+    image: penthertz/rfswift_resolute:network
+  - name: ue
+    image: penthertz/rfswift_resolute:telecom_5G
+    depends_on: [gnb]
+  - name: gnb
+    image: penthertz/rfswift_resolute:telecom_5G
+    depends_on: [core]
+  - name: core
+    image: penthertz/rfswift_resolute:telecom_5G
+`)
+	lab, err := LoadLab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordered, err := lab.startOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range ordered {
+		names = append(names, c.Name)
+	}
+	if got, want := strings.Join(names, ","), "wireshark,core,gnb,ue"; got != want {
+		t.Errorf("start order = %s, want %s", got, want)
+	}
+	if got, want := lab.natNetworkName(), "rfswift_nat_5glab"; got != want {
+		t.Errorf("natNetworkName() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadLabRejectsInvalidManifests(t *testing.T) {
+	cases := map[string]string{
+		"cycle": `
+name: lab
+containers:
+  - {name: a, image: x:y, depends_on: [b]}
+  - {name: b, image: x:y, depends_on: [a]}
+`,
+		"unknown dependency": `
+name: lab
+containers:
+  - {name: a, image: x:y, depends_on: [ghost]}
+`,
+		"duplicate name": `
+name: lab
+containers:
+  - {name: a, image: x:y}
+  - {name: a, image: x:y}
+`,
+		"no image nor profile": `
+name: lab
+containers:
+  - {name: a}
+`,
+		"no name": `
+containers:
+  - {name: a, image: x:y}
+`,
+	}
+	for desc, content := range cases {
+		if _, err := LoadLab(writeLab(t, content)); err == nil {
+			t.Errorf("%s: LoadLab succeeded, want an error", desc)
+		}
+	}
+}
+
+func TestParseDesktopConfig(t *testing.T) {
+	cases := map[string][3]string{
+		"":                  {"http", "127.0.0.1", "6080"},
+		"http:0.0.0.0:6081": {"http", "0.0.0.0", "6081"},
+		"vnc::":             {"vnc", "127.0.0.1", "5900"},
+	}
+	for in, want := range cases {
+		proto, host, port := parseDesktopConfig(in)
+		if got := [3]string{proto, host, port}; got != want {
+			t.Errorf("parseDesktopConfig(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestResolveLabContainerFromProfile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("profiles directory follows %APPDATA% on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "")
+	t.Setenv(profileCatalogsEnvVar, "")
+	writeProfiles(t, ProfilesDirByPlatform(), map[string]string{
+		"sdr.yaml": "name: sdr\nimage: img:sdr\nseccomp: /etc/rfswift/sdr.json\npulse_server: tcp:10.0.0.2:4713\ndisplay: :1\n",
+	})
+
+	c, err := resolveLabContainer(LabContainer{Name: "rx", Profile: "sdr", Display: ":2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Image != "img:sdr" || c.Seccomp != "/etc/rfswift/sdr.json" || c.PulseServer != "tcp:10.0.0.2:4713" {
+		t.Errorf("resolveLabContainer() = %+v, want image, seccomp and pulse server of the profile", c)
+	}
+	if c.Display != ":2" {
+		t.Errorf("resolveLabContainer() display = %q, want the inline :2 over the profile", c.Display)
+	}
+
+	saved := containerCfg
+	defer func() { containerCfg = saved }()
+	applyLabContainer(&Lab{Name: "lab", Network: LabNetwork{Name: "lab"}}, c)
+	if containerCfg.seccomp != c.Seccomp || containerCfg.pulseServer != c.PulseServer || containerCfg.xdisplay != c.Display {
+		t.Errorf("applyLabContainer() config seccomp=%q pulse=%q display=%q, want %q %q %q",
+			containerCfg.seccomp, containerCfg.pulseServer, containerCfg.xdisplay, c.Seccomp, c.PulseServer, c.Display)
+	}
+}
@@ -5,16 +5,22 @@
 package dock
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/client"
+
 	common "penthertz/rfswift/common"
 	"penthertz/rfswift/tui"
 )
@@ -176,56 +182,232 @@ func StopLogging() error {
 	return nil
 }
 
-// ReplayLog replays a recorded terminal session using asciinema play or cat depending on file type.
+// ReplayLog replays a recorded terminal session with the native asciicast
+// player, or prints a script log, which has no timing, without escape codes.
 //
 //	in(1): string inputFile path to the .cast or .log recording file to replay
-//	in(2): float64 speed playback speed multiplier passed to asciinema (e.g. 2.0 for double speed)
-//	out: error non-nil if the file does not exist, the required tool is missing, or playback fails
-func ReplayLog(inputFile string, speed float64) error {
+//	in(2): ReplayOptions opts playback speed, idle-time cap and start position
+//	out: error non-nil if the file does not exist or cannot be parsed
+func ReplayLog(inputFile string, opts ReplayOptions) error {
 	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", inputFile)
 	}
 
-	var tool string
-	if strings.HasSuffix(inputFile, ".cast") {
-		tool = "asciinema"
-	} else {
-		tool = "script"
+	common.PrintInfoMessage(fmt.Sprintf("Replaying session from: %s", inputFile))
+
+	if !strings.HasSuffix(inputFile, ".cast") {
+		common.PrintWarningMessage("Script logs don't support playback. Displaying content:")
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			return fmt.Errorf("failed to replay session: %v", err)
+		}
+		fmt.Println(stripANSI(string(data)))
+		return nil
 	}
 
-	common.PrintInfoMessage(fmt.Sprintf("Replaying session from: %s", inputFile))
+	return playCast(inputFile, opts)
+}
 
-	var cmd *exec.Cmd
+// LogMatch is a line of a recording matching 'rfswift log search'.
+type LogMatch struct {
+	Path    string  `json:"path" yaml:"path"`
+	Line    int     `json:"line" yaml:"line"`                         // line number in the plain text export
+	Offset  string  `json:"offset,omitempty" yaml:"offset,omitempty"` // position in the recording, for 'log replay --start'
+	Seconds float64 `json:"seconds" yaml:"seconds"`
+	Time    string  `json:"time,omitempty" yaml:"time,omitempty"` // wall clock time when the recording has a timestamp
+	Text    string  `json:"text" yaml:"text"`
+}
 
-	switch tool {
-	case "asciinema":
-		if _, err := exec.LookPath("asciinema"); err != nil {
-			return fmt.Errorf("asciinema not found. Install with: pip install asciinema")
+// searchRecording returns the lines of one recording matching re. Lines
+// of casts carry their offset; script logs have no timing.
+//
+//	in(1): string path
+//	in(2): *regexp.Regexp re
+//	out: ([]LogMatch, error)
+func searchRecording(path string, re *regexp.Regexp) ([]LogMatch, error) {
+	var matches []LogMatch
+	if !strings.HasSuffix(path, ".cast") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
-		speedStr := fmt.Sprintf("%.1f", speed)
-		cmd = exec.Command("asciinema", "play", "-s", speedStr, inputFile)
-	case "script":
-		common.PrintWarningMessage("Script logs don't support playback. Displaying content:")
-		cmd = exec.Command("cat", inputFile)
+		for i, line := range strings.Split(stripANSI(string(data)), "\n") {
+			if re.MatchString(line) {
+				matches = append(matches, LogMatch{Path: path, Line: i + 1, Text: strings.TrimSpace(line)})
+			}
+		}
+		return matches, nil
 	}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cast, err := loadCast(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range castLines(cast.Events) {
+		if !re.MatchString(line.Text) {
+			continue
+		}
+		m := LogMatch{
+			Path:    path,
+			Line:    i + 1,
+			Offset:  formatCastTime(line.Time),
+			Seconds: math.Round(line.Time*1000) / 1000,
+			Text:    strings.TrimSpace(line.Text),
+		}
+		if cast.Header.Timestamp != 0 {
+			at := time.Unix(cast.Header.Timestamp, 0).Add(time.Duration(line.Time * float64(time.Second)))
+			m.Time = at.Format("2006-01-02 15:04:05")
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to replay session: %v", err)
+// SearchLogs searches the text of all recordings found by FindLogs and
+// prints the matching lines with their offset in the recording.
+//
+//	in(1): string logDir directory to search; defaults to "." when empty
+//	in(2): string pattern regular expression, or literal text when fixed
+//	in(3): bool ignoreCase
+//	in(4): bool fixed
+//	out: error non-nil if the pattern is invalid or the directory walk fails
+func SearchLogs(logDir, pattern string, ignoreCase, fixed bool) error {
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
 	}
 
+	entries, err := FindLogs(logDir)
+	if err != nil {
+		return fmt.Errorf("failed to search directory: %v", err)
+	}
+	matches := []LogMatch{}
+	for _, e := range entries {
+		found, err := searchRecording(e.Path, re)
+		if err != nil {
+			common.PrintWarningMessage(fmt.Sprintf("Skipping %v", err))
+			continue
+		}
+		matches = append(matches, found...)
+	}
+
+	if common.MachineOutput() {
+		return common.PrintStructured("log-matches", matches)
+	}
+	if len(matches) == 0 {
+		common.PrintInfoMessage("No matches found")
+		return nil
+	}
+
+	rows := make([][]string, len(matches))
+	for i, m := range matches {
+		offset := m.Offset
+		if offset == "" {
+			offset = fmt.Sprintf("line %d", m.Line)
+		}
+		text := m.Text
+		if r := []rune(text); len(r) > 100 {
+			text = string(r[:97]) + "..."
+		}
+		rows[i] = []string{m.Path, offset, m.Time, text}
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   fmt.Sprintf("Matches for '%s'", strings.TrimPrefix(pattern, "(?i)")),
+		Headers: []string{"File", "Offset", "Time", "Line"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			if col == 1 {
+				return tui.ColorPrimary
+			}
+			return ""
+		},
+	})
+	common.PrintInfoMessage("Jump to a match with: rfswift log replay <file> --start <offset>")
+	return nil
+}
+
+// ExportLog writes an excerpt of a recording as plain text without escape
+// codes, or as a cast trimmed to the excerpt, to attach to reports.
+//
+//	in(1): string inputFile .cast or script .log recording
+//	in(2): string outputFile destination; stdout when empty
+//	in(3): string format "text" or "cast"; inferred from outputFile when empty
+//	in(4): string from start of the excerpt ("90", "1:30"); the beginning when empty
+//	in(5): string to end of the excerpt; the end of the recording when empty
+//	out: error
+func ExportLog(inputFile, outputFile, format, from, to string) error {
+	if format == "" {
+		format = "text"
+		if strings.HasSuffix(outputFile, ".cast") {
+			format = "cast"
+		}
+	}
+	if format != "text" && format != "cast" {
+		return fmt.Errorf("unknown export format '%s' (use text or cast)", format)
+	}
+	fromSec, err := parseCastTime(from)
+	if err != nil {
+		return err
+	}
+	toSec, err := parseCastTime(to)
+	if err != nil {
+		return err
+	}
+	if toSec > 0 && toSec < fromSec {
+		return fmt.Errorf("--to (%s) is before --from (%s)", to, from)
+	}
+
+	var out bytes.Buffer
+	if !strings.HasSuffix(inputFile, ".cast") {
+		if format == "cast" || from != "" || to != "" {
+			return fmt.Errorf("%s is a script log without timing: only a full text export is possible", inputFile)
+		}
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			return err
+		}
+		out.WriteString(stripANSI(string(data)) + "\n")
+	} else {
+		cast, err := loadCast(inputFile)
+		if err != nil {
+			return err
+		}
+		excerpt := cast.trim(fromSec, toSec)
+		if format == "cast" {
+			if err := excerpt.write(&out); err != nil {
+				return err
+			}
+		} else {
+			for _, line := range castLines(excerpt.Events) {
+				out.WriteString(line.Text + "\n")
+			}
+		}
+	}
+
+	if outputFile == "" {
+		// Banners and notices went to stderr (see common.RawOutputCommands)
+		_, err := common.DocumentWriter().Write(out.Bytes())
+		return err
+	}
+	if err := os.WriteFile(outputFile, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %v", outputFile, err)
+	}
+	common.PrintSuccessMessage(fmt.Sprintf("Exported %s excerpt to %s", format, outputFile))
 	return nil
 }
 
 // LogEntry holds metadata about a recorded session file.
 type LogEntry struct {
-	Path    string
-	Tool    string
-	Size    float64 // KB
-	ModTime string
+	Path    string  `json:"path" yaml:"path"`
+	Tool    string  `json:"tool" yaml:"tool"`
+	Size    float64 `json:"size_kb" yaml:"size_kb"` // KB
+	ModTime string  `json:"modified" yaml:"modified"`
 }
 
 // FindLogs searches a directory for rfswift session recordings and returns metadata.
@@ -278,6 +460,13 @@ func ListLogs(logDir string) error {
 		return fmt.Errorf("failed to search directory: %v", err)
 	}
 
+	if common.MachineOutput() {
+		if entries == nil {
+			entries = []LogEntry{}
+		}
+		return common.PrintStructured("logs", entries)
+	}
+
 	if len(entries) == 0 {
 		common.PrintInfoMessage("No session recordings found")
 		return nil
@@ -297,88 +486,61 @@ func ListLogs(logDir string) error {
 	return nil
 }
 
-// ContainerRunWithRecording runs a container with session recording via asciinema or script.
+// recordingPath returns the recording file of a session, generating a
+// timestamped name in the current directory when none is given.
 //
-//	in(1): string containerName name to assign to the new container
-//	in(2): string recordOutput path for the recording output file; auto-generated with timestamp when empty
-//	in(3): string image image name to run; passed as -i flag when non-empty
-//	in(4): map[string]string extraArgs additional CLI flag/value pairs to append to the run command
-//	out: error non-nil if the recording tool is unavailable or the recorded session fails
-func ContainerRunWithRecording(containerName string, recordOutput string, image string, extraArgs map[string]string) error {
-	tool, err := detectLoggingTool(false)
-	if err != nil {
-		return err
-	}
-
-	if recordOutput == "" {
-		timestamp := time.Now().Format("20060102-150405")
-		if tool == "asciinema" {
-			recordOutput = fmt.Sprintf("rfswift-run-%s-%s.cast", containerName, timestamp)
-		} else {
-			recordOutput = fmt.Sprintf("rfswift-run-%s-%s.log", containerName, timestamp)
-		}
-	}
-
-	common.PrintInfoMessage(fmt.Sprintf("🔴 Recording session with %s to: %s", tool, recordOutput))
-
-	executable, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %v", err)
-	}
-
-	runCmdStr := fmt.Sprintf("%s run -n %s", executable, containerName)
+//	in(1): string recordOutput  path requested by --record-output
+//	in(2): string mode  "run" or "exec"
+//	in(3): string containerName
+//	out: string
+func recordingPath(recordOutput, mode, containerName string) string {
+	if recordOutput != "" {
+		return recordOutput
+	}
+	timestamp := time.Now().Format("20060102-150405")
+	return fmt.Sprintf("rfswift-%s-%s-%s.cast", mode, containerName, timestamp)
+}
 
-	if image != "" {
-		runCmdStr += fmt.Sprintf(" -i %s", image)
+// sessionTitle returns the title of a recorded session of a container.
+func sessionTitle(ctx context.Context, cli *client.Client, containerID string) string {
+	name := containerID
+	if containerJSON, err := inspectContainer(ctx, cli, containerID); err == nil {
+		name = strings.TrimPrefix(containerJSON.Name, "/")
 	}
+	return "RF Swift: " + name
+}
 
-	for flag, value := range extraArgs {
-		if value != "" {
-			runCmdStr += fmt.Sprintf(" %s %s", flag, value)
-		}
-	}
-
-	var recordCmd *exec.Cmd
-
-	switch tool {
-	case "asciinema":
-		recordCmd = exec.Command("asciinema", "rec", "-c", runCmdStr, recordOutput)
-	case "script":
-		if runtime.GOOS == "darwin" {
-			recordCmd = exec.Command("script", "-q", "-c", runCmdStr, recordOutput)
-		} else {
-			recordCmd = exec.Command("script", "-q", "-f", "-c", runCmdStr, recordOutput)
-		}
+// printRecordingSaved reports where a recorded session was written.
+func printRecordingSaved(recordOutput string) {
+	if info, err := os.Stat(recordOutput); err == nil && info.Size() > 0 {
+		common.PrintSuccessMessage(fmt.Sprintf("🔴 Session recorded to: %s", recordOutput))
 	}
+}
 
-	recordCmd.Stdin = os.Stdin
-	recordCmd.Stdout = os.Stdout
-	recordCmd.Stderr = os.Stderr
-	recordCmd.Env = append(os.Environ(), "RFSWIFT_RECORDING=1")
-
-	if err := recordCmd.Run(); err != nil {
-		return fmt.Errorf("recording session failed: %v", err)
-	}
+// ContainerRunWithRecording creates a container and records its interactive
+// session natively as asciicast v2, without asciinema or script on the host.
+//
+//	in(1): string containerName name to assign to the new container
+//	in(2): string recordOutput path for the recording output file; auto-generated with timestamp when empty
+func ContainerRunWithRecording(containerName string, recordOutput string) {
+	recordOutput = recordingPath(recordOutput, "run", containerName)
+	common.PrintInfoMessage(fmt.Sprintf("🔴 Recording session to: %s", recordOutput))
 
-	fmt.Printf("\033]0;Terminal\007")
-	common.PrintSuccessMessage(fmt.Sprintf("🔴 Session recorded to: %s", recordOutput))
+	containerCfg.recordFile = recordOutput
+	defer func() { containerCfg.recordFile = "" }()
+	ContainerRun(containerName)
 
-	return nil
+	printRecordingSaved(recordOutput)
 }
 
-// ContainerExecWithRecording executes into a running container with session recording via asciinema or script.
+// ContainerExecWithRecording executes into a running container and records
+// the session natively as asciicast v2.
 //
 //	in(1): string containerIdentifier ID or name of the target container; falls back to the latest rfswift container when empty
-//	in(2): string workingDir working directory inside the container; omitted from the exec command when set to "/root" or empty
+//	in(2): string workingDir working directory inside the container
 //	in(3): string recordOutput path for the recording output file; auto-generated with timestamp when empty
-//	in(4): string execCommand shell command to run inside the container; omitted when set to "/bin/bash" or empty
-//	out: error non-nil if no container is found, the engine client fails, or the recorded session fails
-func ContainerExecWithRecording(containerIdentifier string, workingDir string, recordOutput string, execCommand string) error {
-	tool, err := detectLoggingTool(false)
-	if err != nil {
-		return err
-	}
-
+//	out: error non-nil if no container is found or the engine client fails
+func ContainerExecWithRecording(containerIdentifier string, workingDir string, recordOutput string) error {
 	if containerIdentifier == "" {
 		labelKey := "org.container.project"
 		labelValue := "rfswift"
@@ -394,69 +556,20 @@ func ContainerExecWithRecording(containerIdentifier string, workingDir string, r
 	if err != nil {
 		return err
 	}
-	defer cli.Close()
-
 	containerName := containerIdentifier
 	containerJSON, err := inspectContainer(ctx, cli, containerIdentifier)
+	cli.Close()
 	if err == nil {
 		containerName = strings.TrimPrefix(containerJSON.Name, "/")
 	}
 
-	if recordOutput == "" {
-		timestamp := time.Now().Format("20060102-150405")
-		if tool == "asciinema" {
-			recordOutput = fmt.Sprintf("rfswift-exec-%s-%s.cast", containerName, timestamp)
-		} else {
-			recordOutput = fmt.Sprintf("rfswift-exec-%s-%s.log", containerName, timestamp)
-		}
-	}
-
-	common.PrintInfoMessage(fmt.Sprintf("🔴 Recording session with %s to: %s", tool, recordOutput))
-
-	executable, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %v", err)
-	}
-
-	execCmdStr := fmt.Sprintf("%s exec -c %s", executable, containerIdentifier)
-
-	if workingDir != "" && workingDir != "/root" {
-		execCmdStr += fmt.Sprintf(" -w %s", workingDir)
-	}
-
-	if execCommand != "" && execCommand != "/bin/bash" {
-		execCmdStr += fmt.Sprintf(" -e %s", execCommand)
-	}
-
-	// Pass through VPN config to the recording subprocess
-	if containerCfg.vpn != "" {
-		execCmdStr += fmt.Sprintf(" --vpn %s", containerCfg.vpn)
-	}
-
-	var recordCmd *exec.Cmd
-
-	switch tool {
-	case "asciinema":
-		recordCmd = exec.Command("asciinema", "rec", "-c", execCmdStr, recordOutput)
-	case "script":
-		if runtime.GOOS == "darwin" {
-			recordCmd = exec.Command("script", "-q", "-c", execCmdStr, recordOutput)
-		} else {
-			recordCmd = exec.Command("script", "-q", "-f", "-c", execCmdStr, recordOutput)
-		}
-	}
-
-	recordCmd.Stdin = os.Stdin
-	recordCmd.Stdout = os.Stdout
-	recordCmd.Stderr = os.Stderr
-	recordCmd.Env = append(os.Environ(), "RFSWIFT_RECORDING=1")
-
-	if err := recordCmd.Run(); err != nil {
-		return fmt.Errorf("recording session failed: %v", err)
-	}
+	recordOutput = recordingPath(recordOutput, "exec", containerName)
+	common.PrintInfoMessage(fmt.Sprintf("🔴 Recording session to: %s", recordOutput))
 
-	fmt.Printf("\033]0;Terminal\007")
-	common.PrintSuccessMessage(fmt.Sprintf("📹 Session recorded to: %s", recordOutput))
+	containerCfg.recordFile = recordOutput
+	defer func() { containerCfg.recordFile = "" }()
+	ContainerExec(containerIdentifier, workingDir)
 
+	printRecordingSaved(recordOutput)
 	return nil
 }
@@ -306,14 +306,14 @@ func natIPAM(subnet string, gateway string) *dockernetwork.IPAM {
 
 // NetworkInfo holds display information for a NAT network.
 type NetworkInfo struct {
-	Name       string
-	ID         string
-	Subnet     string
-	Gateway    string
-	Container  string
-	Driver     string
-	Shared     bool
-	Containers int
+	Name       string `json:"name" yaml:"name"`
+	ID         string `json:"id" yaml:"id"`
+	Subnet     string `json:"subnet" yaml:"subnet"`
+	Gateway    string `json:"gateway" yaml:"gateway"`
+	Container  string `json:"container" yaml:"container"`
+	Driver     string `json:"driver" yaml:"driver"`
+	Shared     bool   `json:"shared" yaml:"shared"`
+	Containers int    `json:"connected" yaml:"connected"`
 }
 
 // ListNATNetworks returns all RF Swift NAT networks.
@@ -330,7 +330,7 @@ func ListNATNetworks() ([]NetworkInfo, error) {
 		return nil, fmt.Errorf("failed to list networks: %v", err)
 	}
 
-	var result []NetworkInfo
+	result := []NetworkInfo{}
 	for _, n := range networksRes.Items {
 		if n.Labels[NATLabel] != "true" {
 			continue
@@ -364,6 +364,13 @@ func DisplayNATNetworks() {
 		return
 	}
 
+	if common.MachineOutput() {
+		if err := common.PrintStructured("networks", networks); err != nil {
+			common.PrintErrorMessage(err)
+		}
+		return
+	}
+
 	if len(networks) == 0 {
 		common.PrintInfoMessage("No RF Swift NAT networks found")
 		return
@@ -0,0 +1,325 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Minimal PDF writer: standard Type1 fonts, text, rectangles, lines and
+ * JPEG images, enough for reports without external tools
+ */
+
+package dock
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// A4 page size in points.
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+// pdfFont is one of the standard Type1 fonts every PDF reader provides.
+type pdfFont int
+
+const (
+	pdfHelvetica pdfFont = iota
+	pdfHelveticaBold
+	pdfHelveticaOblique
+	pdfCourier
+)
+
+var pdfFontNames = [...]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Courier"}
+
+// Glyph widths of printable ASCII (32-126) in 1/1000 em, from the Adobe
+// font metrics. Helvetica-Oblique shares the Helvetica widths and Courier is
+// monospaced (600).
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// winAnsiSpecials maps the characters of the 0x80-0x9F range of
+// WinAnsiEncoding; 0xA0-0xFF match Latin-1.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// pdfEncodeText converts text to WinAnsiEncoding, the encoding of the
+// standard fonts. Tabs become spaces, other control characters are dropped
+// and characters the encoding lacks become '?'.
+//
+//	in(1): string s
+//	out: []byte
+func pdfEncodeText(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r < 0x20 || r == 0x7f:
+		case r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsiSpecials[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// glyphWidth returns the width of a WinAnsi character in 1/1000 em.
+func glyphWidth(font pdfFont, c byte) int {
+	if font == pdfCourier {
+		return 600
+	}
+	if c >= 32 && c <= 126 {
+		if font == pdfHelveticaBold {
+			return helveticaBoldWidths[c-32]
+		}
+		return helveticaWidths[c-32]
+	}
+	switch c {
+	case 0x85, 0x97: // … —
+		return 1000
+	case 0x95: // •
+		return 350
+	}
+	return 556
+}
+
+// pdfTextWidth returns the width of text in points.
+//
+//	in(1): pdfFont font
+//	in(2): float64 size  in points
+//	in(3): string s
+//	out: float64
+func pdfTextWidth(font pdfFont, size float64, s string) float64 {
+	total := 0
+	for _, c := range pdfEncodeText(s) {
+		total += glyphWidth(font, c)
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfLiteral returns b as a PDF literal string.
+func pdfLiteral(b []byte) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for _, c := range b {
+		if c == '\\' || c == '(' || c == ')' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// pdfUnicode returns s as a UTF-16BE hex string, for document information.
+func pdfUnicode(s string) string {
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteByte('>')
+	return sb.String()
+}
+
+// pdfColor is an RGB color with components in 0..1.
+type pdfColor struct{ R, G, B float64 }
+
+// hexColor parses a "#rrggbb" color, as used by the HTML report styles.
+func hexColor(s string) pdfColor {
+	v, _ := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	return pdfColor{float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255}
+}
+
+func (c pdfColor) String() string {
+	return fmt.Sprintf("%.3f %.3f %.3f", c.R, c.G, c.B)
+}
+
+// pdfImage is an embedded JPEG.
+type pdfImage struct {
+	data          []byte
+	width, height int
+}
+
+// pdfDocument collects pages drawn with top-left based coordinates in
+// points and writes them as a PDF file.
+type pdfDocument struct {
+	title   string
+	created time.Time
+	pages   []*bytes.Buffer
+	images  []pdfImage
+}
+
+func newPDFDocument(title string) *pdfDocument {
+	return &pdfDocument{title: title, created: time.Now()}
+}
+
+// addPage starts a new page; drawing goes to the last page.
+func (d *pdfDocument) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// text draws s with its baseline at y.
+func (d *pdfDocument) text(font pdfFont, size, x, y float64, color pdfColor, s string) {
+	fmt.Fprintf(d.page(), "BT /F%d %.2f Tf %s rg %.2f %.2f Td %s Tj ET\n",
+		font+1, size, color, x, pdfPageHeight-y, pdfLiteral(pdfEncodeText(s)))
+}
+
+// fillRect fills a rectangle whose top-left corner is (x, y).
+func (d *pdfDocument) fillRect(x, y, w, h float64, color pdfColor) {
+	fmt.Fprintf(d.page(), "%s rg %.2f %.2f %.2f %.2f re f\n", color, x, pdfPageHeight-y-h, w, h)
+}
+
+// strokeRect outlines a rectangle; dash is the dash length, 0 for a solid line.
+func (d *pdfDocument) strokeRect(x, y, w, h, width, dash float64, color pdfColor) {
+	fmt.Fprintf(d.page(), "q %s RG %.2f w [%s] 0 d %.2f %.2f %.2f %.2f re S Q\n",
+		color, width, dashPattern(dash), x, pdfPageHeight-y-h, w, h)
+}
+
+// line draws a straight line.
+func (d *pdfDocument) line(x1, y1, x2, y2, width float64, color pdfColor) {
+	fmt.Fprintf(d.page(), "%s RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		color, width, x1, pdfPageHeight-y1, x2, pdfPageHeight-y2)
+}
+
+func dashPattern(dash float64) string {
+	if dash <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", dash)
+}
+
+// addJPEG embeds a JPEG image and returns its index for drawImage.
+func (d *pdfDocument) addJPEG(data []byte, width, height int) int {
+	d.images = append(d.images, pdfImage{data, width, height})
+	return len(d.images) - 1
+}
+
+// drawImage draws an embedded image in the w x h box at (x, y).
+func (d *pdfDocument) drawImage(index int, x, y, w, h float64) {
+	fmt.Fprintf(d.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, pdfPageHeight-y-h, index)
+}
+
+// write encodes the document. Objects are numbered: catalog, page tree,
+// document information, fonts, images, then a page and its content stream
+// for each page.
+//
+//	in(1): io.Writer w
+//	out: error
+func (d *pdfDocument) write(w io.Writer) error {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict string, data []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	const catalogObj, pagesObj, infoObj, firstFontObj = 1, 2, 3, 4
+	firstImageObj := firstFontObj + len(pdfFontNames)
+	firstPageObj := firstImageObj + len(d.images)
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %.2f %.2f] >>",
+		strings.Join(kids, " "), len(d.pages), pdfPageWidth, pdfPageHeight))
+
+	object(fmt.Sprintf("<< /Title %s /Producer (RF Swift) /CreationDate (D:%s) >>",
+		pdfUnicode(d.title), d.created.UTC().Format("20060102150405Z")))
+
+	for _, name := range pdfFontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for _, img := range d.images {
+		stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+			img.width, img.height), img.data)
+	}
+
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for i := range pdfFontNames {
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i+1, firstFontObj+i)
+	}
+	resources.WriteString(" >>")
+	if len(d.images) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i := range d.images {
+			fmt.Fprintf(&resources, " /Im%d %d 0 R", i, firstImageObj+i)
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources %s /Contents %d 0 R >>",
+			pagesObj, resources.String(), firstPageObj+2*i+1))
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(content.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		stream("/Filter /FlateDecode", z.Bytes())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, catalogObj, infoObj, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
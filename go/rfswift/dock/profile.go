@@ -13,6 +13,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 
@@ -23,24 +24,90 @@ import (
 
 // Profile defines a preset configuration for quick container creation.
 // Profiles are stored as YAML files in the user's profiles directory.
+//
+// A profile can extend others (extends:); fields tagged merge:"append" are
+// comma-separated lists accumulated along the inheritance chain, the other
+// fields are overridden by the most derived profile that sets them.
 type Profile struct {
-	Name         string `yaml:"name"`
-	Description  string `yaml:"description"`
-	Image        string `yaml:"image"`
-	Network      string `yaml:"network,omitempty"`
-	ExposedPorts string `yaml:"exposed_ports,omitempty"`
-	PortBindings string `yaml:"port_bindings,omitempty"`
-	Desktop      bool   `yaml:"desktop,omitempty"`
-	DesktopSSL   bool   `yaml:"desktop_ssl,omitempty"`
-	NoX11        bool   `yaml:"no_x11,omitempty"`
-	Privileged   bool   `yaml:"privileged,omitempty"`
-	Realtime     bool   `yaml:"realtime,omitempty"`
-	Devices      string `yaml:"devices,omitempty"`
-	Bindings     string `yaml:"bindings,omitempty"`
-	Caps         string `yaml:"caps,omitempty"`
-	Cgroups      string `yaml:"cgroups,omitempty"`
-	GPUs         string `yaml:"gpus,omitempty"`
-	VPN          string `yaml:"vpn,omitempty"`
+	Name         string         `json:"name" yaml:"name"`
+	Extends      ProfileParents `json:"extends,omitempty" yaml:"extends,omitempty"`
+	Description  string         `json:"description" yaml:"description"`
+	Image        string         `json:"image" yaml:"image"`
+	Network      string         `json:"network,omitempty" yaml:"network,omitempty"`
+	ExposedPorts string         `json:"exposed_ports,omitempty" yaml:"exposed_ports,omitempty" merge:"append"`
+	PortBindings string         `json:"port_bindings,omitempty" yaml:"port_bindings,omitempty" merge:"append"`
+	Desktop      bool           `json:"desktop,omitempty" yaml:"desktop,omitempty"`
+	DesktopSSL   bool           `json:"desktop_ssl,omitempty" yaml:"desktop_ssl,omitempty"`
+	NoX11        bool           `json:"no_x11,omitempty" yaml:"no_x11,omitempty"`
+	Privileged   bool           `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	Realtime     bool           `json:"realtime,omitempty" yaml:"realtime,omitempty"`
+	Devices      string         `json:"devices,omitempty" yaml:"devices,omitempty" merge:"append"`
+	Bindings     string         `json:"bindings,omitempty" yaml:"bindings,omitempty" merge:"append"`
+	Caps         string         `json:"caps,omitempty" yaml:"caps,omitempty" merge:"append"`
+	Cgroups      string         `json:"cgroups,omitempty" yaml:"cgroups,omitempty" merge:"append"`
+	GPUs         string         `json:"gpus,omitempty" yaml:"gpus,omitempty"`
+	VPN          string         `json:"vpn,omitempty" yaml:"vpn,omitempty"`
+	Ulimits      string         `json:"ulimits,omitempty" yaml:"ulimits,omitempty" merge:"append"`
+	ExtraEnv     string         `json:"extraenv,omitempty" yaml:"extraenv,omitempty" merge:"append"`
+	ExtraHosts   string         `json:"extrahosts,omitempty" yaml:"extrahosts,omitempty" merge:"append"`
+	Seccomp      string         `json:"seccomp,omitempty" yaml:"seccomp,omitempty"`
+	Shell        string         `json:"shell,omitempty" yaml:"shell,omitempty"`
+	Workspace    string         `json:"workspace,omitempty" yaml:"workspace,omitempty"` // "none", "cwd" or a host path
+	DesktopProto string         `json:"desktop_proto,omitempty" yaml:"desktop_proto,omitempty"`
+	DesktopHost  string         `json:"desktop_host,omitempty" yaml:"desktop_host,omitempty"`
+	DesktopPort  string         `json:"desktop_port,omitempty" yaml:"desktop_port,omitempty"`
+	DesktopPass  string         `json:"desktop_pass,omitempty" yaml:"desktop_pass,omitempty"`
+	PulseServer  string         `json:"pulse_server,omitempty" yaml:"pulse_server,omitempty"`
+	Display      string         `json:"display,omitempty" yaml:"display,omitempty"`
+	CPUs         string         `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	Memory       string         `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CpusetCpus   string         `json:"cpuset_cpus,omitempty" yaml:"cpuset_cpus,omitempty"`
+	ShmSize      string         `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+	PidsLimit    string         `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+}
+
+// DesktopConfig returns the profile desktop settings as a --desktop-config
+// value (proto:host:port), or "" when the profile sets none of them.
+//
+//	out: string desktop specification
+func (p Profile) DesktopConfig() string {
+	if p.DesktopProto == "" && p.DesktopHost == "" && p.DesktopPort == "" {
+		return ""
+	}
+	return p.DesktopProto + ":" + p.DesktopHost + ":" + p.DesktopPort
+}
+
+// WorkspacePath returns the profile workspace as ContainerSetWorkspace expects
+// it: "cwd" is replaced by the current directory.
+//
+//	out: string workspace ("" when the profile does not set one)
+func (p Profile) WorkspacePath() string {
+	if p.Workspace == "cwd" {
+		cwd, _ := os.Getwd()
+		return cwd
+	}
+	return p.Workspace
+}
+
+// Resources returns the engine resource limits the profile sets.
+//
+//	out: ResourceLimits cpus, memory, cpuset, shm size and pids limit
+func (p Profile) Resources() ResourceLimits {
+	return ResourceLimits{CPUs: p.CPUs, Memory: p.Memory, CpusetCpus: p.CpusetCpus, ShmSize: p.ShmSize, PidsLimit: p.PidsLimit}
+}
+
+// secretMask replaces the desktop password in displayed profiles.
+const secretMask = "********"
+
+// Masked returns the profile with its desktop password masked, for every
+// document but export bundles.
+//
+//	out: Profile copy safe to print
+func (p Profile) Masked() Profile {
+	if p.DesktopPass != "" {
+		p.DesktopPass = secretMask
+	}
+	return p
 }
 
 // Building blocks shared by the default profiles.
@@ -225,8 +292,9 @@ func DefaultProfiles() []Profile {
 	}
 }
 
-// ProfilesDirByPlatform returns the platform-specific profiles directory path.
-func ProfilesDirByPlatform() string {
+// rfswiftConfigDir returns the platform-specific RF Swift configuration
+// directory, resolved for the invoking user under sudo.
+func rfswiftConfigDir() string {
 	homeDir := os.Getenv("HOME")
 	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
 		if u, err := user.Lookup(sudoUser); err == nil {
@@ -236,39 +304,50 @@ func ProfilesDirByPlatform() string {
 
 	switch runtime.GOOS {
 	case "windows":
-		return filepath.Join(os.Getenv("APPDATA"), "rfswift", "profiles")
+		return filepath.Join(os.Getenv("APPDATA"), "rfswift")
 	case "darwin":
-		return filepath.Join(homeDir, "Library", "Application Support", "rfswift", "profiles")
+		return filepath.Join(homeDir, "Library", "Application Support", "rfswift")
 	default:
-		return filepath.Join(homeDir, ".config", "rfswift", "profiles")
+		return filepath.Join(homeDir, ".config", "rfswift")
 	}
 }
 
-// LoadProfiles loads all profiles from the user's profiles directory.
+// ProfilesDirByPlatform returns the platform-specific profiles directory path.
+func ProfilesDirByPlatform() string {
+	return filepath.Join(rfswiftConfigDir(), "profiles")
+}
+
+// loadProfileSources reads the profile files of the profiles directory and
+// of the configured catalogs as written, without resolving inheritance.
+func loadProfileSources() []profileSource {
+	_, sources, _ := scanProfiles(ProfilesDirByPlatform(), ProfileCatalogDirs())
+	return sources
+}
+
+// LoadProfiles loads all profiles from the user's profiles directory and the
+// read-only catalogs, with their inheritance resolved. A profile whose
+// inheritance is broken (unknown parent, cycle) is returned as written,
+// after a warning.
 func LoadProfiles() []Profile {
-	dir := ProfilesDirByPlatform()
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil
+	sources := loadProfileSources()
+	byName := make(map[string]profileSource, len(sources))
+	for _, src := range sources {
+		byName[strings.ToLower(src.Profile.Name)] = src
 	}
 
 	var profiles []Profile
-	for _, entry := range entries {
-		if entry.IsDir() || (!strings.HasSuffix(entry.Name(), ".yaml") && !strings.HasSuffix(entry.Name(), ".yml")) {
+	for _, src := range sources {
+		if len(src.Profile.Extends) == 0 {
+			profiles = append(profiles, src.Profile)
 			continue
 		}
-		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		r, err := resolveProfile(src.Profile.Name, byName)
 		if err != nil {
+			common.PrintWarningMessage(err.Error())
+			profiles = append(profiles, src.Profile)
 			continue
 		}
-		var p Profile
-		if err := yaml.Unmarshal(data, &p); err != nil {
-			continue
-		}
-		if p.Name == "" {
-			p.Name = strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".yaml"), ".yml")
-		}
-		profiles = append(profiles, p)
+		profiles = append(profiles, r.Profile)
 	}
 	return profiles
 }
@@ -278,15 +357,31 @@ func GetAllProfiles() []Profile {
 	return LoadProfiles()
 }
 
-// GetProfileByName finds a profile by name from the YAML directory.
+// GetProfileByName finds a profile by name from the YAML directory and
+// resolves its inheritance.
 func GetProfileByName(name string) (*Profile, error) {
+	r, err := ResolveProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &r.Profile, nil
+}
+
+// GetUnresolvedProfile finds a profile by name as written in its file,
+// without merging the profiles it extends.
+func GetUnresolvedProfile(name string) (*Profile, error) {
 	name = strings.ToLower(strings.TrimSpace(name))
-	for _, p := range LoadProfiles() {
-		if strings.ToLower(p.Name) == name {
+	for _, src := range loadProfileSources() {
+		if strings.ToLower(src.Profile.Name) == name {
+			p := src.Profile
 			return &p, nil
 		}
 	}
-	return nil, fmt.Errorf("profile '%s' not found. Run 'rfswift profile init' to generate default profiles or 'rfswift profile create' to create one", name)
+	return nil, profileNotFound(name)
+}
+
+func profileNotFound(name string) error {
+	return fmt.Errorf("profile '%s' not found. Run 'rfswift profile init' to generate default profiles or 'rfswift profile create' to create one", strings.ToLower(strings.TrimSpace(name)))
 }
 
 // SaveProfile saves a profile as a YAML file in the profiles directory.
@@ -375,6 +470,12 @@ func DeleteProfile(name string) error {
 	path := filepath.Join(dir, filename)
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
+		profiles, _ := LoadProfileCatalog()
+		for _, c := range profiles {
+			if c.ReadOnly() && strings.EqualFold(c.Profile.Name, strings.TrimSpace(name)) {
+				return fmt.Errorf("profile '%s' comes from the read-only catalog %s", name, c.Catalog)
+			}
+		}
 		return fmt.Errorf("profile '%s' not found at %s", name, path)
 	}
 
@@ -492,7 +593,7 @@ func differsFromDefault(path string, def Profile) bool {
 	if err := yaml.Unmarshal(data, &stored); err != nil {
 		return true
 	}
-	return stored != def
+	return !reflect.DeepEqual(stored, def)
 }
 
 // GetProfileNames returns just the names of all available profiles.
@@ -0,0 +1,394 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Profile bundles: export profiles to a multi-document YAML file and import
+ * them from a file, a directory or a URL
+ */
+
+package dock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	profileImportTimeout = 30 * time.Second
+	profileImportMaxSize = 1 << 20
+)
+
+// Conflict handling of 'rfswift profile import' when a profile name is
+// already in use.
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+)
+
+// ProfileImportResult tells what happened to one imported profile.
+type ProfileImportResult struct {
+	Name    string `json:"name" yaml:"name"`
+	Action  string `json:"action" yaml:"action"` // imported, skipped, overwritten, renamed
+	NewName string `json:"new_name,omitempty" yaml:"new_name,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// bundleDoc is one profile document of a bundle, kept as a YAML node so the
+// keys it sets (and its comments) are written back unchanged.
+type bundleDoc struct {
+	node *yaml.Node
+	src  profileSource
+}
+
+// ExportProfiles writes the named profiles, as written in their files, to w
+// as a multi-document YAML bundle. The profiles they extend are added before
+// them so the bundle imports on its own.
+//
+//	in(1): []string names profiles to export
+//	in(2): io.Writer w destination
+//	out: []string names exported, parents first
+//	out: error if a profile or one of its parents is unknown
+func ExportProfiles(names []string, w io.Writer) ([]string, error) {
+	profiles, _ := LoadProfileCatalog()
+	byName := make(map[string]CatalogProfile, len(profiles))
+	for _, c := range profiles {
+		byName[strings.ToLower(c.Profile.Name)] = c
+	}
+
+	var ordered []CatalogProfile
+	seen := make(map[string]bool)
+	var add func(name, child string, depth int) error
+	add = func(name, child string, depth int) error {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if seen[key] {
+			return nil
+		}
+		c, ok := byName[key]
+		if !ok {
+			if child != "" {
+				return fmt.Errorf("profile '%s' extends unknown profile '%s'", child, name)
+			}
+			return profileNotFound(name)
+		}
+		if depth > len(byName) {
+			return fmt.Errorf("profile inheritance cycle through '%s'", c.Profile.Name)
+		}
+		for _, parent := range c.Profile.Extends {
+			if err := add(parent, c.Profile.Name, depth+1); err != nil {
+				return err
+			}
+		}
+		seen[key] = true
+		ordered = append(ordered, c)
+		return nil
+	}
+	for _, name := range names {
+		if err := add(name, "", 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "# RF Swift profile bundle: %d profile(s)\n", len(ordered)); err != nil {
+		return nil, err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	var exported []string
+	for _, c := range ordered {
+		data, err := os.ReadFile(c.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile '%s': %w", c.Profile.Name, err)
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", c.Path, err)
+		}
+		// A profile named after its file gets its name written out
+		setProfileNodeValue(&doc, "name", c.Profile.Name)
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+		exported = append(exported, c.Profile.Name)
+	}
+	return exported, enc.Close()
+}
+
+// parseProfileBundle splits a bundle (or a single profile file) into its
+// profile documents. Every document must be a valid profile with a name.
+//
+//	in(1): []byte data YAML stream
+//	in(2): string origin file or URL, for error messages
+//	out: []bundleDoc profile documents
+//	out: error on invalid YAML or a nameless profile
+func parseProfileBundle(data []byte, origin string) ([]bundleDoc, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []bundleDoc
+	for i := 1; ; i++ {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("%s: %w", origin, err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue // empty document, e.g. after a trailing "---"
+		}
+		if node.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s: document %d is not a profile", origin, i)
+		}
+		src, err := parseProfileNode(&node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", origin, i, err)
+		}
+		if strings.TrimSpace(src.Profile.Name) == "" {
+			return nil, fmt.Errorf("%s: document %d has no name", origin, i)
+		}
+		if !safeProfileName(src.Profile.Name) {
+			return nil, fmt.Errorf("%s: document %d: invalid profile name '%s' (no path separators, '..' or leading dot)", origin, i, src.Profile.Name)
+		}
+		docs = append(docs, bundleDoc{node: &node, src: src})
+	}
+	return docs, nil
+}
+
+// safeProfileName reports whether a profile name maps to a file inside the
+// profiles directory. Names come from import sources, which may be remote.
+func safeProfileName(name string) bool {
+	name = strings.TrimSpace(name)
+	return !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..") && !strings.HasPrefix(name, ".")
+}
+
+// readProfileImport loads the profile documents of an import source: an
+// http(s) URL, a directory (every .yaml/.yml file in it) or a file.
+//
+//	in(1): string source URL, directory or file
+//	out: []bundleDoc profile documents
+//	out: error if the source cannot be read or holds an invalid profile
+func readProfileImport(source string) ([]bundleDoc, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: profileImportTimeout}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", source, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download %s: %s", source, resp.Status)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, profileImportMaxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", source, err)
+		}
+		if len(data) > profileImportMaxSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", source, profileImportMaxSize)
+		}
+		return parseProfileBundle(data, source)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{source}
+	if info.IsDir() {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && isProfileFile(entry.Name()) {
+				files = append(files, filepath.Join(source, entry.Name()))
+			}
+		}
+	}
+
+	var docs []bundleDoc
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileDocs, err := parseProfileBundle(data, file)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, fileDocs...)
+	}
+	return docs, nil
+}
+
+// importProfileDocs writes profile documents to dir. A name already in
+// existing is skipped, overwritten or renamed ("name-2", "name-3" ...)
+// depending on mode; profiles of the same import that extend a renamed one
+// follow the new name.
+//
+//	in(1): []bundleDoc docs profiles to import
+//	in(2): string dir destination profiles directory
+//	in(3): map[string]bool existing lower-case names already in use
+//	in(4): string mode ImportSkip, ImportOverwrite or ImportRename
+//	out: []ProfileImportResult outcome per profile, in import order
+//	out: error on duplicate names in the import or a write failure
+func importProfileDocs(docs []bundleDoc, dir string, existing map[string]bool, mode string) ([]ProfileImportResult, error) {
+	switch mode {
+	case ImportSkip, ImportOverwrite, ImportRename:
+	default:
+		return nil, fmt.Errorf("invalid conflict mode '%s' (use %s, %s or %s)", mode, ImportSkip, ImportOverwrite, ImportRename)
+	}
+
+	inImport := make(map[string]bool, len(docs))
+	for _, d := range docs {
+		key := strings.ToLower(d.src.Profile.Name)
+		if inImport[key] {
+			return nil, fmt.Errorf("profile '%s' is defined twice in the import", d.src.Profile.Name)
+		}
+		inImport[key] = true
+	}
+
+	taken := make(map[string]bool, len(existing)+len(docs))
+	for k := range existing {
+		taken[k] = true
+	}
+	for k := range inImport {
+		taken[k] = true
+	}
+
+	results := make([]ProfileImportResult, len(docs))
+	renamed := make(map[string]string)
+	for i, d := range docs {
+		name := d.src.Profile.Name
+		results[i] = ProfileImportResult{Name: name, Action: "imported"}
+		if !existing[strings.ToLower(name)] {
+			continue
+		}
+		switch mode {
+		case ImportSkip:
+			results[i].Action = "skipped"
+		case ImportOverwrite:
+			results[i].Action = "overwritten"
+		case ImportRename:
+			newName := name
+			for n := 2; taken[strings.ToLower(newName)]; n++ {
+				newName = fmt.Sprintf("%s-%d", name, n)
+			}
+			taken[strings.ToLower(newName)] = true
+			renamed[strings.ToLower(name)] = newName
+			results[i].Action = "renamed"
+			results[i].NewName = newName
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	for i, d := range docs {
+		if results[i].Action == "skipped" {
+			continue
+		}
+		name := d.src.Profile.Name
+		if results[i].NewName != "" {
+			name = results[i].NewName
+			setProfileNodeValue(d.node, "name", name)
+		}
+		if parents, changed := renameParents(d.src.Profile.Extends, renamed); changed {
+			var value yaml.Node
+			if err := value.Encode(parents); err != nil {
+				return nil, err
+			}
+			setProfileNode(d.node, "extends", &value)
+		}
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(d.node); err != nil {
+			return nil, err
+		}
+		enc.Close()
+
+		path := filepath.Join(dir, profileFilename(name))
+		if rel, err := filepath.Rel(dir, path); err != nil || rel != filepath.Base(path) {
+			return nil, fmt.Errorf("profile '%s' would be written outside %s", name, dir)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write profile '%s': %w", name, err)
+		}
+		results[i].Path = path
+	}
+	return results, nil
+}
+
+// renameParents maps the parents of a profile through renamed.
+func renameParents(parents ProfileParents, renamed map[string]string) (ProfileParents, bool) {
+	changed := false
+	out := make(ProfileParents, len(parents))
+	for i, p := range parents {
+		out[i] = p
+		if n, ok := renamed[strings.ToLower(p)]; ok {
+			out[i] = n
+			changed = true
+		}
+	}
+	return out, changed
+}
+
+// setProfileNodeValue sets a scalar key of a profile document.
+func setProfileNodeValue(doc *yaml.Node, key, value string) {
+	setProfileNode(doc, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// setProfileNode sets key of a profile document, replacing its value in
+// place or adding it first so "name" stays at the top.
+func setProfileNode(doc *yaml.Node, key string, value *yaml.Node) {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+		}
+		doc = doc.Content[0]
+	} else if doc.Kind == 0 {
+		*doc = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == key {
+			doc.Content[i+1] = value
+			return
+		}
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	doc.Content = append([]*yaml.Node{keyNode, value}, doc.Content...)
+}
+
+// ImportProfiles imports the profiles of a file, directory or URL into the
+// personal profiles directory. Names already in use, personal or from a
+// catalog, are handled according to mode.
+//
+//	in(1): string source file, directory or http(s) URL
+//	in(2): string mode ImportSkip, ImportOverwrite or ImportRename
+//	out: []ProfileImportResult outcome per profile
+//	out: error if the source is unreadable or invalid
+func ImportProfiles(source, mode string) ([]ProfileImportResult, error) {
+	docs, err := readProfileImport(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no profile found in %s", source)
+	}
+
+	profiles, _ := LoadProfileCatalog()
+	existing := make(map[string]bool, len(profiles))
+	for _, c := range profiles {
+		existing[strings.ToLower(c.Profile.Name)] = true
+	}
+	return importProfileDocs(docs, ProfilesDirByPlatform(), existing, mode)
+}
@@ -0,0 +1,212 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeProfiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanProfiles(t *testing.T) {
+	personal, team, vendor := t.TempDir(), t.TempDir(), t.TempDir()
+	writeProfiles(t, personal, map[string]string{"wifi.yaml": "name: wifi\nimage: img:mine\n"})
+	writeProfiles(t, team, map[string]string{
+		"wifi.yaml":  "name: WiFi\nimage: img:team\n",
+		"gsm.yaml":   "name: gsm\nimage: img:gsm\n",
+		"notes.txt":  "not a profile",
+		"broken.yml": "name: [\n",
+	})
+	writeProfiles(t, vendor, map[string]string{"wifi.yaml": "name: wifi\n", "lte.yml": "image: img:lte\n"})
+
+	kept, sources, collisions := scanProfiles(personal, []string{team, vendor})
+	var got []string
+	for _, c := range kept {
+		got = append(got, c.Profile.Name+"@"+c.Catalog)
+	}
+	if want := []string{"wifi@", "gsm@" + team, "lte@" + vendor}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanProfiles() = %v, want %v", got, want)
+	}
+	if len(sources) != len(kept) || sources[0].Profile.Image != "img:mine" {
+		t.Errorf("scanProfiles() sources = %+v, want the personal wifi first", sources)
+	}
+	if kept[0].ReadOnly() || !kept[1].ReadOnly() {
+		t.Errorf("ReadOnly() personal = %v, catalog = %v", kept[0].ReadOnly(), kept[1].ReadOnly())
+	}
+
+	want := []ProfileCollision{{
+		Name:     "wifi",
+		Used:     filepath.Join(personal, "wifi.yaml"),
+		Shadowed: []string{filepath.Join(team, "wifi.yaml"), filepath.Join(vendor, "wifi.yaml")},
+	}}
+	if !reflect.DeepEqual(collisions, want) {
+		t.Errorf("scanProfiles() collisions = %+v, want %+v", collisions, want)
+	}
+}
+
+func TestParseProfileBundle(t *testing.T) {
+	docs, err := parseProfileBundle([]byte("# bundle\nname: base\nrealtime: false\n---\nname: child\nextends: base\n---\n"), "bundle.yaml")
+	if err != nil {
+		t.Fatalf("parseProfileBundle() error = %v", err)
+	}
+	if len(docs) != 2 || docs[1].src.Profile.Extends[0] != "base" || !docs[0].src.keys["realtime"] {
+		t.Errorf("parseProfileBundle() = %+v", docs)
+	}
+
+	bad := map[string]string{
+		"image: img:a\n":         "has no name",
+		"- a\n- b\n":             "is not a profile",
+		"name: a\nextends: {}\n": "document 1",
+		"name: a\n---\nname: [":  "bundle.yaml",
+		"name: ../../evil\n":     "invalid profile name",
+		"name: sub/evil\n":       "invalid profile name",
+		"name: 'sub\\evil'\n":    "invalid profile name",
+		"name: .hidden\n":        "invalid profile name",
+	}
+	for doc, wantErr := range bad {
+		if _, err := parseProfileBundle([]byte(doc), "bundle.yaml"); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parseProfileBundle(%q) error = %v, want %q", doc, err, wantErr)
+		}
+	}
+}
+
+func TestImportProfileDocs(t *testing.T) {
+	bundle := "name: base\nrealtime: false # keep\ncaps: NET_RAW\n---\nname: child\nextends: [base, usb]\n---\nname: fresh\n"
+	existing := map[string]bool{"base": true, "base-2": true}
+
+	tests := []struct {
+		mode    string
+		actions []string
+		files   []string
+	}{
+		{ImportSkip, []string{"skipped", "imported", "imported"}, []string{"child.yaml", "fresh.yaml"}},
+		{ImportOverwrite, []string{"overwritten", "imported", "imported"}, []string{"base.yaml", "child.yaml", "fresh.yaml"}},
+		{ImportRename, []string{"renamed", "imported", "imported"}, []string{"base-3.yaml", "child.yaml", "fresh.yaml"}},
+	}
+
+	for _, tt := range tests {
+		docs, err := parseProfileBundle([]byte(bundle), "bundle.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		results, err := importProfileDocs(docs, dir, existing, tt.mode)
+		if err != nil {
+			t.Fatalf("importProfileDocs(%s) error = %v", tt.mode, err)
+		}
+		var actions []string
+		for _, r := range results {
+			actions = append(actions, r.Action)
+		}
+		if !reflect.DeepEqual(actions, tt.actions) {
+			t.Errorf("importProfileDocs(%s) actions = %v, want %v", tt.mode, actions, tt.actions)
+		}
+		entries, _ := os.ReadDir(dir)
+		var files []string
+		for _, e := range entries {
+			files = append(files, e.Name())
+		}
+		if !reflect.DeepEqual(files, tt.files) {
+			t.Errorf("importProfileDocs(%s) files = %v, want %v", tt.mode, files, tt.files)
+		}
+	}
+
+	// Renamed: the new name is written and the child follows it
+	docs, _ := parseProfileBundle([]byte(bundle), "bundle.yaml")
+	dir := t.TempDir()
+	results, err := importProfileDocs(docs, dir, existing, ImportRename)
+	if err != nil || results[0].NewName != "base-3" {
+		t.Fatalf("importProfileDocs(rename) = %+v, %v, want base renamed to base-3", results, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "base-3.yaml"))
+	src, err := parseProfileSource(data)
+	if err != nil || src.Profile.Name != "base-3" || !src.keys["realtime"] || !strings.Contains(string(data), "# keep") {
+		t.Errorf("renamed profile = %q, want name base-3 with its keys and comments", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "child.yaml"))
+	if src, _ := parseProfileSource(data); !reflect.DeepEqual(src.Profile.Extends, ProfileParents{"base-3", "usb"}) {
+		t.Errorf("child extends = %v, want [base-3 usb]", src.Profile.Extends)
+	}
+
+	docs, _ = parseProfileBundle([]byte("name: a\n---\nname: A\n"), "dup.yaml")
+	if _, err := importProfileDocs(docs, t.TempDir(), nil, ImportSkip); err == nil || !strings.Contains(err.Error(), "defined twice") {
+		t.Errorf("importProfileDocs(duplicates) error = %v, want defined twice", err)
+	}
+	if _, err := importProfileDocs(nil, t.TempDir(), nil, "merge"); err == nil {
+		t.Error("importProfileDocs(merge) error = nil, want invalid mode")
+	}
+
+	// Names that escape the profiles directory are refused even if a
+	// document bypassed parseProfileBundle
+	root := t.TempDir()
+	dir = filepath.Join(root, "a", "profiles")
+	docs, _ = parseProfileBundle([]byte("name: evil\n"), "evil.yaml")
+	docs[0].src.Profile.Name = "../../evil"
+	if _, err := importProfileDocs(docs, dir, nil, ImportSkip); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("importProfileDocs(../../evil) error = %v, want outside the directory", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil.yaml")); err == nil {
+		t.Error("importProfileDocs(../../evil) wrote a file outside the profiles directory")
+	}
+}
+
+func TestExportImportProfiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("profiles directory follows %APPDATA% on Windows")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "")
+	catalog := t.TempDir()
+	t.Setenv(profileCatalogsEnvVar, catalog)
+
+	writeProfiles(t, ProfilesDirByPlatform(), map[string]string{
+		"child.yaml": "name: child\nextends: base\nrealtime: false\n",
+	})
+	writeProfiles(t, catalog, map[string]string{"base.yaml": "image: img:base\nrealtime: true\n"})
+
+	var buf bytes.Buffer
+	exported, err := ExportProfiles([]string{"child"}, &buf)
+	if err != nil {
+		t.Fatalf("ExportProfiles() error = %v", err)
+	}
+	if want := []string{"base", "child"}; !reflect.DeepEqual(exported, want) {
+		t.Errorf("ExportProfiles() = %v, want %v", exported, want)
+	}
+	if _, err := ExportProfiles([]string{"nope"}, &bytes.Buffer{}); err == nil {
+		t.Error("ExportProfiles(nope) error = nil, want not found")
+	}
+
+	// Import the bundle into a fresh home: the explicit false survives
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(profileCatalogsEnvVar, "")
+	bundle := filepath.Join(t.TempDir(), "bundle.yaml")
+	if err := os.WriteFile(bundle, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := ImportProfiles(bundle, ImportSkip)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("ImportProfiles() = %+v, %v", results, err)
+	}
+	p, err := GetProfileByName("child")
+	if err != nil || p.Image != "img:base" || p.Realtime {
+		t.Errorf("imported child = %+v, %v, want the base image without realtime", p, err)
+	}
+}
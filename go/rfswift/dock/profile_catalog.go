@@ -0,0 +1,252 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Read-only profile catalogs (shared profile directories, e.g. a team git
+ * checkout) scanned alongside the personal profiles directory
+ */
+
+package dock
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	profileCatalogsFile   = "profile-catalogs"
+	profileCatalogsEnvVar = "RFSWIFT_PROFILE_CATALOGS"
+)
+
+// CatalogProfile is a profile as found on disk with the file it comes from.
+type CatalogProfile struct {
+	Profile Profile `json:"profile" yaml:"profile"`
+	Path    string  `json:"path" yaml:"path"`
+	Catalog string  `json:"catalog,omitempty" yaml:"catalog,omitempty"` // "" for the personal profiles directory
+}
+
+// ReadOnly reports whether the profile comes from a catalog.
+func (c CatalogProfile) ReadOnly() bool {
+	return c.Catalog != ""
+}
+
+// ProfileCollision reports a profile name defined in several places: the
+// first path is used, the others are shadowed.
+type ProfileCollision struct {
+	Name     string   `json:"name" yaml:"name"`
+	Used     string   `json:"used" yaml:"used"`
+	Shadowed []string `json:"shadowed" yaml:"shadowed"`
+}
+
+// profileCatalogsPath returns the file listing the catalog directories.
+func profileCatalogsPath() string {
+	return filepath.Join(rfswiftConfigDir(), profileCatalogsFile)
+}
+
+// ProfileCatalogDirs returns the configured catalog directories, in priority
+// order: those of RFSWIFT_PROFILE_CATALOGS (separated like PATH), then those
+// listed one per line in the profile-catalogs file of the config directory.
+//
+//	out: []string catalog directories
+func ProfileCatalogDirs() []string {
+	var dirs []string
+	add := func(dir string) {
+		dir = strings.TrimSpace(dir)
+		if dir == "" || strings.HasPrefix(dir, "#") {
+			return
+		}
+		dir = expandHome(dir)
+		if !containsString(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, dir := range filepath.SplitList(os.Getenv(profileCatalogsEnvVar)) {
+		add(dir)
+	}
+	if f, err := os.Open(profileCatalogsPath()); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			add(scanner.Text())
+		}
+	}
+	return dirs
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// AddProfileCatalog appends a directory to the profile-catalogs file.
+//
+//	in(1): string dir catalog directory
+//	out: error if the directory does not exist or the file cannot be written
+func AddProfileCatalog(dir string) error {
+	abs, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("catalog %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("catalog %s is not a directory", dir)
+	}
+
+	dirs := readProfileCatalogsFile()
+	if containsString(dirs, abs) {
+		return fmt.Errorf("catalog %s is already configured", abs)
+	}
+	return writeProfileCatalogsFile(append(dirs, abs))
+}
+
+// RemoveProfileCatalog removes a directory from the profile-catalogs file.
+//
+//	in(1): string dir catalog directory, as listed or as a relative path
+//	out: error if the directory is not configured
+func RemoveProfileCatalog(dir string) error {
+	abs, _ := filepath.Abs(expandHome(dir))
+	dirs := readProfileCatalogsFile()
+	var kept []string
+	for _, d := range dirs {
+		if d != dir && d != abs {
+			kept = append(kept, d)
+		}
+	}
+	if len(kept) == len(dirs) {
+		return fmt.Errorf("catalog %s is not configured in %s", dir, profileCatalogsPath())
+	}
+	return writeProfileCatalogsFile(kept)
+}
+
+func readProfileCatalogsFile() []string {
+	data, err := os.ReadFile(profileCatalogsPath())
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+func writeProfileCatalogsFile(dirs []string) error {
+	if err := os.MkdirAll(rfswiftConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	content := "# Read-only profile directories scanned after the personal profiles, one per line\n"
+	for _, d := range dirs {
+		content += d + "\n"
+	}
+	return os.WriteFile(profileCatalogsPath(), []byte(content), 0644)
+}
+
+// readProfileDir parses the profile files of a directory. Files that cannot
+// be read or parsed are skipped.
+//
+//	in(1): string dir directory to scan
+//	in(2): string catalog catalog the directory belongs to ("" for personal)
+//	out: []CatalogProfile profiles found
+//	out: []profileSource the same profiles with the keys each file sets
+func readProfileDir(dir, catalog string) ([]CatalogProfile, []profileSource) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+
+	var (
+		found   []CatalogProfile
+		sources []profileSource
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !isProfileFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		src, err := parseProfileSource(data)
+		if err != nil {
+			continue
+		}
+		if src.Profile.Name == "" {
+			src.Profile.Name = strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ".yaml"), ".yml")
+		}
+		found = append(found, CatalogProfile{Profile: src.Profile, Path: path, Catalog: catalog})
+		sources = append(sources, src)
+	}
+	return found, sources
+}
+
+func isProfileFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
+
+// scanProfiles reads the personal profiles directory, then each catalog. The
+// first definition of a name wins: personal profiles shadow catalogs, and an
+// earlier catalog shadows a later one.
+//
+//	in(1): string personal personal profiles directory
+//	in(2): []string catalogs catalog directories in priority order
+//	out: []CatalogProfile profiles kept, personal ones first
+//	out: []profileSource sources of the profiles kept, same order
+//	out: []ProfileCollision names defined more than once
+func scanProfiles(personal string, catalogs []string) ([]CatalogProfile, []profileSource, []ProfileCollision) {
+	var (
+		kept       []CatalogProfile
+		sources    []profileSource
+		collisions []ProfileCollision
+	)
+	index := make(map[string]int)    // lower-case name -> position in kept
+	collided := make(map[string]int) // lower-case name -> position in collisions
+
+	scan := func(dir, catalog string) {
+		found, srcs := readProfileDir(dir, catalog)
+		for i, c := range found {
+			key := strings.ToLower(c.Profile.Name)
+			if at, dup := index[key]; dup {
+				ci, ok := collided[key]
+				if !ok {
+					collisions = append(collisions, ProfileCollision{Name: kept[at].Profile.Name, Used: kept[at].Path})
+					ci = len(collisions) - 1
+					collided[key] = ci
+				}
+				collisions[ci].Shadowed = append(collisions[ci].Shadowed, c.Path)
+				continue
+			}
+			index[key] = len(kept)
+			kept = append(kept, c)
+			sources = append(sources, srcs[i])
+		}
+	}
+
+	scan(personal, "")
+	for _, dir := range catalogs {
+		scan(dir, dir)
+	}
+	return kept, sources, collisions
+}
+
+// LoadProfileCatalog returns every profile visible to RF Swift, as written,
+// with the file and catalog each comes from and the name collisions found.
+//
+//	out: []CatalogProfile profiles, personal ones first
+//	out: []ProfileCollision names defined more than once
+func LoadProfileCatalog() ([]CatalogProfile, []ProfileCollision) {
+	kept, _, collisions := scanProfiles(ProfilesDirByPlatform(), ProfileCatalogDirs())
+	return kept, collisions
+}
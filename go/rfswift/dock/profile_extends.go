@@ -0,0 +1,236 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Profile inheritance (extends:) and resolution of the effective profile
+ */
+
+package dock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileParents lists the profiles a profile extends. In YAML it is either a
+// single name ("extends: sdr-full") or a list ("extends: [base, wifi]").
+type ProfileParents []string
+
+// UnmarshalYAML accepts a scalar or a sequence of names.
+func (p *ProfileParents) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*p = nil
+		for _, name := range strings.Split(value.Value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				*p = append(*p, name)
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		var names []string
+		if err := value.Decode(&names); err != nil {
+			return err
+		}
+		*p = names
+		return nil
+	}
+	return fmt.Errorf("line %d: extends must be a profile name or a list of names", value.Line)
+}
+
+// MarshalYAML writes a single parent as a scalar.
+func (p ProfileParents) MarshalYAML() (interface{}, error) {
+	if len(p) == 1 {
+		return p[0], nil
+	}
+	return []string(p), nil
+}
+
+// profileSource is a profile as found on disk, with the keys its file sets:
+// an inherited boolean is only overridden by a key actually written.
+type profileSource struct {
+	Profile Profile
+	keys    map[string]bool
+}
+
+// parseProfileSource decodes a profile file and records the keys it sets.
+//
+//	in(1): []byte data YAML document
+//	out: profileSource decoded profile
+//	out: error if the document is not a valid profile
+func parseProfileSource(data []byte) (profileSource, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return profileSource{}, err
+	}
+	return parseProfileNode(&doc)
+}
+
+// parseProfileNode decodes a profile from a parsed YAML document (one
+// document of a profile bundle) and records the keys it sets.
+//
+//	in(1): *yaml.Node doc YAML document
+//	out: profileSource decoded profile
+//	out: error if the document is not a valid profile
+func parseProfileNode(doc *yaml.Node) (profileSource, error) {
+	if doc.Kind == 0 {
+		return profileSource{keys: map[string]bool{}}, nil
+	}
+	var p Profile
+	if err := doc.Decode(&p); err != nil {
+		return profileSource{}, err
+	}
+	var raw map[string]interface{}
+	if err := doc.Decode(&raw); err != nil {
+		return profileSource{}, err
+	}
+	keys := make(map[string]bool, len(raw))
+	for k := range raw {
+		keys[k] = true
+	}
+	return profileSource{Profile: p, keys: keys}, nil
+}
+
+// profileFieldKey returns the YAML key of a Profile field ("" for fields that
+// are not inherited: name and extends).
+func profileFieldKey(f reflect.StructField) string {
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if key == "" || key == "-" || key == "name" || key == "extends" {
+		return ""
+	}
+	return key
+}
+
+// ResolvedProfile is the effective profile after inheritance, with the
+// profiles that contributed each value.
+type ResolvedProfile struct {
+	Profile Profile             `json:"profile" yaml:"profile"`
+	Chain   []string            `json:"chain" yaml:"chain"`     // profiles merged, parents first
+	Sources map[string][]string `json:"sources" yaml:"sources"` // YAML key -> contributing profiles
+}
+
+// resolveProfile merges a profile with its ancestors. Parents are merged in
+// the order listed, depth first, then the profile itself. Fields tagged
+// merge:"append" are comma-separated lists whose items are appended and
+// deduplicated; every other field is a scalar the later profile overrides.
+//
+//	in(1): string name profile to resolve
+//	in(2): map[string]profileSource sources profiles by lower-case name
+//	out: *ResolvedProfile effective profile
+//	out: error on an unknown parent or an inheritance cycle
+func resolveProfile(name string, sources map[string]profileSource) (*ResolvedProfile, error) {
+	root, ok := sources[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found", name)
+	}
+
+	r := &ResolvedProfile{Sources: make(map[string][]string)}
+	merged := make(map[string]bool)
+	var visit func(src profileSource, stack []string) error
+	visit = func(src profileSource, stack []string) error {
+		key := strings.ToLower(src.Profile.Name)
+		for i, s := range stack {
+			if s == key {
+				cycle := append(append([]string{}, stack[i:]...), key)
+				return fmt.Errorf("profile inheritance cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		// A parent shared by two branches (diamond) is merged once
+		if merged[key] {
+			return nil
+		}
+		stack = append(stack, key)
+		for _, parent := range src.Profile.Extends {
+			ps, ok := sources[strings.ToLower(parent)]
+			if !ok {
+				return fmt.Errorf("profile '%s' extends unknown profile '%s'", src.Profile.Name, parent)
+			}
+			if err := visit(ps, stack); err != nil {
+				return err
+			}
+		}
+		merged[key] = true
+		mergeProfile(&r.Profile, src, r.Sources)
+		r.Chain = append(r.Chain, src.Profile.Name)
+		return nil
+	}
+	if err := visit(root, nil); err != nil {
+		return nil, err
+	}
+
+	r.Profile.Name = root.Profile.Name
+	r.Profile.Extends = root.Profile.Extends
+	return r, nil
+}
+
+// mergeProfile applies src on top of dst and records src as a source of the
+// values it contributed.
+func mergeProfile(dst *Profile, src profileSource, sources map[string][]string) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src.Profile)
+	for i := 0; i < dv.NumField(); i++ {
+		field := dv.Type().Field(i)
+		key := profileFieldKey(field)
+		if key == "" || !src.keys[key] {
+			continue
+		}
+		from := sv.Field(i)
+
+		if field.Tag.Get("merge") == "append" && field.Type.Kind() == reflect.String {
+			items := splitProfileList(dv.Field(i).String())
+			added := false
+			for _, item := range splitProfileList(from.String()) {
+				if !containsString(items, item) {
+					items = append(items, item)
+					added = true
+				}
+			}
+			dv.Field(i).SetString(strings.Join(items, ","))
+			if added {
+				sources[key] = append(sources[key], src.Profile.Name)
+			}
+			continue
+		}
+
+		dv.Field(i).Set(from)
+		sources[key] = []string{src.Profile.Name}
+	}
+}
+
+func splitProfileList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveProfile returns the effective profile of name with the profiles that
+// contributed each value (rfswift profile show --resolved).
+//
+//	in(1): string name profile name
+//	out: *ResolvedProfile effective profile
+//	out: error if the profile is missing or its inheritance is broken
+func ResolveProfile(name string) (*ResolvedProfile, error) {
+	sources := make(map[string]profileSource)
+	for _, src := range loadProfileSources() {
+		sources[strings.ToLower(src.Profile.Name)] = src
+	}
+	if _, ok := sources[strings.ToLower(strings.TrimSpace(name))]; !ok {
+		return nil, profileNotFound(name)
+	}
+	return resolveProfile(strings.TrimSpace(name), sources)
+}
@@ -0,0 +1,495 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Profile validation and privilege risk scoring (rfswift profile lint)
+ */
+
+package dock
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/api/types/network"
+
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// Severities of a ProfileFinding.
+const (
+	ProfileError   = "error"
+	ProfileWarning = "warning"
+)
+
+// Risk levels of a ProfileRisk.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+var (
+	// linuxCapabilities lists the capabilities of capabilities(7), without
+	// the CAP_ prefix.
+	linuxCapabilities = map[string]bool{
+		"CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true, "FOWNER": true,
+		"FSETID": true, "KILL": true, "SETGID": true, "SETUID": true, "SETPCAP": true,
+		"LINUX_IMMUTABLE": true, "NET_BIND_SERVICE": true, "NET_BROADCAST": true,
+		"NET_ADMIN": true, "NET_RAW": true, "IPC_LOCK": true, "IPC_OWNER": true,
+		"SYS_MODULE": true, "SYS_RAWIO": true, "SYS_CHROOT": true, "SYS_PTRACE": true,
+		"SYS_PACCT": true, "SYS_ADMIN": true, "SYS_BOOT": true, "SYS_NICE": true,
+		"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true, "MKNOD": true,
+		"LEASE": true, "AUDIT_WRITE": true, "AUDIT_CONTROL": true, "SETFCAP": true,
+		"MAC_OVERRIDE": true, "MAC_ADMIN": true, "SYSLOG": true, "WAKE_ALARM": true,
+		"BLOCK_SUSPEND": true, "AUDIT_READ": true, "PERFMON": true, "BPF": true,
+		"CHECKPOINT_RESTORE": true,
+	}
+
+	// capabilityRisk weighs the capabilities that widen the container's
+	// reach over the host; the others count for 1 point each.
+	capabilityRisk = map[string]int{
+		"ALL":             50,
+		"SYS_MODULE":      30,
+		"SYS_ADMIN":       25,
+		"SYS_PTRACE":      15,
+		"SYS_RAWIO":       15,
+		"DAC_READ_SEARCH": 15,
+		"BPF":             15,
+		"SYS_BOOT":        10,
+		"MAC_ADMIN":       10,
+		"DAC_OVERRIDE":    5,
+		"NET_ADMIN":       5,
+		"SYS_TIME":        5,
+		"PERFMON":         5,
+		"NET_RAW":         2,
+	}
+
+	// sensitiveBindings weighs host paths whose bind mount hands the
+	// container control over the host.
+	sensitiveBindings = map[string]int{
+		"/":                    40,
+		"/var/run/docker.sock": 40,
+		"/run/docker.sock":     40,
+		"/run/podman":          40,
+		"/etc":                 20,
+		"/root":                20,
+		"/boot":                20,
+		"/proc":                20,
+		"/sys":                 15,
+		"/home":                10,
+		"/dev":                 15,
+	}
+
+	// sensitiveDevices weighs device nodes giving raw access to memory or disks.
+	sensitiveDevices = map[string]int{
+		"/dev/mem":  30,
+		"/dev/kmem": 30,
+		"/dev/port": 30,
+		"/dev/sd":   20,
+		"/dev/nvme": 20,
+		"/dev/dm-":  20,
+	}
+
+	cgroupRulePattern = regexp.MustCompile(`^([abc]) (\d+|\*):(\d+|\*) ([rwm]{1,3})$`)
+	gpuIDPattern      = regexp.MustCompile(`^(\d+|(GPU|MIG)-[0-9A-Fa-f-]+)$`)
+
+	// imageRefPattern follows the docker reference grammar: an optional
+	// registry host[:port], lower-case path components, a tag and a digest.
+	imageRefPattern = regexp.MustCompile(`^((([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(:[0-9]+)?|\[[0-9a-fA-F:]+\](:[0-9]+)?)/)?[a-z0-9]+(([._]|__|-+)[a-z0-9]+)*(/[a-z0-9]+(([._]|__|-+)[a-z0-9]+)*)*(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+)
+
+// ProfileFinding is one problem found in a profile.
+type ProfileFinding struct {
+	Field    string `json:"field" yaml:"field"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// RiskFactor is one setting contributing to a profile's risk score.
+type RiskFactor struct {
+	Reason string `json:"reason" yaml:"reason"`
+	Points int    `json:"points" yaml:"points"`
+}
+
+// ProfileRisk rates how much of the host a container created from the
+// profile can reach, from 0 (default container) to 100.
+type ProfileRisk struct {
+	Score   int          `json:"score" yaml:"score"`
+	Level   string       `json:"level" yaml:"level"`
+	Factors []RiskFactor `json:"factors" yaml:"factors"`
+}
+
+// Explain summarizes the main factors, e.g. "privileged + host network + SYS_ADMIN".
+func (r ProfileRisk) Explain() string {
+	if len(r.Factors) == 0 {
+		return "no elevated privileges"
+	}
+	reasons := make([]string, len(r.Factors))
+	for i, f := range r.Factors {
+		reasons[i] = f.Reason
+	}
+	return strings.Join(reasons, " + ")
+}
+
+// ProfileLint is the result of linting one profile.
+type ProfileLint struct {
+	Profile  string           `json:"profile" yaml:"profile"`
+	Valid    bool             `json:"valid" yaml:"valid"`
+	Findings []ProfileFinding `json:"findings" yaml:"findings"`
+	Risk     ProfileRisk      `json:"risk" yaml:"risk"`
+}
+
+// Warnings returns the number of warnings found.
+func (l ProfileLint) Warnings() int {
+	n := 0
+	for _, f := range l.Findings {
+		if f.Severity == ProfileWarning {
+			n++
+		}
+	}
+	return n
+}
+
+// lintProfile checks the syntax of every run option of an (inheritance
+// resolved) profile, the least-privilege rule of the default profiles, and
+// scores its risk.
+//
+//	in(1): Profile p profile to check
+//	out: ProfileLint findings and risk
+func lintProfile(p Profile) ProfileLint {
+	l := ProfileLint{Profile: p.Name, Findings: []ProfileFinding{}}
+	add := func(field, severity, format string, args ...interface{}) {
+		l.Findings = append(l.Findings, ProfileFinding{Field: field, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		add("name", ProfileError, "required")
+	}
+	switch {
+	case p.Image == "":
+		add("image", ProfileError, "required")
+	case !imageRefPattern.MatchString(p.Image):
+		add("image", ProfileError, "invalid image reference %q", p.Image)
+	}
+
+	for _, dev := range splitProfileList(p.Devices) {
+		parts := strings.Split(dev, ":")
+		if len(parts) != 2 {
+			add("devices", ProfileError, "%q: expected /dev/host:/dev/container", dev)
+			continue
+		}
+		for _, p := range parts {
+			if !strings.HasPrefix(p, "/dev/") || path.Clean(p) != p {
+				add("devices", ProfileError, "%q: %q is not a clean path under /dev", dev, p)
+				break
+			}
+		}
+	}
+
+	for _, b := range splitProfileList(p.Bindings) {
+		parts := strings.Split(b, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") {
+			add("bindings", ProfileError, "%q: expected host_path:/container/path[:options]", b)
+		}
+	}
+
+	for _, c := range splitProfileList(p.Caps) {
+		name := strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+		if name != "ALL" && !linuxCapabilities[name] {
+			add("caps", ProfileError, "unknown capability %q", c)
+		}
+	}
+
+	for _, rule := range splitProfileList(p.Cgroups) {
+		if !cgroupRulePattern.MatchString(rule) {
+			add("cgroups", ProfileError, "%q: expected \"<a|b|c> <major|*>:<minor|*> <rwm>\"", rule)
+		}
+	}
+
+	for _, entry := range splitPortBindings(p.PortBindings) {
+		if _, _, err := parsePortBinding(entry); err != nil {
+			add("port_bindings", ProfileError, "%v", err)
+		}
+	}
+	for _, port := range splitProfileList(p.ExposedPorts) {
+		if _, err := network.ParsePort(port); err != nil {
+			add("exposed_ports", ProfileError, "%q: %v", port, err)
+		}
+	}
+
+	if gpus := strings.TrimSpace(p.GPUs); gpus != "" && strings.ToLower(gpus) != "all" {
+		for _, id := range strings.Split(gpus, ",") {
+			if !gpuIDPattern.MatchString(strings.TrimSpace(id)) {
+				add("gpus", ProfileError, "%q: expected \"all\" or comma-separated GPU indexes/UUIDs", id)
+			}
+		}
+	}
+
+	for _, u := range splitProfileList(p.Ulimits) {
+		if name, _, ok := strings.Cut(u, "="); !ok || name == "" {
+			add("ulimits", ProfileError, "%q: expected name=value or name=soft:hard", u)
+		}
+	}
+	for _, e := range splitProfileList(p.ExtraEnv) {
+		if name, _, ok := strings.Cut(e, "="); !ok || name == "" {
+			add("extraenv", ProfileError, "%q: expected KEY=VALUE", e)
+		}
+	}
+	if proto := p.DesktopProto; proto != "" && proto != "http" && proto != "vnc" {
+		add("desktop_proto", ProfileError, "%q: expected http or vnc", proto)
+	}
+	if w := p.Workspace; w != "" && w != "none" && w != "cwd" && !strings.HasPrefix(w, "/") && !strings.HasPrefix(w, "~") {
+		add("workspace", ProfileWarning, "%q is relative: it depends on the directory rfswift runs from", w)
+	}
+	for _, f := range p.Resources().fields() {
+		if f.Err != nil {
+			add(f.Key, ProfileError, "%v", f.Err)
+		}
+	}
+
+	// Least privilege: capabilities and device mappings are preferred over
+	// privileged mode, which is reserved for the yolo profile.
+	if p.Privileged {
+		add("privileged", ProfileWarning, "least privilege: privileged grants every capability and device; list the caps, devices and cgroups the tools need instead")
+		if p.Caps != "" || p.Cgroups != "" {
+			add("privileged", ProfileWarning, "caps and cgroups are redundant with privileged: true")
+		}
+	}
+	for _, c := range splitProfileList(p.Caps) {
+		if strings.TrimPrefix(strings.ToUpper(c), "CAP_") == "ALL" {
+			add("caps", ProfileWarning, "least privilege: ALL grants every capability; list the ones needed")
+		}
+	}
+	for _, rule := range splitProfileList(p.Cgroups) {
+		if m := cgroupRulePattern.FindStringSubmatch(rule); m != nil && (m[1] == "a" || m[2] == "*") {
+			add("cgroups", ProfileWarning, "least privilege: %q allows every device of the host; restrict it to a major number", rule)
+		}
+	}
+	if p.DesktopHost == "0.0.0.0" && p.DesktopPass == "" {
+		add("desktop_pass", ProfileWarning, "desktop listens on all interfaces without a password")
+	}
+
+	l.Risk = profileRisk(p)
+	l.Valid = true
+	for _, f := range l.Findings {
+		if f.Severity == ProfileError {
+			l.Valid = false
+		}
+	}
+	return l
+}
+
+// splitPortBindings splits port bindings the way ParseBindedPorts does.
+func splitPortBindings(s string) []string {
+	if strings.Contains(s, ";;") {
+		var entries []string
+		for _, e := range strings.Split(s, ";;") {
+			if e = strings.TrimSpace(e); e != "" {
+				entries = append(entries, e)
+			}
+		}
+		return entries
+	}
+	return splitProfileList(s)
+}
+
+// profileRisk scores what a container created from p can do to its host.
+// The factors are sorted by weight, heaviest first.
+//
+//	in(1): Profile p profile to rate
+//	out: ProfileRisk score, level and contributing factors
+func profileRisk(p Profile) ProfileRisk {
+	var factors []RiskFactor
+	add := func(points int, format string, args ...interface{}) {
+		factors = append(factors, RiskFactor{Reason: fmt.Sprintf(format, args...), Points: points})
+	}
+
+	if p.Privileged {
+		add(50, "privileged")
+	}
+	if p.Network == "host" || p.Network == "" {
+		add(15, "host network")
+	}
+	for _, c := range splitProfileList(p.Caps) {
+		name := strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+		if points, ok := capabilityRisk[name]; ok {
+			add(points, "%s", name)
+		} else if linuxCapabilities[name] {
+			add(1, "%s", name)
+		}
+	}
+	for _, rule := range splitProfileList(p.Cgroups) {
+		if m := cgroupRulePattern.FindStringSubmatch(rule); m != nil && (m[1] == "a" || m[2] == "*") {
+			add(20, "cgroup %s", rule)
+		}
+	}
+	if p.Seccomp == "unconfined" {
+		add(15, "seccomp unconfined")
+	}
+	for _, b := range splitProfileList(p.Bindings) {
+		host := path.Clean(strings.Split(b, ":")[0])
+		if points, ok := sensitiveBindings[host]; ok {
+			add(points, "bind %s", host)
+		}
+	}
+	for _, d := range splitProfileList(p.Devices) {
+		host := strings.Split(d, ":")[0]
+		for prefix, points := range sensitiveDevices {
+			if strings.HasPrefix(host, prefix) {
+				add(points, "device %s", host)
+				break
+			}
+		}
+	}
+	for _, entry := range splitPortBindings(p.PortBindings) {
+		if _, b, err := parsePortBinding(entry); err == nil && (!b.HostIP.IsValid() || b.HostIP.IsUnspecified()) {
+			add(5, "ports on all interfaces")
+			break
+		}
+	}
+	if p.Desktop && p.DesktopHost == "0.0.0.0" && p.DesktopPass == "" {
+		add(15, "open desktop without password")
+	}
+
+	sort.SliceStable(factors, func(i, j int) bool { return factors[i].Points > factors[j].Points })
+	score := 0
+	for _, f := range factors {
+		score += f.Points
+	}
+	if score > 100 {
+		score = 100
+	}
+	level := RiskLow
+	switch {
+	case score >= 50:
+		level = RiskHigh
+	case score >= 20:
+		level = RiskMedium
+	}
+	if factors == nil {
+		factors = []RiskFactor{}
+	}
+	return ProfileRisk{Score: score, Level: level, Factors: factors}
+}
+
+// LintProfiles lints the named profiles, or every profile when names is
+// empty. A profile whose inheritance cannot be resolved is reported as an
+// error.
+//
+//	in(1): []string names profiles to lint
+//	out: []ProfileLint one result per profile
+//	out: error if a named profile does not exist
+func LintProfiles(names []string) ([]ProfileLint, error) {
+	if len(names) == 0 {
+		for _, src := range loadProfileSources() {
+			names = append(names, src.Profile.Name)
+		}
+	}
+
+	results := []ProfileLint{}
+	for _, name := range names {
+		r, err := ResolveProfile(name)
+		if err != nil {
+			if _, rawErr := GetUnresolvedProfile(name); rawErr != nil {
+				return nil, err
+			}
+			results = append(results, ProfileLint{
+				Profile:  name,
+				Findings: []ProfileFinding{{Field: "extends", Severity: ProfileError, Message: err.Error()}},
+				Risk:     ProfileRisk{Level: RiskLow, Factors: []RiskFactor{}},
+			})
+			continue
+		}
+		results = append(results, lintProfile(r.Profile))
+	}
+	return results, nil
+}
+
+// CheckProfile lints a profile before it is saved.
+//
+//	in(1): Profile p profile to check
+//	out: ProfileLint findings and risk
+func CheckProfile(p Profile) ProfileLint {
+	return lintProfile(p)
+}
+
+// riskColor returns the table color of a risk level.
+func riskColor(level string) lipgloss.Color {
+	switch level {
+	case RiskHigh:
+		return tui.ColorDanger
+	case RiskMedium:
+		return tui.ColorWarning
+	}
+	return tui.ColorSuccess
+}
+
+// DisplayProfileLint prints lint results as tables, or as structured output.
+//
+//	in(1): []ProfileLint results
+//	out: error if structured output fails
+func DisplayProfileLint(results []ProfileLint) error {
+	if common.MachineOutput() {
+		return common.PrintStructured("profile_lint", results)
+	}
+
+	summary := make([][]string, len(results))
+	for i, r := range results {
+		status := "ok"
+		if !r.Valid {
+			status = "invalid"
+		} else if r.Warnings() > 0 {
+			status = fmt.Sprintf("warn (%d)", r.Warnings())
+		}
+		summary[i] = []string{r.Profile, status, fmt.Sprintf("%d (%s)", r.Risk.Score, r.Risk.Level), r.Risk.Explain()}
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   "🔎 Profile lint",
+		Headers: []string{"Profile", "Status", "Risk", "Why"},
+		Rows:    summary,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			switch col {
+			case 1:
+				if !results[row].Valid {
+					return tui.ColorDanger
+				}
+				if results[row].Warnings() > 0 {
+					return tui.ColorWarning
+				}
+				return tui.ColorSuccess
+			case 2:
+				return riskColor(results[row].Risk.Level)
+			}
+			return lipgloss.Color("")
+		},
+	})
+
+	var rows [][]string
+	for _, r := range results {
+		for _, f := range r.Findings {
+			rows = append(rows, []string{r.Profile, f.Field, f.Severity, f.Message})
+		}
+	}
+	if len(rows) > 0 {
+		tui.RenderTable(tui.TableConfig{
+			Title:   "Findings",
+			Headers: []string{"Profile", "Field", "Severity", "Problem"},
+			Rows:    rows,
+			ColorFunc: func(row, col int, content string) lipgloss.Color {
+				if col != 2 {
+					return lipgloss.Color("")
+				}
+				if content == ProfileError {
+					return tui.ColorDanger
+				}
+				return tui.ColorWarning
+			},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintProfile(t *testing.T) {
+	base := Profile{Name: "t", Image: "penthertz/rfswift_noble:sdr_light", Network: "nat"}
+	tests := []struct {
+		name    string
+		modify  func(p *Profile)
+		field   string
+		wantErr bool
+	}{
+		{"valid", func(p *Profile) {}, "", false},
+		{"registry with port and digest", func(p *Profile) {
+			p.Image = "harbor.lab:8443/rf/rfswift@sha256:" + strings.Repeat("a", 64)
+		}, "", false},
+		{"missing image", func(p *Profile) { p.Image = "" }, "image", true},
+		{"upper-case repository", func(p *Profile) { p.Image = "Penthertz/RFSwift:latest" }, "image", true},
+		{"device without container path", func(p *Profile) { p.Devices = "/dev/ttyUSB0" }, "devices", true},
+		{"device outside /dev", func(p *Profile) { p.Devices = "/tmp/x:/dev/x" }, "devices", true},
+		{"device with dot-dot", func(p *Profile) { p.Devices = "/dev/../etc/shadow:/dev/x" }, "devices", true},
+		{"binding to relative path", func(p *Profile) { p.Bindings = "/data:data" }, "bindings", true},
+		{"capability with prefix", func(p *Profile) { p.Caps = "cap_net_admin,SYS_NICE" }, "", false},
+		{"unknown capability", func(p *Profile) { p.Caps = "NET_ADMIN,SYS_EVERYTHING" }, "caps", true},
+		{"cgroup rule", func(p *Profile) { p.Cgroups = "c 189:* rwm,b 8:0 r" }, "", false},
+		{"bad cgroup rule", func(p *Profile) { p.Cgroups = "c 189 rwm" }, "cgroups", true},
+		{"bad cgroup access", func(p *Profile) { p.Cgroups = "c 189:* rwx" }, "cgroups", true},
+		{"port bindings", func(p *Profile) { p.PortBindings = "8080:80/tcp,127.0.0.1:5900:5900/tcp,53/udp:5353" }, "", false},
+		{"port without protocol", func(p *Profile) { p.PortBindings = "8080:80" }, "port_bindings", true},
+		{"port with bad host ip", func(p *Profile) { p.PortBindings = "999.1.1.1:80:80/tcp" }, "port_bindings", true},
+		{"bad exposed port", func(p *Profile) { p.ExposedPorts = "http/tcp" }, "exposed_ports", true},
+		{"gpu indexes", func(p *Profile) { p.GPUs = "0, 1" }, "", false},
+		{"gpu uuid", func(p *Profile) { p.GPUs = "GPU-3a5b7c9d-1234-5678-9abc-def012345678" }, "", false},
+		{"bad gpu", func(p *Profile) { p.GPUs = "first" }, "gpus", true},
+		{"bad ulimit", func(p *Profile) { p.Ulimits = "rtprio" }, "ulimits", true},
+		{"bad env", func(p *Profile) { p.ExtraEnv = "DEBUG" }, "extraenv", true},
+		{"resource limits", func(p *Profile) { p.CPUs, p.Memory, p.CpusetCpus, p.ShmSize = "2", "4g", "0-3", "1g" }, "", false},
+		{"bad memory", func(p *Profile) { p.Memory = "4 gigs" }, "memory", true},
+		{"bad cpuset", func(p *Profile) { p.CpusetCpus = "3-0" }, "cpuset_cpus", true},
+		{"bad desktop proto", func(p *Profile) { p.DesktopProto = "rdp" }, "desktop_proto", true},
+		{"privileged", func(p *Profile) { p.Privileged = true }, "privileged", false},
+		{"all capabilities", func(p *Profile) { p.Caps = "ALL" }, "caps", false},
+		{"wildcard cgroup", func(p *Profile) { p.Cgroups = "a *:* rwm" }, "cgroups", false},
+		{"open desktop", func(p *Profile) { p.DesktopHost = "0.0.0.0" }, "desktop_pass", false},
+	}
+
+	for _, tt := range tests {
+		p := base
+		tt.modify(&p)
+		l := lintProfile(p)
+		if l.Valid == tt.wantErr {
+			t.Errorf("%s: lintProfile().Valid = %v, want %v (%+v)", tt.name, l.Valid, !tt.wantErr, l.Findings)
+		}
+		if tt.field == "" {
+			if len(l.Findings) != 0 {
+				t.Errorf("%s: lintProfile() findings = %+v, want none", tt.name, l.Findings)
+			}
+			continue
+		}
+		found := false
+		for _, f := range l.Findings {
+			if f.Field == tt.field {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: lintProfile() findings = %+v, want one on %s", tt.name, l.Findings, tt.field)
+		}
+	}
+}
+
+func TestProfileRisk(t *testing.T) {
+	tests := []struct {
+		profile Profile
+		score   int
+		level   string
+		explain string
+	}{
+		{Profile{Network: "nat"}, 0, RiskLow, "no elevated privileges"},
+		{Profile{Network: "nat", Caps: "NET_ADMIN,NET_RAW"}, 7, RiskLow, "NET_ADMIN + NET_RAW"},
+		{Profile{Network: "host", Caps: "SYS_PTRACE"}, 30, RiskMedium, "host network + SYS_PTRACE"},
+		{Profile{Privileged: true, Network: "host", Caps: "SYS_ADMIN"}, 90, RiskHigh, "privileged + SYS_ADMIN + host network"},
+		{Profile{Network: "nat", Bindings: "/var/run/docker.sock:/var/run/docker.sock,/:/host:ro"}, 80, RiskHigh, "bind /var/run/docker.sock + bind /"},
+		{Profile{Network: "nat", Seccomp: "unconfined", Cgroups: "c *:* rwm", PortBindings: "8080:80/tcp"}, 40, RiskMedium, "cgroup c *:* rwm + seccomp unconfined + ports on all interfaces"},
+		{Profile{Network: "nat", PortBindings: "127.0.0.1:8080:80/tcp", Devices: "/dev/sda:/dev/sda"}, 20, RiskMedium, "device /dev/sda"},
+		{Profile{Privileged: true, Caps: "ALL", Bindings: "/:/host"}, 100, RiskHigh, "privileged + ALL + bind / + host network"},
+	}
+
+	for _, tt := range tests {
+		r := profileRisk(tt.profile)
+		if r.Score != tt.score || r.Level != tt.level || r.Explain() != tt.explain {
+			t.Errorf("profileRisk(%+v) = %d %s %q, want %d %s %q", tt.profile, r.Score, r.Level, r.Explain(), tt.score, tt.level, tt.explain)
+		}
+	}
+}
+
+// The default profiles follow the least-privilege rule: only yolo is
+// privileged, and every profile is valid.
+func TestDefaultProfilesLeastPrivilege(t *testing.T) {
+	for _, p := range DefaultProfiles() {
+		l := lintProfile(p)
+		if !l.Valid {
+			t.Errorf("default profile %s is invalid: %+v", p.Name, l.Findings)
+		}
+		if p.Name != "yolo" && len(l.Findings) > 0 {
+			t.Errorf("default profile %s breaks least privilege: %+v", p.Name, l.Findings)
+		}
+		if p.Name == "yolo" && l.Risk.Level != RiskHigh {
+			t.Errorf("yolo risk = %+v, want high", l.Risk)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func profileSources(t *testing.T, docs ...string) map[string]profileSource {
+	t.Helper()
+	sources := make(map[string]profileSource)
+	for _, doc := range docs {
+		src, err := parseProfileSource([]byte(doc))
+		if err != nil {
+			t.Fatalf("parseProfileSource(%q) error = %v", doc, err)
+		}
+		sources[strings.ToLower(src.Profile.Name)] = src
+	}
+	return sources
+}
+
+func TestProfileParents(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want ProfileParents
+	}{
+		{"name: a\nextends: base\n", ProfileParents{"base"}},
+		{"name: a\nextends: [base, usb]\n", ProfileParents{"base", "usb"}},
+		{"name: a\nextends:\n  - base\n  - usb\n", ProfileParents{"base", "usb"}},
+		{"name: a\n", nil},
+	}
+
+	for _, tt := range tests {
+		src, err := parseProfileSource([]byte(tt.doc))
+		if err != nil {
+			t.Errorf("parseProfileSource(%q) error = %v", tt.doc, err)
+			continue
+		}
+		if !reflect.DeepEqual(src.Profile.Extends, tt.want) {
+			t.Errorf("parseProfileSource(%q).Extends = %v, want %v", tt.doc, src.Profile.Extends, tt.want)
+		}
+	}
+
+	if _, err := parseProfileSource([]byte("name: a\nextends: {x: 1}\n")); err == nil {
+		t.Error("parseProfileSource(extends mapping) error = nil, want an error")
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	sources := profileSources(t,
+		"name: capture\ncaps: NET_ADMIN,NET_RAW\nbindings: /dev/bus/usb:/dev/bus/usb\nrealtime: true\n",
+		"name: tun\ndevices: /dev/net/tun:/dev/net/tun\ncaps: NET_ADMIN\nnetwork: nat\n",
+		"name: wifi\nextends: [capture, tun]\ndescription: Wi-Fi\nimage: penthertz/rfswift_noble:wifi\nnetwork: host\ncaps: NET_RAW,SYS_ADMIN\nrealtime: false\n",
+	)
+
+	r, err := resolveProfile("wifi", sources)
+	if err != nil {
+		t.Fatalf("resolveProfile(wifi) error = %v", err)
+	}
+	want := Profile{
+		Name:        "wifi",
+		Extends:     ProfileParents{"capture", "tun"},
+		Description: "Wi-Fi",
+		Image:       "penthertz/rfswift_noble:wifi",
+		Network:     "host",
+		Caps:        "NET_ADMIN,NET_RAW,SYS_ADMIN",
+		Bindings:    "/dev/bus/usb:/dev/bus/usb",
+		Devices:     "/dev/net/tun:/dev/net/tun",
+	}
+	if !reflect.DeepEqual(r.Profile, want) {
+		t.Errorf("resolveProfile(wifi) = %+v, want %+v", r.Profile, want)
+	}
+	if want := []string{"capture", "tun", "wifi"}; !reflect.DeepEqual(r.Chain, want) {
+		t.Errorf("resolveProfile(wifi) chain = %v, want %v", r.Chain, want)
+	}
+	wantSources := map[string][]string{
+		"caps":        {"capture", "wifi"}, // tun only repeats NET_ADMIN
+		"bindings":    {"capture"},
+		"devices":     {"tun"},
+		"network":     {"wifi"},
+		"realtime":    {"wifi"},
+		"description": {"wifi"},
+		"image":       {"wifi"},
+	}
+	if !reflect.DeepEqual(r.Sources, wantSources) {
+		t.Errorf("resolveProfile(wifi) sources = %v, want %v", r.Sources, wantSources)
+	}
+
+	// A boolean that is not written is inherited
+	sources = profileSources(t,
+		"name: base\nrealtime: true\nimage: img:a\n",
+		"name: child\nextends: base\n",
+	)
+	if r, err := resolveProfile("child", sources); err != nil || !r.Profile.Realtime || r.Profile.Image != "img:a" {
+		t.Errorf("resolveProfile(child) = %+v, %v, want realtime and image inherited", r, err)
+	}
+}
+
+func TestResolveProfileDiamond(t *testing.T) {
+	sources := profileSources(t,
+		"name: base\ncaps: NET_RAW\n",
+		"name: left\nextends: base\ncaps: NET_ADMIN\n",
+		"name: right\nextends: base\ngpus: all\n",
+		"name: top\nextends: [left, right]\n",
+	)
+	r, err := resolveProfile("top", sources)
+	if err != nil {
+		t.Fatalf("resolveProfile(top) error = %v", err)
+	}
+	if want := []string{"base", "left", "right", "top"}; !reflect.DeepEqual(r.Chain, want) {
+		t.Errorf("resolveProfile(top) chain = %v, want %v", r.Chain, want)
+	}
+	if r.Profile.Caps != "NET_RAW,NET_ADMIN" || r.Profile.GPUs != "all" {
+		t.Errorf("resolveProfile(top) = %+v", r.Profile)
+	}
+}
+
+func TestResolveProfileErrors(t *testing.T) {
+	tests := []struct {
+		docs    []string
+		name    string
+		wantErr string
+	}{
+		{[]string{"name: a\nextends: b\n", "name: b\nextends: c\n", "name: c\nextends: a\n"}, "a", "cycle: a -> b -> c -> a"},
+		{[]string{"name: a\nextends: a\n"}, "a", "cycle: a -> a"},
+		{[]string{"name: a\nextends: missing\n"}, "a", "extends unknown profile 'missing'"},
+		{[]string{"name: a\n"}, "b", "not found"},
+	}
+
+	for _, tt := range tests {
+		_, err := resolveProfile(tt.name, profileSources(t, tt.docs...))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("resolveProfile(%s) error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadProfilesResolvesInheritance(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("profiles directory follows %APPDATA% on Windows")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+
+	dir := ProfilesDirByPlatform()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"base.yaml":   "name: base\nimage: img:base\ncaps: NET_RAW\n",
+		"child.yaml":  "name: child\nextends: base\ncaps: NET_ADMIN\n",
+		"broken.yaml": "name: broken\nextends: nowhere\nimage: img:broken\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := GetProfileByName("Child")
+	if err != nil {
+		t.Fatalf("GetProfileByName(Child) error = %v", err)
+	}
+	if p.Image != "img:base" || p.Caps != "NET_RAW,NET_ADMIN" {
+		t.Errorf("GetProfileByName(Child) = %+v, want the base image and merged caps", p)
+	}
+
+	raw, err := GetUnresolvedProfile("child")
+	if err != nil || raw.Image != "" || raw.Caps != "NET_ADMIN" {
+		t.Errorf("GetUnresolvedProfile(child) = %+v, %v, want the profile as written", raw, err)
+	}
+
+	// A broken chain keeps the profile as written in the listing
+	for _, p := range LoadProfiles() {
+		if p.Name == "broken" && p.Image != "img:broken" {
+			t.Errorf("LoadProfiles() broken = %+v, want it as written", p)
+		}
+	}
+}
+
+func TestProfileRunOptions(t *testing.T) {
+	sources := profileSources(t,
+		"name: base\nulimits: rtprio=95\nextraenv: TZ=UTC\nextrahosts: pluto.local:192.168.2.1\nshell: /bin/zsh\ndesktop_proto: vnc\n",
+		"name: lab\nextends: base\nulimits: memlock=-1\nextraenv: TZ=UTC,LANG=C.UTF-8\nseccomp: unconfined\ndesktop_host: 0.0.0.0\npulse_server: tcp:10.0.0.1:4713\nworkspace: none\n",
+	)
+	r, err := resolveProfile("lab", sources)
+	if err != nil {
+		t.Fatalf("resolveProfile(lab) error = %v", err)
+	}
+	p := r.Profile
+	got := []string{p.Ulimits, p.ExtraEnv, p.ExtraHosts, p.Seccomp, p.Shell, p.PulseServer, p.WorkspacePath(), p.DesktopConfig()}
+	want := []string{"rtprio=95,memlock=-1", "TZ=UTC,LANG=C.UTF-8", "pluto.local:192.168.2.1", "unconfined", "/bin/zsh", "tcp:10.0.0.1:4713", "none", "vnc:0.0.0.0:"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveProfile(lab) run options = %q, want %q", got, want)
+	}
+
+	if got := (Profile{}).DesktopConfig(); got != "" {
+		t.Errorf("Profile{}.DesktopConfig() = %q, want \"\"", got)
+	}
+	if proto, host, port := parseDesktopConfig(p.DesktopConfig()); proto != "vnc" || host != "0.0.0.0" || port != "5900" {
+		t.Errorf("parseDesktopConfig(%q) = %s %s %s, want vnc 0.0.0.0 5900", p.DesktopConfig(), proto, host, port)
+	}
+	cwd, _ := os.Getwd()
+	if got := (Profile{Workspace: "cwd"}).WorkspacePath(); got != cwd {
+		t.Errorf("WorkspacePath(cwd) = %q, want %q", got, cwd)
+	}
+}
+
+func TestProfileMasked(t *testing.T) {
+	p := Profile{Name: "desk", DesktopPass: "s3cret"}
+	if got := p.Masked(); got.DesktopPass != secretMask || got.Name != "desk" {
+		t.Errorf("Masked() = %+v, want the password masked", got)
+	}
+	if p.DesktopPass != "s3cret" {
+		t.Errorf("Masked() changed the profile itself: %q", p.DesktopPass)
+	}
+	if got := (Profile{}).Masked(); got.DesktopPass != "" {
+		t.Errorf("Profile{}.Masked().DesktopPass = %q, want \"\"", got.DesktopPass)
+	}
+}
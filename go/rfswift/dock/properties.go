@@ -110,6 +110,7 @@ func printContainerProperties(ctx context.Context, cli *client.Client, container
 		{Key: "Cgroup rules", Value: props["Cgroups"]},
 		{Key: "Ulimits", Value: props["Ulimits"]},
 		{Key: "GPUs", Value: props["GPUs"]},
+		{Key: "Resources", Value: formatResourceSummary(resourcesFromProps(props))},
 	}
 
 	tui.RenderPropertySheet("🧊 Container Summary", tui.ColorPrimary, items)
@@ -251,6 +252,10 @@ func getContainerProperties(ctx context.Context, cli *client.Client, containerID
 		}
 	}
 	props["GPUs"] = gpuSpec
+	props["USB"] = containerJSON.Config.Labels[USBLabel]
+	props["USBDevices"] = containerJSON.Config.Labels[USBDevicesLabel]
+
+	resourcesToProps(resourceLimitsFromHostConfig(containerJSON.HostConfig), props)
 
 	return props, nil
 }
@@ -1414,7 +1419,8 @@ func UpdatePortBinding(containerID string, binding string, add bool) error {
 //	in(3): string containerID           ID or name of the container to recreate
 //	in(4): map[string]string props      property overrides (keys: Caps, Cgroups, ExposedPorts,
 //	                                    PortBindings, Bindings, XDisplay, Shell, NetworkMode,
-//	                                    Privileged, Devices, Seccomp, ExtraHosts, Ulimits)
+//	                                    Privileged, Devices, Seccomp, ExtraHosts, Ulimits, GPUs,
+//	                                    CPUs, Memory, CpusetCpus, ShmSize, PidsLimit)
 //	out:   error                        non-nil if any step of the recreation process fails
 func recreateContainerWithProperties(ctx context.Context, cli *client.Client, containerID string, props map[string]string) error {
 	// Get fresh container info
@@ -1545,6 +1551,13 @@ func recreateContainerWithProperties(ctx context.Context, cli *client.Client, co
 		applyGPUConfig(props["GPUs"], hostConfig)
 	}
 
+	// Keep the CPU, memory, cpuset, shm and pids limits
+	if err := resourcesFromProps(props).applyToHostConfig(hostConfig); err != nil {
+		common.PrintErrorMessage(err)
+		rollbackContainer(ctx, cli, containerName, tempImageTag, containerJSON)
+		return err
+	}
+
 	if !privileged {
 		hostConfig.Devices = devices
 
@@ -1582,6 +1595,13 @@ func recreateContainerWithProperties(ctx context.Context, cli *client.Client, co
 	} else {
 		containerLabels["org.rfswift.exposed_ports"] = props["ExposedPorts"]
 	}
+	for label, prop := range map[string]string{USBLabel: "USB", USBDevicesLabel: "USBDevices"} {
+		if value, ok := props[prop]; ok && value != "" {
+			containerLabels[label] = value
+		} else if ok {
+			delete(containerLabels, label)
+		}
+	}
 
 	// Determine shell
 	shell := props["Shell"]
@@ -9,105 +9,266 @@ package dock
 import (
 	"archive/tar"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"gopkg.in/yaml.v3"
+	"time"
 
 	"github.com/moby/moby/client"
 	"github.com/moby/moby/client/pkg/jsonmessage"
 	"github.com/moby/term"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	common "penthertz/rfswift/common"
 )
 
-// BuildFromRecipe builds a Docker image from a YAML recipe file, optionally
-// overriding the image tag and disabling the layer cache during the build.
+// BuildFromRecipe builds a Docker image from a YAML recipe file. With
+// opts.Platforms set, one image is built per platform and tagged with the
+// architecture suffix of official images (name:tag_amd64, name:tag_arm64),
+// optionally grouped under a name:tag manifest list.
 //
 //	in(1): string recipeFile  path to the YAML recipe file
-//	in(2): string tagOverride image tag to use instead of the one defined in the recipe (empty string keeps the recipe tag)
-//	in(3): bool   noCache     when true, passes --no-cache to the Docker build
+//	in(2): RecipeBuildOptions opts tag override, cache, variables and target platforms
 //	out:   error              non-nil if any step of the build process fails
-func BuildFromRecipe(recipeFile string, tagOverride string, noCache bool) error {
-	// Read recipe file
+func BuildFromRecipe(recipeFile string, opts RecipeBuildOptions) error {
 	common.PrintInfoMessage(fmt.Sprintf("Reading recipe from: %s", recipeFile))
-	data, err := ioutil.ReadFile(recipeFile)
+	recipe, problems := parseRecipe(recipeFile, opts.Tag, opts.Vars)
+	if err := problems.err(recipeFile); err != nil {
+		return err
+	}
+
+	// Determine context directory (relative to the recipe file by default)
+	contextDir := recipeContextDir(recipeFile, recipe)
+	common.PrintInfoMessage(fmt.Sprintf("Using context directory: %s", contextDir))
+
+	// Fail before building on missing copy sources or undefined script functions
+	checkRecipeFiles(recipe, contextDir, "", &problems)
+	if err := problems.err(recipeFile); err != nil {
+		return err
+	}
+
+	// Generate Dockerfile
+	dockerfile, err := generateDockerfile(recipe)
 	if err != nil {
-		return fmt.Errorf("failed to read recipe file: %v", err)
+		return fmt.Errorf("failed to generate Dockerfile: %v", err)
 	}
 
-	// Parse YAML
-	var recipe BuildRecipe
-	if err := yaml.Unmarshal(data, &recipe); err != nil {
-		return fmt.Errorf("failed to parse recipe: %v", err)
+	common.PrintSuccessMessage("Generated Dockerfile:")
+	fmt.Println(dockerfile)
+	fmt.Println()
+
+	// Hash every step so unchanged prefixes can be reused
+	hashes, err := recipeStepHashes(recipe, contextDir)
+	if err != nil {
+		return fmt.Errorf("failed to hash recipe steps: %v", err)
 	}
+	cache := loadRecipeCache(recipeFile)
+	defer func() {
+		if err := cache.save(recipeFile); err != nil {
+			common.PrintWarningMessage(fmt.Sprintf("Could not save the step cache %s: %v", recipeCachePath(recipeFile), err))
+		}
+	}()
 
-	// Override tag if provided
-	if tagOverride != "" {
-		recipe.Tag = tagOverride
+	if len(opts.Platforms) == 0 {
+		finalImage := fmt.Sprintf("%s:%s", recipe.Name, recipe.Tag)
+		common.PrintSuccessMessage(fmt.Sprintf("Building image: %s", finalImage))
+		if err := buildRecipeTarget(recipe, contextDir, finalImage, nil, opts, hashes, cache, false); err != nil {
+			return err
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("Successfully built image: %s", finalImage))
+		return nil
 	}
 
-	// Determine context directory
-	recipeDir := filepath.Dir(recipeFile)
-	var contextDir string
+	useBuildx := buildxAvailable()
+	var images []string
+	for _, platform := range opts.Platforms {
+		image := platformImage(recipe.Name, recipe.Tag, platform)
+		common.PrintSuccessMessage(fmt.Sprintf("Building image: %s (%s)", image, platform))
 
-	if recipe.Context == "" {
-		// Default: use recipe directory
-		contextDir = recipeDir
-		common.PrintInfoMessage(fmt.Sprintf("Using recipe directory as context: %s", contextDir))
-	} else {
-		// Context specified in recipe
-		if filepath.IsAbs(recipe.Context) {
-			contextDir = recipe.Context
-		} else {
-			// Relative to recipe file location
-			contextDir = filepath.Join(recipeDir, recipe.Context)
+		p := platform
+		if err := buildRecipeTarget(recipe, contextDir, image, &p, opts, hashes, cache, useBuildx); err != nil {
+			printEmulationHint(platform)
+			return err
 		}
-		common.PrintInfoMessage(fmt.Sprintf("Using context directory: %s", contextDir))
+		common.PrintSuccessMessage(fmt.Sprintf("Successfully built image: %s", image))
+		images = append(images, image)
 	}
 
-	// Verify context directory exists
-	if _, err := os.Stat(contextDir); os.IsNotExist(err) {
-		return fmt.Errorf("context directory does not exist: %s", contextDir)
+	if opts.Manifest {
+		list := manifestImage(recipe.Name, recipe.Tag)
+		common.PrintInfoMessage(fmt.Sprintf("Creating manifest list %s", list))
+		if err := createManifestList(list, images); err != nil {
+			return err
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("Manifest list %s groups: %s", list, strings.Join(images, ", ")))
 	}
+	return nil
+}
 
-	// Generate final image name
-	finalImage := fmt.Sprintf("%s:%s", recipe.Name, recipe.Tag)
-	common.PrintSuccessMessage(fmt.Sprintf("Building image: %s", finalImage))
+// buildRecipeTarget builds one image of a recipe. Through the engine API, the
+// leading steps unchanged since the last build are skipped by starting from
+// their checkpoint image, only the files the remaining steps copy are sent,
+// and a per-step summary is printed; buildx builds are left to BuildKit.
+//
+//	in(1): BuildRecipe recipe parsed recipe
+//	in(2): string contextDir build context directory
+//	in(3): string image reference to tag the image with
+//	in(4): *BuildPlatform platform target platform, nil for the host
+//	in(5): RecipeBuildOptions opts build options
+//	in(6): []string hashes step hashes from recipeStepHashes
+//	in(7): *recipeCache cache step cache, updated with this build
+//	in(8): bool useBuildx build with docker buildx
+//	out: error if the build fails
+func buildRecipeTarget(recipe BuildRecipe, contextDir, image string, platform *BuildPlatform, opts RecipeBuildOptions, hashes []string, cache *recipeCache, useBuildx bool) error {
+	start := time.Now()
+	tail := opts.TailLines
+	if tail <= 0 {
+		tail = defaultFailureTail
+	}
 
-	// Generate Dockerfile
-	dockerfile, err := generateDockerfile(recipe)
+	if useBuildx {
+		buildDir, _, err := prepareBuildContext(recipe, contextDir)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(buildDir)
+
+		logPath := buildLogPath(opts.LogFile, image, platform, start)
+		logFile, err := createBuildLog(logPath)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		common.PrintInfoMessage(fmt.Sprintf("Build log: %s", logPath))
+
+		lastLines := &tailBuffer{max: tail}
+		if err := buildxBuild(buildDir, image, *platform, opts, io.MultiWriter(logFile, lastLines)); err != nil {
+			printBuildFailure(&buildFailure{Message: err.Error(), Output: lastLines.lines}, tail, logPath)
+			return err
+		}
+		return nil
+	}
+
+	target := "host"
+	if platform != nil {
+		target = platform.String()
+	}
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
 	if err != nil {
-		return fmt.Errorf("failed to generate Dockerfile: %v", err)
+		return fmt.Errorf("failed to create Docker client: %v", err)
+	}
+	defer cli.Close()
+
+	skip, checkpoint := 0, ""
+	if !opts.NoCache {
+		skip, checkpoint = cache.Targets[target].reusableSteps(hashes, func(id string) bool {
+			_, err := inspectImage(ctx, cli, id)
+			return err == nil
+		})
+	}
+
+	build, origin := recipe, make([]int, len(recipe.Steps))
+	for i := range origin {
+		origin[i] = i
+	}
+	var reused []recipeStepResult
+	if skip > 0 {
+		build, origin = resumeRecipe(recipe, skip, checkpoint)
+		reused = reusedStepResults(recipe, build, skip, cache.Targets[target])
+		common.PrintInfoMessage(fmt.Sprintf("Steps 1-%d are unchanged since the last build: resuming from %s", skip, shortImageID(checkpoint)))
+	}
+
+	if skip > 0 && skip == len(recipe.Steps) {
+		if _, err := cli.ImageTag(ctx, client.ImageTagOptions{Source: checkpoint, Target: image}); err != nil {
+			return fmt.Errorf("failed to tag %s as %s: %v", shortImageID(checkpoint), image, err)
+		}
+		updateRecipeCache(cache, target, image, hashes, reused)
+		printBuildSummary(image, reused, time.Since(start))
+		return nil
+	}
+
+	buildDir, instructions, err := prepareBuildContext(build, contextDir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(buildDir)
+
+	logPath := buildLogPath(opts.LogFile, image, platform, start)
+	logFile, err := createBuildLog(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	common.PrintInfoMessage(fmt.Sprintf("Build log: %s", logPath))
+
+	tracker := newBuildTracker(instructions)
+	tracker.log = logFile
+	buildErr := buildRecipeImage(buildDir, image, platform, opts, tracker)
+	tracker.finish()
+
+	if !tracker.tracked() {
+		common.PrintInfoMessage("The engine did not report build steps (BuildKit): no per-step summary or checkpoints")
+		return buildErr
+	}
+	results := mergeStepResults(reused, tracker.stepResults(build), origin, skip)
+	updateRecipeCache(cache, target, image, hashes, results)
+	printBuildSummary(image, results, time.Since(start))
+
+	if buildErr != nil {
+		if f := tracker.failure(); f != nil {
+			printBuildFailure(f, tail, logPath)
+		}
+	}
+	return buildErr
+}
+
+// prepareBuildContext writes the Dockerfile of a recipe and the files its
+// copy steps need into a new temporary directory, removed by the caller.
+//
+//	in(1): BuildRecipe recipe recipe to render
+//	in(2): string contextDir build context directory
+//	out: string temporary build directory
+//	out: []recipeInstruction owner of each Dockerfile instruction
+//	out: error if rendering or copying fails
+func prepareBuildContext(recipe BuildRecipe, contextDir string) (string, []recipeInstruction, error) {
+	dockerfile, instructions, err := renderDockerfile(recipe)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate Dockerfile: %v", err)
 	}
 
-	// Create temporary directory for build context
 	tempDir, err := os.MkdirTemp("", "rfswift-build-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %v", err)
+		return "", nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Write Dockerfile to temp directory
 	dockerfilePath := filepath.Join(tempDir, "Dockerfile")
 	if err := ioutil.WriteFile(dockerfilePath, []byte(dockerfile), 0644); err != nil {
-		return fmt.Errorf("failed to write Dockerfile: %v", err)
+		os.RemoveAll(tempDir)
+		return "", nil, fmt.Errorf("failed to write Dockerfile: %v", err)
 	}
 
-	common.PrintSuccessMessage("Generated Dockerfile:")
-	fmt.Println(dockerfile)
-	fmt.Println()
-
-	// Copy build context files
 	if err := copyBuildContext(recipe, contextDir, tempDir); err != nil {
-		return fmt.Errorf("failed to copy build context: %v", err)
+		os.RemoveAll(tempDir)
+		return "", nil, fmt.Errorf("failed to copy build context: %v", err)
 	}
+	return tempDir, instructions, nil
+}
 
-	// Build the image
+// buildRecipeImage builds the prepared context through the engine API.
+//
+//	in(1): string contextDir directory holding the Dockerfile and context files
+//	in(2): string image reference to tag the image with
+//	in(3): *BuildPlatform platform target platform, nil for the host
+//	in(4): RecipeBuildOptions opts build options
+//	in(5): io.Writer progress receives a copy of the raw build stream (may be nil)
+//	out: error if the build fails
+func buildRecipeImage(contextDir, image string, platform *BuildPlatform, opts RecipeBuildOptions, progress io.Writer) error {
 	common.PrintInfoMessage("Starting Docker build...")
 	ctx := context.Background()
 	cli, err := NewEngineClient()
@@ -117,7 +278,7 @@ func BuildFromRecipe(recipeFile string, tagOverride string, noCache bool) error
 	defer cli.Close()
 
 	// Create tar archive of build context
-	buildContext, err := createBuildContextTar(tempDir)
+	buildContext, err := createBuildContextTar(contextDir)
 	if err != nil {
 		return fmt.Errorf("failed to create build context: %v", err)
 	}
@@ -125,14 +286,19 @@ func BuildFromRecipe(recipeFile string, tagOverride string, noCache bool) error
 
 	// Build options
 	buildOptions := client.ImageBuildOptions{
-		Tags:       []string{finalImage},
+		Tags:       []string{image},
 		Dockerfile: "Dockerfile",
 		Remove:     true,
-		NoCache:    noCache,
+		NoCache:    opts.NoCache,
+		CacheFrom:  opts.CacheFrom,
 		Labels: map[string]string{
 			"org.container.project": "rfswift",
 		},
 	}
+	if platform != nil {
+		// The engine API takes a single platform per build
+		buildOptions.Platforms = []ocispec.Platform{platform.ociPlatform()}
+	}
 
 	// Start build
 	buildResp, err := cli.ImageBuild(ctx, buildContext, buildOptions)
@@ -142,91 +308,195 @@ func BuildFromRecipe(recipeFile string, tagOverride string, noCache bool) error
 	defer buildResp.Body.Close()
 
 	// Stream build output
+	var stream io.Reader = buildResp.Body
+	if progress != nil {
+		stream = io.TeeReader(buildResp.Body, progress)
+	}
 	termFd, isTerm := term.GetFdInfo(os.Stdout)
-	if err := jsonmessage.DisplayJSONMessagesStream(buildResp.Body, os.Stdout, termFd, isTerm, nil); err != nil {
+	if err := jsonmessage.DisplayJSONMessagesStream(stream, os.Stdout, termFd, isTerm, nil); err != nil {
 		return fmt.Errorf("error during build: %v", err)
 	}
-
-	common.PrintSuccessMessage(fmt.Sprintf("Successfully built image: %s", finalImage))
 	return nil
 }
 
-// generateDockerfile produces a Dockerfile source string by iterating over the
-// steps defined in the provided BuildRecipe and rendering each step type
-// (run, copy, workdir, script, cleanup) into the appropriate Dockerfile
+// generateDockerfile produces a Dockerfile source string by rendering each
+// stage of the BuildRecipe: the named stages first (FROM ... AS <name>), then
+// the final stage with the recipe labels. Every step type (run, copy, workdir,
+// script, cleanup, arg, env, user, entrypoint) maps to the matching Dockerfile
 // instruction.
 //
 //	in(1): BuildRecipe recipe  the parsed recipe describing the image to build
 //	out:   string              the generated Dockerfile content
 //	out:   error               non-nil if the recipe cannot be rendered
 func generateDockerfile(recipe BuildRecipe) (string, error) {
+	dockerfile, _, err := renderDockerfile(recipe)
+	return dockerfile, err
+}
+
+// recipeInstruction ties a Dockerfile instruction to the recipe step that
+// produced it, so build progress can be reported per step.
+type recipeInstruction struct {
+	Stage string    // stage name, "" for the final stage
+	Index int       // step index within the stage, -1 for FROM and LABEL lines
+	Step  BuildStep // the step itself (zero for FROM and LABEL lines)
+}
+
+// renderDockerfile is generateDockerfile that also returns, for every
+// instruction of the Dockerfile in build order, the step it comes from.
+//
+//	in(1): BuildRecipe recipe the parsed recipe
+//	out: string Dockerfile content
+//	out: []recipeInstruction owner of each instruction
+//	out: error if the recipe cannot be rendered
+func renderDockerfile(recipe BuildRecipe) (string, []recipeInstruction, error) {
 	var dockerfile strings.Builder
+	var instructions []recipeInstruction
 
 	// Header
 	dockerfile.WriteString("# Generated by RF Swift Build System\n")
 	dockerfile.WriteString(fmt.Sprintf("# Recipe: %s\n\n", recipe.Name))
 
-	// Base image
+	writeSteps := func(stageName string, steps []BuildStep) error {
+		for i, step := range steps {
+			var rendered strings.Builder
+			if err := writeDockerfileStep(&rendered, step); err != nil {
+				return fmt.Errorf("%s: %v", stepLocation(stageName, i, step.Type), err)
+			}
+			dockerfile.WriteString(rendered.String())
+			for n := countInstructions(rendered.String()); n > 0; n-- {
+				instructions = append(instructions, recipeInstruction{Stage: stageName, Index: i, Step: step})
+			}
+		}
+		return nil
+	}
+
+	for _, stage := range recipe.Stages {
+		dockerfile.WriteString(fmt.Sprintf("FROM %s AS %s\n\n", stage.BaseImage, stage.Name))
+		instructions = append(instructions, recipeInstruction{Stage: stage.Name, Index: -1})
+		if err := writeSteps(stage.Name, stage.Steps); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Final stage
 	dockerfile.WriteString(fmt.Sprintf("FROM %s\n\n", recipe.BaseImage))
+	instructions = append(instructions, recipeInstruction{Index: -1})
 
 	// Labels
 	if len(recipe.Labels) > 0 {
 		for key, value := range recipe.Labels {
 			dockerfile.WriteString(fmt.Sprintf("LABEL \"%s\"=\"%s\"\n", key, value))
+			instructions = append(instructions, recipeInstruction{Index: -1})
 		}
 		dockerfile.WriteString("\n")
 	}
 
-	// Process steps
-	for _, step := range recipe.Steps {
-		switch step.Type {
-		case "run":
-			for _, cmd := range step.Commands {
-				dockerfile.WriteString(fmt.Sprintf("RUN %s\n", cmd))
-			}
-			dockerfile.WriteString("\n")
+	if err := writeSteps("", recipe.Steps); err != nil {
+		return "", nil, err
+	}
 
-		case "copy":
-			for _, item := range step.Items {
-				dockerfile.WriteString(fmt.Sprintf("COPY %s %s\n", item.Source, item.Destination))
-			}
-			dockerfile.WriteString("\n")
+	return dockerfile.String(), instructions, nil
+}
 
-		case "workdir":
-			dockerfile.WriteString(fmt.Sprintf("WORKDIR %s\n\n", step.Path))
+// countInstructions counts the Dockerfile instructions in a rendered
+// fragment: lines that are neither blank, comments nor continuations.
+func countInstructions(fragment string) int {
+	n := 0
+	for _, line := range strings.Split(fragment, "\n") {
+		if line == "" || line[0] == '#' || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		n++
+	}
+	return n
+}
 
-		case "script":
-			if step.Name != "" {
-				dockerfile.WriteString(fmt.Sprintf("# %s\n", step.Name))
-			}
-			if len(step.Functions) > 0 {
-				cmds := make([]string, len(step.Functions))
-				for i, fn := range step.Functions {
-					cmds[i] = fmt.Sprintf("%s %s", step.Script, fn)
-				}
-				dockerfile.WriteString(fmt.Sprintf("RUN %s\n\n", strings.Join(cmds, " && \\\n\t")))
-			}
+// writeDockerfileStep renders a single recipe step.
+//
+//	in(1): *strings.Builder dockerfile  output being generated
+//	in(2): BuildStep step               step to render
+//	out:   error                        non-nil if the step type is unknown
+func writeDockerfileStep(dockerfile *strings.Builder, step BuildStep) error {
+	switch step.Type {
+	case "run":
+		for _, cmd := range step.Commands {
+			dockerfile.WriteString(fmt.Sprintf("RUN %s\n", cmd))
+		}
+		dockerfile.WriteString("\n")
 
-		case "cleanup":
-			cmds := []string{}
-			for _, path := range step.Paths {
-				cmds = append(cmds, fmt.Sprintf("rm -rf %s", path))
-			}
-			if step.AptClean {
-				cmds = append(cmds, "apt-fast clean", "rm -rf /var/lib/apt/lists/*")
+	case "copy":
+		from := ""
+		if step.From != "" {
+			from = fmt.Sprintf("--from=%s ", step.From)
+		}
+		for _, item := range step.Items {
+			dockerfile.WriteString(fmt.Sprintf("COPY %s%s %s\n", from, item.Source, item.Destination))
+		}
+		dockerfile.WriteString("\n")
+
+	case "workdir":
+		dockerfile.WriteString(fmt.Sprintf("WORKDIR %s\n\n", step.Path))
+
+	case "script":
+		if step.Name != "" {
+			dockerfile.WriteString(fmt.Sprintf("# %s\n", step.Name))
+		}
+		if len(step.Functions) > 0 {
+			cmds := make([]string, len(step.Functions))
+			for i, fn := range step.Functions {
+				cmds[i] = fmt.Sprintf("%s %s", step.Script, fn)
 			}
-			if len(cmds) > 0 {
-				dockerfile.WriteString(fmt.Sprintf("RUN %s\n\n", strings.Join(cmds, " && \\\n\t")))
+			dockerfile.WriteString(fmt.Sprintf("RUN %s\n\n", strings.Join(cmds, " && \\\n\t")))
+		}
+
+	case "cleanup":
+		cmds := []string{}
+		for _, path := range step.Paths {
+			cmds = append(cmds, fmt.Sprintf("rm -rf %s", path))
+		}
+		if step.AptClean {
+			cmds = append(cmds, "apt-fast clean", "rm -rf /var/lib/apt/lists/*")
+		}
+		if len(cmds) > 0 {
+			dockerfile.WriteString(fmt.Sprintf("RUN %s\n\n", strings.Join(cmds, " && \\\n\t")))
+		}
+
+	case "arg":
+		for _, key := range sortedKeys(step.Values) {
+			if value := step.Values[key]; value != "" {
+				dockerfile.WriteString(fmt.Sprintf("ARG %s=%q\n", key, value))
+			} else {
+				dockerfile.WriteString(fmt.Sprintf("ARG %s\n", key))
 			}
 		}
-	}
+		dockerfile.WriteString("\n")
+
+	case "env":
+		for _, key := range sortedKeys(step.Values) {
+			dockerfile.WriteString(fmt.Sprintf("ENV %s=%q\n", key, step.Values[key]))
+		}
+		dockerfile.WriteString("\n")
 
-	return dockerfile.String(), nil
+	case "user":
+		dockerfile.WriteString(fmt.Sprintf("USER %s\n\n", step.User))
+
+	case "entrypoint":
+		exec, err := json.Marshal(step.Command)
+		if err != nil {
+			return err
+		}
+		dockerfile.WriteString(fmt.Sprintf("ENTRYPOINT %s\n\n", exec))
+
+	default:
+		return fmt.Errorf("unknown step type %q", step.Type)
+	}
+	return nil
 }
 
 // copyBuildContext copies every file or directory referenced by "copy" steps in
 // the recipe from sourceDir into destDir, preserving the relative source paths
-// so the generated Dockerfile COPY instructions resolve correctly.
+// so the generated Dockerfile COPY instructions resolve correctly. Copies from
+// another stage (from: <stage>) do not read the context and are skipped.
 //
 //	in(1): BuildRecipe recipe  the parsed recipe whose copy steps are inspected
 //	in(2): string      sourceDir  root directory that contains the source files
@@ -236,8 +506,12 @@ func copyBuildContext(recipe BuildRecipe, sourceDir, destDir string) error {
 	// Find all files that need to be copied
 	filesToCopy := make(map[string]bool)
 
-	for _, step := range recipe.Steps {
-		if step.Type == "copy" {
+	steps := append([]BuildStep{}, recipe.Steps...)
+	for _, stage := range recipe.Stages {
+		steps = append(steps, stage.Steps...)
+	}
+	for _, step := range steps {
+		if step.Type == "copy" && step.From == "" {
 			for _, item := range step.Items {
 				filesToCopy[item.Source] = true
 			}
@@ -0,0 +1,651 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Incremental recipe builds: per-step hashes, checkpoints and build summary
+ */
+
+package dock
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// recipeCacheSuffix ends the name of the step cache stored next to a recipe.
+const recipeCacheSuffix = ".rfswift-cache.json"
+
+// Step statuses reported in the build summary.
+const (
+	stepStatusReused = "reused"  // skipped: resumed from the checkpoint image of an earlier build
+	stepStatusCached = "cached"  // run by the engine from its layer cache
+	stepStatusBuilt  = "built"   // actually executed
+	stepStatusEmpty  = "empty"   // renders no instruction
+	stepStatusFailed = "failed"  // the build stopped on it
+	stepStatusNotRun = "not run" // not reached (failed build)
+)
+
+// recipeCache is the step cache of one recipe, keyed by build target ("host"
+// or a platform such as "linux/arm64").
+type recipeCache struct {
+	Targets map[string]*recipeCacheTarget `json:"targets"`
+}
+
+// recipeCacheTarget records the last build of a recipe for one target.
+type recipeCacheTarget struct {
+	Image   string            `json:"image"`
+	Updated time.Time         `json:"updated"`
+	Steps   []recipeCacheStep `json:"steps"`
+}
+
+// recipeCacheStep is one final-stage step: the hash of the recipe up to and
+// including it, and the image the build produced after it.
+type recipeCacheStep struct {
+	Hash       string `json:"hash"`
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+// recipeStepResult is the outcome of one recipe step in a build.
+type recipeStepResult struct {
+	Stage      string
+	Index      int
+	Step       BuildStep
+	Status     string
+	Duration   time.Duration
+	Checkpoint string
+}
+
+// recipeCachePath returns the cache file stored alongside a recipe
+// ("recipes/sdr.yaml" -> "recipes/.sdr.rfswift-cache.json").
+func recipeCachePath(recipeFile string) string {
+	base := filepath.Base(recipeFile)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return filepath.Join(filepath.Dir(recipeFile), "."+base+recipeCacheSuffix)
+}
+
+// loadRecipeCache reads the step cache of a recipe; a missing or unreadable
+// cache is empty.
+func loadRecipeCache(recipeFile string) *recipeCache {
+	cache := &recipeCache{}
+	if data, err := os.ReadFile(recipeCachePath(recipeFile)); err == nil {
+		_ = json.Unmarshal(data, cache)
+	}
+	if cache.Targets == nil {
+		cache.Targets = make(map[string]*recipeCacheTarget)
+	}
+	return cache
+}
+
+// save writes the step cache next to the recipe.
+func (c *recipeCache) save(recipeFile string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(recipeCachePath(recipeFile), append(data, '\n'), 0644)
+}
+
+// recipeStepHashes returns a chained content hash for every final-stage step:
+// each hash covers the base image, labels and builder stages, every earlier
+// step, the step definition and the content of the context files it copies.
+// A step keeps its hash as long as nothing before it changes.
+//
+//	in(1): BuildRecipe recipe parsed recipe
+//	in(2): string contextDir build context directory
+//	out: []string one hash per final-stage step
+//	out: error if a copy source cannot be read
+func recipeStepHashes(recipe BuildRecipe, contextDir string) ([]string, error) {
+	h := sha256.New()
+	header, err := json.Marshal(struct {
+		BaseImage string
+		Labels    map[string]string
+	}{recipe.BaseImage, recipe.Labels})
+	if err != nil {
+		return nil, err
+	}
+	h.Write(header)
+	for _, stage := range recipe.Stages {
+		fmt.Fprintf(h, "\x00stage %s %s", stage.Name, stage.BaseImage)
+		for _, step := range stage.Steps {
+			if err := hashStep(h, step, contextDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+	prev := h.Sum(nil)
+
+	hashes := make([]string, len(recipe.Steps))
+	for i, step := range recipe.Steps {
+		h := sha256.New()
+		h.Write(prev)
+		if err := hashStep(h, step, contextDir); err != nil {
+			return nil, err
+		}
+		prev = h.Sum(nil)
+		hashes[i] = hex.EncodeToString(prev)
+	}
+	return hashes, nil
+}
+
+// hashStep writes a step definition and the context files it copies to h.
+func hashStep(h hash.Hash, step BuildStep, contextDir string) error {
+	def, err := json.Marshal(step)
+	if err != nil {
+		return err
+	}
+	h.Write([]byte{0})
+	h.Write(def)
+
+	if step.Type != "copy" || step.From != "" {
+		return nil
+	}
+	for _, item := range step.Items {
+		matches, _ := filepath.Glob(filepath.Join(contextDir, filepath.FromSlash(item.Source)))
+		for _, match := range matches {
+			if err := hashPath(h, contextDir, match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hashPath writes the names, modes and contents of the files under root to h.
+func hashPath(h hash.Hash, contextDir, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, recipeCacheSuffix) {
+			return nil
+		}
+		rel, _ := filepath.Rel(contextDir, path)
+		fmt.Fprintf(h, "\x00%s %o", filepath.ToSlash(rel), info.Mode())
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+// reusableSteps returns how many leading final-stage steps are unchanged since
+// the last build of this target and still have their checkpoint image.
+//
+//	in(1): []string hashes current step hashes
+//	in(2): func(string) bool exists reports whether a checkpoint image is present
+//	out: int number of steps to skip
+//	out: string checkpoint image to resume from ("" when nothing is reused)
+func (t *recipeCacheTarget) reusableSteps(hashes []string, exists func(string) bool) (int, string) {
+	if t == nil {
+		return 0, ""
+	}
+	same := 0
+	for same < len(hashes) && same < len(t.Steps) && t.Steps[same].Hash == hashes[same] {
+		same++
+	}
+	for n := same; n > 0; n-- {
+		if checkpoint := t.Steps[n-1].Checkpoint; checkpoint != "" && exists(checkpoint) {
+			return n, checkpoint
+		}
+	}
+	return 0, ""
+}
+
+// resumeRecipe returns the recipe that builds the remaining steps on top of a
+// checkpoint image. ARG declarations of the skipped steps are kept since they
+// do not survive in the image; builder stages are kept only when a remaining
+// step copies from one.
+//
+//	in(1): BuildRecipe recipe full recipe
+//	in(2): int skip number of final-stage steps covered by the checkpoint
+//	in(3): string checkpoint image ID to start from
+//	out: BuildRecipe resumed recipe
+//	out: []int index in the full recipe of every resumed step
+func resumeRecipe(recipe BuildRecipe, skip int, checkpoint string) (BuildRecipe, []int) {
+	resumed := recipe
+	resumed.BaseImage = checkpoint
+	resumed.Labels = nil
+	resumed.Steps = nil
+
+	var origin []int
+	for i, step := range recipe.Steps[:skip] {
+		if step.Type == "arg" {
+			resumed.Steps = append(resumed.Steps, step)
+			origin = append(origin, i)
+		}
+	}
+	needsStages := false
+	for i, step := range recipe.Steps[skip:] {
+		resumed.Steps = append(resumed.Steps, step)
+		origin = append(origin, skip+i)
+		if step.Type == "copy" && step.From != "" {
+			needsStages = true
+		}
+	}
+	if !needsStages {
+		resumed.Stages = nil
+	}
+	return resumed, origin
+}
+
+// reusedStepResults reports the steps skipped by resuming from a checkpoint:
+// the first skip final-stage steps, and the builder stages when the resumed
+// recipe no longer needs them.
+//
+//	in(1): BuildRecipe recipe full recipe
+//	in(2): BuildRecipe resumed recipe returned by resumeRecipe
+//	in(3): int skip number of skipped final-stage steps
+//	in(4): *recipeCacheTarget last build of the target, holding the checkpoints
+//	out: []recipeStepResult results with the reused status
+func reusedStepResults(recipe, resumed BuildRecipe, skip int, last *recipeCacheTarget) []recipeStepResult {
+	var results []recipeStepResult
+	if len(resumed.Stages) == 0 {
+		for _, stage := range recipe.Stages {
+			for i, step := range stage.Steps {
+				results = append(results, recipeStepResult{Stage: stage.Name, Index: i, Step: step, Status: stepStatusReused})
+			}
+		}
+	}
+	for i, step := range recipe.Steps[:skip] {
+		res := recipeStepResult{Index: i, Step: step, Status: stepStatusReused}
+		if last != nil && i < len(last.Steps) {
+			res.Checkpoint = last.Steps[i].Checkpoint
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// mergeStepResults combines the reused steps with the results of the build of
+// the resumed recipe, mapping its final-stage steps back to their index in the
+// full recipe. Stage steps come first, then final-stage steps in order.
+//
+//	in(1): []recipeStepResult reused results of reusedStepResults
+//	in(2): []recipeStepResult built results of the resumed build
+//	in(3): []int origin full-recipe index of every resumed step
+//	in(4): int skip number of reused final-stage steps
+//	out: []recipeStepResult all steps of the recipe
+func mergeStepResults(reused, built []recipeStepResult, origin []int, skip int) []recipeStepResult {
+	var stages, final []recipeStepResult
+	for _, res := range reused {
+		if res.Stage != "" {
+			stages = append(stages, res)
+		} else {
+			final = append(final, res)
+		}
+	}
+	for _, res := range built {
+		if res.Stage != "" {
+			stages = append(stages, res)
+			continue
+		}
+		// ARG steps re-declared from the skipped prefix are already reported
+		if res.Index >= len(origin) || origin[res.Index] < skip {
+			continue
+		}
+		res.Index = origin[res.Index]
+		final = append(final, res)
+	}
+	return append(stages, final...)
+}
+
+var (
+	// buildStepLine matches "Step 3/12 : RUN ..." (Docker) and
+	// "[1/2] STEP 3/5: RUN ..." (Podman, numbered per stage).
+	buildStepLine = regexp.MustCompile(`^(?:\[(\d+)/\d+\] )?(?:Step|STEP) (\d+)/\d+ ?:`)
+
+	// buildCacheLine matches " ---> Using cache" and "--> Using cache <id>".
+	buildCacheLine = regexp.MustCompile(`^\s*-{2,3}> Using cache(?: ([0-9a-f]{12,64}))?`)
+
+	// buildImageLine matches the image produced by an instruction: " ---> 1a2b3c4d5e6f".
+	buildImageLine = regexp.MustCompile(`^\s*-{2,3}> ([0-9a-f]{12,64})\s*$`)
+)
+
+// instructionProgress is what the build stream told about one instruction.
+type instructionProgress struct {
+	Seen     bool
+	Cached   bool
+	Failed   bool
+	ImageID  string
+	Command  string   // instruction as echoed by the engine ("RUN ./entrypoint.sh ...")
+	Output   []string // last output lines of the instruction
+	Started  time.Time
+	Duration time.Duration
+}
+
+// maxInstructionOutput bounds the output lines kept per instruction; the
+// build log has them all.
+const maxInstructionOutput = 500
+
+// buildTracker follows the JSON message stream of a classic (non-BuildKit)
+// build and records, for each Dockerfile instruction, whether it ran, came
+// from the cache, the image it produced, its output and how long it took.
+// With a log writer set, every output line is also written there, prefixed
+// with its time and recipe step.
+type buildTracker struct {
+	instructions   []recipeInstruction
+	progress       []instructionProgress
+	stageStarts    []int // index of the FROM instruction of each stage
+	current        int
+	failureMessage string // error message that ended the build
+	pending        []byte // incomplete JSON message
+	text           string // incomplete stream line
+	log            io.Writer
+	now            func() time.Time
+}
+
+// newBuildTracker returns a tracker for a Dockerfile rendered by renderDockerfile.
+func newBuildTracker(instructions []recipeInstruction) *buildTracker {
+	t := &buildTracker{
+		instructions: instructions,
+		progress:     make([]instructionProgress, len(instructions)),
+		current:      -1,
+		now:          time.Now,
+	}
+	for i, instr := range instructions {
+		if i == 0 || instr.Stage != instructions[i-1].Stage {
+			t.stageStarts = append(t.stageStarts, i)
+		}
+	}
+	return t
+}
+
+// Write consumes raw bytes of the build response stream.
+func (t *buildTracker) Write(p []byte) (int, error) {
+	t.pending = append(t.pending, p...)
+	for {
+		i := bytes.IndexByte(t.pending, '\n')
+		if i < 0 {
+			break
+		}
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if json.Unmarshal(bytes.TrimSpace(t.pending[:i]), &msg) == nil {
+			if msg.Stream != "" {
+				t.consumeText(msg.Stream)
+			}
+			if msg.Error != "" {
+				t.failureMessage = msg.Error
+				t.logLine("ERROR: " + msg.Error)
+				if t.current >= 0 {
+					t.progress[t.current].Failed = true
+				}
+			}
+		}
+		t.pending = t.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// consumeText processes the text carried by stream messages line by line.
+func (t *buildTracker) consumeText(s string) {
+	t.text += s
+	for {
+		i := strings.IndexByte(t.text, '\n')
+		if i < 0 {
+			return
+		}
+		t.consumeLine(t.text[:i])
+		t.text = t.text[i+1:]
+	}
+}
+
+func (t *buildTracker) consumeLine(line string) {
+	if m := buildStepLine.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		index := n - 1
+		if m[1] != "" {
+			stage, _ := strconv.Atoi(m[1])
+			if stage < 1 || stage > len(t.stageStarts) {
+				return
+			}
+			index += t.stageStarts[stage-1]
+		}
+		if index < 0 || index >= len(t.progress) {
+			return
+		}
+		t.finish()
+		t.current = index
+		t.progress[index].Seen = true
+		t.progress[index].Started = t.now()
+		t.progress[index].Command = strings.TrimSpace(line[strings.Index(line, ":")+1:])
+		t.logLine(line)
+		return
+	}
+	t.logLine(line)
+	if t.current < 0 {
+		return
+	}
+	if m := buildCacheLine.FindStringSubmatch(line); m != nil {
+		t.progress[t.current].Cached = true
+		if m[1] != "" {
+			t.progress[t.current].ImageID = m[1]
+		}
+		return
+	}
+	if m := buildImageLine.FindStringSubmatch(line); m != nil {
+		t.progress[t.current].ImageID = m[1]
+		return
+	}
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "---> Running in ") {
+		return
+	}
+	out := append(t.progress[t.current].Output, line)
+	if len(out) > maxInstructionOutput {
+		out = out[len(out)-maxInstructionOutput:]
+	}
+	t.progress[t.current].Output = out
+}
+
+// logLine writes a stream line to the build log with its time and the recipe
+// step of the running instruction.
+func (t *buildTracker) logLine(line string) {
+	if t.log == nil {
+		return
+	}
+	where := "-"
+	if t.current >= 0 {
+		instr := t.instructions[t.current]
+		if instr.Index >= 0 {
+			where = stepLocation(instr.Stage, instr.Index, instr.Step.Type)
+		} else if instr.Stage != "" {
+			where = fmt.Sprintf("stage %q", instr.Stage)
+		}
+	}
+	fmt.Fprintf(t.log, "%s [%s] %s\n", t.now().UTC().Format(time.RFC3339), where, line)
+}
+
+// finish closes the timing of the running instruction; call it once the
+// stream ends.
+func (t *buildTracker) finish() {
+	if t.current >= 0 && t.progress[t.current].Duration == 0 {
+		t.progress[t.current].Duration = t.now().Sub(t.progress[t.current].Started)
+	}
+}
+
+// tracked reports whether the stream carried step markers; BuildKit streams
+// do not, and then no per-step outcome is known.
+func (t *buildTracker) tracked() bool {
+	return t.current >= 0
+}
+
+// stepResults aggregates instruction progress per recipe step.
+//
+//	in(1): BuildRecipe recipe the recipe that was rendered
+//	out: []recipeStepResult stage steps first, then final-stage steps
+func (t *buildTracker) stepResults(recipe BuildRecipe) []recipeStepResult {
+	var results []recipeStepResult
+	collect := func(stage string, steps []BuildStep) {
+		// Steps that render nothing share the image of the step before them
+		checkpoint := ""
+		for j, instr := range t.instructions {
+			if instr.Stage == stage && instr.Index == -1 && t.progress[j].ImageID != "" {
+				checkpoint = t.progress[j].ImageID
+			}
+		}
+		stopped := false
+
+		for i, step := range steps {
+			res := recipeStepResult{Stage: stage, Index: i, Step: step, Status: stepStatusEmpty}
+			count, seen, cached, failed := 0, 0, 0, 0
+			image := ""
+			for j, instr := range t.instructions {
+				if instr.Stage != stage || instr.Index != i {
+					continue
+				}
+				p := t.progress[j]
+				count++
+				if p.Seen {
+					seen++
+					res.Duration += p.Duration
+				}
+				if p.Cached {
+					cached++
+				}
+				if p.Failed {
+					failed++
+				}
+				if p.ImageID != "" {
+					image = p.ImageID
+				}
+			}
+			switch {
+			case count == 0 && stopped:
+				res.Status = stepStatusNotRun
+			case count == 0:
+			case failed > 0:
+				res.Status = stepStatusFailed
+			case seen < count:
+				res.Status = stepStatusNotRun
+			case cached == count:
+				res.Status = stepStatusCached
+			default:
+				res.Status = stepStatusBuilt
+			}
+
+			if res.Status == stepStatusFailed || res.Status == stepStatusNotRun {
+				stopped = true
+			}
+			if !stopped {
+				if image != "" {
+					checkpoint = image
+				}
+				res.Checkpoint = checkpoint
+			}
+			results = append(results, res)
+		}
+	}
+	for _, stage := range recipe.Stages {
+		collect(stage.Name, stage.Steps)
+	}
+	collect("", recipe.Steps)
+	return results
+}
+
+// updateRecipeCache records the hashes and checkpoints of a build for target.
+// Steps that did not complete end the recorded prefix.
+//
+//	in(1): *recipeCache cache cache to update
+//	in(2): string target "host" or platform
+//	in(3): string image built image reference
+//	in(4): []string hashes current step hashes
+//	in(5): []recipeStepResult results per-step outcome (final stage, full recipe indices)
+func updateRecipeCache(cache *recipeCache, target, image string, hashes []string, results []recipeStepResult) {
+	entry := &recipeCacheTarget{Image: image, Updated: time.Now().UTC(), Steps: []recipeCacheStep{}}
+	for _, res := range results {
+		if res.Stage != "" {
+			continue
+		}
+		if res.Status == stepStatusNotRun || res.Status == stepStatusFailed || res.Index >= len(hashes) {
+			break
+		}
+		entry.Steps = append(entry.Steps, recipeCacheStep{Hash: hashes[res.Index], Checkpoint: res.Checkpoint})
+	}
+	cache.Targets[target] = entry
+}
+
+// printBuildSummary shows the outcome and duration of every recipe step.
+//
+//	in(1): string image built image reference
+//	in(2): []recipeStepResult results per-step outcome
+//	in(3): time.Duration total wall time of the build
+func printBuildSummary(image string, results []recipeStepResult, total time.Duration) {
+	if len(results) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	rows := make([][]string, len(results))
+	for i, res := range results {
+		counts[res.Status]++
+		duration := "-"
+		if res.Status == stepStatusCached || res.Status == stepStatusBuilt || res.Status == stepStatusFailed {
+			duration = res.Duration.Round(100 * time.Millisecond).String()
+		}
+		rows[i] = []string{stepLocation(res.Stage, res.Index, res.Step.Type), stepSummary(res.Step), res.Status, duration}
+	}
+
+	tui.RenderTable(tui.TableConfig{
+		Title:   fmt.Sprintf("🧱 Build summary: %s", image),
+		Headers: []string{"Step", "Details", "Status", "Time"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			if col != 2 {
+				return lipgloss.Color("")
+			}
+			switch content {
+			case stepStatusReused, stepStatusCached:
+				return tui.ColorSuccess
+			case stepStatusBuilt:
+				return tui.ColorPrimary
+			case stepStatusFailed, stepStatusNotRun:
+				return tui.ColorDanger
+			}
+			return tui.ColorMuted
+		},
+	})
+	common.PrintInfoMessage(fmt.Sprintf("%d reused, %d cached, %d built, %d failed, %d not run in %s",
+		counts[stepStatusReused], counts[stepStatusCached], counts[stepStatusBuilt], counts[stepStatusFailed], counts[stepStatusNotRun],
+		total.Round(100*time.Millisecond)))
+}
+
+// stepSummary returns a short description of a step for tables.
+func stepSummary(step BuildStep) string {
+	detail := step.Name
+	switch {
+	case detail != "":
+	case step.Type == "script":
+		detail = strings.Join(step.Functions, ", ")
+	case step.Type == "run" && len(step.Commands) > 0:
+		detail = step.Commands[0]
+	case step.Type == "copy" && len(step.Items) > 0:
+		detail = step.Items[0].Source
+	case step.Type == "workdir":
+		detail = step.Path
+	}
+	if len(detail) > 48 {
+		detail = detail[:45] + "..."
+	}
+	return detail
+}
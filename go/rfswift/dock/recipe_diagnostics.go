@@ -0,0 +1,219 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Recipe build logs and failure diagnostics
+ */
+
+package dock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	common "penthertz/rfswift/common"
+)
+
+// defaultFailureTail is the number of output lines shown for a failed step.
+const defaultFailureTail = 30
+
+// buildFailure describes where a recipe build stopped.
+type buildFailure struct {
+	Located     bool // false when the failing step is unknown (BuildKit)
+	Instruction recipeInstruction
+	Command     string
+	Function    string   // script function that most likely failed ("" if unknown)
+	Candidates  []string // functions that may have failed when it cannot be told
+	Message     string   // engine error message
+	Output      []string // last output lines of the failed instruction
+}
+
+// buildLogsDir returns the directory holding recipe build logs.
+func buildLogsDir() string {
+	return filepath.Join(rfswiftConfigDir(), "build-logs")
+}
+
+// buildLogPath returns the log file of one build target: the --log path
+// (suffixed with the architecture for multi-platform builds), or a
+// timestamped file in the build logs directory.
+//
+//	in(1): string logFile path requested with --log ("" for the default)
+//	in(2): string image reference being built
+//	in(3): *BuildPlatform platform target platform, nil for the host
+//	in(4): time.Time now build start time
+//	out: string log file path
+func buildLogPath(logFile, image string, platform *BuildPlatform, now time.Time) string {
+	if logFile != "" {
+		if platform == nil {
+			return logFile
+		}
+		ext := filepath.Ext(logFile)
+		return strings.TrimSuffix(logFile, ext) + platform.TagSuffix() + ext
+	}
+	name := strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)
+	return filepath.Join(buildLogsDir(), fmt.Sprintf("%s_%s.log", name, now.Format("20060102-150405")))
+}
+
+// createBuildLog creates the log file of a build and its directory.
+//
+//	in(1): string path log file path
+//	out: *os.File opened log file
+//	out: error if the file cannot be created
+func createBuildLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create build log directory: %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build log: %v", err)
+	}
+	return f, nil
+}
+
+// failure returns where the build stopped, or nil if no instruction failed.
+func (t *buildTracker) failure() *buildFailure {
+	index := -1
+	for i, p := range t.progress {
+		if p.Failed {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		// The error came outside of an instruction (e.g. a missing base image)
+		if t.failureMessage == "" || t.current < 0 {
+			return nil
+		}
+		index = t.current
+	}
+
+	p := t.progress[index]
+	f := &buildFailure{
+		Located:     true,
+		Instruction: t.instructions[index],
+		Command:     p.Command,
+		Message:     t.failureMessage,
+		Output:      p.Output,
+	}
+	if step := f.Instruction.Step; step.Type == "script" {
+		f.Function, f.Candidates = failedFunction(step.Functions, p.Output)
+	}
+	return f
+}
+
+// failedFunction tells which function of a script step failed. The functions
+// of a step run in order in one RUN instruction, so the last one named in the
+// output is the one that was running; with no mention, every function is a
+// candidate.
+//
+//	in(1): []string functions functions of the script step, in order
+//	in(2): []string output output lines of the instruction
+//	out: string function that failed ("" when it cannot be told)
+//	out: []string candidates when it cannot be told
+func failedFunction(functions []string, output []string) (string, []string) {
+	if len(functions) == 1 {
+		return functions[0], nil
+	}
+
+	last, lastLine := "", -1
+	for _, fn := range functions {
+		pattern := regexp.MustCompile(`(^|[^\w-])` + regexp.QuoteMeta(fn) + `($|[^\w-])`)
+		for i := len(output) - 1; i > lastLine; i-- {
+			if pattern.MatchString(output[i]) {
+				last, lastLine = fn, i
+				break
+			}
+		}
+	}
+	if last != "" {
+		return last, nil
+	}
+	return "", functions
+}
+
+// printBuildFailure prints a short report of a failed build: the recipe step
+// and its source line, the function that failed, the engine error and the
+// last output lines of the step.
+//
+//	in(1): *buildFailure f where the build stopped
+//	in(2): int tail number of output lines to show
+//	in(3): string logPath full build log
+func printBuildFailure(f *buildFailure, tail int, logPath string) {
+	step := f.Instruction.Step
+	where := "base image"
+	if !f.Located {
+		where = "an unknown step (BuildKit does not report recipe steps)"
+	} else if f.Instruction.Index >= 0 {
+		where = stepLocation(f.Instruction.Stage, f.Instruction.Index, step.Type)
+		if step.Name != "" {
+			where += fmt.Sprintf(" %q", step.Name)
+		}
+	} else if f.Instruction.Stage != "" {
+		where = fmt.Sprintf("stage %q base image", f.Instruction.Stage)
+	}
+
+	common.PrintErrorMessage(fmt.Errorf("build failed at %s", where))
+	if step.pos.file != "" {
+		location := step.pos.file
+		if step.pos.line > 0 {
+			location = fmt.Sprintf("%s:%d", step.pos.file, step.pos.line)
+		}
+		fmt.Printf("  Recipe:      %s\n", location)
+	}
+	if f.Function != "" {
+		fmt.Printf("  Function:    %s\n", f.Function)
+	} else if len(f.Candidates) > 0 {
+		fmt.Printf("  Function:    one of %s\n", strings.Join(f.Candidates, ", "))
+	}
+	if f.Command != "" {
+		fmt.Printf("  Instruction: %s\n", strings.ReplaceAll(f.Command, "\n", " "))
+	}
+	if f.Message != "" {
+		fmt.Printf("  Error:       %s\n", strings.TrimSpace(f.Message))
+	}
+
+	output := f.Output
+	if tail > 0 && len(output) > tail {
+		output = output[len(output)-tail:]
+	}
+	if len(output) > 0 {
+		of := "the step"
+		if !f.Located {
+			of = "the build output"
+		}
+		fmt.Printf("\n  Last %d line(s) of %s:\n", len(output), of)
+		for _, line := range output {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	if logPath != "" {
+		fmt.Printf("\n  Full log: %s\n", logPath)
+	}
+}
+
+// tailBuffer keeps the last lines written to it; it stands in for the step
+// output when BuildKit (buildx) runs the build.
+type tailBuffer struct {
+	lines   []string
+	partial string
+	max     int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.partial += string(p)
+	for {
+		i := strings.IndexByte(b.partial, '\n')
+		if i < 0 {
+			break
+		}
+		b.lines = append(b.lines, b.partial[:i])
+		if len(b.lines) > b.max {
+			b.lines = b.lines[len(b.lines)-b.max:]
+		}
+		b.partial = b.partial[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,221 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Multi-architecture recipe builds and manifest lists
+ */
+
+package dock
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	common "penthertz/rfswift/common"
+)
+
+// RecipeBuildOptions holds the options of BuildFromRecipe.
+type RecipeBuildOptions struct {
+	Tag       string            // overrides the recipe tag when not empty
+	NoCache   bool              // passes --no-cache to the build
+	Vars      map[string]string // values for ${VAR} references, overriding the recipe's vars block
+	CacheFrom []string          // images to use as layer cache sources
+	Platforms []BuildPlatform   // target platforms; empty builds for the host only
+	Manifest  bool              // assemble a local manifest list from the per-platform images
+	LogFile   string            // build log path; empty for a timestamped file in the build logs directory
+	TailLines int               // output lines shown for a failed step; 0 for the default
+}
+
+// BuildPlatform is one target of a multi-architecture build.
+type BuildPlatform struct {
+	OS      string
+	Arch    string
+	Variant string
+}
+
+// String returns the platform in os/arch[/variant] form.
+func (p BuildPlatform) String() string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Arch, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Arch)
+}
+
+// TagSuffix returns the architecture suffix official images carry ("_arm64").
+func (p BuildPlatform) TagSuffix() string {
+	return "_" + p.Arch
+}
+
+func (p BuildPlatform) ociPlatform() ocispec.Platform {
+	return ocispec.Platform{OS: p.OS, Architecture: p.Arch, Variant: p.Variant}
+}
+
+// platformArchAliases maps architecture spellings to the names used in tag suffixes.
+var platformArchAliases = map[string]string{
+	"amd64":   "amd64",
+	"x86_64":  "amd64",
+	"arm64":   "arm64",
+	"aarch64": "arm64",
+	"riscv64": "riscv64",
+	"arm":     "arm",
+	"armhf":   "arm",
+}
+
+// ParseBuildPlatforms parses a comma-separated platform list such as
+// "linux/amd64,linux/arm64". Only Linux platforms with an architecture that
+// has a tag suffix (amd64, arm64, riscv64, arm) are accepted; "arm" defaults
+// to the v7 variant.
+//
+//	in(1): string spec comma-separated platforms
+//	out: []BuildPlatform platforms in the given order, without duplicates
+//	out: error on a malformed or unsupported platform
+func ParseBuildPlatforms(spec string) ([]BuildPlatform, error) {
+	var platforms []BuildPlatform
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(strings.ToLower(item), "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid platform %q: expected os/arch[/variant]", item)
+		}
+		if parts[0] != "linux" {
+			return nil, fmt.Errorf("unsupported platform %q: only linux images can be built", item)
+		}
+		arch, ok := platformArchAliases[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported platform %q: architecture must be one of amd64, arm64, riscv64, arm", item)
+		}
+
+		p := BuildPlatform{OS: "linux", Arch: arch}
+		if len(parts) == 3 {
+			p.Variant = parts[2]
+		} else if arch == "arm" {
+			p.Variant = "v7"
+		}
+		// arm/v6 and arm/v7 would share the "_arm" suffix
+		if seen[p.TagSuffix()] {
+			continue
+		}
+		seen[p.TagSuffix()] = true
+		platforms = append(platforms, p)
+	}
+
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("no platform given")
+	}
+	return platforms, nil
+}
+
+// platformImage returns the per-platform reference of name:tag, following the
+// official naming: the architecture suffix replaces any suffix already there.
+//
+//	in(1): string name image repository
+//	in(2): string tag recipe tag
+//	in(3): BuildPlatform p target platform
+//	out: string image reference such as "myrepo:sdr_light_arm64"
+func platformImage(name, tag string, p BuildPlatform) string {
+	return fmt.Sprintf("%s:%s%s", name, removeArchitectureSuffix(tag), p.TagSuffix())
+}
+
+// manifestImage returns the reference of the manifest list grouping the
+// per-platform images of name:tag.
+func manifestImage(name, tag string) string {
+	return fmt.Sprintf("%s:%s", name, removeArchitectureSuffix(tag))
+}
+
+// buildxAvailable reports whether builds can go through docker buildx, which
+// handles foreign platforms with BuildKit and QEMU emulation.
+func buildxAvailable() bool {
+	if GetEngine().Type() != EngineDocker || !binaryExists("docker") {
+		return false
+	}
+	return exec.Command("docker", "buildx", "version").Run() == nil
+}
+
+// buildxBuild builds the prepared context for one platform with docker buildx
+// and loads the result into the local image store.
+//
+//	in(1): string contextDir directory holding the Dockerfile and context files
+//	in(2): string image reference to tag the image with
+//	in(3): BuildPlatform p target platform
+//	in(4): RecipeBuildOptions opts build options
+//	in(5): io.Writer output receives a copy of the build output
+//	out: error if the build fails
+func buildxBuild(contextDir, image string, p BuildPlatform, opts RecipeBuildOptions, output io.Writer) error {
+	args := []string{"buildx", "build",
+		"--platform", p.String(),
+		"--load",
+		"--progress", "plain",
+		"--label", "org.container.project=rfswift",
+		"-t", image,
+		"-f", "Dockerfile",
+	}
+	if opts.NoCache {
+		args = append(args, "--no-cache")
+	}
+	for _, ref := range opts.CacheFrom {
+		args = append(args, "--cache-from", ref)
+	}
+	args = append(args, contextDir)
+
+	cmd := exec.Command("docker", args...)
+	cmd.Dir = contextDir
+	cmd.Stdout = io.MultiWriter(os.Stdout, output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker buildx build for %s failed: %v", p, err)
+	}
+	return nil
+}
+
+// createManifestList groups the per-platform images under one local manifest
+// list. Podman builds it from local storage; Docker can only reference images
+// already pushed to a registry, so a failure there comes with that hint.
+//
+//	in(1): string list manifest list reference
+//	in(2): []string images per-platform image references
+//	out: error if the manifest list cannot be created
+func createManifestList(list string, images []string) error {
+	switch GetEngine().Type() {
+	case EnginePodman:
+		// Replace a list left by a previous build
+		_ = exec.Command("podman", "manifest", "rm", list).Run()
+		if out, err := exec.Command("podman", "manifest", "create", list).CombinedOutput(); err != nil {
+			return fmt.Errorf("podman manifest create %s failed: %v: %s", list, err, strings.TrimSpace(string(out)))
+		}
+		for _, image := range images {
+			if out, err := exec.Command("podman", "manifest", "add", list, "containers-storage:"+image).CombinedOutput(); err != nil {
+				return fmt.Errorf("podman manifest add %s failed: %v: %s", image, err, strings.TrimSpace(string(out)))
+			}
+		}
+		return nil
+	default:
+		if !binaryExists("docker") {
+			return fmt.Errorf("docker CLI not found: cannot create manifest list %s", list)
+		}
+		args := append([]string{"manifest", "create", "--amend", list}, images...)
+		if out, err := exec.Command("docker", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("docker manifest create %s failed: %v: %s (Docker manifest lists reference registry images: push %s first)",
+				list, err, strings.TrimSpace(string(out)), strings.Join(images, ", "))
+		}
+		return nil
+	}
+}
+
+// printEmulationHint explains how to enable cross-architecture builds after a
+// foreign-platform build failed.
+func printEmulationHint(p BuildPlatform) {
+	if p.Arch == getArchitecture() {
+		return
+	}
+	common.PrintWarningMessage(fmt.Sprintf("Building for %s on a %s host needs QEMU emulation (binfmt_misc).", p, getArchitecture()))
+	common.PrintInfoMessage("Install it with: docker run --privileged --rm tonistiigi/binfmt --install all (or the qemu-user-static package)")
+}
@@ -0,0 +1,745 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Recipe loading: extends/include resolution, ${VAR} substitution and
+ * schema validation
+ */
+
+package dock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RecipeStepTypes lists the step types a recipe may use.
+var RecipeStepTypes = []string{"run", "copy", "workdir", "script", "cleanup", "arg", "env", "user", "entrypoint"}
+
+var (
+	// recipeVarPattern matches ${NAME} references and the $${ escape.
+	recipeVarPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+	recipeVarNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	recipeStageNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+)
+
+// ParseRecipeVars parses --set key=value pairs.
+//
+//	in(1): []string pairs key=value strings
+//	out: map[string]string variables
+//	out: error if a pair has no '=' or an invalid name
+func ParseRecipeVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set value %q (expected key=value)", pair)
+		}
+		key = strings.TrimSpace(key)
+		if !recipeVarNamePattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid variable name %q in --set %q", key, pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// recipePos is the position of a recipe element in its source file.
+type recipePos struct {
+	file string
+	line int
+}
+
+// RecipeProblem is one problem found while loading or validating a recipe.
+type RecipeProblem struct {
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// Severities of a RecipeProblem.
+const (
+	RecipeError   = "error"
+	RecipeWarning = "warning"
+)
+
+// Location returns "file:line", or just the file when the line is unknown.
+func (p RecipeProblem) Location() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return p.File
+}
+
+func (p RecipeProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Location(), p.Message)
+}
+
+// recipeProblems collects problems in source order.
+type recipeProblems []RecipeProblem
+
+func (ps *recipeProblems) add(pos recipePos, severity, format string, args ...interface{}) {
+	*ps = append(*ps, RecipeProblem{File: pos.file, Line: pos.line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+func (ps *recipeProblems) errorf(pos recipePos, format string, args ...interface{}) {
+	ps.add(pos, RecipeError, format, args...)
+}
+
+func (ps recipeProblems) hasErrors() bool {
+	for _, p := range ps {
+		if p.Severity == RecipeError {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps recipeProblems) sorted() []RecipeProblem {
+	out := append([]RecipeProblem{}, ps...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+// err returns the errors as a single error listing one problem per line.
+func (ps recipeProblems) err(recipeFile string) error {
+	var lines []string
+	for _, p := range ps.sorted() {
+		if p.Severity == RecipeError {
+			lines = append(lines, "  "+p.String())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	noun := "problem"
+	if len(lines) > 1 {
+		noun = "problems"
+	}
+	return fmt.Errorf("invalid recipe %s (%d %s):\n%s", recipeFile, len(lines), noun, strings.Join(lines, "\n"))
+}
+
+// loadRecipe reads a recipe file, resolves its extends chain and include
+// fragments, substitutes ${VAR} references and validates the result.
+//
+//	in(1): string recipeFile path to the YAML recipe
+//	in(2): string tagOverride tag replacing the recipe tag (empty keeps it)
+//	in(3): map[string]string vars values overriding the recipe's vars block
+//	out: BuildRecipe expanded recipe
+//	out: error listing every problem if the recipe cannot be loaded or is invalid
+func loadRecipe(recipeFile string, tagOverride string, vars map[string]string) (BuildRecipe, error) {
+	recipe, problems := parseRecipe(recipeFile, tagOverride, vars)
+	if err := problems.err(recipeFile); err != nil {
+		return BuildRecipe{}, err
+	}
+	return recipe, nil
+}
+
+// parseRecipe loads and validates a recipe like loadRecipe, but returns every
+// problem found instead of failing, along with whatever could be decoded.
+//
+//	in(1): string recipeFile path to the YAML recipe
+//	in(2): string tagOverride tag replacing the recipe tag (empty keeps it)
+//	in(3): map[string]string vars values overriding the recipe's vars block
+//	out: BuildRecipe expanded recipe, possibly partial
+//	out: recipeProblems problems found
+func parseRecipe(recipeFile string, tagOverride string, vars map[string]string) (BuildRecipe, recipeProblems) {
+	var problems recipeProblems
+	recipe := resolveRecipe(recipeFile, nil, &problems)
+	if problems.hasErrors() && recipe.file == "" {
+		return recipe, problems
+	}
+
+	expandRecipeVars(&recipe, vars)
+
+	if tagOverride != "" {
+		recipe.Tag = tagOverride
+	}
+
+	validateRecipe(recipe, &problems)
+	return recipe, problems
+}
+
+// RenderRecipe returns the fully expanded Dockerfile of a recipe without
+// building it.
+//
+//	in(1): string recipeFile path to the YAML recipe
+//	in(2): string tagOverride tag replacing the recipe tag (empty keeps it)
+//	in(3): map[string]string vars values overriding the recipe's vars block
+//	out: string generated Dockerfile
+//	out: error if the recipe cannot be loaded or rendered
+func RenderRecipe(recipeFile string, tagOverride string, vars map[string]string) (string, error) {
+	recipe, err := loadRecipe(recipeFile, tagOverride, vars)
+	if err != nil {
+		return "", err
+	}
+	return generateDockerfile(recipe)
+}
+
+// enterRecipeFile records path in the chain of files being resolved.
+//
+//	in(1): string path recipe or fragment file
+//	in(2): []string chain absolute paths of the files including it
+//	out: []string chain extended with path
+//	out: error if path is already in the chain
+func enterRecipeFile(path string, chain []string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, seen := range chain {
+		if seen == abs {
+			cycle := append(append([]string{}, chain[i:]...), abs)
+			return nil, fmt.Errorf("recipe cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	// Copy so sibling includes do not share the backing array
+	return append(chain[:len(chain):len(chain)], abs), nil
+}
+
+// yamlLinePattern extracts the line number yaml.v3 puts in its error messages.
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// unknownFieldPattern matches yaml.v3 strict-mode errors for unknown keys.
+var unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+
+// decodeRecipeFile strictly decodes a recipe or fragment file into out:
+// unknown keys and type mismatches are reported as problems with their line,
+// and decoding continues so every problem of the file is reported at once.
+//
+//	in(1): string path file to decode
+//	in(2): interface{} out destination (*BuildRecipe or *[]BuildStep ...)
+//	in(3): *recipeProblems problems receives the decode problems
+//	out: *yaml.Node root node of the document, nil if the file cannot be parsed
+func decodeRecipeFile(path string, out interface{}, problems *recipeProblems) *yaml.Node {
+	fileOnly := recipePos{file: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		problems.errorf(fileOnly, "cannot read file: %v", err)
+		return nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		pos, msg := fileOnly, err.Error()
+		if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+			pos.line, _ = strconv.Atoi(m[1])
+			msg = m[2]
+		}
+		problems.errorf(pos, "invalid YAML: %s", msg)
+		return nil
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && err != io.EOF {
+		typeErr, ok := err.(*yaml.TypeError)
+		if !ok {
+			problems.errorf(fileOnly, "%v", err)
+			return doc.Content[0]
+		}
+		for _, msg := range typeErr.Errors {
+			pos := fileOnly
+			if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+				pos.line, _ = strconv.Atoi(m[1])
+				msg = m[2]
+			}
+			if m := unknownFieldPattern.FindStringSubmatch(msg); m != nil {
+				msg = fmt.Sprintf("unknown field %q", m[1])
+			}
+			problems.errorf(pos, "%s", msg)
+		}
+	}
+	return doc.Content[0]
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setStepPositions records the source line of each decoded step.
+func setStepPositions(steps []BuildStep, seq *yaml.Node, file string) {
+	for i := range steps {
+		steps[i].pos = recipePos{file: file}
+		if seq != nil && seq.Kind == yaml.SequenceNode && i < len(seq.Content) {
+			steps[i].pos.line = seq.Content[i].Line
+		}
+	}
+}
+
+// resolveRecipe parses a recipe file, splices its include fragments and merges
+// it over the recipe it extends. Paths are resolved relative to the directory
+// of the file that references them, like Context. Problems are collected and
+// resolution goes on with what could be read.
+//
+//	in(1): string path recipe file
+//	in(2): []string chain files already being resolved, for cycle detection
+//	in(3): *recipeProblems problems receives the problems found
+//	out: BuildRecipe recipe without extends or include steps (file is empty
+//	     if the file could not be read)
+func resolveRecipe(path string, chain []string, problems *recipeProblems) BuildRecipe {
+	chain, err := enterRecipeFile(path, chain)
+	if err != nil {
+		problems.errorf(recipePos{file: path}, "%v", err)
+		return BuildRecipe{}
+	}
+
+	var recipe BuildRecipe
+	root := decodeRecipeFile(path, &recipe, problems)
+	if root == nil {
+		if !problems.hasErrors() {
+			problems.errorf(recipePos{file: path}, "empty recipe")
+		}
+		return BuildRecipe{}
+	}
+	if root.Kind != yaml.MappingNode {
+		problems.errorf(recipePos{file: path, line: root.Line}, "a recipe must be a mapping (name, base_image, steps ...)")
+		return BuildRecipe{}
+	}
+	recipe.file = path
+
+	dir := filepath.Dir(path)
+	setStepPositions(recipe.Steps, mappingValue(root, "steps"), path)
+	recipe.Steps = spliceIncludes(recipe.Steps, dir, chain, problems)
+
+	stagesNode := mappingValue(root, "stages")
+	for i := range recipe.Stages {
+		stage := &recipe.Stages[i]
+		stage.pos = recipePos{file: path}
+		var stageNode *yaml.Node
+		if stagesNode != nil && i < len(stagesNode.Content) {
+			stageNode = stagesNode.Content[i]
+			stage.pos.line = stageNode.Line
+		}
+		setStepPositions(stage.Steps, mappingValue(stageNode, "steps"), path)
+		stage.Steps = spliceIncludes(stage.Steps, dir, chain, problems)
+	}
+
+	if recipe.Extends == "" {
+		return recipe
+	}
+
+	extendsPos := recipePos{file: path}
+	if node := mappingValue(root, "extends"); node != nil {
+		extendsPos.line = node.Line
+	}
+	parentPath := resolveRecipePath(dir, recipe.Extends)
+	var parentProblems recipeProblems
+	parent := resolveRecipe(parentPath, chain, &parentProblems)
+	for _, p := range parentProblems {
+		if p.File == parentPath && p.Line == 0 {
+			// The parent itself is unusable: report it where it is referenced
+			problems.errorf(extendsPos, "extends %s: %s", recipe.Extends, p.Message)
+			continue
+		}
+		*problems = append(*problems, p)
+	}
+	if parent.file == "" {
+		recipe.Extends = ""
+		return recipe
+	}
+	if parent.Context != "" && !filepath.IsAbs(parent.Context) {
+		// Keep the parent's context pointing at the same directory
+		parent.Context = filepath.Join(filepath.Dir(parentPath), parent.Context)
+		if rel, err := filepath.Rel(dir, parent.Context); err == nil {
+			parent.Context = rel
+		}
+	}
+	merged := mergeRecipes(parent, recipe)
+	merged.file = path
+	return merged
+}
+
+// resolveRecipePath resolves a path referenced from a file in dir.
+func resolveRecipePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// spliceIncludes replaces every include step with the steps of its fragment.
+// A fragment is a YAML list of steps, or a mapping with a steps key; it may
+// include other fragments.
+//
+//	in(1): []BuildStep steps steps possibly containing include steps
+//	in(2): string dir directory include paths are relative to
+//	in(3): []string chain files already being resolved, for cycle detection
+//	in(4): *recipeProblems problems receives unreadable fragments and cycles
+//	out: []BuildStep steps without include steps
+func spliceIncludes(steps []BuildStep, dir string, chain []string, problems *recipeProblems) []BuildStep {
+	var out []BuildStep
+	for _, step := range steps {
+		if step.Include == "" {
+			out = append(out, step)
+			continue
+		}
+		if step.Type != "" {
+			problems.errorf(step.pos, "include cannot be combined with type %q", step.Type)
+			continue
+		}
+
+		fragmentPath := resolveRecipePath(dir, step.Include)
+		var fragmentProblems recipeProblems
+		fragment := readStepFragment(fragmentPath, chain, &fragmentProblems)
+		for _, p := range fragmentProblems {
+			if p.File == fragmentPath && p.Line == 0 {
+				problems.errorf(step.pos, "include %s: %s", step.Include, p.Message)
+				continue
+			}
+			*problems = append(*problems, p)
+		}
+		out = append(out, fragment...)
+	}
+	return out
+}
+
+// readStepFragment reads the steps of an include fragment.
+//
+//	in(1): string path fragment file
+//	in(2): []string chain files already being resolved, for cycle detection
+//	in(3): *recipeProblems problems receives the problems found
+//	out: []BuildStep fragment steps, with nested includes spliced
+func readStepFragment(path string, chain []string, problems *recipeProblems) []BuildStep {
+	chain, err := enterRecipeFile(path, chain)
+	if err != nil {
+		problems.errorf(recipePos{file: path}, "%v", err)
+		return nil
+	}
+
+	var doc yaml.Node
+	if data, err := os.ReadFile(path); err == nil {
+		yaml.Unmarshal(data, &doc)
+	}
+
+	var steps []BuildStep
+	var root *yaml.Node
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		var fragment struct {
+			Steps []BuildStep `yaml:"steps"`
+		}
+		root = decodeRecipeFile(path, &fragment, problems)
+		steps = fragment.Steps
+		setStepPositions(steps, mappingValue(root, "steps"), path)
+	} else {
+		root = decodeRecipeFile(path, &steps, problems)
+		setStepPositions(steps, root, path)
+	}
+	return spliceIncludes(steps, filepath.Dir(path), chain, problems)
+}
+
+// mergeRecipes applies child over parent: scalar fields set in the child win,
+// vars and labels are merged with child values taking precedence, stages with
+// the same name are replaced by the child's, and the child's steps run after
+// the parent's.
+//
+//	in(1): BuildRecipe parent resolved parent recipe
+//	in(2): BuildRecipe child recipe extending it
+//	out: BuildRecipe merged recipe
+func mergeRecipes(parent, child BuildRecipe) BuildRecipe {
+	merged := parent
+	merged.Extends = ""
+	if child.Name != "" {
+		merged.Name = child.Name
+	}
+	if child.BaseImage != "" {
+		merged.BaseImage = child.BaseImage
+	}
+	if child.Tag != "" {
+		merged.Tag = child.Tag
+	}
+	if child.Context != "" {
+		merged.Context = child.Context
+	}
+
+	merged.Vars = mergeStringMaps(parent.Vars, child.Vars)
+	merged.Labels = mergeStringMaps(parent.Labels, child.Labels)
+
+	merged.Stages = append([]BuildStage{}, parent.Stages...)
+	for _, stage := range child.Stages {
+		replaced := false
+		for i := range merged.Stages {
+			if merged.Stages[i].Name == stage.Name {
+				merged.Stages[i] = stage
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Stages = append(merged.Stages, stage)
+		}
+	}
+
+	merged.Steps = append(append([]BuildStep{}, parent.Steps...), child.Steps...)
+	return merged
+}
+
+func mergeStringMaps(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// expandVars replaces ${NAME} references found in vars. Unknown references are
+// kept as-is so build-time ARG/ENV and shell variables still reach the
+// Dockerfile; $${NAME} produces a literal ${NAME}.
+//
+//	in(1): string s text to expand
+//	in(2): map[string]string vars variable values
+//	out: string expanded text
+func expandVars(s string, vars map[string]string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return recipeVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+		if value, ok := vars[match[2:len(match)-1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// expandRecipeVars substitutes ${VAR} references in every string of the recipe.
+// Values from overrides (--set) take precedence over the recipe's vars block.
+//
+//	in(1): *BuildRecipe recipe recipe to expand in place
+//	in(2): map[string]string overrides command-line values
+func expandRecipeVars(recipe *BuildRecipe, overrides map[string]string) {
+	vars := make(map[string]string)
+	for k, v := range recipe.Vars {
+		vars[k] = v
+	}
+	for k, v := range overrides {
+		vars[k] = v
+	}
+	if len(vars) == 0 {
+		return
+	}
+
+	recipe.Name = expandVars(recipe.Name, vars)
+	recipe.BaseImage = expandVars(recipe.BaseImage, vars)
+	recipe.Tag = expandVars(recipe.Tag, vars)
+	recipe.Context = expandVars(recipe.Context, vars)
+	for k, v := range recipe.Labels {
+		recipe.Labels[k] = expandVars(v, vars)
+	}
+	for i := range recipe.Stages {
+		recipe.Stages[i].Name = expandVars(recipe.Stages[i].Name, vars)
+		recipe.Stages[i].BaseImage = expandVars(recipe.Stages[i].BaseImage, vars)
+		expandSteps(recipe.Stages[i].Steps, vars)
+	}
+	expandSteps(recipe.Steps, vars)
+}
+
+func expandSteps(steps []BuildStep, vars map[string]string) {
+	expandList := func(list []string) {
+		for i := range list {
+			list[i] = expandVars(list[i], vars)
+		}
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		expandList(step.Commands)
+		for j := range step.Items {
+			step.Items[j].Source = expandVars(step.Items[j].Source, vars)
+			step.Items[j].Destination = expandVars(step.Items[j].Destination, vars)
+		}
+		step.From = expandVars(step.From, vars)
+		step.Path = expandVars(step.Path, vars)
+		step.Name = expandVars(step.Name, vars)
+		step.Script = expandVars(step.Script, vars)
+		expandList(step.Functions)
+		expandList(step.Paths)
+		for k, v := range step.Values {
+			step.Values[k] = expandVars(v, vars)
+		}
+		step.User = expandVars(step.User, vars)
+		expandList(step.Command)
+	}
+}
+
+// stepLocation describes a step for error messages, e.g.
+// `stage "builder" step 2 (copy)` or `step 4 (script)` for the final stage.
+//
+//	in(1): string stage stage name, empty for the final stage
+//	in(2): int index 0-based step index
+//	in(3): string stepType step type
+//	out: string location
+func stepLocation(stage string, index int, stepType string) string {
+	loc := fmt.Sprintf("step %d", index+1)
+	if stepType != "" {
+		loc += fmt.Sprintf(" (%s)", stepType)
+	}
+	if stage != "" {
+		loc = fmt.Sprintf("stage %q %s", stage, loc)
+	}
+	return loc
+}
+
+// validateRecipe checks the recipe schema and records every problem found,
+// each pointing at the offending stage or step.
+//
+//	in(1): BuildRecipe recipe expanded recipe
+//	in(2): *recipeProblems problems receives the problems found
+func validateRecipe(recipe BuildRecipe, problems *recipeProblems) {
+	top := recipePos{file: recipe.file}
+
+	if recipe.Name == "" {
+		problems.errorf(top, "name: required")
+	}
+	if recipe.Tag == "" {
+		problems.errorf(top, "tag: required (set it in the recipe or with --tag)")
+	}
+	if recipe.BaseImage == "" {
+		problems.errorf(top, "base_image: required")
+	}
+
+	stages := make(map[string]bool)
+	for i, stage := range recipe.Stages {
+		switch {
+		case stage.Name == "":
+			problems.errorf(stage.pos, "stages[%d]: name required", i+1)
+		case !recipeStageNamePattern.MatchString(stage.Name):
+			problems.errorf(stage.pos, "stage %q: invalid name (use lower-case letters, digits, '_', '.' and '-')", stage.Name)
+		case stages[stage.Name]:
+			problems.errorf(stage.pos, "stage %q: duplicate stage name", stage.Name)
+		}
+		if stage.BaseImage == "" {
+			problems.errorf(stage.pos, "stage %q: base_image required", stage.Name)
+		}
+		for j, step := range stage.Steps {
+			for _, err := range validateStep(step, stages) {
+				problems.errorf(step.pos, "%s: %v", stepLocation(stage.Name, j, step.Type), err)
+			}
+		}
+		// Later stages (and the final one) may copy from this stage
+		stages[stage.Name] = true
+	}
+
+	for j, step := range recipe.Steps {
+		for _, err := range validateStep(step, stages) {
+			problems.errorf(step.pos, "%s: %v", stepLocation("", j, step.Type), err)
+		}
+	}
+}
+
+// validateStep checks the fields required by a step type.
+//
+//	in(1): BuildStep step step to check
+//	in(2): map[string]bool stages names of the stages defined before the step
+//	out: []error problems found
+func validateStep(step BuildStep, stages map[string]bool) []error {
+	var errs []error
+	addf := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch step.Type {
+	case "run":
+		if len(step.Commands) == 0 {
+			addf("commands: at least one command required")
+		}
+	case "copy":
+		if len(step.Items) == 0 {
+			addf("items: at least one item required")
+		}
+		for i, item := range step.Items {
+			if item.Source == "" {
+				addf("items[%d]: source required", i+1)
+			}
+			if item.Destination == "" {
+				addf("items[%d]: destination required", i+1)
+			}
+		}
+		// Names without '/' or ':' are stage names; anything else is an image
+		if step.From != "" && !stages[step.From] && !strings.ContainsAny(step.From, "/:") {
+			addf("from: unknown stage %q (stages must be defined before they are copied from)", step.From)
+		}
+	case "workdir":
+		if step.Path == "" {
+			addf("path: required")
+		}
+	case "script":
+		if step.Script == "" {
+			addf("script: required")
+		}
+		if len(step.Functions) == 0 {
+			addf("functions: at least one function required")
+		}
+	case "cleanup":
+		if len(step.Paths) == 0 && !step.AptClean {
+			addf("paths or apt_clean: required")
+		}
+	case "arg", "env":
+		if len(step.Values) == 0 {
+			addf("values: at least one variable required")
+		}
+		for _, key := range sortedKeys(step.Values) {
+			if !recipeVarNamePattern.MatchString(key) {
+				addf("values: invalid variable name %q", key)
+			}
+		}
+	case "user":
+		if step.User == "" {
+			addf("user: required")
+		}
+	case "entrypoint":
+		if len(step.Command) == 0 {
+			addf("command: required")
+		}
+	case "":
+		addf("type: required (one of %s)", strings.Join(RecipeStepTypes, ", "))
+	default:
+		addf("unknown step type %q (one of %s)", step.Type, strings.Join(RecipeStepTypes, ", "))
+	}
+	return errs
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
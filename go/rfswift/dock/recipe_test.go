@@ -0,0 +1,646 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRecipe(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"GR": "3.10", "IMG": "penthertz/rfswift_noble"}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"${IMG}:sdr_light", "penthertz/rfswift_noble:sdr_light"},
+		{"gnuradio-${GR}-${GR}", "gnuradio-3.10-3.10"},
+		{"echo ${HOME}", "echo ${HOME}"},
+		{"echo $${GR}", "echo ${GR}"},
+		{"no vars", "no vars"},
+	}
+
+	for _, tt := range tests {
+		if got := expandVars(tt.in, vars); got != tt.want {
+			t.Errorf("expandVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRecipeVars(t *testing.T) {
+	vars, err := ParseRecipeVars([]string{"GR=3.10", "OPTS=a=b"})
+	if err != nil {
+		t.Fatalf("ParseRecipeVars error: %v", err)
+	}
+	if vars["GR"] != "3.10" || vars["OPTS"] != "a=b" {
+		t.Errorf("ParseRecipeVars = %v", vars)
+	}
+
+	for _, bad := range []string{"GR", "1GR=x", "G-R=x"} {
+		if _, err := ParseRecipeVars([]string{bad}); err == nil {
+			t.Errorf("ParseRecipeVars(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestGenerateMultiStageDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeRecipe(t, dir, "recipe.yaml", `
+name: rfswift_gr
+tag: ${GR}
+base_image: penthertz/rfswift_noble:base
+vars:
+  GR: "3.9"
+  PREFIX: /opt/gr
+stages:
+  - name: builder
+    base_image: penthertz/rfswift_noble:build
+    steps:
+      - type: arg
+        values:
+          JOBS: ""
+      - type: run
+        commands:
+          - make -j$${JOBS} install PREFIX=${PREFIX}
+steps:
+  - type: copy
+    from: builder
+    items:
+      - source: ${PREFIX}
+        destination: ${PREFIX}
+  - type: env
+    values:
+      PATH: ${PREFIX}/bin:$PATH
+  - type: user
+    user: sdr
+  - type: entrypoint
+    command: ["/usr/bin/tini", "--"]
+`)
+
+	recipe, err := loadRecipe(path, "", map[string]string{"GR": "3.10"})
+	if err != nil {
+		t.Fatalf("loadRecipe error: %v", err)
+	}
+	if recipe.Tag != "3.10" {
+		t.Errorf("tag = %q, want --set value %q", recipe.Tag, "3.10")
+	}
+
+	dockerfile, err := generateDockerfile(recipe)
+	if err != nil {
+		t.Fatalf("generateDockerfile error: %v", err)
+	}
+	for _, want := range []string{
+		"FROM penthertz/rfswift_noble:build AS builder\n",
+		"ARG JOBS\n",
+		"RUN make -j${JOBS} install PREFIX=/opt/gr\n",
+		"FROM penthertz/rfswift_noble:base\n",
+		"COPY --from=builder /opt/gr /opt/gr\n",
+		`ENV PATH="/opt/gr/bin:$PATH"` + "\n",
+		"USER sdr\n",
+		`ENTRYPOINT ["/usr/bin/tini","--"]` + "\n",
+	} {
+		if !strings.Contains(dockerfile, want) {
+			t.Errorf("generated Dockerfile lacks %q:\n%s", want, dockerfile)
+		}
+	}
+	if strings.Index(dockerfile, "AS builder") > strings.Index(dockerfile, "FROM penthertz/rfswift_noble:base") {
+		t.Errorf("builder stage must come before the final stage:\n%s", dockerfile)
+	}
+}
+
+func TestValidateRecipeReportsSteps(t *testing.T) {
+	recipe := BuildRecipe{
+		Name:      "rfswift_gr",
+		Tag:       "latest",
+		BaseImage: "penthertz/rfswift_noble:base",
+		Stages: []BuildStage{{
+			Name:      "builder",
+			BaseImage: "penthertz/rfswift_noble:build",
+			Steps:     []BuildStep{{Type: "run"}},
+		}},
+		Steps: []BuildStep{
+			{Type: "copy", From: "compiler", Items: []CopyItem{{Source: "/opt/gr"}}},
+			{Type: "workdri", Path: "/root"},
+			{Type: "env", Values: map[string]string{"BAD-NAME": "x"}},
+		},
+	}
+
+	var problems recipeProblems
+	validateRecipe(recipe, &problems)
+	err := problems.err("recipe.yaml")
+	if err == nil {
+		t.Fatal("validateRecipe found no problem, want errors")
+	}
+	for _, want := range []string{
+		`stage "builder" step 1 (run): commands: at least one command required`,
+		`step 1 (copy): items[1]: destination required`,
+		`step 1 (copy): from: unknown stage "compiler"`,
+		`step 2 (workdri): unknown step type "workdri"`,
+		`step 3 (env): values: invalid variable name "BAD-NAME"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validateRecipe error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadBundledRecipe(t *testing.T) {
+	recipe, err := loadRecipe(filepath.Join("..", "..", "..", "recipes", "sdr_light.yaml"), "", nil)
+	if err != nil {
+		t.Fatalf("loadRecipe(sdr_light.yaml) error: %v", err)
+	}
+	dockerfile, err := generateDockerfile(recipe)
+	if err != nil {
+		t.Fatalf("generateDockerfile(sdr_light.yaml) error: %v", err)
+	}
+	for _, want := range []string{"RUN apt-fast update\n", "WORKDIR /root/\n", "apt-fast clean"} {
+		if !strings.Contains(dockerfile, want) {
+			t.Errorf("sdr_light.yaml Dockerfile lacks included %q", want)
+		}
+	}
+}
+
+func TestRecipeExtendsAndInclude(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "fragments"), 0755)
+	os.MkdirAll(filepath.Join(dir, "base", "files"), 0755)
+	writeRecipe(t, dir, "fragments/apt.yaml", `
+- type: run
+  commands: ["apt-fast update"]
+- include: clean.yaml
+`)
+	writeRecipe(t, dir, "fragments/clean.yaml", `
+steps:
+  - type: cleanup
+    apt_clean: true
+`)
+	writeRecipe(t, dir, "base/base.yaml", `
+name: rfswift_base
+tag: base
+base_image: penthertz/rfswift_noble:core
+context: files
+vars:
+  GR: "3.9"
+labels:
+  org.container.project: rfswift
+steps:
+  - include: ../fragments/apt.yaml
+`)
+	path := writeRecipe(t, dir, "child.yaml", `
+extends: base/base.yaml
+name: rfswift_child
+vars:
+  GR: "3.10"
+labels:
+  org.container.author: test
+steps:
+  - type: run
+    commands: ["echo gnuradio ${GR}"]
+`)
+
+	recipe, err := loadRecipe(path, "", nil)
+	if err != nil {
+		t.Fatalf("loadRecipe error: %v", err)
+	}
+	if recipe.Name != "rfswift_child" || recipe.Tag != "base" || recipe.BaseImage != "penthertz/rfswift_noble:core" {
+		t.Errorf("merged scalars = %q %q %q", recipe.Name, recipe.Tag, recipe.BaseImage)
+	}
+	if recipe.Context != filepath.Join("base", "files") {
+		t.Errorf("inherited context = %q, want %q", recipe.Context, filepath.Join("base", "files"))
+	}
+	if len(recipe.Labels) != 2 {
+		t.Errorf("merged labels = %v, want both parent and child labels", recipe.Labels)
+	}
+
+	var types []string
+	for _, step := range recipe.Steps {
+		types = append(types, step.Type)
+	}
+	if got, want := strings.Join(types, ","), "run,cleanup,run"; got != want {
+		t.Errorf("resolved step types = %s, want %s", got, want)
+	}
+	if got := recipe.Steps[2].Commands[0]; got != "echo gnuradio 3.10" {
+		t.Errorf("child var did not override parent var: %q", got)
+	}
+}
+
+func TestRecipeCycles(t *testing.T) {
+	dir := t.TempDir()
+	writeRecipe(t, dir, "a.yaml", "extends: b.yaml\nname: a\n")
+	writeRecipe(t, dir, "b.yaml", "extends: a.yaml\nname: b\n")
+	writeRecipe(t, dir, "loop.yaml", "- include: loop.yaml\n")
+	writeRecipe(t, dir, "c.yaml", "name: c\ntag: t\nbase_image: x\nsteps:\n  - include: loop.yaml\n")
+
+	for _, name := range []string{"a.yaml", "c.yaml"} {
+		_, err := loadRecipe(filepath.Join(dir, name), "", nil)
+		if err == nil || !strings.Contains(err.Error(), "recipe cycle") {
+			t.Errorf("loadRecipe(%q) error = %v, want recipe cycle", name, err)
+		}
+	}
+
+	// The same fragment included twice is not a cycle
+	writeRecipe(t, dir, "apt.yaml", "- type: run\n  commands: [\"apt-fast update\"]\n")
+	writeRecipe(t, dir, "twice.yaml", "name: d\ntag: t\nbase_image: x\nsteps:\n  - include: apt.yaml\n  - include: apt.yaml\n")
+	if recipe, err := loadRecipe(filepath.Join(dir, "twice.yaml"), "", nil); err != nil || len(recipe.Steps) != 2 {
+		t.Errorf("loadRecipe(twice.yaml) = %d steps, %v; want 2 steps", len(recipe.Steps), err)
+	}
+}
+
+func TestValidateRecipeFile(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	writeRecipe(t, dir, "scripts/entrypoint.sh", `#!/bin/bash
+source "$(dirname "$0")/sdr.sh"
+common_install() {
+	true
+}
+"$1"
+`)
+	writeRecipe(t, dir, "scripts/sdr.sh", `function gqrx_soft_install {
+	true
+}
+`)
+	path := writeRecipe(t, dir, "recipe.yaml", `name: rfswift_test
+tag: latest
+base_image: penthertz/rfswift_noble:core
+steps:
+  - type: copy
+    items:
+      - source: scripts
+        destination: /root/scripts
+      - source: missing.conf
+        destination: /etc/missing.conf
+      - source: ../outside
+        destination: /tmp/outside
+  - type: workdir
+    path: /root/scripts
+  - type: script
+    script: ./entrypoint.sh
+    functions: [common_install, gqrx_soft_install, typo_install]
+  - type: run
+    comands: [echo typo]
+  - type: workdri
+    path: /root
+`)
+
+	v := ValidateRecipeFile(path, nil, "")
+	if v.Valid {
+		t.Fatalf("ValidateRecipeFile(%s) is valid, want problems", path)
+	}
+
+	var got []string
+	for _, p := range v.Problems {
+		got = append(got, filepath.Base(p.Location())+" "+p.Message)
+	}
+	report := strings.Join(got, "\n")
+	for _, want := range []string{
+		`recipe.yaml:5 step 1 (copy): items[2]: source "missing.conf" not found`,
+		`recipe.yaml:5 step 1 (copy): items[3]: source "../outside" is outside the build context`,
+		`recipe.yaml:15 step 3 (script): function "typo_install" is not defined in ./entrypoint.sh`,
+		`recipe.yaml:19 unknown field "comands"`,
+		`recipe.yaml:18 step 4 (run): commands: at least one command required`,
+		`recipe.yaml:20 step 5 (workdri): unknown step type "workdri"`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("validation report lacks %q:\n%s", want, report)
+		}
+	}
+	for _, unwanted := range []string{`"common_install"`, `"gqrx_soft_install"`} {
+		if strings.Contains(report, unwanted) {
+			t.Errorf("function %s is defined but reported:\n%s", unwanted, report)
+		}
+	}
+}
+
+func TestValidateRecipeIncludedLines(t *testing.T) {
+	dir := t.TempDir()
+	writeRecipe(t, dir, "fragment.yaml", "- type: run\n  commands: [ok]\n- type: user\n")
+	path := writeRecipe(t, dir, "recipe.yaml", "name: a\ntag: b\nbase_image: c\nsteps:\n  - include: fragment.yaml\n  - include: nowhere.yaml\n")
+
+	v := ValidateRecipeFile(path, nil, "")
+	var got []string
+	for _, p := range v.Problems {
+		got = append(got, filepath.Base(p.Location())+" "+p.Message)
+	}
+	report := strings.Join(got, "\n")
+	for _, want := range []string{
+		"fragment.yaml:3 step 2 (user): user: required",
+		"recipe.yaml:6 include nowhere.yaml: cannot read file",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("validation report lacks %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestParseBuildPlatforms(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{"linux/amd64,linux/arm64", []string{"linux/amd64", "linux/arm64"}, false},
+		{" linux/x86_64 , linux/aarch64 ", []string{"linux/amd64", "linux/arm64"}, false},
+		{"linux/arm", []string{"linux/arm/v7"}, false},
+		{"linux/riscv64,linux/riscv64", []string{"linux/riscv64"}, false},
+		{"linux/arm/v6,linux/arm/v7", []string{"linux/arm/v6"}, false},
+		{"windows/amd64", nil, true},
+		{"linux/s390x", nil, true},
+		{"amd64", nil, true},
+		{",", nil, true},
+	}
+
+	for _, tt := range tests {
+		platforms, err := ParseBuildPlatforms(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBuildPlatforms(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		var got []string
+		for _, p := range platforms {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ParseBuildPlatforms(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestPlatformImage(t *testing.T) {
+	arm64 := BuildPlatform{OS: "linux", Arch: "arm64"}
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"sdr_light", "me/rfswift:sdr_light_arm64"},
+		{"sdr_light_amd64", "me/rfswift:sdr_light_arm64"},
+		{"sdr_light_1.2.0", "me/rfswift:sdr_light_1.2.0_arm64"},
+	}
+
+	for _, tt := range tests {
+		if got := platformImage("me/rfswift", tt.tag, arm64); got != tt.want {
+			t.Errorf("platformImage(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+	if got := manifestImage("me/rfswift", "sdr_light_amd64"); got != "me/rfswift:sdr_light" {
+		t.Errorf("manifestImage(%q) = %q, want %q", "sdr_light_amd64", got, "me/rfswift:sdr_light")
+	}
+}
+
+func TestRecipeStepHashes(t *testing.T) {
+	dir := t.TempDir()
+	writeRecipe(t, dir, "a.txt", "one")
+	recipe := BuildRecipe{
+		BaseImage: "ubuntu:24.04",
+		Steps: []BuildStep{
+			{Type: "run", Commands: []string{"apt update"}},
+			{Type: "copy", Items: []CopyItem{{Source: "a.txt", Destination: "/a.txt"}}},
+			{Type: "run", Commands: []string{"make"}},
+		},
+	}
+
+	before, err := recipeStepHashes(recipe, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeRecipe(t, dir, "a.txt", "two")
+	after, err := recipeStepHashes(recipe, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0] != after[0] {
+		t.Errorf("step 1 hash changed with a file it does not copy")
+	}
+	if before[1] == after[1] || before[2] == after[2] {
+		t.Errorf("copy step and later steps should change with the copied file")
+	}
+
+	recipe.Steps[2].Commands = []string{"make install"}
+	edited, _ := recipeStepHashes(recipe, dir)
+	if edited[1] != after[1] || edited[2] == after[2] {
+		t.Errorf("editing step 3 should only change its own hash")
+	}
+}
+
+func TestReusableSteps(t *testing.T) {
+	last := &recipeCacheTarget{Steps: []recipeCacheStep{
+		{Hash: "h1", Checkpoint: "img1"},
+		{Hash: "h2", Checkpoint: "img2"},
+		{Hash: "h3", Checkpoint: "img3"},
+	}}
+	present := map[string]bool{"img1": true, "img2": true, "img3": true}
+	exists := func(id string) bool { return present[id] }
+
+	tests := []struct {
+		hashes     []string
+		wantSkip   int
+		checkpoint string
+	}{
+		{[]string{"h1", "h2", "h3"}, 3, "img3"},
+		{[]string{"h1", "h2", "x3", "h4"}, 2, "img2"},
+		{[]string{"x1", "h2"}, 0, ""},
+		{[]string{"h1", "h2", "h3", "h4"}, 3, "img3"},
+	}
+	for _, tt := range tests {
+		skip, checkpoint := last.reusableSteps(tt.hashes, exists)
+		if skip != tt.wantSkip || checkpoint != tt.checkpoint {
+			t.Errorf("reusableSteps(%v) = %d, %q, want %d, %q", tt.hashes, skip, checkpoint, tt.wantSkip, tt.checkpoint)
+		}
+	}
+
+	delete(present, "img2")
+	if skip, checkpoint := last.reusableSteps([]string{"h1", "h2", "x3"}, exists); skip != 1 || checkpoint != "img1" {
+		t.Errorf("with a pruned checkpoint reusableSteps = %d, %q, want 1, %q", skip, checkpoint, "img1")
+	}
+	var none *recipeCacheTarget
+	if skip, _ := none.reusableSteps([]string{"h1"}, exists); skip != 0 {
+		t.Errorf("reusableSteps without a previous build = %d, want 0", skip)
+	}
+}
+
+func TestResumeRecipe(t *testing.T) {
+	recipe := BuildRecipe{
+		BaseImage: "ubuntu:24.04",
+		Labels:    map[string]string{"a": "b"},
+		Stages:    []BuildStage{{Name: "builder", BaseImage: "golang"}},
+		Steps: []BuildStep{
+			{Type: "arg", Values: map[string]string{"VERSION": "1"}},
+			{Type: "run", Commands: []string{"apt update"}},
+			{Type: "run", Commands: []string{"make"}},
+		},
+	}
+
+	resumed, origin := resumeRecipe(recipe, 2, "sha256:abc")
+	if resumed.BaseImage != "sha256:abc" || resumed.Labels != nil || resumed.Stages != nil {
+		t.Errorf("resumeRecipe kept base %q, labels %v, stages %v", resumed.BaseImage, resumed.Labels, resumed.Stages)
+	}
+	if len(resumed.Steps) != 2 || resumed.Steps[0].Type != "arg" || resumed.Steps[1].Commands[0] != "make" {
+		t.Errorf("resumeRecipe steps = %+v, want the ARG then step 3", resumed.Steps)
+	}
+	if len(origin) != 2 || origin[0] != 0 || origin[1] != 2 {
+		t.Errorf("resumeRecipe origin = %v, want [0 2]", origin)
+	}
+
+	recipe.Steps = append(recipe.Steps, BuildStep{Type: "copy", From: "builder", Items: []CopyItem{{Source: "/out", Destination: "/opt"}}})
+	if resumed, _ := resumeRecipe(recipe, 2, "sha256:abc"); len(resumed.Stages) != 1 {
+		t.Errorf("resumeRecipe dropped the stage a remaining step copies from")
+	}
+}
+
+func TestBuildTracker(t *testing.T) {
+	recipe := BuildRecipe{
+		BaseImage: "ubuntu:24.04",
+		Steps: []BuildStep{
+			{Type: "run", Commands: []string{"apt update", "apt install -y git"}},
+			{Type: "workdir", Path: "/root"},
+			{Type: "script", Script: "./entry.sh", Functions: []string{"install_soapy_modules"}},
+			{Type: "script", Script: "./entry.sh"},
+		},
+	}
+	_, instructions, err := renderDockerfile(recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := strings.Join([]string{
+		`{"stream":"Step 1/5 : FROM ubuntu:24.04\n"}`,
+		`{"stream":" ---> 1111111111aa\n"}`,
+		`{"stream":"Step 2/5 : RUN apt update\n"}`,
+		`{"stream":" ---> Using cache\n"}`,
+		`{"stream":" ---> 2222222222bb\n"}`,
+		`{"stream":"Step 3/5 : RUN apt install -y git\n"}`,
+		`{"stream":" ---> Using cache\n ---> 3333333333cc\n"}`,
+		`{"stream":"Step 4/5 : WORKDIR /root\n"}`,
+		`{"stream":" ---> Running in 9999999999ff\n"}`,
+		`{"stream":" ---> 4444444444dd\n"}`,
+		`{"stream":"Step 5/5 : RUN ./entry.sh install_soapy_modules\n"}`,
+		`{"stream":" ---> Running in 8888888888ee\n"}`,
+		`{"error":"The command returned a non-zero code: 1"}`,
+	}, "\n") + "\n"
+
+	tracker := newBuildTracker(instructions)
+	// Feed the stream in small chunks to exercise partial messages
+	for i := 0; i < len(stream); i += 7 {
+		end := i + 7
+		if end > len(stream) {
+			end = len(stream)
+		}
+		tracker.Write([]byte(stream[i:end]))
+	}
+	tracker.finish()
+
+	results := tracker.stepResults(recipe)
+	want := []struct {
+		status     string
+		checkpoint string
+	}{
+		{stepStatusCached, "3333333333cc"},
+		{stepStatusBuilt, "4444444444dd"},
+		{stepStatusFailed, ""},
+		{stepStatusNotRun, ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("stepResults returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].Status != w.status {
+			t.Errorf("step %d status = %q, want %q", i+1, results[i].Status, w.status)
+		}
+		if results[i].Checkpoint != w.checkpoint {
+			t.Errorf("step %d checkpoint = %q, want %q", i+1, results[i].Checkpoint, w.checkpoint)
+		}
+	}
+}
+
+func TestFailedFunction(t *testing.T) {
+	functions := []string{"gnuradio_soft_install", "common_sources_and_sinks", "install_soapy_modules"}
+	tests := []struct {
+		output     []string
+		want       string
+		candidates int
+	}{
+		{[]string{"[+] gnuradio_soft_install", "ok", "[+] common_sources_and_sinks", "[+] install_soapy_modules", "E: Unable to locate package"}, "install_soapy_modules", 0},
+		{[]string{"[+] gnuradio_soft_install", "make: *** [all] Error 2"}, "gnuradio_soft_install", 0},
+		{[]string{"[+] install_soapy_modules_extra"}, "", 3},
+		{nil, "", 3},
+	}
+	for _, tt := range tests {
+		got, candidates := failedFunction(functions, tt.output)
+		if got != tt.want || len(candidates) != tt.candidates {
+			t.Errorf("failedFunction(%q) = %q, %v, want %q with %d candidates", tt.output, got, candidates, tt.want, tt.candidates)
+		}
+	}
+	if got, _ := failedFunction([]string{"only_one"}, nil); got != "only_one" {
+		t.Errorf("failedFunction with a single function = %q, want %q", got, "only_one")
+	}
+}
+
+func TestBuildTrackerFailure(t *testing.T) {
+	step := BuildStep{Type: "script", Name: "SDR", Script: "./entrypoint.sh", Functions: []string{"a_install", "b_install"}, pos: recipePos{file: "sdr.yaml", line: 12}}
+	recipe := BuildRecipe{BaseImage: "ubuntu", Steps: []BuildStep{{Type: "workdir", Path: "/root"}, step}}
+	_, instructions, err := renderDockerfile(recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log strings.Builder
+	tracker := newBuildTracker(instructions)
+	tracker.log = &log
+	stream := []string{
+		`{"stream":"Step 1/3 : FROM ubuntu\n ---> 1111111111aa\n"}`,
+		`{"stream":"Step 2/3 : WORKDIR /root\n ---> 2222222222bb\n"}`,
+		`{"stream":"Step 3/3 : RUN ./entrypoint.sh a_install \u0026\u0026 ./entrypoint.sh b_install\n"}`,
+		`{"stream":" ---> Running in 3333333333cc\n"}`,
+		`{"stream":"installing a_install\n"}`,
+		`{"stream":"installing b_install\nE: package not found\n"}`,
+		`{"errorDetail":{"code":100},"error":"The command returned a non-zero code: 100"}`,
+	}
+	tracker.Write([]byte(strings.Join(stream, "\n") + "\n"))
+	tracker.finish()
+
+	f := tracker.failure()
+	if f == nil {
+		t.Fatal("failure() = nil, want the failed script step")
+	}
+	if f.Instruction.Index != 1 || f.Function != "b_install" || f.Instruction.Step.pos.line != 12 {
+		t.Errorf("failure() = step %d function %q line %d, want step 1, b_install, line 12", f.Instruction.Index, f.Function, f.Instruction.Step.pos.line)
+	}
+	if len(f.Output) != 3 || f.Output[2] != "E: package not found" {
+		t.Errorf("failure().Output = %q, want the 3 output lines of the step", f.Output)
+	}
+	if !strings.Contains(f.Message, "non-zero code: 100") {
+		t.Errorf("failure().Message = %q", f.Message)
+	}
+	if !strings.Contains(log.String(), "[step 2 (script)] E: package not found") {
+		t.Errorf("build log lacks the step prefix:\n%s", log.String())
+	}
+}
+
+func TestBuildLogPath(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	arm64 := &BuildPlatform{OS: "linux", Arch: "arm64"}
+
+	if got := buildLogPath("out/build.log", "me/rf:sdr", nil, now); got != "out/build.log" {
+		t.Errorf("buildLogPath(--log) = %q, want %q", got, "out/build.log")
+	}
+	if got := buildLogPath("out/build.log", "me/rf:sdr", arm64, now); got != "out/build_arm64.log" {
+		t.Errorf("buildLogPath(--log, arm64) = %q, want %q", got, "out/build_arm64.log")
+	}
+	if got := filepath.Base(buildLogPath("", "me/rf:sdr_arm64", arm64, now)); got != "me_rf_sdr_arm64_20260304-050607.log" {
+		t.Errorf("buildLogPath default = %q, want %q", got, "me_rf_sdr_arm64_20260304-050607.log")
+	}
+}
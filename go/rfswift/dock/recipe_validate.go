@@ -0,0 +1,335 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Recipe validation against the build context (rfswift build validate)
+ */
+
+package dock
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// RecipeValidation is the result of ValidateRecipeFile.
+type RecipeValidation struct {
+	Recipe   string          `json:"recipe" yaml:"recipe"`
+	Valid    bool            `json:"valid" yaml:"valid"`
+	Problems []RecipeProblem `json:"problems" yaml:"problems"`
+}
+
+var (
+	// shellFunctionPattern matches "name() {" and "function name {" definitions.
+	shellFunctionPattern = regexp.MustCompile(`(?m)^\s*(?:function\s+([A-Za-z_][\w.:-]*)\s*(?:\(\s*\))?|([A-Za-z_][\w.:-]*)\s*\(\s*\))\s*\{?`)
+
+	// shellSourcePattern matches "source file" and ". file" lines.
+	shellSourcePattern = regexp.MustCompile(`(?m)^\s*(?:source|\.)\s+([^;|&#\n]+)`)
+)
+
+// recipeContextDir returns the build context directory of a recipe: its
+// context field resolved relative to the recipe file, or the recipe directory.
+//
+//	in(1): string recipeFile path to the recipe
+//	in(2): BuildRecipe recipe loaded recipe
+//	out: string context directory
+func recipeContextDir(recipeFile string, recipe BuildRecipe) string {
+	recipeDir := filepath.Dir(recipeFile)
+	if recipe.Context == "" {
+		return recipeDir
+	}
+	if filepath.IsAbs(recipe.Context) {
+		return recipe.Context
+	}
+	return filepath.Join(recipeDir, recipe.Context)
+}
+
+// ValidateRecipeFile checks a recipe without building it: strict decoding
+// (unknown keys are rejected), step types and required fields, copy sources
+// present in the build context, and script functions defined in the
+// referenced script. Every problem is reported with its file and line.
+//
+//	in(1): string recipeFile path to the recipe
+//	in(2): map[string]string vars values for ${VAR} references
+//	in(3): string scriptsDir local directory holding the recipe scripts when
+//	       they come from the base image rather than the build context
+//	out: RecipeValidation problems found
+func ValidateRecipeFile(recipeFile string, vars map[string]string, scriptsDir string) RecipeValidation {
+	recipe, problems := parseRecipe(recipeFile, "", vars)
+	if recipe.file != "" {
+		checkRecipeFiles(recipe, recipeContextDir(recipeFile, recipe), scriptsDir, &problems)
+	}
+
+	return RecipeValidation{
+		Recipe:   recipeFile,
+		Valid:    !problems.hasErrors(),
+		Problems: problems.sorted(),
+	}
+}
+
+// checkRecipeFiles verifies the files a recipe needs on the host: copy
+// sources must exist inside the context, and functions called by script
+// steps must be defined in the script (or the files it sources).
+//
+//	in(1): BuildRecipe recipe loaded recipe
+//	in(2): string contextDir build context directory
+//	in(3): string scriptsDir optional local copy of scripts from the base image
+//	in(4): *recipeProblems problems receives the problems found
+func checkRecipeFiles(recipe BuildRecipe, contextDir string, scriptsDir string, problems *recipeProblems) {
+	if info, err := os.Stat(contextDir); err != nil || !info.IsDir() {
+		problems.errorf(recipePos{file: recipe.file}, "context directory does not exist: %s", contextDir)
+		return
+	}
+
+	functionsCache := make(map[string]map[string]bool)
+	unchecked := make(map[string]bool) // scripts already reported as not found
+	checkStage := func(stageName string, steps []BuildStep) {
+		workdir := ""
+		var copies []recipeCopy
+		for i, step := range steps {
+			loc := stepLocation(stageName, i, step.Type)
+			switch step.Type {
+			case "workdir":
+				workdir = imagePath(workdir, step.Path)
+			case "copy":
+				for j, item := range step.Items {
+					if item.Source == "" || step.From != "" {
+						continue
+					}
+					hostPath, err := contextSourcePath(contextDir, item.Source)
+					if err != nil {
+						problems.errorf(step.pos, "%s: items[%d]: %v", loc, j+1, err)
+						continue
+					}
+					copies = append(copies, recipeCopy{host: hostPath, dest: imagePath(workdir, item.Destination), destIsDir: strings.HasSuffix(item.Destination, "/")})
+				}
+			case "script":
+				if step.Script == "" || len(step.Functions) == 0 {
+					continue
+				}
+				hostScript := locateRecipeScript(step.Script, workdir, copies, contextDir, scriptsDir)
+				if hostScript == "" {
+					if unchecked[step.Script] {
+						continue
+					}
+					unchecked[step.Script] = true
+					problems.add(step.pos, RecipeWarning, "%s: cannot check functions of %s: it is not copied from the build context (use --scripts <dir> to check against a local copy)", loc, step.Script)
+					continue
+				}
+				defined, ok := functionsCache[hostScript]
+				if !ok {
+					defined = shellFunctions(hostScript, map[string]bool{})
+					functionsCache[hostScript] = defined
+				}
+				for _, fn := range step.Functions {
+					if !defined[fn] {
+						problems.errorf(step.pos, "%s: function %q is not defined in %s (%s)", loc, fn, step.Script, hostScript)
+					}
+				}
+			}
+		}
+	}
+
+	for _, stage := range recipe.Stages {
+		checkStage(stage.Name, stage.Steps)
+	}
+	checkStage("", recipe.Steps)
+}
+
+// recipeCopy is a copy step item mapped to its host source and image path.
+type recipeCopy struct {
+	host      string
+	dest      string
+	destIsDir bool
+}
+
+// imagePath resolves p inside the image relative to workdir ("" if unknown).
+func imagePath(workdir, p string) string {
+	if path.IsAbs(p) || workdir == "" {
+		return path.Clean(p)
+	}
+	return path.Join(workdir, p)
+}
+
+// contextSourcePath resolves a copy source inside the build context.
+//
+//	in(1): string contextDir build context directory
+//	in(2): string source copy source as written in the recipe
+//	out: string host path of the source
+//	out: error if the source escapes the context or does not exist
+func contextSourcePath(contextDir, source string) (string, error) {
+	hostPath := filepath.Join(contextDir, filepath.FromSlash(source))
+	rel, err := filepath.Rel(contextDir, hostPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("source %q is outside the build context %s", source, contextDir)
+	}
+
+	if strings.ContainsAny(source, "*?[") {
+		matches, err := filepath.Glob(hostPath)
+		if err != nil || len(matches) == 0 {
+			return "", fmt.Errorf("source %q matches no file in the build context %s", source, contextDir)
+		}
+		return hostPath, nil
+	}
+	if _, err := os.Stat(hostPath); err != nil {
+		return "", fmt.Errorf("source %q not found in the build context %s", source, contextDir)
+	}
+	return hostPath, nil
+}
+
+// locateRecipeScript finds the host file of a script run by a script step:
+// through the copy steps that put it in the image, then in scriptsDir, then
+// at the same relative path in the context.
+//
+//	in(1): string script script path as written in the step
+//	in(2): string workdir image working directory at that step
+//	in(3): []recipeCopy copies copy steps seen so far in the stage
+//	in(4): string contextDir build context directory
+//	in(5): string scriptsDir optional local copy of the image scripts
+//	out: string host path, or "" if the script cannot be found
+func locateRecipeScript(script, workdir string, copies []recipeCopy, contextDir, scriptsDir string) string {
+	inImage := imagePath(workdir, script)
+
+	for i := len(copies) - 1; i >= 0; i-- {
+		c := copies[i]
+		info, err := os.Stat(c.host)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			if rel := strings.TrimPrefix(inImage, c.dest+"/"); rel != inImage {
+				candidate := filepath.Join(c.host, filepath.FromSlash(rel))
+				if fileExists(candidate) {
+					return candidate
+				}
+			}
+			continue
+		}
+		target := c.dest
+		if c.destIsDir {
+			target = path.Join(c.dest, filepath.Base(c.host))
+		}
+		if target == inImage {
+			return c.host
+		}
+	}
+
+	if scriptsDir != "" {
+		if candidate := filepath.Join(scriptsDir, path.Base(script)); fileExists(candidate) {
+			return candidate
+		}
+	}
+	if !path.IsAbs(script) {
+		if candidate := filepath.Join(contextDir, filepath.FromSlash(script)); fileExists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
+// shellFunctions returns the functions defined in a shell script and in the
+// scripts it sources (resolved relative to it, globs allowed).
+//
+//	in(1): string script host path of the script
+//	in(2): map[string]bool visited scripts already read
+//	out: map[string]bool function names
+func shellFunctions(script string, visited map[string]bool) map[string]bool {
+	functions := make(map[string]bool)
+	if visited[script] {
+		return functions
+	}
+	visited[script] = true
+
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return functions
+	}
+
+	for _, m := range shellFunctionPattern.FindAllStringSubmatch(string(data), -1) {
+		if m[1] != "" {
+			functions[m[1]] = true
+		} else if m[2] != "" {
+			functions[m[2]] = true
+		}
+	}
+
+	dir := filepath.Dir(script)
+	for _, m := range shellSourcePattern.FindAllStringSubmatch(string(data), -1) {
+		sourced := strings.NewReplacer(`"`, "", "'", "").Replace(strings.TrimSpace(m[1]))
+		// "$SCRIPT_DIR/lib.sh", "$(dirname "$0")/lib.sh": keep the part after the variable
+		if i := strings.LastIndex(sourced, "$"); i != -1 {
+			slash := strings.Index(sourced[i:], "/")
+			if slash == -1 {
+				continue
+			}
+			sourced = sourced[i+slash+1:]
+		}
+		if !filepath.IsAbs(sourced) {
+			sourced = filepath.Join(dir, sourced)
+		}
+		matches, _ := filepath.Glob(sourced)
+		for _, match := range matches {
+			for fn := range shellFunctions(match, visited) {
+				functions[fn] = true
+			}
+		}
+	}
+	return functions
+}
+
+// DisplayRecipeValidation prints the result of ValidateRecipeFile as a table,
+// or as a structured document with --output json|yaml.
+//
+//	in(1): RecipeValidation v validation result
+//	out: error if encoding the structured document fails
+func DisplayRecipeValidation(v RecipeValidation) error {
+	if common.MachineOutput() {
+		return common.PrintStructured("recipe_validation", v)
+	}
+
+	if len(v.Problems) > 0 {
+		rows := make([][]string, len(v.Problems))
+		for i, p := range v.Problems {
+			rows[i] = []string{p.Location(), p.Severity, p.Message}
+		}
+		tui.RenderTable(tui.TableConfig{
+			Title:   fmt.Sprintf("📋 Recipe check: %s", v.Recipe),
+			Headers: []string{"Location", "Severity", "Problem"},
+			Rows:    rows,
+			ColorFunc: func(row, col int, content string) lipgloss.Color {
+				if col != 1 {
+					return lipgloss.Color("")
+				}
+				if content == RecipeError {
+					return tui.ColorDanger
+				}
+				return tui.ColorWarning
+			},
+		})
+	}
+
+	if v.Valid {
+		common.PrintSuccessMessage(fmt.Sprintf("Recipe %s is valid", v.Recipe))
+	} else {
+		errors := 0
+		for _, p := range v.Problems {
+			if p.Severity == RecipeError {
+				errors++
+			}
+		}
+		common.PrintErrorMessage(fmt.Errorf("recipe %s has %d error(s)", v.Recipe, errors))
+	}
+	return nil
+}
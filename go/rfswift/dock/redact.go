@@ -0,0 +1,423 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Redaction of secrets from session recordings, with a sidecar noting each redaction
+ */
+
+package dock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	common "penthertz/rfswift/common"
+)
+
+// redactionMark replaces every redacted secret.
+const redactionMark = "[REDACTED]"
+
+// secretValue matches an option value up to whitespace, quotes or an
+// escape sequence.
+const secretValue = `[^\s"'\x1b]+`
+
+// redactRule is a named pattern whose "secret" group (or whole match when it
+// has none) is redacted.
+type redactRule struct {
+	Name string
+	re   *regexp.Regexp
+}
+
+// builtinRedactRules cover the secrets RF Swift itself handles.
+var builtinRedactRules = []redactRule{
+	{"tailscale-key", regexp.MustCompile(`tskey-[A-Za-z0-9][A-Za-z0-9_-]{7,}`)},
+	{"tailscale-key", regexp.MustCompile(`(?:TS_AUTHKEY=|--auth-?key[= ]+)["']?(?P<secret>` + secretValue + `)`)},
+	{"netbird-key", regexp.MustCompile(`(?:NB_SETUP_KEY=|--setup-key[= ]+|netbird:)["']?(?P<secret>` + secretValue + `)`)},
+	{"desktop-password", regexp.MustCompile(`(?:--desktop-pass[= ]+|RFSWIFT_DESKTOP_PASS=)["']?(?P<secret>` + secretValue + `)`)},
+	{"authorization", regexp.MustCompile(`(?i)\b(?:proxy-)?authorization:\s*(?:(?:bearer|basic|token|digest)\s+)?(?P<secret>` + secretValue + `)`)},
+	{"api-token", regexp.MustCompile(`(?i)\b(?:api[_-]?key|api[_-]?token|access[_-]?token)["']?\s*[=:]\s*["']?(?P<secret>` + secretValue + `)`)},
+}
+
+// compileRedactRules returns the built-in rules followed by user regexes;
+// a user regex redacts its "secret" group when it has one.
+//
+//	in(1): []string patterns
+//	out: ([]redactRule, error)
+func compileRedactRules(patterns []string) ([]redactRule, error) {
+	rules := append([]redactRule(nil), builtinRedactRules...)
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %v", p, err)
+		}
+		rules = append(rules, redactRule{fmt.Sprintf("custom-%d", i+1), re})
+	}
+	return rules, nil
+}
+
+// literalRedactRule redacts a known secret value, such as the password
+// given on the command line of the recorded session.
+func literalRedactRule(name, secret string) (redactRule, bool) {
+	if len(secret) < 4 {
+		return redactRule{}, false
+	}
+	return redactRule{name, regexp.MustCompile(regexp.QuoteMeta(secret))}, true
+}
+
+// sessionSecretRules returns literal rules for the desktop password and VPN
+// key of the current session.
+func sessionSecretRules() []redactRule {
+	var rules []redactRule
+	if r, ok := literalRedactRule("desktop-password", containerCfg.desktopPass); ok {
+		rules = append(rules, r)
+	}
+	if vpnType, vpnArg, err := parseVPN(containerCfg.vpn); err == nil && (vpnType == VPNTailscale || vpnType == VPNNetbird) {
+		key := vpnArg
+		for _, prefix := range []string{"--auth-key=", "--auth-key ", "--setup-key=", "--setup-key "} {
+			key = strings.TrimPrefix(key, prefix)
+		}
+		if r, ok := literalRedactRule(vpnType+"-key", key); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// redactSpan is a secret found in a stream, as byte offsets.
+type redactSpan struct {
+	start, end int
+	rule       string
+}
+
+// findSecrets returns the non-overlapping secrets of text, earliest first.
+func findSecrets(text string, rules []redactRule) []redactSpan {
+	var spans []redactSpan
+	for _, rule := range rules {
+		group := rule.re.SubexpIndex("secret")
+		for _, m := range rule.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if group > 0 {
+				start, end = m[2*group], m[2*group+1]
+			}
+			// Skip empty groups and marks left by an earlier pass
+			if start >= 0 && end > start && text[start:end] != redactionMark {
+				spans = append(spans, redactSpan{start, end, rule.Name})
+			}
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var merged []redactSpan
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start < merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// Redaction notes one redacted secret; the secret itself is never kept.
+type Redaction struct {
+	Rule   string  `json:"rule" yaml:"rule"`
+	Stream string  `json:"stream,omitempty" yaml:"stream,omitempty"` // "o" output or "i" input of a cast
+	Offset float64 `json:"offset" yaml:"offset"`                     // seconds into the recording, 0 for script logs
+	Length int     `json:"length" yaml:"length"`                     // length of the secret in bytes
+}
+
+// redactText redacts the secrets of a whole text, e.g. a script log.
+//
+//	in(1): string text
+//	in(2): []redactRule rules
+//	out: (string, []Redaction)
+func redactText(text string, rules []redactRule) (string, []Redaction) {
+	spans := findSecrets(text, rules)
+	var b strings.Builder
+	var notes []Redaction
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.start])
+		b.WriteString(redactionMark)
+		last = s.end
+		notes = append(notes, Redaction{Rule: s.rule, Length: s.end - s.start})
+	}
+	b.WriteString(text[last:])
+	return b.String(), notes
+}
+
+// redactEvents redacts the output and input streams of a cast. Each stream
+// is matched as a whole, so a secret typed or echoed one key per event is
+// found too; the mark goes in the event where the secret starts and the rest
+// of it is removed from the following events, keeping their timing.
+//
+//	in(1): []castEvent events
+//	in(2): []redactRule rules
+//	out: ([]castEvent, []Redaction)
+func redactEvents(events []castEvent, rules []redactRule) ([]castEvent, []Redaction) {
+	out := append([]castEvent(nil), events...)
+	var notes []Redaction
+
+	for _, code := range []string{castOutput, castInput} {
+		var idx, starts []int
+		var stream strings.Builder
+		for i, e := range events {
+			if e.Code == code {
+				idx = append(idx, i)
+				starts = append(starts, stream.Len())
+				stream.WriteString(e.Data)
+			}
+		}
+		spans := findSecrets(stream.String(), rules)
+		if len(spans) == 0 {
+			continue
+		}
+
+		text := stream.String()
+		s := 0
+		for k, i := range idx {
+			a := starts[k]
+			b := a + len(events[i].Data)
+			var data strings.Builder
+			pos := a
+			for pos < b {
+				for s < len(spans) && spans[s].end <= pos {
+					s++
+				}
+				if s == len(spans) || spans[s].start >= b {
+					data.WriteString(text[pos:b])
+					break
+				}
+				span := spans[s]
+				if span.start > pos {
+					data.WriteString(text[pos:span.start])
+					pos = span.start
+				}
+				if span.start >= a {
+					data.WriteString(redactionMark)
+					notes = append(notes, Redaction{span.rule, code, events[i].Time, span.end - span.start})
+				}
+				pos = min(span.end, b)
+			}
+			out[i].Data = data.String()
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].Offset < notes[j].Offset })
+	return out, notes
+}
+
+// redactionSidecar is the "<recording>.redactions.json" file noting each
+// redaction pass over a recording.
+type redactionSidecar struct {
+	Recording string          `json:"recording"`
+	Passes    []redactionPass `json:"passes"`
+}
+
+type redactionPass struct {
+	Date       string      `json:"date"`
+	Rules      []string    `json:"rules"`
+	Redactions []Redaction `json:"redactions"`
+}
+
+// redactionSidecarPath returns the sidecar file of a recording.
+func redactionSidecarPath(recording string) string {
+	return recording + ".redactions.json"
+}
+
+// appendRedactionSidecar records a redaction pass next to a recording.
+func appendRedactionSidecar(recording string, rules []redactRule, notes []Redaction) error {
+	path := redactionSidecarPath(recording)
+	sidecar := redactionSidecar{Recording: filepath.Base(recording)}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			return fmt.Errorf("invalid redaction sidecar %s: %v", path, err)
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range rules {
+		if !seen[r.Name] {
+			seen[r.Name] = true
+			names = append(names, r.Name)
+		}
+	}
+	sidecar.Passes = append(sidecar.Passes, redactionPass{
+		Date:       time.Now().Format(time.RFC3339),
+		Rules:      names,
+		Redactions: notes,
+	})
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// redactRecording redacts a .cast or script log into outputFile (the input
+// itself when empty) and notes the redactions in the sidecar of the output.
+//
+//	in(1): string inputFile
+//	in(2): string outputFile
+//	in(3): []redactRule rules
+//	out: ([]Redaction, error)
+func redactRecording(inputFile, outputFile string, rules []redactRule) ([]Redaction, error) {
+	if outputFile == "" {
+		outputFile = inputFile
+	}
+
+	var out bytes.Buffer
+	var notes []Redaction
+	if strings.HasSuffix(inputFile, ".cast") {
+		cast, err := loadCast(inputFile)
+		if err != nil {
+			return nil, err
+		}
+		cast.Events, notes = redactEvents(cast.Events, rules)
+		if err := cast.write(&out); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			return nil, err
+		}
+		var text string
+		text, notes = redactText(string(data), rules)
+		out.WriteString(text)
+	}
+
+	if len(notes) == 0 && outputFile == inputFile {
+		return nil, nil
+	}
+	// Write through a temporary file so a failure never leaves half a recording
+	tmp := outputFile + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", outputFile, err)
+	}
+	if err := os.Rename(tmp, outputFile); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("failed to write %s: %v", outputFile, err)
+	}
+	if err := appendRedactionSidecar(outputFile, rules, notes); err != nil {
+		return notes, err
+	}
+	return notes, nil
+}
+
+// RedactLog redacts secrets from a recording with the built-in rules and
+// user regexes, in place unless outputFile is given.
+//
+//	in(1): string inputFile .cast or script .log recording
+//	in(2): string outputFile destination; the input itself when empty
+//	in(3): []string patterns extra regexes; a "secret" named group limits the redaction to it
+//	out: error
+func RedactLog(inputFile, outputFile string, patterns []string) error {
+	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
+		return fmt.Errorf("file not found: %s", inputFile)
+	}
+	rules, err := compileRedactRules(patterns)
+	if err != nil {
+		return err
+	}
+	notes, err := redactRecording(inputFile, outputFile, rules)
+	if err != nil {
+		return err
+	}
+
+	if outputFile == "" {
+		outputFile = inputFile
+	}
+	if common.MachineOutput() {
+		if notes == nil {
+			notes = []Redaction{}
+		}
+		return common.PrintStructured("redactions", notes)
+	}
+	if len(notes) == 0 {
+		common.PrintInfoMessage(fmt.Sprintf("No secrets found in %s", inputFile))
+		return nil
+	}
+	common.PrintSuccessMessage(fmt.Sprintf("Redacted %d secret(s) into %s (noted in %s)",
+		len(notes), outputFile, redactionSidecarPath(outputFile)))
+	return nil
+}
+
+// splitAtLineBreak splits an event after its last line break, so that the
+// completed lines can be written while the rest of the line is held.
+func splitAtLineBreak(e castEvent) []castEvent {
+	i := strings.LastIndexAny(e.Data, "\r\n")
+	if i < 0 || i == len(e.Data)-1 {
+		return []castEvent{e}
+	}
+	head, tail := e, e
+	head.Data, tail.Data = e.Data[:i+1], e.Data[i+1:]
+	return []castEvent{head, tail}
+}
+
+// completeLines returns how many events, split by splitAtLineBreak, can be
+// redacted and written: those before the first event of a stream whose line
+// is not complete yet, as a secret typed or printed on it may go on.
+func completeLines(events []castEvent) int {
+	open := make(map[string]int) // first event of each incomplete line
+	for i, e := range events {
+		if e.Code != castOutput && e.Code != castInput {
+			continue
+		}
+		if _, ok := open[e.Code]; !ok {
+			open[e.Code] = i
+		}
+		if strings.HasSuffix(e.Data, "\r") || strings.HasSuffix(e.Data, "\n") {
+			delete(open, e.Code)
+		}
+	}
+	n := len(events)
+	for _, i := range open {
+		n = min(n, i)
+	}
+	return n
+}
+
+// sessionRedactRules returns the rules a native recording is redacted with:
+// the built-in ones, --record-redact regexes and the secrets of the session.
+//
+//	out: []redactRule
+func sessionRedactRules() []redactRule {
+	rules, err := compileRedactRules(containerCfg.recordRedact)
+	if err != nil {
+		common.PrintWarningMessage(err.Error())
+		rules = append([]redactRule(nil), builtinRedactRules...)
+	}
+	return append(rules, sessionSecretRules()...)
+}
+
+// noteSessionRedactions records the redactions applied while recording a
+// session in the sidecar of the recording.
+//
+//	in(1): string path  recording
+//	in(2): []redactRule rules
+//	in(3): []Redaction notes
+func noteSessionRedactions(path string, rules []redactRule, notes []Redaction) {
+	if len(notes) == 0 {
+		return
+	}
+	if err := appendRedactionSidecar(path, rules, notes); err != nil {
+		common.PrintWarningMessage(fmt.Sprintf("Failed to note the redactions: %v", err))
+		return
+	}
+	common.PrintInfoMessage(fmt.Sprintf("Redacted %d secret(s) from the recording (see %s)", len(notes), redactionSidecarPath(path)))
+}
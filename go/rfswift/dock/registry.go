@@ -0,0 +1,581 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Remote registry backends: Docker Hub API and OCI Distribution API
+ */
+
+package dock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	mediaTypeOCIIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList        = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest    = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerHubHost              = "docker.io"
+	registryRequestTimeout     = 10 * time.Second
+	insecureRegistriesEnvVar   = "RFSWIFT_INSECURE_REGISTRIES"
+	registryTagsPageSize       = 1000
+	registryMaxManifestPayload = 4 << 20
+)
+
+// Registry is a remote image registry RF Swift can query for the tags of a
+// repository. Both backends return the same Tag shape: the digest of the tag
+// as pulled (index digest for multi-arch tags), the push or build date and the
+// compressed image size for the requested architecture.
+type Registry interface {
+	Name() string
+	ListTags(repo string, architecture string) ([]Tag, error)
+	GetTag(repo, name, architecture string) (Tag, bool, error)
+}
+
+// errManifestNotFound is returned when the registry has no manifest for a
+// tag or digest.
+var errManifestNotFound = errors.New("manifest not found")
+
+// splitRegistryHost splits a repository reference into its registry host and
+// repository path, following the docker reference rules: the first component
+// is a host only if it contains a '.' or ':' or is "localhost".
+//
+//	in(1): string repo repository reference (e.g. "harbor.lab:8443/rf/rfswift_resolute")
+//	out: string registry host, "docker.io" when none is given
+//	out: string repository path on that registry
+func splitRegistryHost(repo string) (string, string) {
+	repo = strings.TrimPrefix(strings.TrimPrefix(repo, "https://"), "http://")
+	i := strings.Index(repo, "/")
+	if i == -1 {
+		return dockerHubHost, repo
+	}
+	first := repo[:i]
+	if first != "localhost" && !strings.ContainsAny(first, ".:") {
+		return dockerHubHost, repo
+	}
+	if isDockerHubHost(first) {
+		return dockerHubHost, repo[i+1:]
+	}
+	return first, repo[i+1:]
+}
+
+// isDockerHubHost reports whether host is one of Docker Hub's registry names.
+func isDockerHubHost(host string) bool {
+	switch host {
+	case dockerHubHost, "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return true
+	}
+	return false
+}
+
+// registryForRepo returns the backend serving repo together with the
+// repository path to pass to it.
+//
+//	in(1): string repo repository reference, optionally prefixed with a registry host
+//	out: Registry backend for the host
+//	out: string repository path on that registry
+func registryForRepo(repo string) (Registry, string) {
+	host, path := splitRegistryHost(repo)
+	if host == dockerHubHost {
+		return &dockerHubRegistry{client: &http.Client{Timeout: registryRequestTimeout}}, path
+	}
+	return newOCIRegistry(host), path
+}
+
+// registryUsesHTTP reports whether a registry must be reached over plain HTTP:
+// loopback registries (a local registry:2) and hosts listed in
+// RFSWIFT_INSECURE_REGISTRIES (comma-separated).
+func registryUsesHTTP(host string) bool {
+	for _, h := range strings.Split(os.Getenv(insecureRegistriesEnvVar), ",") {
+		if strings.TrimSpace(h) == host {
+			return true
+		}
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// splitImageRef splits an image reference into repository and tag. Unlike a
+// plain split on ':', a registry port ("host:5000/repo") is kept in the
+// repository. The tag defaults to "latest".
+//
+//	in(1): string ref image reference
+//	out: string repository
+//	out: string tag
+func splitImageRef(ref string) (string, string) {
+	if i := strings.Index(ref, "@"); i != -1 {
+		ref = ref[:i]
+	}
+	colon := strings.LastIndex(ref, ":")
+	if colon == -1 || colon < strings.LastIndex(ref, "/") {
+		return ref, "latest"
+	}
+	return ref[:colon], ref[colon+1:]
+}
+
+// ---------------------------------------------------------------------------
+// Docker Hub backend
+// ---------------------------------------------------------------------------
+
+// dockerHubRegistry lists tags through the hub.docker.com repository API,
+// which returns digests, push dates and sizes in a single paginated call.
+type dockerHubRegistry struct {
+	client *http.Client
+}
+
+func (h *dockerHubRegistry) Name() string {
+	return "Docker Hub"
+}
+
+// ListTags fetches all pages of tags from a Docker Hub repository, keeping
+// multi-arch (OCI index) tags built for architecture.
+//
+//	in(1): string repo Docker Hub repository (e.g. "penthertz/rfswift_resolute")
+//	in(2): string architecture target architecture to filter tags by
+//	out: []Tag matching tags in API order
+//	out: error non-nil if any HTTP request or JSON parsing step fails
+func (h *dockerHubRegistry) ListTags(repo string, architecture string) ([]Tag, error) {
+	var tags []Tag
+	url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/?page_size=100", repo)
+
+	for url != "" {
+		resp, err := h.client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close() // Close immediately after reading, not deferred in loop
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get tags: %s", resp.Status)
+		}
+
+		var response DockerHubResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+
+		for _, hubTag := range response.Results {
+			if tag, ok := hubTagToTag(hubTag, architecture); ok {
+				tags = append(tags, tag)
+			}
+		}
+
+		url = response.Next
+	}
+
+	return tags, nil
+}
+
+// GetTag fetches a single tag of a Docker Hub repository.
+//
+//	in(1): string repo Docker Hub repository (e.g. "penthertz/rfswift_resolute")
+//	in(2): string name tag name
+//	in(3): string architecture target architecture
+//	out: Tag matching tag
+//	out: bool false if the tag does not exist or is not built for architecture
+//	out: error non-nil if the HTTP request or JSON parsing fails
+func (h *dockerHubRegistry) GetTag(repo, name, architecture string) (Tag, bool, error) {
+	resp, err := h.client.Get(fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/tags/%s", repo, url.PathEscape(name)))
+	if err != nil {
+		return Tag{}, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Tag{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Tag{}, false, fmt.Errorf("failed to get tag %s: %s", name, resp.Status)
+	}
+
+	var hubTag DockerHubTag
+	if err := json.NewDecoder(resp.Body).Decode(&hubTag); err != nil {
+		return Tag{}, false, err
+	}
+	tag, ok := hubTagToTag(hubTag, architecture)
+	return tag, ok, nil
+}
+
+// hubTagToTag converts a Docker Hub tag entry, keeping only multi-arch (OCI
+// index) tags built for architecture.
+//
+//	in(1): DockerHubTag hubTag
+//	in(2): string architecture target architecture
+//	out: Tag converted tag
+//	out: bool false if the tag is filtered out
+func hubTagToTag(hubTag DockerHubTag, architecture string) (Tag, bool) {
+	if strings.HasPrefix(hubTag.Name, "cache_") {
+		return Tag{}, false
+	}
+
+	if hubTag.MediaType != mediaTypeOCIIndex {
+		return Tag{}, false
+	}
+
+	lastPushed, err := time.Parse(time.RFC3339, hubTag.LastUpdated)
+	if err != nil {
+		log.Printf("Warning: Could not parse date for tag %s: %v", hubTag.Name, err)
+		return Tag{}, false
+	}
+
+	tagArch := determineArchitectureFromTag(hubTag.Name, architecture)
+	if tagArch != architecture {
+		return Tag{}, false
+	}
+
+	return Tag{
+		Name:          hubTag.Name,
+		TagLastPushed: lastPushed,
+		Images:        []Image{{Architecture: tagArch, Digest: hubTag.Digest}},
+		FullSize:      hubTag.FullSize,
+	}, true
+}
+
+// ---------------------------------------------------------------------------
+// OCI Distribution backend
+// ---------------------------------------------------------------------------
+
+// ociRegistry lists tags of any registry implementing the OCI Distribution
+// API (registry:2, Harbor, GitLab, Nexus, ...). Authentication follows the
+// registry's WWW-Authenticate challenge with credentials from the docker
+// credential store.
+type ociRegistry struct {
+	host    string
+	baseURL string
+	client  *http.Client
+	creds   registryCredentials
+	auth    string // Authorization header value once a challenge was answered
+}
+
+// ociDescriptor is a content descriptor inside an OCI manifest or index.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant,omitempty"`
+	} `json:"platform,omitempty"`
+}
+
+// ociManifest covers both image manifests (Config/Layers) and indexes or
+// docker manifest lists (Manifests).
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Config    ociDescriptor   `json:"config"`
+	Layers    []ociDescriptor `json:"layers"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociImageConfig holds the config blob fields RF Swift reads.
+type ociImageConfig struct {
+	Created      time.Time `json:"created"`
+	Architecture string    `json:"architecture"`
+}
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// newOCIRegistry creates an OCI Distribution client for host, loading its
+// credentials from the docker credential store.
+//
+//	in(1): string host registry host, optionally with a port
+//	out: *ociRegistry
+func newOCIRegistry(host string) *ociRegistry {
+	scheme := "https"
+	if registryUsesHTTP(host) {
+		scheme = "http"
+	}
+	return &ociRegistry{
+		host:    host,
+		baseURL: scheme + "://" + host,
+		client:  &http.Client{Timeout: registryRequestTimeout},
+		creds:   lookupRegistryCredentials(host),
+	}
+}
+
+func (r *ociRegistry) Name() string {
+	return r.host
+}
+
+// ListTags lists repo's tags and resolves each one built for architecture to
+// its digest, creation date (from the image config blob) and size. Tags that
+// fail to resolve are skipped with a warning so one broken tag does not hide
+// the rest of the repository.
+//
+//	in(1): string repo repository path on the registry
+//	in(2): string architecture target architecture
+//	out: []Tag resolved tags
+//	out: error non-nil if the tag list cannot be fetched
+func (r *ociRegistry) ListTags(repo string, architecture string) ([]Tag, error) {
+	names, err := r.tagNames(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	for _, name := range names {
+		if strings.HasPrefix(name, "cache_") {
+			continue
+		}
+		if determineArchitectureFromTag(name, architecture) != architecture {
+			continue
+		}
+
+		tag, found, err := r.resolveTag(repo, name, architecture)
+		if err != nil {
+			log.Printf("Warning: Could not resolve tag %s: %v", name, err)
+			continue
+		}
+		if found {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// GetTag resolves a single tag from its manifest, without listing the
+// repository.
+//
+//	in(1): string repo repository path on the registry
+//	in(2): string name tag name
+//	in(3): string architecture target architecture
+//	out: Tag resolved tag
+//	out: bool false if the tag does not exist or has no image for architecture
+//	out: error non-nil on registry or decoding errors
+func (r *ociRegistry) GetTag(repo, name, architecture string) (Tag, bool, error) {
+	if strings.HasPrefix(name, "cache_") {
+		return Tag{}, false, nil
+	}
+	tag, found, err := r.resolveTag(repo, name, architecture)
+	if errors.Is(err, errManifestNotFound) {
+		return Tag{}, false, nil
+	}
+	return tag, found, err
+}
+
+// tagNames returns every tag of repo, following Link pagination.
+func (r *ociRegistry) tagNames(repo string) ([]string, error) {
+	var names []string
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", r.baseURL, repo, registryTagsPageSize)
+
+	for next != "" {
+		resp, err := r.get(next, "application/json", repo)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, registryMaxManifestPayload))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to get tags from %s: %s", r.host, resp.Status)
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("invalid tag list from %s: %v", r.host, err)
+		}
+		names = append(names, page.Tags...)
+
+		next = ""
+		if m := linkNextPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			ref, err := url.Parse(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pagination link from %s: %v", r.host, err)
+			}
+			base, _ := url.Parse(r.baseURL)
+			next = base.ResolveReference(ref).String()
+		}
+	}
+	return names, nil
+}
+
+// resolveTag fetches the manifest of a tag, selects the architecture entry
+// when it is an index, and reads the config blob for the creation date.
+//
+//	in(1): string repo repository path
+//	in(2): string name tag name
+//	in(3): string architecture target architecture
+//	out: Tag resolved tag
+//	out: bool false if the tag has no image for architecture
+//	out: error non-nil on registry or decoding errors
+func (r *ociRegistry) resolveTag(repo, name, architecture string) (Tag, bool, error) {
+	top, digest, err := r.manifest(repo, name)
+	if err != nil {
+		return Tag{}, false, err
+	}
+
+	image := top
+	if len(top.Manifests) > 0 {
+		var selected *ociDescriptor
+		for i, m := range top.Manifests {
+			if m.Platform != nil && m.Platform.Architecture == architecture && (m.Platform.OS == "" || m.Platform.OS == "linux") {
+				selected = &top.Manifests[i]
+				break
+			}
+		}
+		if selected == nil {
+			return Tag{}, false, nil
+		}
+		if image, _, err = r.manifest(repo, selected.Digest); err != nil {
+			return Tag{}, false, err
+		}
+	}
+
+	cfg, err := r.imageConfig(repo, image.Config.Digest)
+	if err != nil {
+		return Tag{}, false, err
+	}
+	if len(top.Manifests) == 0 && cfg.Architecture != "" && cfg.Architecture != architecture {
+		return Tag{}, false, nil
+	}
+
+	size := image.Config.Size
+	for _, l := range image.Layers {
+		size += l.Size
+	}
+
+	return Tag{
+		Name:          name,
+		TagLastPushed: cfg.Created,
+		Images:        []Image{{Architecture: architecture, Digest: digest}},
+		FullSize:      size,
+	}, true, nil
+}
+
+// manifest fetches a manifest or index by tag or digest and returns it with
+// its content digest.
+func (r *ociRegistry) manifest(repo, reference string) (ociManifest, string, error) {
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerManifest}, ", ")
+	resp, err := r.get(fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL, repo, reference), accept, repo)
+	if err != nil {
+		return ociManifest{}, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ociManifest{}, "", fmt.Errorf("manifest %s: %w", reference, errManifestNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return ociManifest{}, "", fmt.Errorf("manifest %s: %s", reference, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, registryMaxManifestPayload))
+	if err != nil {
+		return ociManifest{}, "", err
+	}
+
+	var m ociManifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return ociManifest{}, "", fmt.Errorf("invalid manifest %s: %v", reference, err)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return m, digest, nil
+}
+
+// imageConfig fetches and decodes an image config blob.
+func (r *ociRegistry) imageConfig(repo, digest string) (ociImageConfig, error) {
+	var cfg ociImageConfig
+	if digest == "" {
+		return cfg, fmt.Errorf("manifest has no config")
+	}
+	resp, err := r.get(fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL, repo, digest), "*/*", repo)
+	if err != nil {
+		return cfg, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cfg, fmt.Errorf("config blob %s: %s", digest, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, registryMaxManifestPayload)).Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config blob %s: %v", digest, err)
+	}
+	return cfg, nil
+}
+
+// get performs an authenticated GET. On a 401 it answers the registry's
+// challenge (bearer token or basic auth) once and retries the request.
+func (r *ociRegistry) get(target, accept, repo string) (*http.Response, error) {
+	resp, err := r.doGet(target, accept)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	auth, err := r.authorize(challenge, repo)
+	if err != nil {
+		return nil, err
+	}
+	r.auth = auth
+	return r.doGet(target, accept)
+}
+
+func (r *ociRegistry) doGet(target, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	if r.auth != "" {
+		req.Header.Set("Authorization", r.auth)
+	}
+	return r.client.Do(req)
+}
+
+// sortAndDedupeTags orders tags newest first and keeps the most recent entry
+// for each tag name.
+//
+//	in(1): []Tag tags tags as returned by a backend
+//	out: []Tag deduplicated tags sorted by push date descending
+func sortAndDedupeTags(tags []Tag) []Tag {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].TagLastPushed.After(tags[j].TagLastPushed)
+	})
+
+	seen := make(map[string]bool)
+	var result []Tag
+	for _, tag := range tags {
+		if seen[tag.Name] {
+			continue
+		}
+		seen[tag.Name] = true
+		result = append(result, tag)
+	}
+	return result
+}
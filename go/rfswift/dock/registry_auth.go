@@ -0,0 +1,307 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Registry credentials from the docker credential store and token auth
+ */
+
+package dock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/moby/api/types/registry"
+)
+
+// dockerHubAuthKey is the key Docker Hub credentials are stored under.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// registryCredentials are the credentials stored for one registry host.
+type registryCredentials struct {
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+func (c registryCredentials) empty() bool {
+	return c.Username == "" && c.Password == "" && c.IdentityToken == ""
+}
+
+// dockerAuthFile mirrors the parts of ~/.docker/config.json (and podman's
+// auth.json, which uses the same layout) that hold credentials.
+type dockerAuthFile struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// registryAuthFiles returns the credential files to search, docker first.
+func registryAuthFiles() []string {
+	homeDir := os.Getenv("HOME")
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		if u, err := user.Lookup(sudoUser); err == nil {
+			homeDir = u.HomeDir
+		}
+	}
+
+	var files []string
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		files = append(files, filepath.Join(dir, "config.json"))
+	} else {
+		files = append(files, filepath.Join(homeDir, ".docker", "config.json"))
+	}
+	if f := os.Getenv("REGISTRY_AUTH_FILE"); f != "" {
+		files = append(files, f)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		files = append(files, filepath.Join(dir, "containers", "auth.json"))
+	}
+	return files
+}
+
+// lookupRegistryCredentials finds credentials for host in the docker
+// credential store: per-registry credential helpers first, then inline
+// "auths" entries, then the default credsStore. Missing credentials are not
+// an error; anonymous access is attempted instead.
+//
+//	in(1): string host registry host ("docker.io" for Docker Hub)
+//	out: registryCredentials, empty when none are stored
+func lookupRegistryCredentials(host string) registryCredentials {
+	keys := []string{host, "https://" + host, "http://" + host}
+	if host == dockerHubHost {
+		keys = []string{dockerHubAuthKey, "index.docker.io", dockerHubHost}
+	}
+
+	for _, path := range registryAuthFiles() {
+		var cfg dockerAuthFile
+		if err := loadJSON(path, &cfg); err != nil {
+			continue
+		}
+
+		for _, key := range keys {
+			if helper, ok := cfg.CredHelpers[key]; ok {
+				if creds, err := credentialsFromHelper(helper, key); err == nil {
+					return creds
+				}
+			}
+		}
+
+		for _, key := range keys {
+			entry, ok := cfg.Auths[key]
+			if !ok {
+				continue
+			}
+			creds := registryCredentials{IdentityToken: entry.IdentityToken}
+			if decoded, err := base64.StdEncoding.DecodeString(entry.Auth); err == nil {
+				if user, pass, ok := strings.Cut(string(decoded), ":"); ok {
+					creds.Username, creds.Password = user, pass
+				}
+			}
+			if !creds.empty() {
+				return creds
+			}
+		}
+
+		if cfg.CredsStore != "" {
+			for _, key := range keys {
+				if creds, err := credentialsFromHelper(cfg.CredsStore, key); err == nil {
+					return creds
+				}
+			}
+		}
+	}
+	return registryCredentials{}
+}
+
+// credentialsFromHelper runs docker-credential-<helper> get for serverURL.
+//
+//	in(1): string helper credential helper suffix (e.g. "desktop", "pass")
+//	in(2): string serverURL key the credentials are stored under
+//	out: registryCredentials
+//	out: error if the helper is missing or has no entry for serverURL
+func credentialsFromHelper(helper, serverURL string) (registryCredentials, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	out, err := cmd.Output()
+	if err != nil {
+		return registryCredentials{}, err
+	}
+
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return registryCredentials{}, err
+	}
+	// Helpers return "<token>" as the username for identity tokens
+	if resp.Username == "<token>" {
+		return registryCredentials{IdentityToken: resp.Secret}, nil
+	}
+	return registryCredentials{Username: resp.Username, Password: resp.Secret}, nil
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example/token",service="registry",scope="repository:a/b:pull"`.
+//
+//	in(1): string header WWW-Authenticate value
+//	out: string lower-cased scheme ("bearer", "basic")
+//	out: map[string]string challenge parameters
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end == -1 {
+				value, rest = after[1:], ""
+			} else {
+				value, rest = after[1:end+1], after[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(after, ",")
+		}
+		params[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return strings.ToLower(scheme), params
+}
+
+// authorize answers a registry challenge and returns the Authorization header
+// to send: basic credentials as-is, or a bearer token fetched from the realm
+// for the pull scope of repo.
+//
+//	in(1): string challenge WWW-Authenticate header of the 401 response
+//	in(2): string repo repository path, used when the challenge has no scope
+//	out: string Authorization header value
+//	out: error if the token server refuses the credentials
+func (r *ociRegistry) authorize(challenge, repo string) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+
+	switch scheme {
+	case "basic":
+		if r.creds.Username == "" {
+			return "", fmt.Errorf("%s requires credentials (run: docker login %s)", r.host, r.host)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(r.creds.Username+":"+r.creds.Password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("%s: unsupported authentication challenge %q", r.host, challenge)
+	}
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("%s: bearer challenge without realm", r.host)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repo)
+	}
+
+	var req *http.Request
+	var err error
+	if r.creds.IdentityToken != "" {
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {r.creds.IdentityToken},
+			"service":       {params["service"]},
+			"scope":         {scope},
+			"client_id":     {"rfswift"},
+		}
+		req, err = http.NewRequest(http.MethodPost, realm, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		query := url.Values{"scope": {scope}}
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		sep := "?"
+		if strings.Contains(realm, "?") {
+			sep = "&"
+		}
+		req, err = http.NewRequest(http.MethodGet, realm+sep+query.Encode(), nil)
+		if err == nil && r.creds.Username != "" {
+			req.SetBasicAuth(r.creds.Username, r.creds.Password)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request to %s failed: %v", realm, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("token request to %s failed: %s %s", realm, resp.Status, bytes.TrimSpace(body))
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response from %s: %v", realm, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("token response from %s has no token", realm)
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// registryAuthFor returns the encoded X-Registry-Auth value the engine needs
+// to pull ref from a private registry, or "" for anonymous pulls.
+//
+//	in(1): string ref image reference
+//	out: string base64url-encoded registry.AuthConfig
+func registryAuthFor(ref string) string {
+	repo, _ := splitImageRef(ref)
+	host, _ := splitRegistryHost(repo)
+	creds := lookupRegistryCredentials(host)
+	if creds.empty() {
+		return ""
+	}
+
+	serverAddress := host
+	if host == dockerHubHost {
+		serverAddress = dockerHubAuthKey
+	}
+	encoded, err := json.Marshal(registry.AuthConfig{
+		Username:      creds.Username,
+		Password:      creds.Password,
+		IdentityToken: creds.IdentityToken,
+		ServerAddress: serverAddress,
+	})
+	if err != nil {
+		return ""
+	}
+	return base64.URLEncoding.EncodeToString(encoded)
+}
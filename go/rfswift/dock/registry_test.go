@@ -0,0 +1,361 @@
+/* This code is part of RF Swift by @Penthertz
+*  Tests for the OCI Distribution registry backend, against an in-process
+*  stand-in for registry:2 with token authentication.
+ */
+
+package dock
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRegistry serves a minimal OCI Distribution API for one repository.
+type fakeRegistry struct {
+	repo      string
+	tags      []string
+	manifests map[string][]byte // by tag and by digest
+	blobs     map[string][]byte
+	user      string
+	pass      string
+	token     string
+	listCalls int // tags/list requests served
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (f *fakeRegistry) addBlob(b []byte) string {
+	d := digestOf(b)
+	f.blobs[d] = b
+	return d
+}
+
+func (f *fakeRegistry) addManifest(ref string, b []byte) string {
+	d := digestOf(b)
+	f.manifests[d] = b
+	if ref != "" {
+		f.manifests[ref] = b
+	}
+	return d
+}
+
+// addImage publishes an image manifest for arch and returns its digest.
+func (f *fakeRegistry) addImage(arch string, created time.Time, layerSize int64) string {
+	config, _ := json.Marshal(map[string]interface{}{
+		"created":      created.Format(time.RFC3339),
+		"architecture": arch,
+		"os":           "linux",
+	})
+	configDigest := f.addBlob(config)
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIManifest,
+		"config":        map[string]interface{}{"mediaType": "application/vnd.oci.image.config.v1+json", "digest": configDigest, "size": len(config)},
+		"layers":        []map[string]interface{}{{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:layer" + arch, "size": layerSize}},
+	})
+	return f.addManifest("", manifest)
+}
+
+// addIndex publishes a multi-arch index under tag and returns its digest.
+func (f *fakeRegistry) addIndex(tag string, created time.Time, archs ...string) string {
+	var entries []map[string]interface{}
+	for _, arch := range archs {
+		d := f.addImage(arch, created, 1000)
+		entries = append(entries, map[string]interface{}{
+			"mediaType": mediaTypeOCIManifest,
+			"digest":    d,
+			"size":      100,
+			"platform":  map[string]string{"architecture": arch, "os": "linux"},
+		})
+	}
+	index, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     mediaTypeOCIIndex,
+		"manifests":     entries,
+	})
+	f.tags = append(f.tags, tag)
+	return f.addManifest(tag, index)
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != f.user || pass != f.pass {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:"+f.repo+":pull" {
+			http.Error(w, "bad scope", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": f.token})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake-registry"`, r.Host))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefix := "/v2/" + f.repo + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	rest := strings.TrimPrefix(r.URL.Path, prefix)
+
+	switch {
+	case rest == "tags/list":
+		f.listCalls++
+		// Two tags per page to exercise Link pagination
+		last := r.URL.Query().Get("last")
+		start := 0
+		for i, t := range f.tags {
+			if t == last {
+				start = i + 1
+			}
+		}
+		end := start + 2
+		if end < len(f.tags) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=2&last=%s>; rel="next"`, f.repo, f.tags[end-1]))
+		} else {
+			end = len(f.tags)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": f.repo, "tags": f.tags[start:end]})
+	case strings.HasPrefix(rest, "manifests/"):
+		b, ok := f.manifests[strings.TrimPrefix(rest, "manifests/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var m struct {
+			MediaType string `json:"mediaType"`
+		}
+		json.Unmarshal(b, &m)
+		w.Header().Set("Content-Type", m.MediaType)
+		w.Header().Set("Docker-Content-Digest", digestOf(b))
+		w.Write(b)
+	case strings.HasPrefix(rest, "blobs/"):
+		b, ok := f.blobs[strings.TrimPrefix(rest, "blobs/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeDockerConfig(t *testing.T, host, user, pass string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := map[string]interface{}{
+		"auths": map[string]interface{}{
+			host: map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))},
+		},
+	}
+	b, _ := json.Marshal(cfg)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("REGISTRY_AUTH_FILE", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+}
+
+func TestOCIRegistryListTags(t *testing.T) {
+	fake := &fakeRegistry{
+		repo:      "rf/rfswift_resolute",
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+		user:      "ci",
+		pass:      "s3cret",
+		token:     "tok-123",
+	}
+	latest := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	older := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	latestDigest := fake.addIndex("sdr_full_amd64", latest, "amd64", "arm64")
+	versionDigest := fake.addIndex("sdr_full_1.2.0_amd64", older, "amd64")
+	fake.addIndex("sdr_full_arm64", latest, "arm64")
+	fake.addIndex("cache_sdr_full_amd64", latest, "amd64")
+	fake.addIndex("broken_amd64", latest, "arm64") // index without an amd64 entry
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	writeDockerConfig(t, host, fake.user, fake.pass)
+
+	reg, path := registryForRepo(host + "/" + fake.repo)
+	if reg.Name() != host || path != fake.repo {
+		t.Fatalf("registryForRepo = (%s, %s), want (%s, %s)", reg.Name(), path, host, fake.repo)
+	}
+
+	tags, err := reg.ListTags(path, "amd64")
+	if err != nil {
+		t.Fatalf("ListTags error: %v", err)
+	}
+	tags = sortAndDedupeTags(tags)
+
+	if len(tags) != 2 {
+		t.Fatalf("ListTags returned %d tags (%v), want 2", len(tags), tags)
+	}
+	want := []struct {
+		name   string
+		digest string
+		date   time.Time
+	}{
+		{"sdr_full_amd64", latestDigest, latest},
+		{"sdr_full_1.2.0_amd64", versionDigest, older},
+	}
+	for i, w := range want {
+		got := tags[i]
+		if got.Name != w.name || got.Images[0].Digest != w.digest || !got.TagLastPushed.Equal(w.date) {
+			t.Errorf("tag %d = {%s %s %s}, want {%s %s %s}", i, got.Name, got.Images[0].Digest, got.TagLastPushed, w.name, w.digest, w.date)
+		}
+		if got.FullSize <= 1000 {
+			t.Errorf("tag %s size = %d, want config + layer size", got.Name, got.FullSize)
+		}
+	}
+
+	// Versions are resolved through the same backend as the official Hub repos
+	versions, err := GetRemoteVersionsForRepo(host+"/"+fake.repo, "amd64")
+	if err != nil {
+		t.Fatalf("GetRemoteVersionsForRepo error: %v", err)
+	}
+	if got := FormatVersionsString(versions["sdr_full"], 0); got != "latest, 1.2.0" {
+		t.Errorf("versions[sdr_full] = %q, want %q", got, "latest, 1.2.0")
+	}
+}
+
+func TestFindRemoteTagOCI(t *testing.T) {
+	fake := &fakeRegistry{
+		repo:      "rf/rfswift_resolute",
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+		user:      "ci",
+		pass:      "s3cret",
+		token:     "tok-123",
+	}
+	created := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	digest := fake.addIndex("sdr_full_amd64", created, "amd64", "arm64")
+	fake.addIndex("wifi_amd64", created, "arm64") // index without an amd64 entry
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	writeDockerConfig(t, host, fake.user, fake.pass)
+	repo := host + "/" + fake.repo
+
+	tag, err := findRemoteTag(repo, "sdr_full", "amd64")
+	if err != nil {
+		t.Fatalf("findRemoteTag(sdr_full) error: %v", err)
+	}
+	if tag.Name != "sdr_full_amd64" || tag.Images[0].Digest != digest || !tag.TagLastPushed.Equal(created) {
+		t.Errorf("findRemoteTag(sdr_full) = {%s %s %s}, want {sdr_full_amd64 %s %s}", tag.Name, tag.Images[0].Digest, tag.TagLastPushed, digest, created)
+	}
+
+	for _, name := range []string{"missing", "wifi"} {
+		if _, err := findRemoteTag(repo, name, "amd64"); err == nil || err.Error() != "tag not found" {
+			t.Errorf("findRemoteTag(%s) error = %v, want tag not found", name, err)
+		}
+	}
+	if fake.listCalls != 0 {
+		t.Errorf("findRemoteTag listed the repository %d times, want direct manifest lookups", fake.listCalls)
+	}
+}
+
+func TestOCIRegistryRejectsBadCredentials(t *testing.T) {
+	fake := &fakeRegistry{
+		repo:      "rf/rfswift_resolute",
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+		user:      "ci",
+		pass:      "s3cret",
+		token:     "tok-123",
+	}
+	fake.addIndex("sdr_full_amd64", time.Now(), "amd64")
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	writeDockerConfig(t, host, "ci", "wrong")
+
+	reg, path := registryForRepo(host + "/" + fake.repo)
+	if _, err := reg.ListTags(path, "amd64"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ListTags with bad credentials error = %v, want 401 from token server", err)
+	}
+}
+
+func TestSplitRegistryHost(t *testing.T) {
+	tests := []struct {
+		repo, host, path string
+	}{
+		{"penthertz/rfswift_resolute", "docker.io", "penthertz/rfswift_resolute"},
+		{"docker.io/penthertz/rfswift_resolute", "docker.io", "penthertz/rfswift_resolute"},
+		{"index.docker.io/penthertz/rfswift", "docker.io", "penthertz/rfswift"},
+		{"harbor.lab.local/rf/rfswift_resolute", "harbor.lab.local", "rf/rfswift_resolute"},
+		{"localhost:5000/rfswift", "localhost:5000", "rfswift"},
+		{"localhost/rfswift", "localhost", "rfswift"},
+		{"ubuntu", "docker.io", "ubuntu"},
+	}
+	for _, tt := range tests {
+		host, path := splitRegistryHost(tt.repo)
+		if host != tt.host || path != tt.path {
+			t.Errorf("splitRegistryHost(%q) = (%q, %q), want (%q, %q)", tt.repo, host, path, tt.host, tt.path)
+		}
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	tests := []struct {
+		ref, repo, tag string
+	}{
+		{"penthertz/rfswift_resolute:sdr_full", "penthertz/rfswift_resolute", "sdr_full"},
+		{"localhost:5000/rfswift:sdr_full_amd64", "localhost:5000/rfswift", "sdr_full_amd64"},
+		{"localhost:5000/rfswift", "localhost:5000/rfswift", "latest"},
+		{"ubuntu", "ubuntu", "latest"},
+		{"harbor.lab/rf/x:t@sha256:abc", "harbor.lab/rf/x", "t"},
+	}
+	for _, tt := range tests {
+		repo, tag := splitImageRef(tt.ref)
+		if repo != tt.repo || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = (%q, %q), want (%q, %q)", tt.ref, repo, tag, tt.repo, tt.tag)
+		}
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.example/token?x=1,2",service="registry.example",scope="repository:a/b:pull,push"`)
+	if scheme != "bearer" {
+		t.Errorf("scheme = %q, want bearer", scheme)
+	}
+	want := map[string]string{
+		"realm":   "https://auth.example/token?x=1,2",
+		"service": "registry.example",
+		"scope":   "repository:a/b:pull,push",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+		}
+	}
+
+	if scheme, params := parseAuthChallenge(`Basic realm=Registry`); scheme != "basic" || params["realm"] != "Registry" {
+		t.Errorf("parseAuthChallenge(basic) = (%q, %v)", scheme, params)
+	}
+}
@@ -37,6 +37,7 @@ type ReportArtifact struct {
 	Name     string
 	Size     string
 	Modified string
+	ModTime  time.Time
 	Category string // "recording", "capture", "log", "config", "other"
 }
 
@@ -65,6 +66,7 @@ type ReportData struct {
 	WorkspacePath string
 
 	// Content
+	Timeline   []TimelineEntry
 	Recordings []ReportArtifact
 	History    []string
 	Artifacts  []ReportArtifact
@@ -148,7 +150,10 @@ func GenerateReport(containerName string, format ReportFormat, outputPath string
 
 	// Extract shell history from the container
 	common.PrintInfoMessage("Extracting shell history...")
-	data.History = extractShellHistory(ctx, containerName)
+	history := extractShellHistory(ctx, containerName)
+	for _, h := range history {
+		data.History = append(data.History, h.Command)
+	}
 
 	// Inventory workspace artifacts
 	if workspacePath != "" {
@@ -156,6 +161,9 @@ func GenerateReport(containerName string, format ReportFormat, outputPath string
 		data.Artifacts = collectArtifacts(workspacePath)
 	}
 
+	// Merge everything with a time into one chronological view
+	data.Timeline = buildTimeline(history, data.Recordings, data.Artifacts)
+
 	// Generate the report
 	if outputPath == "" {
 		ext := ".md"
@@ -248,6 +256,7 @@ func findRecordingsInDir(dir string) []ReportArtifact {
 				Name:     info.Name(),
 				Size:     formatSize(info.Size()),
 				Modified: info.ModTime().Format("2006-01-02 15:04"),
+				ModTime:  info.ModTime(),
 				Category: "recording",
 			})
 		}
@@ -257,8 +266,8 @@ func findRecordingsInDir(dir string) []ReportArtifact {
 }
 
 // extractShellHistory reads shell history from the container's filesystem.
-func extractShellHistory(ctx context.Context, containerName string) []string {
-	var history []string
+func extractShellHistory(ctx context.Context, containerName string) []historyEntry {
+	var history []historyEntry
 
 	// Try to read history files from the container using docker/podman cp
 	historyFiles := []string{
@@ -282,15 +291,7 @@ func extractShellHistory(ctx context.Context, containerName string) []string {
 		if err != nil {
 			continue
 		}
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			// Skip zsh history metadata lines (: timestamp:0;)
-			if line == "" || strings.HasPrefix(line, ": ") {
-				continue
-			}
-			history = append(history, line)
-		}
+		history = parseShellHistory(string(output))
 		if len(history) > 0 {
 			break // Got history from one file, done
 		}
@@ -319,6 +320,7 @@ func collectArtifacts(workspacePath string) []ReportArtifact {
 			Name:     info.Name(),
 			Size:     formatSize(info.Size()),
 			Modified: info.ModTime().Format("2006-01-02 15:04"),
+			ModTime:  info.ModTime(),
 			Category: categorizeFile(info.Name()),
 		})
 		return nil
@@ -425,6 +427,17 @@ const markdownTemplate = `# {{.Title}}
 ` + "```" + `
 {{end}}
 
+## Timeline
+
+{{if .Timeline}}
+| Time | Source | Event |
+|------|--------|-------|
+{{range .Timeline}}| {{.When}} | {{.Source}} | {{mdcell .Event}} |
+{{end}}
+{{else}}
+_No timestamped activity found. Enable zsh EXTENDED_HISTORY or bash HISTTIMEFORMAT to place commands on the timeline._
+{{end}}
+
 ## Session Recordings
 
 {{if .Recordings}}
@@ -472,7 +485,8 @@ _Add your assessment notes, findings, and observations below._
 
 func writeMarkdownReport(data ReportData, outputPath string) error {
 	funcMap := template.FuncMap{
-		"inc": func(i int) int { return i + 1 },
+		"inc":    func(i int) int { return i + 1 },
+		"mdcell": markdownCell,
 	}
 	tmpl, err := template.New("report").Funcs(funcMap).Parse(markdownTemplate)
 	if err != nil {
@@ -518,6 +532,10 @@ const htmlTemplateStr = `<!DOCTYPE html>
   .badge-image { background: #fce7f3; color: #9d174d; }
   .badge-other { background: #f1f5f9; color: #475569; }
   .badge-recording { background: #ccfbf1; color: #065f46; }
+  .badge-command { background: #1e293b; color: #e2e8f0; }
+  .badge-session { background: #ccfbf1; color: #065f46; }
+  .badge-artifact { background: #dbeafe; color: #1e40af; }
+  code.cmd { white-space: pre-wrap; }
   .notes { background: var(--card); border: 2px dashed var(--border); border-radius: 8px; padding: 1.5rem; margin: 1rem 0; min-height: 100px; }
   .footer { text-align: center; color: var(--muted); font-size: 0.8rem; margin-top: 3rem; padding-top: 1rem; border-top: 1px solid var(--border); }
   @media print { body { padding: 0; } .footer { page-break-before: avoid; } }
@@ -554,6 +572,14 @@ const htmlTemplateStr = `<!DOCTYPE html>
 
 {{if .Bindings}}<h3>Volume Bindings</h3><pre>{{.Bindings}}</pre>{{end}}
 
+<h2>Timeline</h2>
+{{if .Timeline}}
+<table>
+<tr><th style="width:20%">Time</th><th style="width:12%">Source</th><th>Event</th></tr>
+{{range .Timeline}}<tr><td>{{.When}}</td><td><span class="badge badge-{{.Source}}">{{.Source}}</span></td><td>{{if eq .Source "command"}}<code class="cmd">{{.Event}}</code>{{else}}{{.Event}}{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p><em>No timestamped activity found.</em></p>{{end}}
+
 <h2>Session Recordings</h2>
 {{if .Recordings}}
 <table>
@@ -605,43 +631,3 @@ func writeHTMLReport(data ReportData, outputPath string) error {
 
 	return tmpl.Execute(f, data)
 }
-
-func writePDFReport(data ReportData, outputPath string) error {
-	// First generate HTML, then convert to PDF
-	htmlPath := strings.TrimSuffix(outputPath, ".pdf") + ".tmp.html"
-
-	if err := writeHTMLReport(data, htmlPath); err != nil {
-		return err
-	}
-	defer os.Remove(htmlPath)
-
-	// Try pandoc first, then wkhtmltopdf
-	if _, err := exec.LookPath("pandoc"); err == nil {
-		cmd := exec.Command("pandoc", htmlPath, "-o", outputPath,
-			"--pdf-engine=weasyprint",
-			"--metadata", fmt.Sprintf("title=%s", data.Title))
-		if out, err := cmd.CombinedOutput(); err != nil {
-			// Fallback: try pandoc with default engine
-			cmd2 := exec.Command("pandoc", htmlPath, "-o", outputPath)
-			if out2, err2 := cmd2.CombinedOutput(); err2 != nil {
-				return fmt.Errorf("pandoc failed: %s\n%s", string(out), string(out2))
-			}
-		} else {
-			_ = out
-		}
-		return nil
-	}
-
-	if _, err := exec.LookPath("wkhtmltopdf"); err == nil {
-		cmd := exec.Command("wkhtmltopdf", "--quiet", htmlPath, outputPath)
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("wkhtmltopdf failed: %s", string(out))
-		}
-		return nil
-	}
-
-	// No PDF tool available — keep the HTML and inform the user
-	finalHTML := strings.TrimSuffix(outputPath, ".pdf") + ".html"
-	os.Rename(htmlPath, finalHTML)
-	return fmt.Errorf("PDF generation requires pandoc or wkhtmltopdf.\nHTML report saved to: %s\nInstall pandoc: https://pandoc.org/installing.html", finalHTML)
-}
@@ -0,0 +1,501 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Native PDF report writer, laid out like the Markdown report, with
+ * thumbnails of the workspace images
+ */
+
+package dock
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Page layout in points.
+const (
+	pdfMargin       = 50.0
+	pdfContentWidth = pdfPageWidth - 2*pdfMargin
+	pdfBottom       = pdfPageHeight - pdfMargin - 10
+)
+
+// Thumbnails: at most pdfMaxThumbnails images, scaled to pdfThumbnailSide
+// pixels, skipping images larger than pdfMaxImagePixels.
+const (
+	pdfMaxThumbnails  = 12
+	pdfThumbnailSide  = 360
+	pdfMaxImagePixels = 50_000_000
+	pdfThumbnailBox   = 110.0
+)
+
+// Colors of the HTML report styles.
+var (
+	pdfPrimary = hexColor("#0891b2")
+	pdfText    = hexColor("#1e293b")
+	pdfMuted   = hexColor("#64748b")
+	pdfBorder  = hexColor("#e2e8f0")
+	pdfStripe  = hexColor("#f1f5f9")
+	pdfWhite   = hexColor("#ffffff")
+)
+
+// pdfColumn describes a table column; Width is a fraction of the content width.
+type pdfColumn struct {
+	Title string
+	Width float64
+	Font  pdfFont
+}
+
+// pdfThumbnail is a workspace image scaled down and encoded as JPEG.
+type pdfThumbnail struct {
+	Name          string
+	Data          []byte
+	Width, Height int
+}
+
+// pdfWrapText splits text into lines no wider than width. Words longer than a
+// line are broken.
+//
+//	in(1): pdfFont font
+//	in(2): float64 size
+//	in(3): string text
+//	in(4): float64 width
+//	out: []string
+func pdfWrapText(font pdfFont, size float64, text string, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if pdfTextWidth(font, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for pdfTextWidth(font, size, word) > width {
+				cut := nextRune(word, 0)
+				for cut < len(word) && pdfTextWidth(font, size, word[:nextRune(word, cut)]) <= width {
+					cut = nextRune(word, cut)
+				}
+				lines = append(lines, word[:cut])
+				word = word[cut:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// nextRune returns the index after the rune starting at i.
+func nextRune(s string, i int) int {
+	_, n := utf8.DecodeRuneInString(s[i:])
+	return i + n
+}
+
+// pdfReport lays the report out top to bottom, starting new pages as needed.
+type pdfReport struct {
+	doc  *pdfDocument
+	data ReportData
+	y    float64 // top of the free space on the page
+	page int
+}
+
+func (r *pdfReport) newPage() {
+	r.doc.addPage()
+	r.page++
+	r.y = pdfMargin
+	if r.page > 1 {
+		footer := pdfPageHeight - 30
+		r.doc.line(pdfMargin, footer-12, pdfPageWidth-pdfMargin, footer-12, 0.5, pdfBorder)
+		r.doc.text(pdfHelvetica, 8, pdfMargin, footer, pdfMuted, "RF Swift report — "+r.data.ContainerName)
+		num := fmt.Sprintf("Page %d", r.page)
+		r.doc.text(pdfHelvetica, 8, pdfPageWidth-pdfMargin-pdfTextWidth(pdfHelvetica, 8, num), footer, pdfMuted, num)
+	}
+}
+
+// ensure starts a new page unless h points fit on the current one.
+func (r *pdfReport) ensure(h float64) bool {
+	if r.y+h > pdfBottom {
+		r.newPage()
+		return true
+	}
+	return false
+}
+
+func (r *pdfReport) centered(font pdfFont, size float64, color pdfColor, text string) {
+	for _, l := range pdfWrapText(font, size, text, pdfContentWidth) {
+		r.doc.text(font, size, (pdfPageWidth-pdfTextWidth(font, size, l))/2, r.y+size, color, l)
+		r.y += size * 1.3
+	}
+}
+
+func (r *pdfReport) heading(text string) {
+	if !r.ensure(70) && r.y > pdfMargin {
+		r.y += 14
+	}
+	r.doc.text(pdfHelveticaBold, 15, pdfMargin, r.y+15, pdfPrimary, text)
+	r.y += 21
+	r.doc.line(pdfMargin, r.y, pdfPageWidth-pdfMargin, r.y, 0.75, pdfBorder)
+	r.y += 10
+}
+
+func (r *pdfReport) subheading(text string) {
+	r.ensure(40)
+	r.doc.text(pdfHelveticaBold, 11.5, pdfMargin, r.y+11.5, pdfText, text)
+	r.y += 18
+}
+
+func (r *pdfReport) paragraph(font pdfFont, size float64, color pdfColor, text string) {
+	lh := size * 1.4
+	for _, l := range pdfWrapText(font, size, text, pdfContentWidth) {
+		r.ensure(lh)
+		r.doc.text(font, size, pdfMargin, r.y+size, color, l)
+		r.y += lh
+	}
+	r.y += 6
+}
+
+// table draws rows under a header row that is repeated on each page.
+// Cells longer than maxCellLines lines are cut.
+func (r *pdfReport) table(cols []pdfColumn, rows [][]string) {
+	const size, lh, pad, maxCellLines = 9.0, 11.5, 4.0, 30
+
+	header := func() {
+		r.ensure(lh + 2*pad)
+		r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, lh+2*pad, pdfPrimary)
+		x := pdfMargin
+		for _, c := range cols {
+			r.doc.text(pdfHelveticaBold, 8, x+pad, r.y+pad+8.5, pdfWhite, strings.ToUpper(c.Title))
+			x += c.Width * pdfContentWidth
+		}
+		r.y += lh + 2*pad
+	}
+	header()
+
+	for i, row := range rows {
+		cells := make([][]string, len(cols))
+		n := 1
+		for j, c := range cols {
+			if j < len(row) {
+				cells[j] = pdfWrapText(c.Font, size, row[j], c.Width*pdfContentWidth-2*pad)
+			}
+			if len(cells[j]) > maxCellLines {
+				cells[j] = append(cells[j][:maxCellLines-1], "…")
+			}
+			n = max(n, len(cells[j]))
+		}
+		h := float64(n)*lh + 2*pad
+		if r.ensure(h) {
+			header()
+		}
+		if i%2 == 1 {
+			r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, h, pdfStripe)
+		}
+		x := pdfMargin
+		for j, c := range cols {
+			for k, l := range cells[j] {
+				r.doc.text(c.Font, size, x+pad, r.y+pad+float64(k)*lh+9, pdfText, l)
+			}
+			x += c.Width * pdfContentWidth
+		}
+		r.y += h
+		r.doc.line(pdfMargin, r.y, pdfPageWidth-pdfMargin, r.y, 0.5, pdfBorder)
+	}
+	r.y += 12
+}
+
+// propertyTable draws a two-column table of name/value pairs.
+func (r *pdfReport) propertyTable(title string, rows [][]string) {
+	r.table([]pdfColumn{{title, 0.3, pdfHelveticaBold}, {"Value", 0.7, pdfHelvetica}}, rows)
+}
+
+// codeBlock draws lines in a monospaced font on a shaded background,
+// wrapping long lines.
+func (r *pdfReport) codeBlock(lines []string) {
+	const size, lh, pad = 8.0, 10.5, 8.0
+	width := pdfContentWidth - 2*pad
+
+	r.ensure(lh + 2*pad)
+	r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, pad, pdfStripe)
+	r.y += pad
+	for _, line := range lines {
+		for _, l := range pdfWrapText(pdfCourier, size, line, width) {
+			if r.ensure(lh) {
+				r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, pad, pdfStripe)
+				r.y += pad
+			}
+			r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, lh, pdfStripe)
+			r.doc.text(pdfCourier, size, pdfMargin+pad, r.y+8, pdfText, l)
+			r.y += lh
+		}
+	}
+	r.doc.fillRect(pdfMargin, r.y, pdfContentWidth, pad, pdfStripe)
+	r.y += pad + 12
+}
+
+// thumbnails draws images in a grid of three per row, with their names.
+func (r *pdfReport) thumbnails(thumbs []pdfThumbnail) {
+	const perRow, caption = 3, 16.0
+	cell := pdfContentWidth / perRow
+	for i, t := range thumbs {
+		col := i % perRow
+		if col == 0 {
+			if i > 0 {
+				r.y += pdfThumbnailBox + caption + 8
+			}
+			r.ensure(pdfThumbnailBox + caption)
+		}
+		x := pdfMargin + float64(col)*cell
+		box := cell - 10
+		scale := min(box/float64(t.Width), pdfThumbnailBox/float64(t.Height))
+		w, h := float64(t.Width)*scale, float64(t.Height)*scale
+		idx := r.doc.addJPEG(t.Data, t.Width, t.Height)
+		r.doc.drawImage(idx, x+(box-w)/2, r.y+(pdfThumbnailBox-h)/2, w, h)
+		r.doc.strokeRect(x, r.y, box, pdfThumbnailBox, 0.5, 0, pdfBorder)
+
+		name := t.Name
+		for pdfTextWidth(pdfHelvetica, 8, name) > box && len(name) > 1 {
+			name = name[:len(name)-1]
+		}
+		r.doc.text(pdfHelvetica, 8, x, r.y+pdfThumbnailBox+11, pdfMuted, name)
+	}
+	if len(thumbs) > 0 {
+		r.y += pdfThumbnailBox + caption + 12
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// render lays out the report with the sections of the Markdown report,
+// after a title page.
+func (r *pdfReport) render(thumbs []pdfThumbnail) {
+	d := r.data
+	workspace := orDefault(d.WorkspacePath, "not mounted")
+
+	// Title page
+	r.newPage()
+	r.y = 240
+	r.centered(pdfHelveticaBold, 24, pdfPrimary, d.Title)
+	r.y += 8
+	r.doc.line(pdfPageWidth/2-80, r.y, pdfPageWidth/2+80, r.y, 1.5, pdfPrimary)
+	r.y += 16
+	r.centered(pdfHelvetica, 11, pdfMuted, "Generated: "+d.GeneratedAt)
+	r.y += 40
+	r.propertyTable("Container", [][]string{
+		{"Name", d.ContainerName},
+		{"Image", d.ImageName},
+		{"State", d.State},
+		{"Workspace", workspace},
+	})
+	r.y = pdfPageHeight - 80
+	r.centered(pdfHelvetica, 9, pdfMuted, "Report generated by RF Swift (https://rfswift.io) by @Penthertz")
+
+	r.newPage()
+	r.heading("Container Summary")
+	r.propertyTable("Property", [][]string{
+		{"Name", d.ContainerName},
+		{"ID", d.ContainerID},
+		{"Image", d.ImageName},
+		{"State", d.State},
+		{"Created", d.CreatedAt},
+		{"Age", d.Duration},
+		{"Workspace", workspace},
+	})
+
+	r.heading("Environment Configuration")
+	r.propertyTable("Setting", [][]string{
+		{"Network", d.NetworkMode},
+		{"Privileged", d.Privileged},
+		{"Devices", orDefault(d.Devices, "none")},
+		{"Capabilities", orDefault(d.Capabilities, "default")},
+		{"Cgroups", orDefault(d.Cgroups, "default")},
+		{"GPUs", orDefault(d.GPUs, "none")},
+		{"Ulimits", orDefault(d.Ulimits, "default")},
+	})
+	if d.Bindings != "" {
+		r.subheading("Volume Bindings")
+		r.codeBlock(strings.Split(d.Bindings, "\n"))
+	}
+
+	r.heading("Timeline")
+	if len(d.Timeline) > 0 {
+		rows := make([][]string, len(d.Timeline))
+		for i, e := range d.Timeline {
+			rows[i] = []string{e.When, e.Source, e.Event}
+		}
+		r.table([]pdfColumn{{"Time", 0.22, pdfHelvetica}, {"Source", 0.13, pdfHelvetica}, {"Event", 0.65, pdfHelvetica}}, rows)
+	} else {
+		r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, "No timestamped activity found. Enable zsh EXTENDED_HISTORY or bash HISTTIMEFORMAT to place commands on the timeline.")
+	}
+
+	r.heading("Session Recordings")
+	if len(d.Recordings) > 0 {
+		rows := make([][]string, len(d.Recordings))
+		for i, rec := range d.Recordings {
+			rows[i] = []string{fmt.Sprint(i + 1), rec.Name, rec.Size, rec.Modified}
+		}
+		r.table([]pdfColumn{{"#", 0.07, pdfHelvetica}, {"File", 0.53, pdfHelvetica}, {"Size", 0.15, pdfHelvetica}, {"Date", 0.25, pdfHelvetica}}, rows)
+		r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, "Replay with: rfswift log replay -i <file>")
+	} else {
+		r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, "No session recordings found.")
+	}
+
+	r.heading("Shell History")
+	if len(d.History) > 0 {
+		r.codeBlock(d.History)
+	} else {
+		r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, "No shell history available. Start the container and use --record to capture sessions.")
+	}
+
+	r.heading("Workspace Artifacts")
+	if len(d.Artifacts) > 0 {
+		rows := make([][]string, len(d.Artifacts))
+		for i, a := range d.Artifacts {
+			rows[i] = []string{a.Path, a.Category, a.Size, a.Modified}
+		}
+		r.table([]pdfColumn{{"File", 0.5, pdfHelvetica}, {"Category", 0.14, pdfHelvetica}, {"Size", 0.12, pdfHelvetica}, {"Modified", 0.24, pdfHelvetica}}, rows)
+		if len(thumbs) > 0 {
+			r.subheading("Images")
+			r.thumbnails(thumbs)
+		}
+	} else {
+		msg := "No files found in workspace."
+		if d.WorkspacePath == "" {
+			msg += " Workspace was not mounted for this container."
+		}
+		r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, msg)
+	}
+
+	r.heading("Notes")
+	r.paragraph(pdfHelveticaOblique, 9.5, pdfMuted, "Add your assessment notes, findings, and observations below.")
+	r.ensure(140)
+	r.doc.strokeRect(pdfMargin, r.y, pdfContentWidth, 140, 1, 4, pdfBorder)
+}
+
+// scaleImage shrinks img so that its longest side is at most maxSide pixels,
+// averaging up to 4x4 source pixels per destination pixel and flattening
+// transparency onto white.
+//
+//	in(1): image.Image img
+//	in(2): int maxSide
+//	out: *image.RGBA
+func scaleImage(img image.Image, maxSide int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if longest := max(sw, sh); longest > maxSide {
+		dw = max(1, sw*maxSide/longest)
+		dh = max(1, sh*maxSide/longest)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := b.Min.Y+dy*sh/dh, b.Min.Y+max((dy+1)*sh/dh, dy*sh/dh+1)
+		ystep := max(1, (y1-y0)/4)
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := b.Min.X+dx*sw/dw, b.Min.X+max((dx+1)*sw/dw, dx*sw/dw+1)
+			xstep := max(1, (x1-x0)/4)
+
+			var rs, gs, bs, n uint32
+			for y := y0; y < y1; y += ystep {
+				for x := x0; x < x1; x += xstep {
+					r, g, bl, a := img.At(x, y).RGBA()
+					rs += (r + 0xffff - a) >> 8
+					gs += (g + 0xffff - a) >> 8
+					bs += (bl + 0xffff - a) >> 8
+					n++
+				}
+			}
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(rs/n), uint8(gs/n), uint8(bs/n), 0xff
+		}
+	}
+	return dst
+}
+
+// loadThumbnail decodes a PNG, JPEG or GIF image and returns it scaled down
+// and encoded as JPEG.
+//
+//	in(1): string path
+//	out: (pdfThumbnail, error)
+func loadThumbnail(path string) (pdfThumbnail, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return pdfThumbnail{}, err
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return pdfThumbnail{}, err
+	}
+	if cfg.Width*cfg.Height > pdfMaxImagePixels {
+		return pdfThumbnail{}, fmt.Errorf("image too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return pdfThumbnail{}, err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return pdfThumbnail{}, err
+	}
+
+	thumb := scaleImage(img, pdfThumbnailSide)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return pdfThumbnail{}, err
+	}
+	b := thumb.Bounds()
+	return pdfThumbnail{filepath.Base(path), buf.Bytes(), b.Dx(), b.Dy()}, nil
+}
+
+// collectThumbnails returns thumbnails of the images among the workspace
+// artifacts, skipping those that cannot be decoded (such as SVG).
+func collectThumbnails(workspacePath string, artifacts []ReportArtifact) []pdfThumbnail {
+	var thumbs []pdfThumbnail
+	for _, a := range artifacts {
+		if a.Category != "image" || len(thumbs) == pdfMaxThumbnails {
+			continue
+		}
+		path := a.Path
+		if !filepath.IsAbs(path) && workspacePath != "" {
+			path = filepath.Join(workspacePath, path)
+		}
+		if t, err := loadThumbnail(path); err == nil {
+			thumbs = append(thumbs, t)
+		}
+	}
+	return thumbs
+}
+
+func writePDFReport(data ReportData, outputPath string) error {
+	doc := newPDFDocument(data.Title)
+	report := &pdfReport{doc: doc, data: data}
+	report.render(collectThumbnails(data.WorkspacePath, data.Artifacts))
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	return doc.write(f)
+}
@@ -0,0 +1,171 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFEncodeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []byte
+	}{
+		{"RF Swift", []byte("RF Swift")},
+		{"a\tb\x01c", []byte("a bc")},
+		{"Sébastien — 5 €", []byte{'S', 0xe9, 'b', 'a', 's', 't', 'i', 'e', 'n', ' ', 0x97, ' ', '5', ' ', 0x80}},
+		{"漢", []byte("?")},
+	}
+	for _, tt := range tests {
+		if got := pdfEncodeText(tt.in); !bytes.Equal(got, tt.want) {
+			t.Errorf("pdfEncodeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFTextWidth(t *testing.T) {
+	tests := []struct {
+		font pdfFont
+		text string
+		want float64
+	}{
+		{pdfHelvetica, "", 0},
+		{pdfHelvetica, "A", 6.67},
+		{pdfHelvetica, "~", 5.84},
+		{pdfHelveticaOblique, "il", 4.44},
+		{pdfHelveticaBold, "z~", 10.84},
+		{pdfHelvetica, "—", 10},
+		{pdfCourier, "abc", 18},
+	}
+	for _, tt := range tests {
+		if got := pdfTextWidth(tt.font, 10, tt.text); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("pdfTextWidth(%v, 10, %q) = %v, want %v", tt.font, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPDFWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width float64
+		want  []string
+	}{
+		{"", 100, []string{""}},
+		{"abc def", 100, []string{"abc def"}},
+		{"abc def", 20, []string{"abc", "def"}},
+		{"abcdefgh", 30, []string{"abcde", "fgh"}},
+		{"one\n\ntwo", 100, []string{"one", "", "two"}},
+	}
+	for _, tt := range tests {
+		got := pdfWrapText(pdfCourier, 10, tt.text, tt.width)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("pdfWrapText(%q, %v) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			src.Set(x, y, color.NRGBA{0, 0, 255, 255})
+		}
+	}
+	// Transparent corner is flattened onto white
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			src.Set(x, y, color.NRGBA{0, 0, 0, 0})
+		}
+	}
+
+	got := scaleImage(src, 360)
+	if b := got.Bounds(); b.Dx() != 360 || b.Dy() != 180 {
+		t.Fatalf("scaleImage(800x400, 360) = %dx%d, want 360x180", b.Dx(), b.Dy())
+	}
+	if c := got.RGBAAt(0, 0); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("scaleImage() transparent pixel = %v, want white", c)
+	}
+	if c := got.RGBAAt(200, 100); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("scaleImage() pixel = %v, want blue", c)
+	}
+
+	small := scaleImage(src.SubImage(image.Rect(100, 100, 150, 120)), 360)
+	if b := small.Bounds(); b.Dx() != 50 || b.Dy() != 20 {
+		t.Errorf("scaleImage(50x20, 360) = %dx%d, want 50x20", b.Dx(), b.Dy())
+	}
+}
+
+func TestWritePDFReport(t *testing.T) {
+	workspace := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(workspace, "spectrum.png"), pngData.Bytes(), 0644)
+	os.WriteFile(filepath.Join(workspace, "diagram.svg"), []byte("<svg/>"), 0644)
+
+	var history []string
+	for i := 0; i < 150; i++ {
+		history = append(history, fmt.Sprintf("rtl_433 -f 868M -R %d (%s)", i, strings.Repeat("x", i)))
+	}
+	data := ReportData{
+		Title:         "RF Swift Assessment Report — lab",
+		ContainerName: "lab",
+		GeneratedAt:   "2026-10-17 10:00:00",
+		WorkspacePath: workspace,
+		Bindings:      "/dev/bus/usb:/dev/bus/usb",
+		History:       history,
+		Timeline:      []TimelineEntry{{When: "2026-10-17 09:00:00", Source: timelineCommand, Event: "hackrf_info"}},
+		Artifacts:     collectArtifacts(workspace),
+	}
+
+	out := filepath.Join(t.TempDir(), "report.pdf")
+	if err := writePDFReport(data, out); err != nil {
+		t.Fatalf("writePDFReport() error: %v", err)
+	}
+	pdf, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("writePDFReport() output is not framed as a PDF")
+	}
+	if n := bytes.Count(pdf, []byte("/Subtype /Image")); n != 1 {
+		t.Errorf("writePDFReport() embedded %d images, want 1 (SVG is not decodable)", n)
+	}
+	if m := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(pdf); m == nil {
+		t.Errorf("writePDFReport() output has no page tree")
+	} else if n, _ := strconv.Atoi(string(m[1])); n < 3 {
+		t.Errorf("writePDFReport() wrote %d pages, want the history to span several pages", n)
+	}
+
+	// Every cross-reference entry points at its object
+	m := regexp.MustCompile(`(?s)startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("writePDFReport() output has no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(pdf[xref:]), "\n")
+	size, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for obj := 1; obj < size; obj++ {
+		off, _ := strconv.Atoi(strings.Fields(lines[2+obj])[0])
+		if want := fmt.Sprintf("%d 0 obj\n", obj); !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", obj, pdf[off:off+len(want)], want)
+		}
+	}
+}
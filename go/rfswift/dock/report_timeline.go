@@ -0,0 +1,186 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Report timeline: shell history timestamps, recording sessions and
+ * workspace artifacts merged into one chronological view
+ */
+
+package dock
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeline sources, also used as badge names by the HTML report.
+const (
+	timelineCommand  = "command"
+	timelineSession  = "session"
+	timelineArtifact = "artifact"
+)
+
+// timelineTimeFormat is the layout of TimelineEntry.When.
+const timelineTimeFormat = "2006-01-02 15:04:05"
+
+// TimelineEntry is one event of the report timeline.
+type TimelineEntry struct {
+	Time   time.Time
+	When   string
+	Source string // "command", "session" or "artifact"
+	Event  string
+}
+
+// historyEntry is a shell history command, with the time it was run when
+// the shell recorded it.
+type historyEntry struct {
+	Time    time.Time
+	Command string
+}
+
+// parseShellHistory parses bash or zsh history. zsh extended history lines
+// (": <start>:<elapsed>;<command>", continued with a trailing backslash)
+// and bash HISTTIMEFORMAT comments ("#<epoch>") give the commands their time;
+// plain lines are kept with a zero time.
+//
+//	in(1): string content
+//	out: []historyEntry
+func parseShellHistory(content string) []historyEntry {
+	var entries []historyEntry
+	var pending time.Time // bash timestamp comment waiting for its command
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		if ts, ok := strings.CutPrefix(line, "#"); ok {
+			if sec, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				pending = time.Unix(sec, 0)
+				continue
+			}
+		}
+
+		when := pending
+		pending = time.Time{}
+		if meta, cmd, ok := strings.Cut(line, ";"); ok && strings.HasPrefix(meta, ": ") {
+			start, _, _ := strings.Cut(strings.TrimSpace(meta[2:]), ":")
+			if sec, err := strconv.ParseInt(start, 10, 64); err == nil {
+				when = time.Unix(sec, 0)
+				line = cmd
+				// zsh writes the newlines of multi-line commands as "\\\n"
+				for strings.HasSuffix(line, "\\") && i+1 < len(lines) {
+					i++
+					line = strings.TrimSuffix(line, "\\") + "\n" + strings.TrimRight(lines[i], "\r")
+				}
+			}
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		entries = append(entries, historyEntry{when, line})
+	}
+	return entries
+}
+
+// typedLines renders the input events of a recording as the lines typed,
+// with the time each line was started. Input is only recorded with
+// --record-input.
+//
+//	in(1): []castEvent events
+//	out: []castLine
+func typedLines(events []castEvent) []castLine {
+	keys := strings.NewReplacer("\r", "\n", "\x7f", "\b")
+	var input []castEvent
+	for _, e := range events {
+		if e.Code == castInput {
+			input = append(input, castEvent{e.Time, castOutput, keys.Replace(e.Data)})
+		}
+	}
+	return castLines(input)
+}
+
+// castTimeline returns the timeline entries of an asciicast recording: its
+// start and end and, when input was recorded, the lines typed. Recordings
+// without a header timestamp are placed so that they end at modTime.
+//
+//	in(1): *castFile cast
+//	in(2): string name  shown in the entries
+//	in(3): time.Time modTime  of the recording file
+//	out: []TimelineEntry
+func castTimeline(cast *castFile, name string, modTime time.Time) []TimelineEntry {
+	duration := cast.Duration()
+	start := modTime.Add(-time.Duration(duration * float64(time.Second)))
+	if cast.Header.Timestamp != 0 {
+		start = time.Unix(cast.Header.Timestamp, 0)
+	}
+	at := func(offset float64) time.Time {
+		return start.Add(time.Duration(offset * float64(time.Second)))
+	}
+
+	entries := []TimelineEntry{{Time: start, Source: timelineSession,
+		Event: fmt.Sprintf("Recording %s started", name)}}
+	for _, l := range typedLines(cast.Events) {
+		if text := strings.TrimSpace(l.Text); text != "" {
+			entries = append(entries, TimelineEntry{Time: at(l.Time), Source: timelineCommand,
+				Event: fmt.Sprintf("%s (typed in %s at %s)", text, name, formatCastTime(l.Time))})
+		}
+	}
+	return append(entries, TimelineEntry{Time: at(duration), Source: timelineSession,
+		Event: fmt.Sprintf("Recording %s ended (%s)", name, formatCastTime(duration))})
+}
+
+// recordingTimeline returns the timeline entries of a session recording.
+// Script logs only carry their modification time.
+//
+//	in(1): ReportArtifact rec
+//	out: []TimelineEntry
+func recordingTimeline(rec ReportArtifact) []TimelineEntry {
+	if strings.HasSuffix(rec.Path, ".cast") {
+		if cast, err := loadCast(rec.Path); err == nil {
+			return castTimeline(cast, rec.Name, rec.ModTime)
+		}
+	}
+	return []TimelineEntry{{Time: rec.ModTime, Source: timelineSession,
+		Event: fmt.Sprintf("Recording %s last written", rec.Name)}}
+}
+
+// buildTimeline merges timestamped shell history, recording sessions and
+// artifact modification times into one chronological list. Commands without
+// a timestamp stay out of it; they are listed under Shell History.
+//
+//	in(1): []historyEntry history
+//	in(2): []ReportArtifact recordings
+//	in(3): []ReportArtifact artifacts
+//	out: []TimelineEntry
+func buildTimeline(history []historyEntry, recordings, artifacts []ReportArtifact) []TimelineEntry {
+	var timeline []TimelineEntry
+	for _, h := range history {
+		if !h.Time.IsZero() {
+			timeline = append(timeline, TimelineEntry{Time: h.Time, Source: timelineCommand, Event: h.Command})
+		}
+	}
+	for _, r := range recordings {
+		timeline = append(timeline, recordingTimeline(r)...)
+	}
+	for _, a := range artifacts {
+		timeline = append(timeline, TimelineEntry{Time: a.ModTime, Source: timelineArtifact,
+			Event: fmt.Sprintf("%s written (%s, %s)", a.Path, a.Category, a.Size)})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	for i := range timeline {
+		timeline[i].When = timeline[i].Time.Format(timelineTimeFormat)
+	}
+	return timeline
+}
+
+// markdownCell makes text safe inside a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "\r", "", "\n", " ⏎ ").Replace(text)
+}
@@ -0,0 +1,157 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseShellHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []historyEntry
+	}{
+		{"plain bash", "ls\n\n  uhd_find_devices  \n", []historyEntry{
+			{time.Time{}, "ls"},
+			{time.Time{}, "uhd_find_devices"},
+		}},
+		{"zsh extended", ": 1700000000:0;hackrf_info\n: 1700000060:12;rtl_433 -f 868M\n", []historyEntry{
+			{time.Unix(1700000000, 0), "hackrf_info"},
+			{time.Unix(1700000060, 0), "rtl_433 -f 868M"},
+		}},
+		{"zsh multi-line", ": 1700000000:3;for f in *.cs8; do\\\n  ls $f\\\ndone\nls\n", []historyEntry{
+			{time.Unix(1700000000, 0), "for f in *.cs8; do\n  ls $f\ndone"},
+			{time.Time{}, "ls"},
+		}},
+		{"zsh semicolons in command", ": 1700000000:0;cd /tmp; ls\r\n", []historyEntry{
+			{time.Unix(1700000000, 0), "cd /tmp; ls"},
+		}},
+		{"bash HISTTIMEFORMAT", "#1700000000\nkismet\n#not a time\nls\n", []historyEntry{
+			{time.Unix(1700000000, 0), "kismet"},
+			{time.Time{}, "#not a time"},
+			{time.Time{}, "ls"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseShellHistory(tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseShellHistory(%q) = %+v, want %+v", tt.content, got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Time.Equal(tt.want[i].Time) || got[i].Command != tt.want[i].Command {
+					t.Errorf("parseShellHistory(%q)[%d] = %+v, want %+v", tt.content, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCastTimeline(t *testing.T) {
+	cast := &castFile{
+		Header: castHeader{Version: 2, Width: 80, Height: 24, Timestamp: 1700000000},
+		Events: []castEvent{
+			{0.5, castOutput, "$ "},
+			{1.0, castInput, "h"},
+			{1.1, castInput, "ackrf_inof"},
+			{1.2, castInput, "\x7f\x7ffo"},
+			{1.5, castInput, "\r"},
+			{4.0, castOutput, "Found HackRF\r\n"},
+		},
+	}
+	start := time.Unix(1700000000, 0)
+	want := []TimelineEntry{
+		{Time: start, Source: timelineSession, Event: "Recording s.cast started"},
+		{Time: start.Add(time.Second), Source: timelineCommand, Event: "hackrf_info (typed in s.cast at 00:01.0)"},
+		{Time: start.Add(4 * time.Second), Source: timelineSession, Event: "Recording s.cast ended (00:04.0)"},
+	}
+
+	got := castTimeline(cast, "s.cast", time.Time{})
+	if len(got) != len(want) {
+		t.Fatalf("castTimeline() = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Source != want[i].Source || got[i].Event != want[i].Event {
+			t.Errorf("castTimeline()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Without a header timestamp the recording ends at the file time
+	cast.Header.Timestamp = 0
+	mod := time.Unix(1800000000, 0)
+	got = castTimeline(cast, "s.cast", mod)
+	if first := got[0].Time; !first.Equal(mod.Add(-4 * time.Second)) {
+		t.Errorf("castTimeline() without timestamp starts at %v, want %v", first, mod.Add(-4*time.Second))
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "rfswift-session.log")
+	if err := os.WriteFile(log, []byte("Script started\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Unix(1700000000, 0)
+	history := []historyEntry{
+		{base.Add(30 * time.Second), "rtl_433"},
+		{time.Time{}, "ls"},
+		{base, "hackrf_info"},
+	}
+	recordings := []ReportArtifact{{Path: log, Name: "rfswift-session.log", ModTime: base.Add(90 * time.Second)}}
+	artifacts := []ReportArtifact{{Path: "capture.cs8", Size: "1.0 MB", Category: "capture", ModTime: base.Add(60 * time.Second)}}
+
+	got := buildTimeline(history, recordings, artifacts)
+	want := []string{
+		"hackrf_info",
+		"rtl_433",
+		"capture.cs8 written (capture, 1.0 MB)",
+		"Recording rfswift-session.log last written",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("buildTimeline() = %+v, want events %q", got, want)
+	}
+	for i := range got {
+		if got[i].Event != want[i] {
+			t.Errorf("buildTimeline()[%d].Event = %q, want %q", i, got[i].Event, want[i])
+		}
+		if got[i].When != got[i].Time.Format(timelineTimeFormat) {
+			t.Errorf("buildTimeline()[%d].When = %q, want %q", i, got[i].When, got[i].Time.Format(timelineTimeFormat))
+		}
+	}
+}
+
+func TestReportTimelineRendering(t *testing.T) {
+	data := ReportData{
+		Title: "t",
+		Timeline: []TimelineEntry{
+			{When: "2023-11-14 22:13:20", Source: timelineCommand, Event: "grep a | sort"},
+		},
+	}
+	dir := t.TempDir()
+
+	md := filepath.Join(dir, "r.md")
+	if err := writeMarkdownReport(data, md); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := os.ReadFile(md)
+	if row := `| 2023-11-14 22:13:20 | command | grep a \| sort |`; !strings.Contains(string(out), row) {
+		t.Errorf("markdown report has no row %q:\n%s", row, out)
+	}
+
+	html := filepath.Join(dir, "r.html")
+	if err := writeHTMLReport(data, html); err != nil {
+		t.Fatal(err)
+	}
+	out, _ = os.ReadFile(html)
+	if cell := `<span class="badge badge-command">command</span></td><td><code class="cmd">grep a | sort</code>`; !strings.Contains(string(out), cell) {
+		t.Errorf("HTML report has no cell %q", cell)
+	}
+}
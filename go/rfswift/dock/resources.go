@@ -0,0 +1,484 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Engine-level resource limits (CPUs, memory, cpuset pinning, shm size,
+ * pids limit) for run, profiles, labs and live container updates
+ */
+
+package dock
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// minMemoryLimit is the smallest memory limit the engines accept.
+const minMemoryLimit = 6 * 1024 * 1024
+
+var (
+	byteSizePattern = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*([kmgt]?)(?:i?b)?$`)
+	cpusetPattern   = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+)
+
+// ResourceLimits holds the engine-level resource options of a container, as
+// the user writes them ("1.5", "4g", "0-3"). An empty field is not set; "0"
+// or "none" removes a limit ("none" only for cpuset_cpus, where 0 is a CPU).
+type ResourceLimits struct {
+	CPUs       string `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	Memory     string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CpusetCpus string `json:"cpuset_cpus,omitempty" yaml:"cpuset_cpus,omitempty"`
+	ShmSize    string `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+	PidsLimit  string `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+}
+
+// IsZero reports whether no resource option is set.
+func (r ResourceLimits) IsZero() bool {
+	return r == ResourceLimits{}
+}
+
+// WithDefaults fills the fields r leaves empty with those of d.
+//
+//	in(1): ResourceLimits d fallback values (e.g. from a profile)
+//	out: ResourceLimits merged limits
+func (r ResourceLimits) WithDefaults(d ResourceLimits) ResourceLimits {
+	setIfNotEmpty(&d.CPUs, r.CPUs)
+	setIfNotEmpty(&d.Memory, r.Memory)
+	setIfNotEmpty(&d.CpusetCpus, r.CpusetCpus)
+	setIfNotEmpty(&d.ShmSize, r.ShmSize)
+	setIfNotEmpty(&d.PidsLimit, r.PidsLimit)
+	return d
+}
+
+// resourceField pairs a resource option key with its value and parse error.
+type resourceField struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+// fields returns the options set in r, in display order, with the error
+// each value raises.
+func (r ResourceLimits) fields() []resourceField {
+	var fields []resourceField
+	add := func(key, value string, check func(string) error) {
+		if value != "" {
+			fields = append(fields, resourceField{Key: key, Value: value, Err: check(value)})
+		}
+	}
+	add("cpus", r.CPUs, func(s string) error { _, err := parseCPUs(s); return err })
+	add("memory", r.Memory, func(s string) error { _, err := parseMemoryLimit(s); return err })
+	add("cpuset_cpus", r.CpusetCpus, func(s string) error { _, err := parseCpuset(s); return err })
+	add("shm_size", r.ShmSize, func(s string) error { _, err := parseByteSize(s); return err })
+	add("pids_limit", r.PidsLimit, func(s string) error { _, err := parsePidsLimit(s); return err })
+	return fields
+}
+
+// Validate checks every option set in r.
+//
+//	out: error describing the first invalid option
+func (r ResourceLimits) Validate() error {
+	for _, f := range r.fields() {
+		if f.Err != nil {
+			return f.Err
+		}
+	}
+	return nil
+}
+
+// clearsLimit reports whether a value asks to remove a limit.
+func clearsLimit(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "0", "none", "unlimited":
+		return true
+	}
+	return false
+}
+
+// parseCPUs converts a CPU count such as "1.5" to nano CPUs.
+//
+//	in(1): string s number of CPUs ("0" or "none" for no limit)
+//	out: int64 nano CPUs, error if s is not a positive number
+func parseCPUs(s string) (int64, error) {
+	if clearsLimit(s) {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || f <= 0 || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid cpus %q: want a number of CPUs such as 2 or 1.5", s)
+	}
+	return int64(math.Round(f * 1e9)), nil
+}
+
+// parseByteSize converts a size such as "512m" or "2g" to bytes. Units are
+// binary (k=1024) and case-insensitive; a trailing "b" or "ib" is accepted.
+//
+//	in(1): string s size ("0" or "none" for no value)
+//	out: int64 bytes, error if s is not a size
+func parseByteSize(s string) (int64, error) {
+	if clearsLimit(s) {
+		return 0, nil
+	}
+	m := byteSizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: want a number with an optional k, m, g or t suffix (e.g. 512m)", s)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	shift := 0
+	if m[2] != "" {
+		shift = strings.Index("kmgt", strings.ToLower(m[2])) + 1
+	}
+	return int64(n * float64(int64(1)<<(10*shift))), nil
+}
+
+// parseMemoryLimit is parseByteSize with the engines' minimum memory limit.
+func parseMemoryLimit(s string) (int64, error) {
+	n, err := parseByteSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %q: want a size such as 512m or 4g", s)
+	}
+	if n != 0 && n < minMemoryLimit {
+		return 0, fmt.Errorf("invalid memory %q: the minimum memory limit is 6m", s)
+	}
+	return n, nil
+}
+
+// parseCpuset checks a cpuset such as "0-3" or "0,2,4-7".
+//
+//	in(1): string s CPU list ("none" for every CPU)
+//	out: string normalised list ("" for every CPU), error if s is not a list
+func parseCpuset(s string) (string, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if strings.EqualFold(s, "none") || strings.EqualFold(s, "all") {
+		return "", nil
+	}
+	if !cpusetPattern.MatchString(s) {
+		return "", fmt.Errorf("invalid cpuset_cpus %q: want CPU numbers and ranges such as 0-3 or 0,2", s)
+	}
+	for _, part := range strings.Split(s, ",") {
+		if lo, hi, ok := strings.Cut(part, "-"); ok {
+			a, _ := strconv.Atoi(lo)
+			b, _ := strconv.Atoi(hi)
+			if a > b {
+				return "", fmt.Errorf("invalid cpuset_cpus %q: range %s is reversed", s, part)
+			}
+		}
+	}
+	return s, nil
+}
+
+// parsePidsLimit converts a pids limit. "0", "-1", "none" and "unlimited"
+// all remove the limit and give -1, the value the engines understand.
+//
+//	in(1): string s maximum number of processes
+//	out: int64 limit, error if s is not a positive integer
+func parsePidsLimit(s string) (int64, error) {
+	if clearsLimit(s) || strings.TrimSpace(s) == "-1" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid pids_limit %q: want a positive number of processes or 'unlimited'", s)
+	}
+	return n, nil
+}
+
+// formatCPUs renders nano CPUs as a CPU count ("" when unlimited).
+func formatCPUs(nano int64) string {
+	if nano <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(nano)/1e9, 'f', -1, 64)
+}
+
+// formatByteSize renders bytes with the largest unit that divides them
+// exactly, in the form parseByteSize reads ("" for 0).
+func formatByteSize(n int64) string {
+	if n <= 0 {
+		return ""
+	}
+	for i, unit := range []string{"t", "g", "m", "k"} {
+		size := int64(1) << (10 * (4 - i))
+		if n%size == 0 {
+			return fmt.Sprintf("%d%s", n/size, unit)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// formatPidsLimit renders a pids limit ("" when unlimited).
+func formatPidsLimit(pids *int64) string {
+	if pids == nil || *pids <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(*pids, 10)
+}
+
+// formatResourceSummary renders the limits set in r as "key=value" pairs.
+func formatResourceSummary(r ResourceLimits) string {
+	var parts []string
+	for _, f := range r.fields() {
+		parts = append(parts, f.Key+"="+f.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resourceLimitsFromHostConfig reads the limits a container was created with.
+//
+//	in(1): *container.HostConfig hc inspected host configuration
+//	out: ResourceLimits current limits ("" for those not set)
+func resourceLimitsFromHostConfig(hc *container.HostConfig) ResourceLimits {
+	if hc == nil {
+		return ResourceLimits{}
+	}
+	return ResourceLimits{
+		CPUs:       formatCPUs(hc.NanoCPUs),
+		Memory:     formatByteSize(hc.Memory),
+		CpusetCpus: hc.CpusetCpus,
+		ShmSize:    formatByteSize(hc.ShmSize),
+		PidsLimit:  formatPidsLimit(hc.PidsLimit),
+	}
+}
+
+// resourceTargets points at the host config fields a ResourceLimits sets, so
+// the same code fills the API HostConfig and the on-disk hostconfig.json.
+// A nil target is left alone.
+type resourceTargets struct {
+	NanoCPUs   *int64
+	Memory     *int64
+	MemorySwap *int64
+	CpusetCpus *string
+	ShmSize    *int64
+	PidsLimit  **int64
+}
+
+// apply writes the options set in r to the targets. A memory limit also sets
+// the swap limit to twice its value, the engines' default for a new container,
+// so an update never conflicts with a smaller swap limit set earlier.
+//
+//	in(1): resourceTargets t fields to fill
+//	out: error if an option is invalid
+func (r ResourceLimits) apply(t resourceTargets) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if r.CPUs != "" && t.NanoCPUs != nil {
+		*t.NanoCPUs, _ = parseCPUs(r.CPUs)
+	}
+	if r.Memory != "" && t.Memory != nil {
+		*t.Memory, _ = parseMemoryLimit(r.Memory)
+		if t.MemorySwap != nil {
+			*t.MemorySwap = 2 * *t.Memory
+		}
+	}
+	if r.CpusetCpus != "" && t.CpusetCpus != nil {
+		*t.CpusetCpus, _ = parseCpuset(r.CpusetCpus)
+	}
+	if r.ShmSize != "" && t.ShmSize != nil {
+		*t.ShmSize, _ = parseByteSize(r.ShmSize)
+	}
+	if r.PidsLimit != "" && t.PidsLimit != nil {
+		pids, _ := parsePidsLimit(r.PidsLimit)
+		*t.PidsLimit = &pids
+	}
+	return nil
+}
+
+// applyToHostConfig sets the limits on a host config used to create a container.
+//
+//	in(1): *container.HostConfig hc host config being built
+//	out: error if an option is invalid
+func (r ResourceLimits) applyToHostConfig(hc *container.HostConfig) error {
+	return r.apply(resourceTargets{
+		NanoCPUs:   &hc.NanoCPUs,
+		Memory:     &hc.Memory,
+		MemorySwap: &hc.MemorySwap,
+		CpusetCpus: &hc.CpusetCpus,
+		ShmSize:    &hc.ShmSize,
+		PidsLimit:  &hc.PidsLimit,
+	})
+}
+
+// LiveUpdatable reports whether the engine update API can apply r to a
+// running container. The shm size is fixed when /dev/shm is mounted, and the
+// update API reads a zero CPU, memory or cpuset value as "unchanged", so
+// removing one of those limits needs the container to be recreated.
+//
+//	out: bool true when r can be applied without a restart
+func (r ResourceLimits) LiveUpdatable() bool {
+	if r.ShmSize != "" {
+		return false
+	}
+	for _, v := range []string{r.CPUs, r.Memory} {
+		if v != "" && clearsLimit(v) {
+			return false
+		}
+	}
+	cpuset, _ := parseCpuset(r.CpusetCpus)
+	return r.CpusetCpus == "" || cpuset != ""
+}
+
+// resourcesToProps stores limits in a property map for recreation.
+func resourcesToProps(r ResourceLimits, props map[string]string) {
+	props["CPUs"] = r.CPUs
+	props["Memory"] = r.Memory
+	props["CpusetCpus"] = r.CpusetCpus
+	props["ShmSize"] = r.ShmSize
+	props["PidsLimit"] = r.PidsLimit
+}
+
+// resourcesFromProps reads limits stored by resourcesToProps.
+func resourcesFromProps(props map[string]string) ResourceLimits {
+	return ResourceLimits{
+		CPUs:       props["CPUs"],
+		Memory:     props["Memory"],
+		CpusetCpus: props["CpusetCpus"],
+		ShmSize:    props["ShmSize"],
+		PidsLimit:  props["PidsLimit"],
+	}
+}
+
+// UpdateResources changes the resource limits of an existing container. CPU,
+// memory, cpuset and pids limits go through the engine update API and apply
+// immediately; the shm size, removed limits and engines refusing the update
+// fall back to editing the configuration (Docker) or recreating the container.
+//
+//	in(1): string containerID container ID or name
+//	in(2): ResourceLimits limits options to change (empty fields are kept)
+//	out: error
+func UpdateResources(containerID string, limits ResourceLimits) error {
+	if limits.IsZero() {
+		return fmt.Errorf("no resource option given (use --cpus, --memory, --cpuset-cpus, --shm-size or --pids-limit)")
+	}
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %v", err)
+	}
+	containerName := strings.TrimPrefix(containerJSON.Name, "/")
+
+	if limits.LiveUpdatable() {
+		var resources container.Resources
+		limits.apply(resourceTargets{
+			NanoCPUs:   &resources.NanoCPUs,
+			Memory:     &resources.Memory,
+			MemorySwap: &resources.MemorySwap,
+			CpusetCpus: &resources.CpusetCpus,
+			PidsLimit:  &resources.PidsLimit,
+		})
+		result, err := cli.ContainerUpdate(ctx, containerJSON.ID, client.ContainerUpdateOptions{Resources: &resources})
+		if err == nil {
+			for _, w := range result.Warnings {
+				common.PrintWarningMessage(w)
+			}
+			common.PrintSuccessMessage(fmt.Sprintf("Resource limits of '%s' updated live", containerName))
+			return nil
+		}
+		common.PrintWarningMessage(fmt.Sprintf("%s refused the live update (%v) — updating the container configuration instead", GetEngine().Name(), err))
+	} else if limits.ShmSize != "" {
+		common.PrintInfoMessage("The shm size cannot change on a running container — updating the container configuration")
+	}
+
+	if !EngineSupportsDirectConfigEdit() {
+		common.PrintInfoMessage(fmt.Sprintf("%s does not support direct config editing — using container recreation", GetEngine().Name()))
+		props, err := getContainerProperties(ctx, cli, containerID)
+		if err != nil {
+			return err
+		}
+		resourcesToProps(limits.WithDefaults(resourcesFromProps(props)), props)
+		return recreateContainerWithProperties(ctx, cli, containerID, props)
+	}
+
+	// Docker path: direct hostconfig.json edit
+	return directEditContainer(ctx, cli, containerID, containerName, func(hostConfig *HostConfigFull, _ map[string]interface{}) (bool, error) {
+		if err := limits.apply(resourceTargets{
+			NanoCPUs:   &hostConfig.NanoCpus,
+			Memory:     &hostConfig.Memory,
+			MemorySwap: &hostConfig.MemorySwap,
+			CpusetCpus: &hostConfig.CpusetCpus,
+			ShmSize:    &hostConfig.ShmSize,
+			PidsLimit:  &hostConfig.PidsLimit,
+		}); err != nil {
+			return false, err
+		}
+		common.PrintSuccessMessage(fmt.Sprintf("Updated resource limits of '%s'", containerName))
+		return true, nil
+	})
+}
+
+// ContainerResources is the structured form of ShowContainerResources.
+type ContainerResources struct {
+	Container string         `json:"container" yaml:"container"`
+	Limits    ResourceLimits `json:"limits" yaml:"limits"`
+}
+
+// ShowContainerResources prints the resource limits of a container and how
+// each one can be changed.
+//
+//	in(1): string containerID container ID or name
+//	out: error
+func ShowContainerResources(containerID string) error {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %v", err)
+	}
+	containerName := strings.TrimPrefix(containerJSON.Name, "/")
+	limits := resourceLimitsFromHostConfig(containerJSON.HostConfig)
+
+	if common.MachineOutput() {
+		return common.PrintStructured("resources", ContainerResources{Container: containerName, Limits: limits})
+	}
+
+	orDefault := func(value, unset string) string {
+		if value == "" {
+			return unset
+		}
+		return value
+	}
+	rows := [][]string{
+		{"CPUs", orDefault(limits.CPUs, "unlimited"), "live"},
+		{"Memory", orDefault(limits.Memory, "unlimited"), "live"},
+		{"CPU pinning", orDefault(limits.CpusetCpus, "all CPUs"), "live"},
+		{"Shared memory", orDefault(limits.ShmSize, "engine default"), "restart"},
+		{"Processes", orDefault(limits.PidsLimit, "unlimited"), "live"},
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   fmt.Sprintf("📊 Resources of %s", containerName),
+		Headers: []string{"Resource", "Limit", "Update"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			if col == 2 && content == "restart" {
+				return tui.ColorWarning
+			}
+			return lipgloss.Color("")
+		},
+	})
+	return nil
+}
@@ -0,0 +1,144 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestParseResourceValues(t *testing.T) {
+	cpus := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"2", 2e9, true},
+		{"1.5", 1.5e9, true},
+		{"0.25", 250e6, true},
+		{"0", 0, true},
+		{"none", 0, true},
+		{"-1", 0, false},
+		{"two", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+	}
+	for _, tt := range cpus {
+		got, err := parseCPUs(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseCPUs(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	sizes := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"1024", 1024, true},
+		{"512m", 512 << 20, true},
+		{"2G", 2 << 30, true},
+		{"1.5g", 3 << 29, true},
+		{"64MiB", 64 << 20, true},
+		{"256kb", 256 << 10, true},
+		{"none", 0, true},
+		{"4x", 0, false},
+		{"-1m", 0, false},
+	}
+	for _, tt := range sizes {
+		got, err := parseByteSize(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseByteSize(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := parseMemoryLimit("1m"); err == nil {
+		t.Error("parseMemoryLimit(1m) error = nil, want below the minimum")
+	}
+
+	cpusets := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"0-3", "0-3", true},
+		{"0, 2,4-7", "0,2,4-7", true},
+		{"0", "0", true},
+		{"none", "", true},
+		{"3-1", "", false},
+		{"0-", "", false},
+		{"a", "", false},
+	}
+	for _, tt := range cpusets {
+		got, err := parseCpuset(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseCpuset(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	pids := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"512", 512, true},
+		{"unlimited", -1, true},
+		{"-1", -1, true},
+		{"0", -1, true},
+		{"-5", 0, false},
+		{"many", 0, false},
+	}
+	for _, tt := range pids {
+		got, err := parsePidsLimit(tt.in)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parsePidsLimit(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestResourceLimitsHostConfig(t *testing.T) {
+	limits := ResourceLimits{CPUs: "1.5", Memory: "4g", CpusetCpus: "0-3", ShmSize: "1g", PidsLimit: "1024"}
+	var hc container.HostConfig
+	if err := limits.applyToHostConfig(&hc); err != nil {
+		t.Fatalf("applyToHostConfig() error = %v", err)
+	}
+	if hc.NanoCPUs != 1.5e9 || hc.Memory != 4<<30 || hc.MemorySwap != 8<<30 || hc.CpusetCpus != "0-3" || hc.ShmSize != 1<<30 || *hc.PidsLimit != 1024 {
+		t.Errorf("applyToHostConfig() = %+v", hc.Resources)
+	}
+	if got := resourceLimitsFromHostConfig(&hc); got != limits {
+		t.Errorf("resourceLimitsFromHostConfig() = %+v, want %+v", got, limits)
+	}
+
+	// Empty fields leave the host config alone, "none" removes the limit
+	if err := (ResourceLimits{Memory: "none"}).applyToHostConfig(&hc); err != nil || hc.Memory != 0 || hc.NanoCPUs != 1.5e9 {
+		t.Errorf("applyToHostConfig(memory none) = %+v, %v", hc.Resources, err)
+	}
+	if err := (ResourceLimits{CPUs: "fast"}).applyToHostConfig(&hc); err == nil || !strings.Contains(err.Error(), "cpus") {
+		t.Errorf("applyToHostConfig(cpus fast) error = %v, want invalid cpus", err)
+	}
+}
+
+func TestResourceLimitsLiveUpdatable(t *testing.T) {
+	tests := []struct {
+		limits ResourceLimits
+		want   bool
+	}{
+		{ResourceLimits{CPUs: "2", Memory: "4g"}, true},
+		{ResourceLimits{CpusetCpus: "0-1", PidsLimit: "unlimited"}, true},
+		{ResourceLimits{ShmSize: "1g"}, false},
+		{ResourceLimits{Memory: "0"}, false},
+		{ResourceLimits{CpusetCpus: "none"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.limits.LiveUpdatable(); got != tt.want {
+			t.Errorf("LiveUpdatable(%+v) = %v, want %v", tt.limits, got, tt.want)
+		}
+	}
+
+	merged := ResourceLimits{CPUs: "4"}.WithDefaults(ResourceLimits{CPUs: "2", ShmSize: "1g"})
+	if want := (ResourceLimits{CPUs: "4", ShmSize: "1g"}); merged != want {
+		t.Errorf("WithDefaults() = %+v, want %+v", merged, want)
+	}
+}
@@ -174,6 +174,14 @@ func ContainerAddUlimit(ulimit string) {
 	appendCommaSeparated(&containerCfg.ulimits, ulimit)
 }
 
+// ContainerSetResources sets the engine resource limits (CPUs, memory, cpuset,
+// shm size, pids limit) of the container. Empty fields keep their value.
+//
+//	in(1): ResourceLimits limits resource options
+func ContainerSetResources(limits ResourceLimits) {
+	containerCfg.resources = limits.WithDefaults(containerCfg.resources)
+}
+
 // ContainerSetRealtime enables or disables realtime mode (SYS_NICE + rtprio ulimit).
 //
 //	in(1): bool enabled
@@ -181,6 +189,22 @@ func ContainerSetRealtime(enabled bool) {
 	containerCfg.realtime = enabled
 }
 
+// ContainerSetRecordInput sets whether recorded sessions also capture the
+// keys typed, which includes any password entered in the session.
+//
+//	in(1): bool enabled
+func ContainerSetRecordInput(enabled bool) {
+	containerCfg.recordInput = enabled
+}
+
+// ContainerSetRecordRedact adds regexes redacted from recorded sessions on
+// top of the built-in secret patterns.
+//
+//	in(1): []string patterns
+func ContainerSetRecordRedact(patterns []string) {
+	containerCfg.recordRedact = patterns
+}
+
 // ContainerSetDesktop configures desktop/VNC access for the container.
 // When proto is non-empty, desktop mode is enabled with the given protocol,
 // host, and port. The protocol can be "http" (noVNC web) or "vnc" (direct VNC).
@@ -0,0 +1,607 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Named container snapshots: create, list, restore and diff
+ */
+
+package dock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/client"
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+const (
+	// SnapshotRepoPrefix is the local repository snapshot images are stored under,
+	// followed by the lower-cased container name.
+	SnapshotRepoPrefix = "localhost/rfswift_snapshot/"
+
+	// SnapshotLabel marks an image as an RF Swift container snapshot.
+	SnapshotLabel = "org.rfswift.snapshot"
+
+	snapshotContainerLabel  = "org.rfswift.snapshot.container"
+	snapshotNameLabel       = "org.rfswift.snapshot.name"
+	snapshotCreatedLabel    = "org.rfswift.snapshot.created"
+	snapshotPropertiesLabel = "org.rfswift.snapshot.properties"
+	snapshotHostConfigLabel = "org.rfswift.snapshot.hostconfig"
+	snapshotRestoredLabel   = "org.rfswift.restored_from"
+)
+
+// snapshotNamePattern follows the image tag grammar, since the snapshot name
+// becomes the tag of the snapshot image.
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// SnapshotInfo describes one stored snapshot.
+type SnapshotInfo struct {
+	Name      string `json:"name" yaml:"name"`
+	Container string `json:"container" yaml:"container"`
+	Image     string `json:"image" yaml:"image"`
+	Reference string `json:"reference" yaml:"reference"`
+	ID        string `json:"id" yaml:"id"`
+	Created   string `json:"created" yaml:"created"`
+	Size      int64  `json:"size_bytes" yaml:"size_bytes"`
+}
+
+// SnapshotChange is one filesystem change reported by SnapshotDiff.
+type SnapshotChange struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Path string `json:"path" yaml:"path"`
+}
+
+// snapshotDiffDocument is the structured form of SnapshotDiff.
+type snapshotDiffDocument struct {
+	Container string           `json:"container" yaml:"container"`
+	BaseImage string           `json:"base_image" yaml:"base_image"`
+	Changes   []SnapshotChange `json:"changes" yaml:"changes"`
+}
+
+// snapshotReference returns the image reference a snapshot is stored under.
+//
+//	in(1): string containerName container the snapshot belongs to
+//	in(2): string name snapshot name
+//	out: string image reference
+func snapshotReference(containerName, name string) string {
+	return SnapshotRepoPrefix + strings.ToLower(containerName) + ":" + name
+}
+
+// validateSnapshotName checks that name can be used as an image tag.
+//
+//	in(1): string name snapshot name
+//	out: error if the name is empty or contains invalid characters
+func validateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '_', '.' and '-' (max 128 chars, not starting with '.' or '-')", name)
+	}
+	return nil
+}
+
+// snapshotLabels builds the labels stored on a snapshot image: the container's
+// own labels plus the snapshot metadata, the run properties and the full host
+// config needed to recreate the container.
+//
+//	in(1): container.InspectResponse containerJSON inspected container
+//	in(2): string name snapshot name
+//	in(3): map[string]string props properties from getContainerProperties
+//	in(4): time.Time created snapshot time
+//	out: map[string]string labels
+//	out: error if the properties or host config cannot be encoded
+func snapshotLabels(containerJSON container.InspectResponse, name string, props map[string]string, created time.Time) (map[string]string, error) {
+	labels := make(map[string]string)
+	if containerJSON.Config != nil {
+		for k, v := range containerJSON.Config.Labels {
+			labels[k] = v
+		}
+	}
+
+	propsJSON, err := json.Marshal(props)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container properties: %v", err)
+	}
+	hostConfigJSON, err := json.Marshal(containerJSON.HostConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode host config: %v", err)
+	}
+
+	labels["org.container.project"] = "rfswift"
+	labels["org.rfswift.original_image"] = getDisplayImageName(containerJSON)
+	labels[SnapshotLabel] = "true"
+	labels[snapshotContainerLabel] = strings.TrimPrefix(containerJSON.Name, "/")
+	labels[snapshotNameLabel] = name
+	labels[snapshotCreatedLabel] = created.Format(time.RFC3339)
+	labels[snapshotPropertiesLabel] = string(propsJSON)
+	labels[snapshotHostConfigLabel] = string(hostConfigJSON)
+	return labels, nil
+}
+
+// restoredContainerLabels returns the labels for a container recreated from a
+// snapshot: the snapshot image labels without the snapshot metadata.
+//
+//	in(1): map[string]string imageLabels labels of the snapshot image
+//	in(2): string ref snapshot image reference
+//	out: map[string]string container labels
+func restoredContainerLabels(imageLabels map[string]string, ref string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range imageLabels {
+		if k == SnapshotLabel || strings.HasPrefix(k, SnapshotLabel+".") {
+			continue
+		}
+		labels[k] = v
+	}
+	labels[snapshotRestoredLabel] = ref
+	return labels
+}
+
+// SnapshotCreate commits a container to a named snapshot image. The run
+// properties and host config are stored as image labels so the container can
+// be recreated later with SnapshotRestore.
+//
+//	in(1): string containerName container ID or name
+//	in(2): string name snapshot name; a timestamp is used when empty
+//	out: error
+func SnapshotCreate(containerName string, name string) error {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container '%s': %v", containerName, err)
+	}
+	containerName = strings.TrimPrefix(containerJSON.Name, "/")
+
+	created := time.Now()
+	if name == "" {
+		name = created.Format("20060102-150405")
+	}
+	if err := validateSnapshotName(name); err != nil {
+		return err
+	}
+
+	ref := snapshotReference(containerName, name)
+	if _, err := inspectImage(ctx, cli, ref); err == nil {
+		return fmt.Errorf("snapshot '%s' already exists for container '%s'", name, containerName)
+	}
+
+	props, err := getContainerProperties(ctx, cli, containerJSON.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read container properties: %v", err)
+	}
+
+	labels, err := snapshotLabels(containerJSON, name, props, created)
+	if err != nil {
+		return err
+	}
+
+	common.PrintInfoMessage(fmt.Sprintf("Snapshotting container '%s' as '%s'...", containerName, name))
+
+	// The container is paused while committing so the snapshot is consistent.
+	commitResp, err := cli.ContainerCommit(ctx, containerJSON.ID, client.ContainerCommitOptions{
+		Reference: ref,
+		Comment:   fmt.Sprintf("RF Swift: snapshot '%s' of container '%s'", name, containerName),
+		Config: &container.Config{
+			Labels: labels,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit container: %v", err)
+	}
+
+	common.PrintSuccessMessage(fmt.Sprintf("Snapshot '%s' created: %s (ID: %s)", name, ref, shortImageID(commitResp.ID)))
+	return nil
+}
+
+// ListSnapshots returns the stored snapshots, newest first.
+//
+//	in(1): string containerName only list snapshots of this container; empty for all
+//	out: []SnapshotInfo
+//	out: error
+func ListSnapshots(containerName string) ([]SnapshotInfo, error) {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	return listSnapshots(ctx, cli, containerName)
+}
+
+func listSnapshots(ctx context.Context, cli *client.Client, containerName string) ([]SnapshotInfo, error) {
+	filters := make(client.Filters)
+	filters.Add("label", SnapshotLabel+"=true")
+	imagesRes, err := cli.ImageList(ctx, client.ImageListOptions{Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshot images: %v", err)
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, img := range imagesRes.Items {
+		owner := img.Labels[snapshotContainerLabel]
+		if containerName != "" && !strings.EqualFold(owner, containerName) {
+			continue
+		}
+		ref := snapshotReference(owner, img.Labels[snapshotNameLabel])
+		for _, tag := range img.RepoTags {
+			if strings.HasPrefix(tag, SnapshotRepoPrefix) || strings.HasPrefix("localhost/"+tag, SnapshotRepoPrefix) {
+				ref = tag
+				break
+			}
+		}
+		created := img.Labels[snapshotCreatedLabel]
+		if created == "" {
+			created = time.Unix(img.Created, 0).Format(time.RFC3339)
+		}
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:      img.Labels[snapshotNameLabel],
+			Container: owner,
+			Image:     img.Labels["org.rfswift.original_image"],
+			Reference: ref,
+			ID:        shortImageID(img.ID),
+			Created:   created,
+			Size:      img.Size,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Created != snapshots[j].Created {
+			return snapshots[i].Created > snapshots[j].Created
+		}
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots, nil
+}
+
+// DisplaySnapshots prints the stored snapshots as a table, or as a structured
+// document with --output json|yaml.
+//
+//	in(1): string containerName only list snapshots of this container; empty for all
+func DisplaySnapshots(containerName string) {
+	snapshots, err := ListSnapshots(containerName)
+	if err != nil {
+		common.PrintErrorMessage(err)
+		os.Exit(1)
+	}
+
+	if common.MachineOutput() {
+		if err := common.PrintStructured("snapshots", snapshots); err != nil {
+			common.PrintErrorMessage(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(snapshots) == 0 {
+		common.PrintInfoMessage("No snapshots found. Create one with: rfswift snapshot create -c <container>")
+		return
+	}
+
+	rows := make([][]string, 0, len(snapshots))
+	for _, s := range snapshots {
+		created := s.Created
+		if t, err := time.Parse(time.RFC3339, s.Created); err == nil {
+			created = t.Format("2006-01-02 15:04:05")
+		}
+		rows = append(rows, []string{s.Container, s.Name, s.Image, created, s.ID, formatSize(s.Size)})
+	}
+
+	tui.RenderTable(tui.TableConfig{
+		Title:   "📸 Container Snapshots",
+		Headers: []string{"Container", "Snapshot", "Image", "Created", "ID", "Size"},
+		Rows:    rows,
+	})
+}
+
+// findSnapshot resolves a snapshot by name, optionally scoped to a container.
+//
+//	in(1): context.Context ctx
+//	in(2): *client.Client cli
+//	in(3): string containerName owning container; empty to search all snapshots
+//	in(4): string name snapshot name
+//	out: SnapshotInfo
+//	out: error if no snapshot or more than one matches
+func findSnapshot(ctx context.Context, cli *client.Client, containerName, name string) (SnapshotInfo, error) {
+	snapshots, err := listSnapshots(ctx, cli, containerName)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	var matches []SnapshotInfo
+	for _, s := range snapshots {
+		if s.Name == name {
+			matches = append(matches, s)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if containerName != "" {
+			return SnapshotInfo{}, fmt.Errorf("no snapshot '%s' found for container '%s'", name, containerName)
+		}
+		return SnapshotInfo{}, fmt.Errorf("no snapshot '%s' found", name)
+	case 1:
+		return matches[0], nil
+	}
+
+	owners := make([]string, len(matches))
+	for i, m := range matches {
+		owners[i] = m.Container
+	}
+	return SnapshotInfo{}, fmt.Errorf("snapshot '%s' exists for several containers (%s); select one with --container", name, strings.Join(owners, ", "))
+}
+
+// SnapshotRestore recreates a container from a snapshot with the host config
+// it had when the snapshot was taken. An existing container with the same
+// name is replaced after confirmation; it is removed only once the new
+// container exists and kept as is if the restore fails. The restored
+// container is not started.
+//
+//	in(1): string containerName owning container; empty to search all snapshots
+//	in(2): string name snapshot name
+//	in(3): bool force replace an existing container without asking
+//	out: error
+func SnapshotRestore(containerName string, name string, force bool) error {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	snap, err := findSnapshot(ctx, cli, containerName, name)
+	if err != nil {
+		return err
+	}
+	containerName = snap.Container
+
+	imageJSON, err := inspectImage(ctx, cli, snap.Reference)
+	if err != nil {
+		return fmt.Errorf("failed to inspect snapshot image '%s': %v", snap.Reference, err)
+	}
+	var imageLabels map[string]string
+	if imageJSON.Config != nil {
+		imageLabels = imageJSON.Config.Labels
+	}
+
+	hostConfig := &container.HostConfig{}
+	raw := imageLabels[snapshotHostConfigLabel]
+	if raw == "" {
+		return fmt.Errorf("snapshot '%s' has no stored host config", name)
+	}
+	if err := json.Unmarshal([]byte(raw), hostConfig); err != nil {
+		return fmt.Errorf("snapshot '%s' has an invalid host config: %v", name, err)
+	}
+
+	// Replace the current container, if any. It is only moved aside here and
+	// removed once the new one exists, so a failed restore leaves it in place.
+	var previousID string
+	if existing, err := inspectContainer(ctx, cli, containerName); err == nil {
+		if !force {
+			if !tui.IsInteractive() {
+				return fmt.Errorf("container '%s' exists; use --force to replace it", containerName)
+			}
+			if !tui.Confirm(fmt.Sprintf("Replace container '%s' with snapshot '%s'?", containerName, name)) {
+				common.PrintInfoMessage("Restore cancelled.")
+				return nil
+			}
+		}
+		aside := fmt.Sprintf("%s_rfswift_old_%d", containerName, time.Now().UnixNano())
+		if _, err := cli.ContainerRename(ctx, existing.ID, client.ContainerRenameOptions{NewName: aside}); err != nil {
+			return fmt.Errorf("failed to move container '%s' aside: %v", containerName, err)
+		}
+		previousID = existing.ID
+	}
+
+	var newContainerID string
+	defer func() {
+		if previousID == "" {
+			return
+		}
+		if newContainerID == "" {
+			if _, err := cli.ContainerRename(ctx, previousID, client.ContainerRenameOptions{NewName: containerName}); err != nil {
+				common.PrintWarningMessage(fmt.Sprintf("Restore failed and container %s could not be renamed back to '%s': %v", shortImageID(previousID), containerName, err))
+			} else {
+				common.PrintInfoMessage(fmt.Sprintf("Restore failed: container '%s' was kept unchanged.", containerName))
+			}
+			return
+		}
+		common.PrintInfoMessage(fmt.Sprintf("Removing the replaced container %s...", shortImageID(previousID)))
+		if _, err := cli.ContainerRemove(ctx, previousID, client.ContainerRemoveOptions{Force: true}); err != nil {
+			common.PrintWarningMessage(fmt.Sprintf("Could not remove the replaced container %s: %v (you can remove it manually)", shortImageID(previousID), err))
+		}
+	}()
+
+	labels := restoredContainerLabels(imageLabels, snap.Reference)
+
+	// Recreate the NAT network if it was removed with the container
+	var networkingConfig *network.NetworkingConfig
+	if netName := string(hostConfig.NetworkMode); strings.HasPrefix(netName, NATNetworkPrefix) {
+		if existing, _ := findNATNetwork(ctx, cli, netName); existing == "" {
+			target := netName
+			if netName == networkName(containerName) {
+				target = ""
+			}
+			if _, _, err := createOrJoinNATNetwork(ctx, cli, containerName, target, labels["org.rfswift.nat_subnet"]); err != nil {
+				return fmt.Errorf("failed to recreate NAT network '%s': %v", netName, err)
+			}
+		}
+		networkingConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{netName: {}},
+		}
+	}
+
+	if len(hostConfig.DeviceCgroupRules) == 0 {
+		if label := labels["org.rfswift.cgroup_rules"]; label != "" {
+			hostConfig.DeviceCgroupRules = strings.Split(label, ",")
+		}
+	}
+	if !EngineSupportsDirectConfigEdit() {
+		sanitizeHostConfigForPodman(hostConfig)
+	}
+
+	// Env, Cmd and Entrypoint come from the snapshot image
+	containerConfig := &container.Config{
+		Image:        snap.Reference,
+		OpenStdin:    true,
+		StdinOnce:    false,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		Labels:       labels,
+	}
+
+	common.PrintInfoMessage(fmt.Sprintf("Recreating container '%s' from snapshot '%s'...", containerName, name))
+
+	if len(hostConfig.DeviceCgroupRules) > 0 && GetEngine().Type() == EnginePodman && !IsRootlessPodman() {
+		cid, err := podmanCreateViaCLI(containerName, snap.Reference, containerConfig, hostConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create container via Podman CLI: %v", err)
+		}
+		newContainerID = cid
+	} else {
+		if IsRootlessPodman() && len(hostConfig.DeviceCgroupRules) > 0 {
+			common.PrintWarningMessage("Rootless Podman: dropping device cgroup rules (not supported)")
+			hostConfig.DeviceCgroupRules = nil
+		}
+		resp, err := cli.ContainerCreate(ctx, client.ContainerCreateOptions{
+			Config:           containerConfig,
+			HostConfig:       hostConfig,
+			NetworkingConfig: networkingConfig,
+			Name:             containerName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create container: %v", err)
+		}
+		newContainerID = resp.ID
+	}
+
+	common.PrintSuccessMessage(fmt.Sprintf("Container '%s' restored from snapshot '%s' (ID: %s)", containerName, name, shortImageID(newContainerID)))
+	common.PrintInfoMessage(fmt.Sprintf("Start it with: rfswift exec -c %s", containerName))
+	return nil
+}
+
+// SnapshotDelete removes a stored snapshot image.
+//
+//	in(1): string containerName owning container; empty to search all snapshots
+//	in(2): string name snapshot name
+//	out: error
+func SnapshotDelete(containerName string, name string) error {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	snap, err := findSnapshot(ctx, cli, containerName, name)
+	if err != nil {
+		return err
+	}
+	if _, err := cli.ImageRemove(ctx, snap.Reference, client.ImageRemoveOptions{}); err != nil {
+		return fmt.Errorf("failed to remove snapshot '%s': %v", name, err)
+	}
+	common.PrintSuccessMessage(fmt.Sprintf("Snapshot '%s' of container '%s' removed", name, snap.Container))
+	return nil
+}
+
+// SnapshotDiff lists the filesystem changes of a container relative to the
+// image it was created from (the last restored snapshot, if any), using the
+// engine's container diff API.
+//
+//	in(1): string containerName container ID or name
+//	in(2): string pathPrefix only show changes under this path; empty for all
+//	out: error
+func SnapshotDiff(containerName string, pathPrefix string) error {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container '%s': %v", containerName, err)
+	}
+	containerName = strings.TrimPrefix(containerJSON.Name, "/")
+
+	diffRes, err := cli.ContainerDiff(ctx, containerJSON.ID, client.ContainerDiffOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to diff container '%s': %v", containerName, err)
+	}
+
+	baseImage := ""
+	if containerJSON.Config != nil {
+		baseImage = containerJSON.Config.Image
+	}
+
+	changes := []SnapshotChange{}
+	for _, c := range diffRes.Changes {
+		if pathPrefix != "" && !strings.HasPrefix(c.Path, pathPrefix) {
+			continue
+		}
+		changes = append(changes, SnapshotChange{Kind: c.Kind.String(), Path: c.Path})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	if common.MachineOutput() {
+		return common.PrintStructured("snapshot_diff", snapshotDiffDocument{
+			Container: containerName,
+			BaseImage: baseImage,
+			Changes:   changes,
+		})
+	}
+
+	if len(changes) == 0 {
+		common.PrintInfoMessage(fmt.Sprintf("No filesystem changes in '%s' since %s", containerName, baseImage))
+		return nil
+	}
+
+	rows := make([][]string, len(changes))
+	for i, c := range changes {
+		rows[i] = []string{c.Kind, c.Path}
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   fmt.Sprintf("📝 Changes in '%s' since %s (A=added, C=changed, D=deleted)", containerName, baseImage),
+		Headers: []string{"Kind", "Path"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			if col != 0 {
+				return lipgloss.Color("")
+			}
+			switch content {
+			case "A":
+				return tui.ColorSuccess
+			case "D":
+				return tui.ColorDanger
+			}
+			return tui.ColorWarning
+		},
+	})
+	return nil
+}
+
+// shortImageID trims the digest algorithm and shortens an image or container ID.
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
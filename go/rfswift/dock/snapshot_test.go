@@ -0,0 +1,83 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"20261017-153000", true},
+		{"before_firmware.v2", true},
+		{"", false},
+		{"-leading-dash", false},
+		{".hidden", false},
+		{"with space", false},
+		{"with/slash", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateSnapshotName(tt.name); (err == nil) != tt.valid {
+			t.Errorf("validateSnapshotName(%q) error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestSnapshotLabelsRoundTrip(t *testing.T) {
+	containerJSON := container.InspectResponse{
+		Name: "/sdr_lab",
+		Config: &container.Config{
+			Image:  "penthertz/rfswift_noble:sdr_full",
+			Labels: map[string]string{"org.rfswift.cgroup_rules": "c 189:* rwm"},
+		},
+		HostConfig: &container.HostConfig{
+			NetworkMode: "rfswift_nat_sdr_lab",
+			Binds:       []string{"/dev/bus/usb:/dev/bus/usb"},
+			Resources:   container.Resources{DeviceCgroupRules: []string{"c 189:* rwm"}},
+		},
+	}
+	props := map[string]string{"Privileged": "false"}
+
+	labels, err := snapshotLabels(containerJSON, "before", props, time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("snapshotLabels error: %v", err)
+	}
+	if labels[snapshotContainerLabel] != "sdr_lab" || labels[snapshotNameLabel] != "before" || labels[SnapshotLabel] != "true" {
+		t.Errorf("snapshot metadata labels = %v", labels)
+	}
+	if got := labels["org.rfswift.original_image"]; got != "penthertz/rfswift_noble:sdr_full" {
+		t.Errorf("original image label = %q, want %q", got, "penthertz/rfswift_noble:sdr_full")
+	}
+
+	var hc container.HostConfig
+	if err := json.Unmarshal([]byte(labels[snapshotHostConfigLabel]), &hc); err != nil {
+		t.Fatalf("host config label does not decode: %v", err)
+	}
+	if hc.NetworkMode != "rfswift_nat_sdr_lab" || len(hc.Binds) != 1 || len(hc.DeviceCgroupRules) != 1 {
+		t.Errorf("decoded host config = %+v, want the inspected one", hc)
+	}
+
+	restored := restoredContainerLabels(labels, snapshotReference("sdr_lab", "before"))
+	for k := range restored {
+		if k == SnapshotLabel || strings.HasPrefix(k, SnapshotLabel+".") {
+			t.Errorf("restored container keeps snapshot label %q", k)
+		}
+	}
+	if restored["org.rfswift.cgroup_rules"] != "c 189:* rwm" {
+		t.Errorf("restored container lost label org.rfswift.cgroup_rules: %v", restored)
+	}
+	if restored[snapshotRestoredLabel] != "localhost/rfswift_snapshot/sdr_lab:before" {
+		t.Errorf("restored_from = %q", restored[snapshotRestoredLabel])
+	}
+}
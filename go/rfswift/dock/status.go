@@ -0,0 +1,685 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Live health checks of the desktop, VPN, audio and X11 subsystems of a running container
+ */
+
+package dock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// VPNLabel records the VPN type (never its key) a container was created with.
+const VPNLabel = "org.rfswift.vpn"
+
+// desktopLabel records the desktop endpoint as "proto://host:port".
+const desktopLabel = "org.rfswift.desktop"
+
+// Exit codes of the in-container probe scripts that are not plain failures.
+const (
+	probeUnverifiable = 2 // the tool needed to verify is missing from the image
+	probeRefused      = 3 // the server answered but refused the client
+)
+
+// RunContainerStatus probes the desktop, VPN, audio and X11 subsystems of a
+// running container and prints a report in the doctor format.
+//
+//	in(1): string containerName  container ID or name
+//	out: (int failed checks, error)
+func RunContainerStatus(containerName string) (int, error) {
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerName)
+	if err != nil {
+		return 0, fmt.Errorf("container '%s' not found: %v", containerName, err)
+	}
+	name := strings.TrimPrefix(containerJSON.Name, "/")
+	report := &DoctorReport{}
+
+	if !common.MachineOutput() {
+		tui.PrintStatusHeader(name)
+	}
+
+	if containerJSON.State == nil || !containerJSON.State.Running {
+		report.add(CheckResult{"Container", "fail",
+			fmt.Sprintf("Not running (start it: rfswift exec -c %s)", name)})
+		printReportAs("status", name, report)
+		return report.fail, nil
+	}
+	report.add(CheckResult{"Container", "ok", fmt.Sprintf("Running (%s)", containerJSON.Config.Image)})
+
+	checkDesktopStatus(ctx, cli, containerJSON, name, report)
+	checkVPNStatus(ctx, cli, containerJSON, name, report)
+	checkAudioStatus(ctx, cli, containerJSON, report)
+	checkX11Status(ctx, cli, containerJSON, report)
+
+	printReportAs("status", name, report)
+	return report.fail, nil
+}
+
+// containerEnv returns the value of an environment variable of the container.
+func containerEnv(containerJSON container.InspectResponse, key string) string {
+	if containerJSON.Config == nil {
+		return ""
+	}
+	for _, kv := range containerJSON.Config.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------------
+// Desktop
+// ---------------------------------------------------------------------------
+
+// desktopEndpoint is the VNC/noVNC listener configured for a container.
+type desktopEndpoint struct {
+	Proto string
+	Host  string
+	Port  int
+}
+
+// parseDesktopLabel parses the "proto://host:port" desktop label.
+//
+//	in(1): string label
+//	out: (desktopEndpoint, bool ok)
+func parseDesktopLabel(label string) (desktopEndpoint, bool) {
+	proto, hostPort, ok := strings.Cut(label, "://")
+	if !ok || proto == "" {
+		return desktopEndpoint{}, false
+	}
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return desktopEndpoint{}, false
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return desktopEndpoint{}, false
+	}
+	return desktopEndpoint{Proto: proto, Host: host, Port: port}, true
+}
+
+// desktopEndpointOf returns the desktop listener of a container from its
+// label, or from the RFSWIFT_DESKTOP_* environment when the label is missing.
+func desktopEndpointOf(containerJSON container.InspectResponse) (desktopEndpoint, bool) {
+	if containerJSON.Config != nil {
+		if ep, ok := parseDesktopLabel(containerJSON.Config.Labels[desktopLabel]); ok {
+			return ep, true
+		}
+	}
+	proto := containerEnv(containerJSON, "RFSWIFT_DESKTOP_PROTO")
+	port := containerEnv(containerJSON, "RFSWIFT_DESKTOP_PORT")
+	if proto == "" || port == "" {
+		return desktopEndpoint{}, false
+	}
+	host := containerEnv(containerJSON, "RFSWIFT_DESKTOP_HOST")
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return parseDesktopLabel(fmt.Sprintf("%s://%s", proto, net.JoinHostPort(host, port)))
+}
+
+// listeningTCPPorts parses /proc/net/tcp and /proc/net/tcp6 and returns the
+// set of ports in the LISTEN state.
+//
+//	in(1): string procNetTCP  concatenated file contents
+//	out: map[int]bool
+func listeningTCPPorts(procNetTCP string) map[int]bool {
+	ports := make(map[int]bool)
+	for _, line := range strings.Split(procNetTCP, "\n") {
+		fields := strings.Fields(line)
+		// sl local_address rem_address st ...; 0A is TCP_LISTEN
+		if len(fields) < 4 || fields[3] != "0A" {
+			continue
+		}
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		port, err := strconv.ParseInt(fields[1][i+1:], 16, 32)
+		if err != nil {
+			continue
+		}
+		ports[int(port)] = true
+	}
+	return ports
+}
+
+// containerListens reports whether a TCP port is listening inside a container.
+func containerListens(ctx context.Context, cli *client.Client, containerID string, port int) (bool, error) {
+	out, _, err := execScriptAsRoot(ctx, cli, containerID, "cat /proc/net/tcp /proc/net/tcp6 2>/dev/null")
+	if err != nil {
+		return false, err
+	}
+	return listeningTCPPorts(out)[port], nil
+}
+
+// waitForDesktop polls until the desktop port listens inside the container.
+//
+//	in(1): context.Context ctx
+//	in(2): *client.Client cli
+//	in(3): string containerID
+//	in(4): int port
+//	in(5): time.Duration timeout
+//	out: bool listening
+func waitForDesktop(ctx context.Context, cli *client.Client, containerID string, port int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if ok, err := containerListens(ctx, cli, containerID, port); ok || err != nil {
+			return ok
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// desktopHostAddress returns the host address the desktop port is reachable
+// at, or "" when a non-host network container does not publish it.
+func desktopHostAddress(containerJSON container.InspectResponse, ep desktopEndpoint) string {
+	netMode := ""
+	if containerJSON.HostConfig != nil {
+		netMode = string(containerJSON.HostConfig.NetworkMode)
+	}
+	if netMode == "host" {
+		return net.JoinHostPort(dialableHost(ep.Host), strconv.Itoa(ep.Port))
+	}
+	if containerJSON.NetworkSettings == nil {
+		return ""
+	}
+	for port, bindings := range containerJSON.NetworkSettings.Ports {
+		if int(port.Num()) != ep.Port || string(port.Proto()) != "tcp" {
+			continue
+		}
+		for _, b := range bindings {
+			if b.HostPort == "" {
+				continue
+			}
+			host := ""
+			if b.HostIP.IsValid() {
+				host = b.HostIP.String()
+			}
+			return net.JoinHostPort(dialableHost(host), b.HostPort)
+		}
+	}
+	return ""
+}
+
+// dialableHost maps wildcard listen addresses to the loopback address.
+func dialableHost(host string) string {
+	switch host {
+	case "", "0.0.0.0", "::":
+		return "127.0.0.1"
+	}
+	return host
+}
+
+func checkDesktopStatus(ctx context.Context, cli *client.Client, containerJSON container.InspectResponse, name string, report *DoctorReport) {
+	ep, ok := desktopEndpointOf(containerJSON)
+	if !ok {
+		report.add(CheckResult{"Desktop", "skip", "Not enabled"})
+		return
+	}
+	server := "VNC server"
+	if ep.Proto == "http" {
+		server = "noVNC (websockify)"
+	}
+
+	listening, err := containerListens(ctx, cli, containerJSON.ID, ep.Port)
+	if err != nil {
+		report.add(CheckResult{"Desktop", "fail", fmt.Sprintf("Probe failed: %v", err)})
+		return
+	}
+	if !listening {
+		report.add(CheckResult{"Desktop", "fail",
+			fmt.Sprintf("%s not listening on port %d (start it: rfswift exec -c %s --desktop)", server, ep.Port, name)})
+		return
+	}
+
+	address := desktopHostAddress(containerJSON, ep)
+	if address == "" {
+		report.add(CheckResult{"Desktop", "warn",
+			fmt.Sprintf("%s listening in the container but port %d is not published (run: rfswift ports bind -c %s -b %d/tcp:127.0.0.1:%d)",
+				server, ep.Port, name, ep.Port, ep.Port)})
+		return
+	}
+	conn, err := net.DialTimeout("tcp", address, 3*time.Second)
+	if err != nil {
+		report.add(CheckResult{"Desktop", "warn",
+			fmt.Sprintf("%s listening in the container but %s is not reachable from the host (check firewall rules)", server, address)})
+		return
+	}
+	conn.Close()
+	report.add(CheckResult{"Desktop", "ok", fmt.Sprintf("%s reachable at %s", server, address)})
+}
+
+// ---------------------------------------------------------------------------
+// VPN
+// ---------------------------------------------------------------------------
+
+// detectVPNType returns the VPN type of a container from its label, falling
+// back to the config mounts and key variables set by --vpn.
+//
+//	in(1): map[string]string labels
+//	in(2): []string env  "KEY=value" entries
+//	in(3): []string mounts  container destination paths
+//	out: string VPN type, "" when none is configured
+func detectVPNType(labels map[string]string, env []string, mounts []string) string {
+	if t := labels[VPNLabel]; t != "" {
+		return t
+	}
+	for _, m := range mounts {
+		switch m {
+		case "/etc/wireguard/wg0.conf":
+			return VPNWireGuard
+		case "/etc/openvpn/client.ovpn":
+			return VPNOpenVPN
+		}
+	}
+	for _, kv := range env {
+		switch {
+		case strings.HasPrefix(kv, "TS_AUTHKEY="):
+			return VPNTailscale
+		case strings.HasPrefix(kv, "NB_SETUP_KEY="):
+			return VPNNetbird
+		}
+	}
+	return ""
+}
+
+// vpnDetectScript finds a VPN started with 'rfswift exec --vpn' on a
+// container created without one.
+const vpnDetectScript = `if [ -S /var/run/tailscale/tailscaled.sock ]; then echo tailscale
+elif [ -d /sys/class/net/wg0 ]; then echo wireguard
+elif grep -qsx netbird /proc/[0-9]*/comm; then echo netbird
+elif grep -qsx openvpn /proc/[0-9]*/comm; then echo openvpn
+fi`
+
+// netInterface is the link, address and route state of a network interface.
+type netInterface struct {
+	Name   string
+	Up     bool
+	Addrs  []string
+	Routes int
+}
+
+// netInfoScript dumps links, addresses and routes in sections parsed by
+// parseNetInfo.
+const netInfoScript = `command -v ip >/dev/null 2>&1 || exit 2
+echo '#link'; ip -o link show
+echo '#addr'; ip -o addr show
+echo '#route'; ip route show; ip -6 route show 2>/dev/null`
+
+// parseNetInfo parses the output of netInfoScript.
+//
+//	in(1): string out
+//	out: map[string]*netInterface  keyed by interface name
+func parseNetInfo(out string) map[string]*netInterface {
+	ifaces := make(map[string]*netInterface)
+	get := func(name string) *netInterface {
+		name, _, _ = strings.Cut(strings.TrimSuffix(name, ":"), "@")
+		if ifaces[name] == nil {
+			ifaces[name] = &netInterface{Name: name}
+		}
+		return ifaces[name]
+	}
+
+	section := ""
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "#") {
+			section = strings.TrimPrefix(strings.TrimSpace(line), "#")
+			continue
+		}
+		fields := strings.Fields(line)
+		switch section {
+		case "link":
+			// 5: wg0: <POINTOPOINT,NOARP,UP,LOWER_UP> mtu 1420 ...
+			if len(fields) < 3 {
+				continue
+			}
+			iface := get(fields[1])
+			for _, flag := range strings.Split(strings.Trim(fields[2], "<>"), ",") {
+				if flag == "UP" {
+					iface.Up = true
+				}
+			}
+		case "addr":
+			// 5: wg0    inet 10.0.0.2/32 scope global wg0
+			if len(fields) < 4 || (fields[2] != "inet" && fields[2] != "inet6") {
+				continue
+			}
+			if strings.HasPrefix(fields[3], "fe80:") {
+				continue
+			}
+			iface := get(fields[1])
+			iface.Addrs = append(iface.Addrs, fields[3])
+		case "route":
+			for i := 0; i+1 < len(fields); i++ {
+				if fields[i] == "dev" {
+					get(fields[i+1]).Routes++
+					break
+				}
+			}
+		}
+	}
+	return ifaces
+}
+
+// tunnelInterface returns the first interface whose name starts with one of
+// the prefixes, preferring interfaces that are up.
+func tunnelInterface(ifaces map[string]*netInterface, prefixes ...string) *netInterface {
+	var found *netInterface
+	for name, iface := range ifaces {
+		for _, p := range prefixes {
+			if !strings.HasPrefix(name, p) {
+				continue
+			}
+			if found == nil || (iface.Up && !found.Up) || (iface.Up == found.Up && name < found.Name) {
+				found = iface
+			}
+		}
+	}
+	return found
+}
+
+// interfaceSummary describes an interface for the report.
+func interfaceSummary(iface *netInterface) string {
+	return fmt.Sprintf("%s up, %s, %d route(s)", iface.Name, strings.Join(iface.Addrs, " "), iface.Routes)
+}
+
+// checkTunnel reports the state of a kernel tunnel interface.
+//
+//	out: bool  true when the interface is up with an address
+func checkTunnel(report *DoctorReport, check string, iface *netInterface, missing string) bool {
+	switch {
+	case iface == nil || !iface.Up:
+		report.add(CheckResult{check, "fail", missing})
+		return false
+	case len(iface.Addrs) == 0:
+		report.add(CheckResult{check, "fail", fmt.Sprintf("%s is up but has no address", iface.Name)})
+		return false
+	case iface.Routes == 0:
+		report.add(CheckResult{check, "warn",
+			fmt.Sprintf("%s is up (%s) but no route uses it (check the routes pushed by the VPN config)", iface.Name, strings.Join(iface.Addrs, " "))})
+		return false
+	}
+	return true
+}
+
+// parseWGHandshakes parses 'wg show <iface> latest-handshakes' and returns
+// the most recent handshake, zero when no peer ever completed one.
+func parseWGHandshakes(out string) time.Time {
+	var latest int64
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if ts, err := strconv.ParseInt(fields[1], 10, 64); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+	if latest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(latest, 0)
+}
+
+// tailscaleStatus is the subset of 'tailscale status --json' we report on.
+type tailscaleStatus struct {
+	BackendState string   `json:"BackendState"`
+	TailscaleIPs []string `json:"TailscaleIPs"`
+	Health       []string `json:"Health"`
+	AuthURL      string   `json:"AuthURL"`
+}
+
+// netbirdStatus is the subset of 'netbird status --json' we report on.
+type netbirdStatus struct {
+	Management struct {
+		Connected bool   `json:"connected"`
+		Error     string `json:"error"`
+	} `json:"management"`
+	Signal struct {
+		Connected bool `json:"connected"`
+	} `json:"signal"`
+	NetbirdIP string `json:"netbirdIp"`
+}
+
+func checkVPNStatus(ctx context.Context, cli *client.Client, containerJSON container.InspectResponse, name string, report *DoctorReport) {
+	var labels map[string]string
+	var env []string
+	if containerJSON.Config != nil {
+		labels, env = containerJSON.Config.Labels, containerJSON.Config.Env
+	}
+	var mounts []string
+	for _, m := range containerJSON.Mounts {
+		mounts = append(mounts, m.Destination)
+	}
+	vpnType := detectVPNType(labels, env, mounts)
+	if vpnType == "" {
+		if out, _, err := execScriptAsRoot(ctx, cli, containerJSON.ID, vpnDetectScript); err == nil {
+			vpnType = strings.TrimSpace(out)
+		}
+	}
+	if vpnType == "" {
+		report.add(CheckResult{"VPN", "skip", "Not configured"})
+		return
+	}
+	check := fmt.Sprintf("VPN (%s)", vpnType)
+
+	netOut, code, err := execScriptAsRoot(ctx, cli, containerJSON.ID, netInfoScript)
+	if err != nil {
+		report.add(CheckResult{check, "fail", fmt.Sprintf("Probe failed: %v", err)})
+		return
+	}
+	ifaces := parseNetInfo(netOut)
+	haveIP := code != probeUnverifiable
+
+	switch vpnType {
+	case VPNWireGuard:
+		if !haveIP {
+			report.add(CheckResult{check, "warn", "Cannot verify wg0: 'ip' is not installed in the container"})
+			return
+		}
+		if !checkTunnel(report, check, ifaces["wg0"],
+			fmt.Sprintf("wg0 is not up (start it: rfswift exec -c %s --vpn wireguard:<wg0.conf>)", name)) {
+			return
+		}
+		out, code, _ := execScriptAsRoot(ctx, cli, containerJSON.ID, "wg show wg0 latest-handshakes")
+		if code != 0 {
+			report.add(CheckResult{check, "warn", interfaceSummary(ifaces["wg0"]) + " ('wg' unavailable, handshake not verified)"})
+			return
+		}
+		last := parseWGHandshakes(out)
+		if last.IsZero() {
+			report.add(CheckResult{check, "warn",
+				interfaceSummary(ifaces["wg0"]) + ", no handshake with any peer yet (check Endpoint and keys in wg0.conf)"})
+			return
+		}
+		report.add(CheckResult{check, "ok",
+			fmt.Sprintf("%s, last handshake %s ago", interfaceSummary(ifaces["wg0"]), time.Since(last).Round(time.Second))})
+
+	case VPNOpenVPN:
+		if !haveIP {
+			report.add(CheckResult{check, "warn", "Cannot verify the tun interface: 'ip' is not installed in the container"})
+			return
+		}
+		iface := tunnelInterface(ifaces, "tun")
+		if !checkTunnel(report, check, iface,
+			"No tun interface is up (check the credentials and remote in client.ovpn, and that the container is privileged)") {
+			return
+		}
+		report.add(CheckResult{check, "ok", interfaceSummary(iface)})
+
+	case VPNTailscale:
+		out, code, err := execScriptAsRoot(ctx, cli, containerJSON.ID, "tailscale status --json 2>/dev/null")
+		var st tailscaleStatus
+		if err != nil || code == 127 || json.Unmarshal([]byte(out), &st) != nil {
+			report.add(CheckResult{check, "fail",
+				fmt.Sprintf("tailscaled is not answering (restart it: rfswift exec -c %s --vpn tailscale)", name)})
+			return
+		}
+		switch {
+		case st.BackendState == "NeedsLogin" || st.BackendState == "NeedsMachineAuth":
+			hint := "run 'tailscale up' in the container"
+			if st.AuthURL != "" {
+				hint = "log in at " + st.AuthURL
+			}
+			report.add(CheckResult{check, "fail", fmt.Sprintf("%s (%s)", st.BackendState, hint)})
+		case st.BackendState != "Running":
+			report.add(CheckResult{check, "fail", fmt.Sprintf("Backend state is %s (run 'tailscale up' in the container)", st.BackendState)})
+		case len(st.TailscaleIPs) == 0:
+			report.add(CheckResult{check, "warn", "Running but no Tailscale address assigned yet"})
+		case len(st.Health) > 0:
+			report.add(CheckResult{check, "warn", fmt.Sprintf("%s, %s", strings.Join(st.TailscaleIPs, " "), st.Health[0])})
+		default:
+			msg := "Running, " + strings.Join(st.TailscaleIPs, " ")
+			if iface := ifaces["tailscale0"]; iface == nil {
+				msg += " (userspace networking, SOCKS5 on localhost:1055)"
+			}
+			report.add(CheckResult{check, "ok", msg})
+		}
+
+	case VPNNetbird:
+		out, code, err := execScriptAsRoot(ctx, cli, containerJSON.ID, "netbird status --json 2>/dev/null")
+		var st netbirdStatus
+		if err != nil || code == 127 || json.Unmarshal([]byte(out), &st) != nil {
+			report.add(CheckResult{check, "fail",
+				fmt.Sprintf("netbird daemon is not answering (restart it: rfswift exec -c %s --vpn netbird)", name)})
+			return
+		}
+		switch {
+		case !st.Management.Connected:
+			msg := "Not connected to the management server (run 'netbird up' in the container)"
+			if st.Management.Error != "" {
+				msg = fmt.Sprintf("Not connected to the management server: %s", st.Management.Error)
+			}
+			report.add(CheckResult{check, "fail", msg})
+		case !st.Signal.Connected:
+			report.add(CheckResult{check, "warn", "Connected to management but not to the signal server (peers cannot be reached)"})
+		case st.NetbirdIP == "":
+			report.add(CheckResult{check, "warn", "Connected but no NetBird address assigned yet"})
+		default:
+			report.add(CheckResult{check, "ok", "Connected, " + st.NetbirdIP})
+		}
+
+	default:
+		report.add(CheckResult{check, "warn", fmt.Sprintf("Unknown VPN type '%s'", vpnType)})
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Audio and X11
+// ---------------------------------------------------------------------------
+
+// pulseProbeScript checks that the PULSE_SERVER of the container answers,
+// with pactl when installed and a plain connection otherwise.
+const pulseProbeScript = `if command -v pactl >/dev/null 2>&1; then pactl info >/dev/null 2>&1; exit $?; fi
+case "$PULSE_SERVER" in
+tcp:*)
+  hp=${PULSE_SERVER#tcp:}
+  command -v bash >/dev/null 2>&1 || exit 2
+  timeout 3 bash -c "exec 3<>/dev/tcp/${hp%:*}/${hp##*:}" 2>/dev/null ;;
+unix:*|/*) test -S "${PULSE_SERVER#unix:}" ;;
+*) exit 2 ;;
+esac`
+
+func checkAudioStatus(ctx context.Context, cli *client.Client, containerJSON container.InspectResponse, report *DoctorReport) {
+	server := containerEnv(containerJSON, "PULSE_SERVER")
+	if server == "" {
+		report.add(CheckResult{"Audio (PulseAudio)", "skip", "PULSE_SERVER not set in the container"})
+		return
+	}
+	_, code, err := execScriptAsRoot(ctx, cli, containerJSON.ID, pulseProbeScript)
+	switch {
+	case err != nil:
+		report.add(CheckResult{"Audio (PulseAudio)", "fail", fmt.Sprintf("Probe failed: %v", err)})
+	case code == 0:
+		report.add(CheckResult{"Audio (PulseAudio)", "ok", fmt.Sprintf("%s reachable from the container", server)})
+	case code == probeUnverifiable || code == 126 || code == 127:
+		report.add(CheckResult{"Audio (PulseAudio)", "warn",
+			fmt.Sprintf("Cannot verify %s: neither pactl nor bash is installed in the container", server)})
+	default:
+		report.add(CheckResult{"Audio (PulseAudio)", "fail",
+			fmt.Sprintf("%s not reachable from the container (run: rfswift host audio enable)", server)})
+	}
+}
+
+// parseDisplay splits an X11 DISPLAY value into its host and display number.
+//
+//	in(1): string display  e.g. ":0", ":1.0", "host:10.0"
+//	out: (host string, number int, ok bool)
+func parseDisplay(display string) (string, int, bool) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	host := display[:i]
+	num, _, _ := strings.Cut(display[i+1:], ".")
+	n, err := strconv.Atoi(num)
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return host, n, true
+}
+
+// x11ProbeScript checks the X socket of a display and, when xset is
+// installed, that the X server accepts the container as a client.
+const x11ProbeScript = `test -S /tmp/.X11-unix/X%d || exit 1
+command -v xset >/dev/null 2>&1 || exit 2
+xset q >/dev/null 2>&1 || exit 3`
+
+func checkX11Status(ctx context.Context, cli *client.Client, containerJSON container.InspectResponse, report *DoctorReport) {
+	display := containerEnv(containerJSON, "DISPLAY")
+	if display == "" {
+		report.add(CheckResult{"X11", "skip", "DISPLAY not set in the container"})
+		return
+	}
+	host, num, ok := parseDisplay(display)
+	if !ok {
+		report.add(CheckResult{"X11", "warn", fmt.Sprintf("Invalid DISPLAY value: %s", display)})
+		return
+	}
+	if host != "" && host != "unix" {
+		report.add(CheckResult{"X11", "skip", fmt.Sprintf("DISPLAY=%s uses TCP, no socket to check", display)})
+		return
+	}
+
+	socket := fmt.Sprintf("/tmp/.X11-unix/X%d", num)
+	_, code, err := execScriptAsRoot(ctx, cli, containerJSON.ID, fmt.Sprintf(x11ProbeScript, num))
+	switch {
+	case err != nil:
+		report.add(CheckResult{"X11", "fail", fmt.Sprintf("Probe failed: %v", err)})
+	case code == 0:
+		report.add(CheckResult{"X11", "ok", fmt.Sprintf("DISPLAY=%s, X server accepts connections", display)})
+	case code == probeUnverifiable:
+		report.add(CheckResult{"X11", "ok", fmt.Sprintf("DISPLAY=%s, %s present (install xset to verify access)", display, socket)})
+	case code == probeRefused:
+		report.add(CheckResult{"X11", "fail",
+			fmt.Sprintf("%s present but the X server refused the connection (run on the host: xhost local:root)", socket)})
+	default:
+		report.add(CheckResult{"X11", "fail",
+			fmt.Sprintf("%s missing in the container (check the X server runs on the host and /tmp/.X11-unix is bound)", socket)})
+	}
+}
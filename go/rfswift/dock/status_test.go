@@ -0,0 +1,141 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDesktopLabel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want desktopEndpoint
+		ok   bool
+	}{
+		{"http://127.0.0.1:6080", desktopEndpoint{"http", "127.0.0.1", 6080}, true},
+		{"vnc://0.0.0.0:5900", desktopEndpoint{"vnc", "0.0.0.0", 5900}, true},
+		{"http://[::1]:6080", desktopEndpoint{"http", "::1", 6080}, true},
+		{"http://127.0.0.1", desktopEndpoint{}, false},
+		{"127.0.0.1:6080", desktopEndpoint{}, false},
+		{"http://host:99999", desktopEndpoint{}, false},
+		{"", desktopEndpoint{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDesktopLabel(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseDesktopLabel(%q) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestListeningTCPPorts(t *testing.T) {
+	procNetTCP := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:17C0 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1234 1
+   1: 0100007F:170C 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1235 1
+   2: 0100007F:A1B2 0100007F:17C0 01 00000000:00000000 00:00000000 00000000     0        0 1236 1
+  sl  local_address                         remote_address                        st
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000
+`
+	got := listeningTCPPorts(procNetTCP)
+	want := map[int]bool{6080: true, 5900: true, 8080: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listeningTCPPorts() = %v, want %v", got, want)
+	}
+}
+
+func TestParseNetInfo(t *testing.T) {
+	out := `#link
+1: lo: <LOOPBACK,UP,LOWER_UP> mtu 65536 qdisc noqueue state UNKNOWN mode DEFAULT group default qlen 1000\    link/loopback 00:00:00:00:00:00 brd 00:00:00:00:00:00
+2: eth0@if12: <BROADCAST,MULTICAST,UP,LOWER_UP> mtu 1500 qdisc noqueue state UP mode DEFAULT group default
+5: wg0: <POINTOPOINT,NOARP,UP,LOWER_UP> mtu 1420 qdisc noqueue state UNKNOWN mode DEFAULT group default qlen 1000\    link/none
+6: tun0: <POINTOPOINT,MULTICAST,NOARP> mtu 1500 qdisc noop state DOWN mode DEFAULT group default qlen 500\    link/none
+#addr
+1: lo    inet 127.0.0.1/8 scope host lo\       valid_lft forever preferred_lft forever
+2: eth0    inet 172.17.0.2/16 brd 172.17.255.255 scope global eth0\       valid_lft forever preferred_lft forever
+2: eth0    inet6 fe80::42:acff:fe11:2/64 scope link \       valid_lft forever preferred_lft forever
+5: wg0    inet 10.66.0.2/32 scope global wg0\       valid_lft forever preferred_lft forever
+#route
+default via 172.17.0.1 dev eth0
+10.66.0.0/24 dev wg0 scope link
+172.17.0.0/16 dev eth0 proto kernel scope link src 172.17.0.2
+`
+	ifaces := parseNetInfo(out)
+	wg := ifaces["wg0"]
+	if wg == nil || !wg.Up || !reflect.DeepEqual(wg.Addrs, []string{"10.66.0.2/32"}) || wg.Routes != 1 {
+		t.Errorf("parseNetInfo() wg0 = %+v", wg)
+	}
+	if eth := ifaces["eth0"]; eth == nil || len(eth.Addrs) != 1 || eth.Routes != 2 {
+		t.Errorf("parseNetInfo() eth0 = %+v, want one address and two routes", eth)
+	}
+	if tun := tunnelInterface(ifaces, "tun"); tun == nil || tun.Up {
+		t.Errorf("tunnelInterface(tun) = %+v, want tun0 down", tun)
+	}
+	if iface := tunnelInterface(ifaces, "wt"); iface != nil {
+		t.Errorf("tunnelInterface(wt) = %+v, want nil", iface)
+	}
+
+	report := &DoctorReport{}
+	if checkTunnel(report, "VPN", ifaces["tun0"], "down") || report.fail != 1 {
+		t.Errorf("checkTunnel(tun0) passed, want a failure")
+	}
+	if !checkTunnel(report, "VPN", wg, "down") {
+		t.Errorf("checkTunnel(wg0) = false, want true")
+	}
+}
+
+func TestParseWGHandshakes(t *testing.T) {
+	out := "aGVsbG8=\t0\nd29ybGQ=\t1700000000\n"
+	if got := parseWGHandshakes(out); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("parseWGHandshakes() = %v, want %v", got, time.Unix(1700000000, 0))
+	}
+	if got := parseWGHandshakes("aGVsbG8=\t0\n"); !got.IsZero() {
+		t.Errorf("parseWGHandshakes(never) = %v, want zero", got)
+	}
+}
+
+func TestDetectVPNType(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		env    []string
+		mounts []string
+		want   string
+	}{
+		{map[string]string{VPNLabel: VPNTailscale}, nil, []string{"/etc/wireguard/wg0.conf"}, VPNTailscale},
+		{nil, nil, []string{"/tmp/.X11-unix", "/etc/wireguard/wg0.conf"}, VPNWireGuard},
+		{nil, nil, []string{"/etc/openvpn/client.ovpn"}, VPNOpenVPN},
+		{nil, []string{"PATH=/usr/bin", "NB_SETUP_KEY=abc"}, nil, VPNNetbird},
+		{nil, []string{"TS_AUTHKEY=tskey-123"}, nil, VPNTailscale},
+		{nil, []string{"DISPLAY=:0"}, []string{"/root"}, ""},
+	}
+	for _, tt := range tests {
+		if got := detectVPNType(tt.labels, tt.env, tt.mounts); got != tt.want {
+			t.Errorf("detectVPNType(%v, %v, %v) = %q, want %q", tt.labels, tt.env, tt.mounts, got, tt.want)
+		}
+	}
+}
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		num  int
+		ok   bool
+	}{
+		{":0", "", 0, true},
+		{":1.0", "", 1, true},
+		{"unix:2", "unix", 2, true},
+		{"192.168.1.10:10.0", "192.168.1.10", 10, true},
+		{"0", "", 0, false},
+		{":x", "", 0, false},
+	}
+	for _, tt := range tests {
+		host, num, ok := parseDisplay(tt.in)
+		if host != tt.host || num != tt.num || ok != tt.ok {
+			t.Errorf("parseDisplay(%q) = %q, %d, %v, want %q, %d, %v", tt.in, host, num, ok, tt.host, tt.num, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Live resource monitor of the running RF Swift containers (rfswift top)
+ */
+
+package dock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+	common "penthertz/rfswift/common"
+	"penthertz/rfswift/tui"
+)
+
+// ContainerStats is one resource usage sample of a container.
+type ContainerStats struct {
+	Name             string  `json:"name" yaml:"name"`
+	ID               string  `json:"id" yaml:"id"`
+	CPUPercent       float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	OnlineCPUs       uint32  `json:"online_cpus" yaml:"online_cpus"`
+	MemoryUsage      uint64  `json:"memory_usage_bytes" yaml:"memory_usage_bytes"`
+	MemoryLimit      uint64  `json:"memory_limit_bytes" yaml:"memory_limit_bytes"`
+	MemoryPercent    float64 `json:"memory_percent" yaml:"memory_percent"`
+	NetRx            uint64  `json:"net_rx_bytes" yaml:"net_rx_bytes"`
+	NetTx            uint64  `json:"net_tx_bytes" yaml:"net_tx_bytes"`
+	BlockRead        uint64  `json:"block_read_bytes" yaml:"block_read_bytes"`
+	BlockWrite       uint64  `json:"block_write_bytes" yaml:"block_write_bytes"`
+	PIDs             uint64  `json:"pids" yaml:"pids"`
+	ThrottledPeriods uint64  `json:"throttled_periods" yaml:"throttled_periods"` // CFS periods the container was throttled (CPU contention)
+}
+
+// computeContainerStats turns an engine stats record into a sample. The CPU
+// percentage needs the previous sample the engine reports in PreCPUStats and
+// is 0 without it; like `docker stats`, 100% is one full CPU.
+//
+//	in(1): *container.StatsResponse s engine stats record
+//	out: ContainerStats sample (Name and ID left to the caller)
+func computeContainerStats(s *container.StatsResponse) ContainerStats {
+	stats := ContainerStats{
+		OnlineCPUs:       s.CPUStats.OnlineCPUs,
+		MemoryUsage:      memoryUsage(s.MemoryStats),
+		MemoryLimit:      s.MemoryStats.Limit,
+		PIDs:             s.PidsStats.Current,
+		ThrottledPeriods: s.CPUStats.ThrottlingData.ThrottledPeriods,
+	}
+	if stats.OnlineCPUs == 0 {
+		stats.OnlineCPUs = uint32(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if s.PreCPUStats.CPUUsage.TotalUsage > 0 && cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * float64(stats.OnlineCPUs) * 100
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, n := range s.Networks {
+		stats.NetRx += n.RxBytes
+		stats.NetTx += n.TxBytes
+	}
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			stats.BlockRead += e.Value
+		case "write":
+			stats.BlockWrite += e.Value
+		}
+	}
+	return stats
+}
+
+// memoryUsage returns the memory used without the reclaimable page cache,
+// as `docker stats` shows it (cgroup v1 and v2 keys).
+func memoryUsage(m container.MemoryStats) uint64 {
+	for _, key := range []string{"total_inactive_file", "inactive_file"} {
+		if v, ok := m.Stats[key]; ok && v < m.Usage {
+			return m.Usage - v
+		}
+	}
+	return m.Usage
+}
+
+// topTarget is a running container watched by rfswift top.
+type topTarget struct {
+	ID   string
+	Name string
+}
+
+// listTopTargets returns the running RF Swift containers, restricted to the
+// given names when any are given.
+func listTopTargets(ctx context.Context, cli *client.Client, names []string) ([]topTarget, error) {
+	filters := make(client.Filters)
+	filters.Add("label", "org.container.project=rfswift")
+	res, err := cli.ContainerList(ctx, client.ContainerListOptions{Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []topTarget
+	for _, c := range res.Items {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		if len(names) > 0 && !containsString(names, name) && !containsString(names, c.ID[:12]) {
+			continue
+		}
+		targets = append(targets, topTarget{ID: c.ID, Name: name})
+	}
+	return targets, nil
+}
+
+// sampleContainerStats takes one sample of every target. The engine waits
+// about a second to measure the CPU usage, so the targets are sampled in
+// parallel.
+func sampleContainerStats(ctx context.Context, cli *client.Client, targets []topTarget) []ContainerStats {
+	samples := make([]ContainerStats, len(targets))
+	ok := make([]bool, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t topTarget) {
+			defer wg.Done()
+			res, err := cli.ContainerStats(ctx, t.ID, client.ContainerStatsOptions{IncludePreviousSample: true})
+			if err != nil {
+				return
+			}
+			defer res.Body.Close()
+			var s container.StatsResponse
+			if err := json.NewDecoder(res.Body).Decode(&s); err != nil {
+				return
+			}
+			samples[i] = computeContainerStats(&s)
+			samples[i].ID, samples[i].Name = t.ID[:12], t.Name
+			ok[i] = true
+		}(i, t)
+	}
+	wg.Wait()
+
+	stats := []ContainerStats{}
+	for i := range samples {
+		if ok[i] {
+			stats = append(stats, samples[i])
+		}
+	}
+	return stats
+}
+
+// statsStreams keeps one engine stats stream per watched container and the
+// latest sample each produced.
+type statsStreams struct {
+	mu      sync.Mutex
+	latest  map[string]ContainerStats
+	cancels map[string]context.CancelFunc
+}
+
+// sync starts a stream for the new targets and stops those of the containers
+// that are gone.
+func (s *statsStreams) sync(ctx context.Context, cli *client.Client, targets []topTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		current[t.ID] = true
+		if _, running := s.cancels[t.ID]; running {
+			continue
+		}
+		streamCtx, cancel := context.WithCancel(ctx)
+		s.cancels[t.ID] = cancel
+		go s.stream(streamCtx, cli, t)
+	}
+	for id, cancel := range s.cancels {
+		if !current[id] {
+			cancel()
+			delete(s.cancels, id)
+			delete(s.latest, id)
+		}
+	}
+}
+
+// stream reads the engine stats stream of a container until it ends.
+func (s *statsStreams) stream(ctx context.Context, cli *client.Client, t topTarget) {
+	res, err := cli.ContainerStats(ctx, t.ID, client.ContainerStatsOptions{Stream: true})
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		var record container.StatsResponse
+		if err := dec.Decode(&record); err != nil {
+			return
+		}
+		sample := computeContainerStats(&record)
+		sample.ID, sample.Name = t.ID[:12], t.Name
+		s.mu.Lock()
+		if _, watched := s.cancels[t.ID]; watched {
+			s.latest[t.ID] = sample
+		}
+		s.mu.Unlock()
+	}
+}
+
+// snapshot returns the latest samples.
+func (s *statsStreams) snapshot() []ContainerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]ContainerStats, 0, len(s.latest))
+	for _, sample := range s.latest {
+		stats = append(stats, sample)
+	}
+	return stats
+}
+
+// sortContainerStats orders samples by CPU usage, busiest first.
+func sortContainerStats(stats []ContainerStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].CPUPercent != stats[j].CPUPercent {
+			return stats[i].CPUPercent > stats[j].CPUPercent
+		}
+		return stats[i].Name < stats[j].Name
+	})
+}
+
+// usageColor colors a percentage: warning from 60%, danger from 90%.
+func usageColor(percent float64) lipgloss.Color {
+	switch {
+	case percent >= 90:
+		return tui.ColorDanger
+	case percent >= 60:
+		return tui.ColorWarning
+	}
+	return tui.ColorSuccess
+}
+
+// renderContainerStats prints the samples as a table.
+func renderContainerStats(title string, stats []ContainerStats) {
+	rows := make([][]string, len(stats))
+	for i, s := range stats {
+		memory := formatSize(int64(s.MemoryUsage))
+		if s.MemoryLimit > 0 {
+			memory += " / " + formatSize(int64(s.MemoryLimit))
+		}
+		rows[i] = []string{
+			s.Name,
+			fmt.Sprintf("%.1f%%", s.CPUPercent),
+			memory,
+			fmt.Sprintf("%.1f%%", s.MemoryPercent),
+			formatSize(int64(s.NetRx)) + " / " + formatSize(int64(s.NetTx)),
+			formatSize(int64(s.BlockRead)) + " / " + formatSize(int64(s.BlockWrite)),
+			fmt.Sprintf("%d", s.PIDs),
+			fmt.Sprintf("%d", s.ThrottledPeriods),
+		}
+	}
+	tui.RenderTable(tui.TableConfig{
+		Title:   title,
+		Headers: []string{"Container", "CPU %", "Memory", "Mem %", "Net I/O (rx / tx)", "Block I/O (r / w)", "PIDs", "Throttled"},
+		Rows:    rows,
+		ColorFunc: func(row, col int, content string) lipgloss.Color {
+			if row < 0 {
+				return lipgloss.Color("")
+			}
+			s := stats[row]
+			switch col {
+			case 1:
+				// Relative to the CPUs the container can see: a saturated
+				// core on an 8-core host is 100% here but only 12.5% load
+				if s.OnlineCPUs > 0 {
+					return usageColor(s.CPUPercent / float64(s.OnlineCPUs))
+				}
+				return usageColor(s.CPUPercent)
+			case 3:
+				return usageColor(s.MemoryPercent)
+			case 7:
+				if s.ThrottledPeriods > 0 {
+					return tui.ColorWarning
+				}
+			}
+			return lipgloss.Color("")
+		},
+	})
+}
+
+// TopContainers shows the CPU, memory, network I/O, block I/O and PIDs of the
+// running RF Swift containers. With once, a single sample is printed;
+// otherwise the engine stats are streamed and the table is redrawn every
+// interval until ctx is cancelled. Structured output prints one document per
+// refresh.
+//
+//	in(1): context.Context ctx cancelled to stop monitoring
+//	in(2): []string names containers to watch (empty for all)
+//	in(3): bool once print a single sample and return
+//	in(4): time.Duration interval refresh interval
+//	out: error if the engine cannot be reached
+func TopContainers(ctx context.Context, names []string, once bool, interval time.Duration) error {
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	targets, err := listTopTargets(ctx, cli, names)
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %v", err)
+	}
+
+	if once {
+		stats := sampleContainerStats(ctx, cli, targets)
+		sortContainerStats(stats)
+		if common.MachineOutput() {
+			return common.PrintStructured("top", stats)
+		}
+		if len(stats) == 0 {
+			common.PrintInfoMessage("No running RF Swift container")
+			return nil
+		}
+		renderContainerStats("📈 RF Swift containers", stats)
+		return nil
+	}
+
+	streams := &statsStreams{latest: map[string]ContainerStats{}, cancels: map[string]context.CancelFunc{}}
+	streams.sync(ctx, cli, targets)
+	if !common.MachineOutput() {
+		common.PrintInfoMessage("Collecting container stats...")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if targets, err = listTopTargets(ctx, cli, names); err == nil {
+			streams.sync(ctx, cli, targets)
+		}
+		stats := streams.snapshot()
+		sortContainerStats(stats)
+
+		if common.MachineOutput() {
+			if err := common.PrintStructured("top", stats); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Print("\033[H\033[2J")
+		title := fmt.Sprintf("📈 RF Swift containers — %s, every %s (Ctrl+C to quit)", time.Now().Format("15:04:05"), interval)
+		if len(stats) == 0 {
+			fmt.Println(title)
+			common.PrintInfoMessage("No running RF Swift container")
+			continue
+		}
+		renderContainerStats(title, stats)
+	}
+}
@@ -0,0 +1,95 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestComputeContainerStats(t *testing.T) {
+	s := &container.StatsResponse{
+		CPUStats: container.CPUStats{
+			CPUUsage:       container.CPUUsage{TotalUsage: 3_000_000_000},
+			SystemUsage:    20_000_000_000,
+			OnlineCPUs:     4,
+			ThrottlingData: container.ThrottlingData{ThrottledPeriods: 7},
+		},
+		PreCPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 2_000_000_000},
+			SystemUsage: 16_000_000_000,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 600 << 20,
+			Limit: 2 << 30,
+			Stats: map[string]uint64{"inactive_file": 88 << 20},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 1000, TxBytes: 200},
+			"eth1": {RxBytes: 24, TxBytes: 56},
+		},
+		BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+			{Op: "read", Value: 4096}, {Op: "Write", Value: 512}, {Op: "Read", Value: 4096}, {Op: "total", Value: 9999},
+		}},
+		PidsStats: container.PidsStats{Current: 42},
+	}
+
+	got := computeContainerStats(s)
+	want := ContainerStats{
+		CPUPercent:       100, // 1s of CPU time over 4s of system time on 4 CPUs
+		OnlineCPUs:       4,
+		MemoryUsage:      512 << 20,
+		MemoryLimit:      2 << 30,
+		MemoryPercent:    25,
+		NetRx:            1024,
+		NetTx:            256,
+		BlockRead:        8192,
+		BlockWrite:       512,
+		PIDs:             42,
+		ThrottledPeriods: 7,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("computeContainerStats() = %+v, want %+v", got, want)
+	}
+
+	// First record of a stream: no previous sample, no CPU percentage
+	s.PreCPUStats = container.CPUStats{}
+	s.CPUStats.OnlineCPUs = 0
+	s.CPUStats.CPUUsage.PercpuUsage = []uint64{1, 2}
+	if got := computeContainerStats(s); got.CPUPercent != 0 || got.OnlineCPUs != 2 {
+		t.Errorf("computeContainerStats(no previous sample) = %v%% on %d CPUs, want 0%% on 2", got.CPUPercent, got.OnlineCPUs)
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		stats map[string]uint64
+		want  uint64
+	}{
+		{nil, 1000},
+		{map[string]uint64{"total_inactive_file": 300}, 700}, // cgroup v1
+		{map[string]uint64{"inactive_file": 100}, 900},       // cgroup v2
+		{map[string]uint64{"inactive_file": 5000}, 1000},     // inconsistent sample
+	}
+	for _, tt := range tests {
+		if got := memoryUsage(container.MemoryStats{Usage: 1000, Stats: tt.stats}); got != tt.want {
+			t.Errorf("memoryUsage(%v) = %d, want %d", tt.stats, got, tt.want)
+		}
+	}
+}
+
+func TestSortContainerStats(t *testing.T) {
+	stats := []ContainerStats{{Name: "b", CPUPercent: 5}, {Name: "c", CPUPercent: 80}, {Name: "a", CPUPercent: 5}}
+	sortContainerStats(stats)
+	var got []string
+	for _, s := range stats {
+		got = append(got, s.Name)
+	}
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortContainerStats() = %v, want %v", got, want)
+	}
+}
@@ -151,6 +151,13 @@ type ContainerConfig struct {
 	vpn          string // format: "type:argument" (e.g., "wireguard:./wg0.conf")
 	workspace    string // host path for workspace mount (empty = auto, "none" = disabled)
 	gpus         string // GPU device requests: "all" or comma-separated device IDs (empty = none)
+	lab          string // lab manifest the container belongs to (empty = standalone)
+	usb          string // --usb selectors (VID:PID[:serial], comma-separated) replacing the USB tree
+	usbDevices   string // devices the --usb selectors resolved to (USBDevicesLabel format)
+	resources    ResourceLimits
+	recordFile   string   // asciicast file the interactive session is recorded to (empty = not recorded)
+	recordInput  bool     // also record the keys typed in the session
+	recordRedact []string // extra regexes redacted from the recording, on top of the built-in secret patterns
 }
 
 var containerCfg = ContainerConfig{
@@ -184,26 +191,51 @@ var containerCfg = ContainerConfig{
 }
 
 // BuildRecipe defines a YAML recipe for building container images.
+// BaseImage and Steps describe the final stage; Stages lists the named stages
+// built before it (e.g. a builder whose artifacts are copied into the final image).
+// Extends names a parent recipe whose stages, steps, vars and labels are inherited.
 type BuildRecipe struct {
+	Extends   string            `yaml:"extends"`
 	Name      string            `yaml:"name"`
 	BaseImage string            `yaml:"base_image"`
 	Tag       string            `yaml:"tag"`
 	Context   string            `yaml:"context"`
+	Vars      map[string]string `yaml:"vars"`
 	Labels    map[string]string `yaml:"labels"`
+	Stages    []BuildStage      `yaml:"stages"`
 	Steps     []BuildStep       `yaml:"steps"`
+
+	file string // recipe file the fields were read from
+}
+
+// BuildStage defines a named intermediate stage of a multi-stage recipe.
+type BuildStage struct {
+	Name      string      `yaml:"name"`
+	BaseImage string      `yaml:"base_image"`
+	Steps     []BuildStep `yaml:"steps"`
+
+	pos recipePos
 }
 
-// BuildStep defines a single step in a build recipe.
+// BuildStep defines a single step in a build recipe. A step with Include set
+// is replaced by the steps of that YAML fragment when the recipe is loaded.
 type BuildStep struct {
-	Type      string     `yaml:"type"`
-	Commands  []string   `yaml:"commands"`
-	Items     []CopyItem `yaml:"items"`
-	Path      string     `yaml:"path"`
-	Name      string     `yaml:"name"`
-	Script    string     `yaml:"script"`
-	Functions []string   `yaml:"functions"`
-	Paths     []string   `yaml:"paths"`
-	AptClean  bool       `yaml:"apt_clean"`
+	Include   string            `yaml:"include"`
+	Type      string            `yaml:"type"`
+	Commands  []string          `yaml:"commands"`
+	Items     []CopyItem        `yaml:"items"`
+	From      string            `yaml:"from"`
+	Path      string            `yaml:"path"`
+	Name      string            `yaml:"name"`
+	Script    string            `yaml:"script"`
+	Functions []string          `yaml:"functions"`
+	Paths     []string          `yaml:"paths"`
+	AptClean  bool              `yaml:"apt_clean"`
+	Values    map[string]string `yaml:"values"`
+	User      string            `yaml:"user"`
+	Command   []string          `yaml:"command"`
+
+	pos recipePos // where the step is defined, after include resolution
 }
 
 // CopyItem defines a source/destination pair for COPY steps.
@@ -541,6 +541,28 @@ func DisableRealtimeMode(containerID string) error {
 	return nil
 }
 
+// UlimitValue is a single ulimit in structured output; -1 means unlimited.
+type UlimitValue struct {
+	Name string `json:"name" yaml:"name"`
+	Soft int64  `json:"soft" yaml:"soft"`
+	Hard int64  `json:"hard" yaml:"hard"`
+}
+
+// RealtimeStatus reports which realtime prerequisites a container has.
+type RealtimeStatus struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	SysNice bool `json:"sys_nice" yaml:"sys_nice"`
+	Rtprio  bool `json:"rtprio" yaml:"rtprio"`
+	Memlock bool `json:"memlock_unlimited" yaml:"memlock_unlimited"`
+}
+
+// ContainerUlimits is the structured form of ListContainerUlimits.
+type ContainerUlimits struct {
+	Container string         `json:"container" yaml:"container"`
+	Ulimits   []UlimitValue  `json:"ulimits" yaml:"ulimits"`
+	Realtime  RealtimeStatus `json:"realtime" yaml:"realtime"`
+}
+
 // ListContainerUlimits prints all ulimits configured on a container and reports whether
 // realtime mode is considered active (SYS_NICE capability + rtprio + memlock=unlimited).
 //
@@ -564,23 +586,6 @@ func ListContainerUlimits(containerID string) error {
 
 	ulimits := containerJSON.HostConfig.Ulimits
 
-	if len(ulimits) == 0 {
-		common.PrintInfoMessage(fmt.Sprintf("Container '%s' has no custom ulimits set", containerName))
-	} else {
-		fmt.Printf("Ulimits for container '%s':\n", containerName)
-		for _, ul := range ulimits {
-			softStr := fmt.Sprintf("%d", ul.Soft)
-			hardStr := fmt.Sprintf("%d", ul.Hard)
-			if ul.Soft == -1 {
-				softStr = "unlimited"
-			}
-			if ul.Hard == -1 {
-				hardStr = "unlimited"
-			}
-			fmt.Printf("  • %s: soft=%s, hard=%s\n", ul.Name, softStr, hardStr)
-		}
-	}
-
 	hasSysNice := false
 	for _, cap := range containerJSON.HostConfig.CapAdd {
 		if cap == "SYS_NICE" {
@@ -600,6 +605,40 @@ func ListContainerUlimits(containerID string) error {
 		}
 	}
 
+	if common.MachineOutput() {
+		report := ContainerUlimits{
+			Container: containerName,
+			Ulimits:   []UlimitValue{},
+			Realtime: RealtimeStatus{
+				Enabled: hasSysNice && hasRtprio && hasMemlock,
+				SysNice: hasSysNice,
+				Rtprio:  hasRtprio,
+				Memlock: hasMemlock,
+			},
+		}
+		for _, ul := range ulimits {
+			report.Ulimits = append(report.Ulimits, UlimitValue{Name: ul.Name, Soft: ul.Soft, Hard: ul.Hard})
+		}
+		return common.PrintStructured("ulimits", report)
+	}
+
+	if len(ulimits) == 0 {
+		common.PrintInfoMessage(fmt.Sprintf("Container '%s' has no custom ulimits set", containerName))
+	} else {
+		fmt.Printf("Ulimits for container '%s':\n", containerName)
+		for _, ul := range ulimits {
+			softStr := fmt.Sprintf("%d", ul.Soft)
+			hardStr := fmt.Sprintf("%d", ul.Hard)
+			if ul.Soft == -1 {
+				softStr = "unlimited"
+			}
+			if ul.Hard == -1 {
+				hardStr = "unlimited"
+			}
+			fmt.Printf("  • %s: soft=%s, hard=%s\n", ul.Name, softStr, hardStr)
+		}
+	}
+
 	fmt.Println()
 	if hasSysNice && hasRtprio && hasMemlock {
 		common.PrintSuccessMessage("Realtime mode: ENABLED")
@@ -92,12 +92,7 @@ func ContainerUpgrade(containerIdentifier string, repositoriesToPreserve string,
 		common.PrintInfoMessage(fmt.Sprintf("Pulling image '%s'...", newImage))
 
 		// Parse image for pulling
-		parts := strings.Split(newImage, ":")
-		repo := parts[0]
-		tag := "latest"
-		if len(parts) > 1 {
-			tag = parts[1]
-		}
+		repo, tag := splitImageRef(newImage)
 
 		// Pull the image
 		architecture := getArchitecture()
@@ -115,7 +110,7 @@ func ContainerUpgrade(containerIdentifier string, repositoriesToPreserve string,
 			}
 		}
 
-		out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{})
+		out, err := cli.ImagePull(ctx, actualPullRef, client.ImagePullOptions{RegistryAuth: registryAuthFor(actualPullRef)})
 		if err != nil {
 			common.PrintErrorMessage(fmt.Errorf("failed to pull image: %v", err))
 			common.PrintInfoMessage("Old container preserved - no changes made")
@@ -0,0 +1,542 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * Per-device USB passthrough resolved from sysfs (VID:PID[:serial])
+ */
+
+package dock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+	common "penthertz/rfswift/common"
+)
+
+const (
+	// USBLabel records the --usb selectors a container was created with.
+	USBLabel = "org.rfswift.usb"
+
+	// USBDevicesLabel records the devices the selectors resolved to, as
+	// "VID:PID[:serial]=<node>", so they can be revoked once unplugged.
+	USBDevicesLabel = "org.rfswift.usb_devices"
+
+	// usbTreePath is the USB device tree that --usb replaces by single nodes.
+	usbTreePath = "/dev/bus/usb"
+
+	// usbTreeCgroupRule grants every USB device; --usb replaces it by exact rules.
+	usbTreeCgroupRule = "c 189:* rwm"
+
+	// usbMajor is the character major of /dev/bus/usb nodes.
+	usbMajor = 189
+)
+
+// sysfsRoot is the sysfs mount point read by the exported helpers; the
+// unexported ones take the root so tests can use a fake tree.
+var sysfsRoot = "/sys"
+
+// USBDevice is a USB device found in sysfs.
+type USBDevice struct {
+	Bus          int    `json:"bus" yaml:"bus"`
+	Device       int    `json:"device" yaml:"device"`
+	VendorID     string `json:"vendor_id" yaml:"vendor_id"`
+	ProductID    string `json:"product_id" yaml:"product_id"`
+	Serial       string `json:"serial,omitempty" yaml:"serial,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty" yaml:"product,omitempty"`
+	Major        int    `json:"major" yaml:"major"`
+	Minor        int    `json:"minor" yaml:"minor"`
+	SysfsName    string `json:"sysfs_name" yaml:"sysfs_name"` // e.g. "1-2.4"
+}
+
+// DevPath returns the device node of the device ("/dev/bus/usb/001/005").
+func (d USBDevice) DevPath() string {
+	return fmt.Sprintf("%s/%03d/%03d", usbTreePath, d.Bus, d.Device)
+}
+
+// CgroupRule returns the device cgroup rule granting this device only.
+func (d USBDevice) CgroupRule() string {
+	return fmt.Sprintf("c %d:%d rwm", d.Major, d.Minor)
+}
+
+// ID returns the VID:PID of the device.
+func (d USBDevice) ID() string {
+	return d.VendorID + ":" + d.ProductID
+}
+
+// String describes the device for messages.
+func (d USBDevice) String() string {
+	s := fmt.Sprintf("%s %s", d.ID(), d.DevPath())
+	if name := strings.TrimSpace(d.Manufacturer + " " + d.Product); name != "" {
+		s += " (" + name + ")"
+	}
+	if d.Serial != "" {
+		s += " serial " + d.Serial
+	}
+	return s
+}
+
+// USBSelector selects USB devices by vendor, product and optional serial.
+type USBSelector struct {
+	VendorID  string
+	ProductID string
+	Serial    string
+}
+
+var usbIDPattern = regexp.MustCompile(`^[0-9a-f]{4}$`)
+
+// ParseUSBSelector parses "VID:PID" or "VID:PID:serial" (IDs in hex).
+//
+//	in(1): string spec selector as given to --usb
+//	out: USBSelector parsed selector
+//	out: error if the IDs are not 4-digit hex values
+func ParseUSBSelector(spec string) (USBSelector, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 3)
+	if len(parts) < 2 {
+		return USBSelector{}, fmt.Errorf("invalid USB selector %q: expected VID:PID[:serial] (e.g. 1d50:6089)", spec)
+	}
+	sel := USBSelector{
+		VendorID:  strings.ToLower(strings.TrimPrefix(parts[0], "0x")),
+		ProductID: strings.ToLower(strings.TrimPrefix(parts[1], "0x")),
+	}
+	if !usbIDPattern.MatchString(sel.VendorID) || !usbIDPattern.MatchString(sel.ProductID) {
+		return USBSelector{}, fmt.Errorf("invalid USB selector %q: vendor and product IDs are 4 hex digits (e.g. 1d50:6089)", spec)
+	}
+	if len(parts) == 3 {
+		sel.Serial = parts[2]
+	}
+	return sel, nil
+}
+
+// String returns the selector in VID:PID[:serial] form.
+func (s USBSelector) String() string {
+	if s.Serial != "" {
+		return fmt.Sprintf("%s:%s:%s", s.VendorID, s.ProductID, s.Serial)
+	}
+	return s.VendorID + ":" + s.ProductID
+}
+
+// Matches reports whether d is selected by s.
+func (s USBSelector) Matches(d USBDevice) bool {
+	return d.VendorID == s.VendorID && d.ProductID == s.ProductID && (s.Serial == "" || d.Serial == s.Serial)
+}
+
+// ListUSBDevices enumerates the USB devices of the host from sysfs.
+//
+//	out: []USBDevice devices sorted by bus and device number
+//	out: error if sysfs cannot be read
+func ListUSBDevices() ([]USBDevice, error) {
+	return listUSBDevices(sysfsRoot)
+}
+
+// listUSBDevices reads <root>/bus/usb/devices: every entry with an idVendor
+// file is a device (interfaces such as "1-2:1.0" have none).
+func listUSBDevices(root string) ([]USBDevice, error) {
+	base := filepath.Join(root, "bus", "usb", "devices")
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return nil, fmt.Errorf("cannot enumerate USB devices: %v", err)
+	}
+
+	devices := []USBDevice{}
+	for _, entry := range entries {
+		dir := filepath.Join(base, entry.Name())
+		vendor := readSysfsAttr(dir, "idVendor")
+		if vendor == "" {
+			continue
+		}
+		d := USBDevice{
+			VendorID:     strings.ToLower(vendor),
+			ProductID:    strings.ToLower(readSysfsAttr(dir, "idProduct")),
+			Serial:       readSysfsAttr(dir, "serial"),
+			Manufacturer: readSysfsAttr(dir, "manufacturer"),
+			Product:      readSysfsAttr(dir, "product"),
+			SysfsName:    entry.Name(),
+		}
+		d.Bus, _ = strconv.Atoi(readSysfsAttr(dir, "busnum"))
+		d.Device, _ = strconv.Atoi(readSysfsAttr(dir, "devnum"))
+		if d.Bus == 0 || d.Device == 0 {
+			continue
+		}
+
+		// "dev" holds major:minor; derive it like the kernel when absent
+		if major, minor, ok := strings.Cut(readSysfsAttr(dir, "dev"), ":"); ok {
+			d.Major, _ = strconv.Atoi(major)
+			d.Minor, _ = strconv.Atoi(minor)
+		} else {
+			d.Major, d.Minor = usbMajor, (d.Bus-1)*128+d.Device-1
+		}
+		devices = append(devices, d)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].Bus != devices[j].Bus {
+			return devices[i].Bus < devices[j].Bus
+		}
+		return devices[i].Device < devices[j].Device
+	})
+	return devices, nil
+}
+
+func readSysfsAttr(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// ResolveUSBDevices returns the host devices matching the given selectors.
+// Every selector must match at least one device.
+//
+//	in(1): []string specs VID:PID[:serial] selectors
+//	out: []USBDevice matching devices, without duplicates
+//	out: error on an invalid selector or one that matches nothing
+func ResolveUSBDevices(specs []string) ([]USBDevice, error) {
+	return resolveUSBDevices(sysfsRoot, specs)
+}
+
+func resolveUSBDevices(root string, specs []string) ([]USBDevice, error) {
+	available, err := listUSBDevices(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []USBDevice
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		sel, err := ParseUSBSelector(spec)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, d := range available {
+			if !sel.Matches(d) {
+				continue
+			}
+			found = true
+			if !seen[d.SysfsName] {
+				seen[d.SysfsName] = true
+				selected = append(selected, d)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no USB device matches %s", sel)
+		}
+	}
+	return selected, nil
+}
+
+// withoutUSBTree removes the whole-tree USB mapping ("/dev/bus/usb[:...]")
+// and its wildcard cgroup rule from a comma-separated list.
+func withoutUSBTree(list string) string {
+	var kept []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		source, _, _ := strings.Cut(item, ":")
+		if source == usbTreePath || strings.Replace(item, "rmw", "rwm", 1) == usbTreeCgroupRule {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return strings.Join(kept, ",")
+}
+
+// hasMajorRule reports whether rules hold a rule for the same device type and
+// major as rule ("c 189:4 rwm" for "c 189:* rwm").
+func hasMajorRule(rules []string, rule string) bool {
+	prefix, _, ok := strings.Cut(rule, ":")
+	if !ok {
+		return false
+	}
+	for _, r := range rules {
+		if strings.HasPrefix(strings.TrimSpace(r), prefix+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerSetUSB restricts the USB devices of the container to create to the
+// devices matching specs: the /dev/bus/usb tree mapping and its "c 189:* rwm"
+// rule are dropped, and each matching device gets its own node and an exact
+// cgroup rule.
+//
+//	in(1): []string specs VID:PID[:serial] selectors (empty keeps the defaults)
+//	out: error if a selector is invalid or matches no device
+func ContainerSetUSB(specs []string) error {
+	if len(specs) == 0 {
+		return nil
+	}
+	devices, err := ResolveUSBDevices(specs)
+	if err != nil {
+		return err
+	}
+
+	containerCfg.devices = withoutUSBTree(containerCfg.devices)
+	containerCfg.extrabinding = withoutUSBTree(containerCfg.extrabinding)
+	containerCfg.cgroups = withoutUSBTree(containerCfg.cgroups)
+	containerCfg.usbforward = ""
+
+	for _, d := range devices {
+		appendCommaSeparated(&containerCfg.devices, d.DevPath()+":"+d.DevPath())
+		appendCommaSeparated(&containerCfg.cgroups, d.CgroupRule())
+		common.PrintInfoMessage(fmt.Sprintf("USB passthrough: %s", d))
+	}
+	containerCfg.usb = strings.Join(specs, ",")
+	containerCfg.usbDevices = formatUSBDeviceEntries(devices)
+	return nil
+}
+
+// formatUSBDeviceEntries renders devices for USBDevicesLabel.
+func formatUSBDeviceEntries(devices []USBDevice) string {
+	entries := make([]string, len(devices))
+	for i, d := range devices {
+		sel := USBSelector{VendorID: d.VendorID, ProductID: d.ProductID, Serial: d.Serial}
+		entries[i] = sel.String() + "=" + d.DevPath()
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseUSBDeviceEntries reads USBDevicesLabel back. The major and minor are
+// derived from the node like the kernel does; malformed entries are skipped.
+func parseUSBDeviceEntries(label string) []USBDevice {
+	var devices []USBDevice
+	for _, entry := range strings.Split(label, ",") {
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			continue
+		}
+		sel, err := ParseUSBSelector(entry[:i])
+		if err != nil {
+			continue
+		}
+		d := USBDevice{VendorID: sel.VendorID, ProductID: sel.ProductID, Serial: sel.Serial}
+		if _, err := fmt.Sscanf(entry[i+1:], usbTreePath+"/%d/%d", &d.Bus, &d.Device); err != nil || d.Bus == 0 || d.Device == 0 {
+			continue
+		}
+		d.Major, d.Minor = usbMajor, (d.Bus-1)*128+d.Device-1
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// usbPassthrough is the USB passthrough recorded on a container: the
+// selectors of USBLabel and the devices of USBDevicesLabel.
+type usbPassthrough struct {
+	specs   []string
+	devices []USBDevice
+}
+
+func readUSBPassthrough(labels map[string]string) usbPassthrough {
+	var p usbPassthrough
+	if label := labels[USBLabel]; label != "" {
+		p.specs = strings.Split(label, ",")
+	}
+	p.devices = parseUSBDeviceEntries(labels[USBDevicesLabel])
+	return p
+}
+
+// add records specs and the devices they resolved to, without duplicates.
+func (p *usbPassthrough) add(specs []string, devices []USBDevice) {
+	for _, spec := range specs {
+		if !containsString(p.specs, spec) {
+			p.specs = append(p.specs, spec)
+		}
+	}
+	for _, d := range devices {
+		known := false
+		for _, r := range p.devices {
+			known = known || r.DevPath() == d.DevPath()
+		}
+		if !known {
+			p.devices = append(p.devices, d)
+		}
+	}
+}
+
+// remove drops the recorded devices matching selectors, and the recorded
+// selectors they cover, and returns the dropped devices.
+func (p *usbPassthrough) remove(selectors []USBSelector) []USBDevice {
+	var keptSpecs []string
+	for _, spec := range p.specs {
+		recorded, err := ParseUSBSelector(spec)
+		covered := false
+		for _, sel := range selectors {
+			covered = covered || (err == nil && recorded.VendorID == sel.VendorID && recorded.ProductID == sel.ProductID &&
+				(sel.Serial == "" || recorded.Serial == sel.Serial))
+		}
+		if !covered {
+			keptSpecs = append(keptSpecs, spec)
+		}
+	}
+	p.specs = keptSpecs
+
+	var kept, removed []USBDevice
+	for _, d := range p.devices {
+		matched := false
+		for _, sel := range selectors {
+			matched = matched || sel.Matches(d)
+		}
+		if matched {
+			removed = append(removed, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	p.devices = kept
+	return removed
+}
+
+// apply writes the passthrough to a label map, deleting empty labels.
+func (p usbPassthrough) apply(labels map[string]string) {
+	set := func(key, value string) {
+		if value == "" {
+			delete(labels, key)
+		} else {
+			labels[key] = value
+		}
+	}
+	set(USBLabel, strings.Join(p.specs, ","))
+	set(USBDevicesLabel, formatUSBDeviceEntries(p.devices))
+}
+
+// UpdateUSBDevices adds or removes the devices matching specs on an existing
+// container: their device nodes through UpdateDeviceBinding, their exact
+// cgroup rules through UpdateCgroupRule, and the USB labels followed by
+// 'usb watch'. Added devices must be plugged in to be resolved; removed ones
+// are matched against the devices recorded on the container, so unplugged
+// devices can be revoked.
+//
+//	in(1): string containerName target container
+//	in(2): []string specs VID:PID[:serial] selectors
+//	in(3): bool add true to grant the devices, false to revoke them
+//	out: error if a selector matches no device or a rule update fails
+func UpdateUSBDevices(containerName string, specs []string, add bool) error {
+	var selectors []USBSelector
+	for _, spec := range specs {
+		sel, err := ParseUSBSelector(spec)
+		if err != nil {
+			return err
+		}
+		selectors = append(selectors, sel)
+	}
+
+	ctx := context.Background()
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerName)
+	if err != nil {
+		return fmt.Errorf("container %s not found", containerName)
+	}
+	var labels map[string]string
+	if containerJSON.Config != nil {
+		labels = containerJSON.Config.Labels
+	}
+	passthrough := readUSBPassthrough(labels)
+
+	var devices []USBDevice
+	action := "Revoking"
+	if add {
+		action = "Granting"
+		if devices, err = ResolveUSBDevices(specs); err != nil {
+			return err
+		}
+		passthrough.add(specs, devices)
+	} else {
+		devices = passthrough.remove(selectors)
+		if len(devices) == 0 {
+			// Containers created before the devices were recorded
+			devices = mappedUSBDevices(containerJSON.HostConfig, selectors)
+		}
+		if len(devices) == 0 {
+			return fmt.Errorf("no USB device matching %s is mapped into container %s", strings.Join(specs, ", "), containerName)
+		}
+	}
+
+	for _, d := range devices {
+		common.PrintInfoMessage(fmt.Sprintf("%s %s", action, d))
+		UpdateDeviceBinding(containerName, d.DevPath(), d.DevPath(), add)
+		if err := UpdateCgroupRule(containerName, d.CgroupRule(), add); err != nil {
+			return err
+		}
+	}
+	return updateUSBLabels(ctx, cli, containerName, passthrough)
+}
+
+// mappedUSBDevices returns the plugged devices matching selectors whose node
+// is mapped into the container.
+func mappedUSBDevices(hostConfig *container.HostConfig, selectors []USBSelector) []USBDevice {
+	present, err := ListUSBDevices()
+	if err != nil || hostConfig == nil {
+		return nil
+	}
+	var devices []USBDevice
+	for _, d := range present {
+		mapped := false
+		for _, m := range hostConfig.Devices {
+			mapped = mapped || m.PathInContainer == d.DevPath()
+		}
+		for _, sel := range selectors {
+			if mapped && sel.Matches(d) {
+				devices = append(devices, d)
+				break
+			}
+		}
+	}
+	return devices
+}
+
+// updateUSBLabels rewrites the USB labels of a container: in config.v2.json
+// on Docker, by recreation on Podman.
+func updateUSBLabels(ctx context.Context, cli *client.Client, containerName string, passthrough usbPassthrough) error {
+	containerID := getContainerIDByName(ctx, containerName)
+	if containerID == "" {
+		return fmt.Errorf("container %s not found", containerName)
+	}
+
+	if !EngineSupportsDirectConfigEdit() {
+		props, err := getContainerProperties(ctx, cli, containerID)
+		if err != nil {
+			return err
+		}
+		props["USB"] = strings.Join(passthrough.specs, ",")
+		props["USBDevices"] = formatUSBDeviceEntries(passthrough.devices)
+		return recreateContainerWithProperties(ctx, cli, containerID, props)
+	}
+
+	return directEditContainer(ctx, cli, containerID, containerName, func(_ *HostConfigFull, configV2 map[string]interface{}) (bool, error) {
+		config, ok := configV2["Config"].(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("config.v2.json has no Config section")
+		}
+		labels := make(map[string]string)
+		if existing, ok := config["Labels"].(map[string]interface{}); ok {
+			for k, v := range existing {
+				labels[k], _ = v.(string)
+			}
+		}
+		passthrough.apply(labels)
+		updated := make(map[string]interface{}, len(labels))
+		for k, v := range labels {
+			updated[k] = v
+		}
+		config["Labels"] = updated
+		return true, nil
+	})
+}
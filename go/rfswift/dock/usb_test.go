@@ -0,0 +1,222 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeSysfsDevice adds a device directory with the given attributes to a fake
+// sysfs tree.
+func writeSysfsDevice(t *testing.T, root, name string, attrs map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, "bus", "usb", "devices", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for attr, value := range attrs {
+		if err := os.WriteFile(filepath.Join(dir, attr), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeSysfsDevice(t, root, "usb1", map[string]string{
+		"idVendor": "1d6b", "idProduct": "0002", "busnum": "1", "devnum": "1", "dev": "189:0",
+	})
+	writeSysfsDevice(t, root, "1-2", map[string]string{
+		"idVendor": "1d50", "idProduct": "6089", "serial": "0000000000000000a06063c8234e6a5f",
+		"manufacturer": "Great Scott Gadgets", "product": "HackRF One",
+		"busnum": "1", "devnum": "5", "dev": "189:4",
+	})
+	writeSysfsDevice(t, root, "1-3", map[string]string{
+		"idVendor": "1D50", "idProduct": "6089", "serial": "0000000000000000457863dc2b3f1a4b",
+		"busnum": "1", "devnum": "7",
+	})
+	writeSysfsDevice(t, root, "2-1", map[string]string{
+		"idVendor": "0bda", "idProduct": "2838", "busnum": "2", "devnum": "3", "dev": "189:130",
+	})
+	// Interfaces have no idVendor and must be skipped
+	writeSysfsDevice(t, root, "1-2:1.0", map[string]string{"bInterfaceClass": "ff"})
+	return root
+}
+
+func TestParseUSBSelector(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    USBSelector
+		wantErr bool
+	}{
+		{"1d50:6089", USBSelector{VendorID: "1d50", ProductID: "6089"}, false},
+		{"0x1D50:0x6089", USBSelector{VendorID: "1d50", ProductID: "6089"}, false},
+		{"1d50:6089:a06063c8", USBSelector{VendorID: "1d50", ProductID: "6089", Serial: "a06063c8"}, false},
+		{"1d50", USBSelector{}, true},
+		{"1d5:6089", USBSelector{}, true},
+		{"hack:rf00", USBSelector{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUSBSelector(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUSBSelector(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUSBSelector(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestListUSBDevices(t *testing.T) {
+	devices, err := listUSBDevices(fakeSysfs(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range devices {
+		got = append(got, d.SysfsName+" "+d.DevPath()+" "+d.CgroupRule())
+	}
+	want := []string{
+		"usb1 /dev/bus/usb/001/001 c 189:0 rwm",
+		"1-2 /dev/bus/usb/001/005 c 189:4 rwm",
+		"1-3 /dev/bus/usb/001/007 c 189:6 rwm", // no dev file: derived minor
+		"2-1 /dev/bus/usb/002/003 c 189:130 rwm",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listUSBDevices() = %v, want %v", got, want)
+	}
+	if devices[2].VendorID != "1d50" {
+		t.Errorf("listUSBDevices() vendor = %q, want lower-case %q", devices[2].VendorID, "1d50")
+	}
+}
+
+func TestResolveUSBDevices(t *testing.T) {
+	root := fakeSysfs(t)
+	tests := []struct {
+		specs   []string
+		want    []string
+		wantErr string
+	}{
+		{[]string{"1d50:6089"}, []string{"1-2", "1-3"}, ""},
+		{[]string{"1d50:6089:0000000000000000457863dc2b3f1a4b"}, []string{"1-3"}, ""},
+		{[]string{"0bda:2838", "1d50:6089", "0bda:2838"}, []string{"2-1", "1-2", "1-3"}, ""},
+		{[]string{"1d50:6089:nope"}, nil, "no USB device matches 1d50:6089:nope"},
+		{[]string{"2cf0:5250"}, nil, "no USB device matches"},
+		{[]string{"bad"}, nil, "invalid USB selector"},
+	}
+
+	for _, tt := range tests {
+		devices, err := resolveUSBDevices(root, tt.specs)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveUSBDevices(%v) error = %v, want %q", tt.specs, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolveUSBDevices(%v) error = %v", tt.specs, err)
+			continue
+		}
+		var got []string
+		for _, d := range devices {
+			got = append(got, d.SysfsName)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveUSBDevices(%v) = %v, want %v", tt.specs, got, tt.want)
+		}
+	}
+}
+
+func TestWithoutUSBTree(t *testing.T) {
+	tests := []struct {
+		list string
+		want string
+	}{
+		{"/dev/bus/usb:/dev/bus/usb,/dev/snd:/dev/snd", "/dev/snd:/dev/snd"},
+		{"c 189:* rwm,c 166:* rwm", "c 166:* rwm"},
+		{"c 189:* rmw", ""},
+		{"/dev/bus/usb/001/005:/dev/bus/usb/001/005", "/dev/bus/usb/001/005:/dev/bus/usb/001/005"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := withoutUSBTree(tt.list); got != tt.want {
+			t.Errorf("withoutUSBTree(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestHasMajorRule(t *testing.T) {
+	tests := []struct {
+		rules []string
+		rule  string
+		want  bool
+	}{
+		{[]string{"c 189:4 rwm"}, "c 189:* rwm", true},
+		{[]string{"c 166:* rwm"}, "c 189:* rwm", false},
+		{[]string{"b 189:4 rwm"}, "c 189:* rwm", false},
+		{nil, "c 189:* rwm", false},
+	}
+
+	for _, tt := range tests {
+		if got := hasMajorRule(tt.rules, tt.rule); got != tt.want {
+			t.Errorf("hasMajorRule(%v, %q) = %v, want %v", tt.rules, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestUSBPassthroughLabels(t *testing.T) {
+	devices, err := resolveUSBDevices(fakeSysfs(t), []string{"1d50:6089", "0bda:2838"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{"org.container.project": "rfswift"}
+	p := readUSBPassthrough(labels)
+	p.add([]string{"1d50:6089"}, devices[:2])
+	p.add([]string{"1d50:6089", "0bda:2838"}, devices)
+	p.apply(labels)
+
+	if got, want := labels[USBLabel], "1d50:6089,0bda:2838"; got != want {
+		t.Errorf("USB label = %q, want %q", got, want)
+	}
+	want := "1d50:6089:0000000000000000a06063c8234e6a5f=/dev/bus/usb/001/005," +
+		"1d50:6089:0000000000000000457863dc2b3f1a4b=/dev/bus/usb/001/007,0bda:2838=/dev/bus/usb/002/003"
+	if got := labels[USBDevicesLabel]; got != want {
+		t.Errorf("USB devices label = %q, want %q", got, want)
+	}
+
+	// Revoking reads the recorded devices: nothing needs to be plugged in
+	p = readUSBPassthrough(labels)
+	sel, _ := ParseUSBSelector("1d50:6089:0000000000000000457863dc2b3f1a4b")
+	removed := p.remove([]USBSelector{sel})
+	if len(removed) != 1 || removed[0].CgroupRule() != "c 189:6 rwm" || removed[0].DevPath() != "/dev/bus/usb/001/007" {
+		t.Errorf("remove(serial) = %+v, want the 001/007 device with rule c 189:6 rwm", removed)
+	}
+	if !reflect.DeepEqual(p.specs, []string{"1d50:6089", "0bda:2838"}) {
+		t.Errorf("remove(serial) kept specs %v, want the VID:PID selector left alone", p.specs)
+	}
+
+	sel, _ = ParseUSBSelector("1d50:6089")
+	if removed := p.remove([]USBSelector{sel}); len(removed) != 1 || removed[0].Bus != 1 || removed[0].Device != 5 {
+		t.Errorf("remove(1d50:6089) = %+v, want the 001/005 device", removed)
+	}
+	sel, _ = ParseUSBSelector("0bda:2838")
+	p.remove([]USBSelector{sel})
+	p.apply(labels)
+	if _, ok := labels[USBLabel]; ok {
+		t.Errorf("USB label = %q after removing every device, want it deleted", labels[USBLabel])
+	}
+	if _, ok := labels[USBDevicesLabel]; ok {
+		t.Errorf("USB devices label = %q after removing every device, want it deleted", labels[USBDevicesLabel])
+	}
+}
@@ -0,0 +1,406 @@
+/* This code is part of RF Swift by @Penthertz
+ * Author(s): Sebastien Dudek (@FlUxIuS)
+ *
+ * USB hotplug follower: mirrors plugged/unplugged devices into a running container
+ */
+
+package dock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+	common "penthertz/rfswift/common"
+)
+
+// USBEvent is a kernel uevent of a USB device (not of one of its interfaces).
+type USBEvent struct {
+	Action  string // "add", "remove", "bind", ...
+	DevPath string // sysfs path below /sys ("/devices/pci0000:00/.../1-2")
+	Device  USBDevice
+}
+
+// USBEventSource delivers USB uevents to the hotplug follower. The netlink
+// source is used on Linux; tests inject their own.
+type USBEventSource interface {
+	// Next blocks until the next USB device event.
+	Next() (USBEvent, error)
+	// Close releases the source; a blocked Next returns an error.
+	Close() error
+}
+
+// parseUEvent decodes a kernel uevent message ("ACTION@DEVPATH\0KEY=VALUE\0...").
+// Only usb_device events are returned.
+//
+//	in(1): []byte msg raw netlink payload
+//	out: USBEvent decoded event
+//	out: bool false if the message is not a USB device event
+func parseUEvent(msg []byte) (USBEvent, bool) {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) < 2 || !bytes.Contains(fields[0], []byte("@")) {
+		// udev monitor messages ("libudev" header) are not kernel events
+		return USBEvent{}, false
+	}
+
+	env := make(map[string]string)
+	for _, field := range fields[1:] {
+		if key, value, ok := strings.Cut(string(field), "="); ok {
+			env[key] = value
+		}
+	}
+	if env["SUBSYSTEM"] != "usb" || env["DEVTYPE"] != "usb_device" {
+		return USBEvent{}, false
+	}
+
+	ev := USBEvent{
+		Action:  env["ACTION"],
+		DevPath: env["DEVPATH"],
+	}
+	ev.Device.SysfsName = path.Base(ev.DevPath)
+	// PRODUCT is "vid/pid/bcdDevice" in hex without leading zeros
+	if parts := strings.Split(env["PRODUCT"], "/"); len(parts) >= 2 {
+		ev.Device.VendorID = padUSBID(parts[0])
+		ev.Device.ProductID = padUSBID(parts[1])
+	}
+	ev.Device.Bus, _ = strconv.Atoi(env["BUSNUM"])
+	ev.Device.Device, _ = strconv.Atoi(env["DEVNUM"])
+	ev.Device.Major, _ = strconv.Atoi(env["MAJOR"])
+	ev.Device.Minor, _ = strconv.Atoi(env["MINOR"])
+	if ev.Device.Major == 0 && ev.Device.Bus > 0 && ev.Device.Device > 0 {
+		ev.Device.Major, ev.Device.Minor = usbMajor, (ev.Device.Bus-1)*128+ev.Device.Device-1
+	}
+	return ev, ev.Action != "" && ev.Device.VendorID != ""
+}
+
+func padUSBID(id string) string {
+	id = strings.ToLower(id)
+	if len(id) < 4 {
+		id = strings.Repeat("0", 4-len(id)) + id
+	}
+	return id
+}
+
+// usbHotplugTarget applies device changes to a container.
+type usbHotplugTarget interface {
+	// AddNode creates the device node inside the container.
+	AddNode(d USBDevice) error
+	// RemoveNode deletes the device node inside the container.
+	RemoveNode(d USBDevice) error
+	// AllowDevice grants or revokes the device in the container cgroup.
+	AllowDevice(d USBDevice, allow bool) error
+}
+
+// usbWatcher follows the devices matching its selectors into a container.
+type usbWatcher struct {
+	selectors []USBSelector
+	source    USBEventSource
+	target    usbHotplugTarget
+	sysfs     string               // sysfs root, read for serial numbers
+	log       io.Writer            // receives one line per change
+	now       func() time.Time     // clock for log lines
+	attached  map[string]USBDevice // devices mapped into the container, by sysfs name
+}
+
+func newUSBWatcher(selectors []USBSelector, source USBEventSource, target usbHotplugTarget, log io.Writer) *usbWatcher {
+	return &usbWatcher{
+		selectors: selectors,
+		source:    source,
+		target:    target,
+		sysfs:     sysfsRoot,
+		log:       log,
+		now:       time.Now,
+		attached:  make(map[string]USBDevice),
+	}
+}
+
+func (w *usbWatcher) logf(format string, args ...interface{}) {
+	fmt.Fprintf(w.log, "%s %s\n", w.now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+func (w *usbWatcher) selected(d USBDevice) bool {
+	for _, sel := range w.selectors {
+		if sel.Matches(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// attach maps a device into the container; failures are logged, not fatal,
+// so one broken device does not stop the follower. A node the container
+// cgroup does not allow is unusable, so it is removed again.
+func (w *usbWatcher) attach(d USBDevice) {
+	if err := w.target.AddNode(d); err != nil {
+		w.logf("error: cannot create %s: %v", d.DevPath(), err)
+		return
+	}
+	if err := w.target.AllowDevice(d, true); err != nil {
+		w.logf("error: cannot allow %s in the container cgroup: %v", d, err)
+		if err := w.target.RemoveNode(d); err != nil {
+			w.logf("error: cannot remove %s: %v", d.DevPath(), err)
+		}
+		return
+	}
+	w.attached[d.SysfsName] = d
+	w.logf("added %s", d)
+}
+
+func (w *usbWatcher) detach(d USBDevice) {
+	delete(w.attached, d.SysfsName)
+	if err := w.target.AllowDevice(d, false); err != nil {
+		w.logf("warning: cannot revoke %s in the container cgroup: %v", d.CgroupRule(), err)
+	}
+	if err := w.target.RemoveNode(d); err != nil {
+		w.logf("error: cannot remove %s: %v", d.DevPath(), err)
+		return
+	}
+	w.logf("removed %s", d)
+}
+
+// sync attaches the matching devices already plugged in.
+func (w *usbWatcher) sync(devices []USBDevice) {
+	for _, d := range devices {
+		if w.selected(d) {
+			w.attach(d)
+		}
+	}
+}
+
+// handle applies one uevent.
+func (w *usbWatcher) handle(ev USBEvent) {
+	switch ev.Action {
+	case "add":
+		d := ev.Device
+		// Serial and strings are not part of the uevent; sysfs has them now
+		dir := filepath.Join(w.sysfs, filepath.FromSlash(ev.DevPath))
+		d.Serial = readSysfsAttr(dir, "serial")
+		d.Manufacturer = readSysfsAttr(dir, "manufacturer")
+		d.Product = readSysfsAttr(dir, "product")
+		if w.selected(d) {
+			w.attach(d)
+		}
+	case "remove":
+		// sysfs is gone on removal: rely on what was attached
+		if d, ok := w.attached[ev.Device.SysfsName]; ok {
+			w.detach(d)
+		}
+	}
+}
+
+// run handles events until the context is cancelled or the source fails.
+func (w *usbWatcher) run(ctx context.Context) error {
+	type result struct {
+		ev  USBEvent
+		err error
+	}
+	events := make(chan result)
+	go func() {
+		for {
+			ev, err := w.source.Next()
+			select {
+			case events <- result{ev, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.source.Close()
+			return nil
+		case r := <-events:
+			if r.err != nil {
+				return r.err
+			}
+			w.handle(r.ev)
+		}
+	}
+}
+
+// cgroupCovers reports whether the device cgroup rules of a container already
+// grant d, in which case no live cgroup change is needed.
+func cgroupCovers(rules []string, d USBDevice) bool {
+	for _, r := range rules {
+		r = strings.TrimSpace(r)
+		if r == d.CgroupRule() || strings.HasPrefix(r, "a") ||
+			strings.HasPrefix(r, fmt.Sprintf("c %d:* ", d.Major)) || strings.HasPrefix(r, "c *:* ") {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceCgroupDir returns the devices cgroup (v1) directory of a process, or
+// "" when the host uses the unified hierarchy (cgroup v2), where device access
+// is an eBPF program that cannot be extended from outside.
+//
+//	in(1): string procCgroup content of /proc/<pid>/cgroup
+//	in(2): string mount cgroup mount point (/sys/fs/cgroup)
+//	out: string devices cgroup directory, or "" on cgroup v2
+func deviceCgroupDir(procCgroup, mount string) string {
+	for _, line := range strings.Split(procCgroup, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "devices" {
+				return filepath.Join(mount, "devices", filepath.FromSlash(parts[2]))
+			}
+		}
+	}
+	return ""
+}
+
+// containerUSBTarget applies hotplug changes to a running container: nodes
+// through exec mknod/rm, access through the devices cgroup.
+type containerUSBTarget struct {
+	ctx        context.Context
+	cli        *client.Client
+	id         string
+	privileged bool
+	rules      []string // device cgroup rules the container was created with
+	cgroupDir  string   // devices cgroup (v1), "" on cgroup v2
+}
+
+func (t *containerUSBTarget) exec(script string) error {
+	output, code, err := execScriptAsRoot(t.ctx, t.cli, t.id, script)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("exit code %d: %s", code, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (t *containerUSBTarget) AddNode(d USBDevice) error {
+	return t.exec(fmt.Sprintf("[ -c %[1]s ] || { mkdir -p %[2]s && mknod -m 0666 %[1]s c %[3]d %[4]d; }",
+		d.DevPath(), path.Dir(d.DevPath()), d.Major, d.Minor))
+}
+
+func (t *containerUSBTarget) RemoveNode(d USBDevice) error {
+	return t.exec(fmt.Sprintf("rm -f %s", d.DevPath()))
+}
+
+func (t *containerUSBTarget) AllowDevice(d USBDevice, allow bool) error {
+	if t.privileged || cgroupCovers(t.rules, d) {
+		return nil
+	}
+	if t.cgroupDir == "" {
+		if allow {
+			return fmt.Errorf("cgroup v2 rules cannot change while the container runs; recreate it with 'rfswift cgroups add -c <container> -r \"c %d:* rwm\"'", d.Major)
+		}
+		return nil
+	}
+	file := "devices.deny"
+	if allow {
+		file = "devices.allow"
+	}
+	return os.WriteFile(filepath.Join(t.cgroupDir, file), []byte(d.CgroupRule()), 0644)
+}
+
+// canFollowHotplug returns an error when replugged USB devices could never be
+// allowed in the container: on cgroup v2 the device rules are fixed at
+// creation, and a replugged device gets a new minor number that exact --usb
+// rules do not cover.
+func (t *containerUSBTarget) canFollowHotplug() error {
+	if t.privileged || t.cgroupDir != "" || cgroupCovers(t.rules, USBDevice{Major: usbMajor, Minor: -1}) {
+		return nil
+	}
+	return fmt.Errorf("on a cgroup v2 host its device rules cannot change while it runs and only allow exact USB devices, "+
+		"so a replugged device (new minor number) could not be opened; re-grant it with 'rfswift usb add', "+
+		"or allow every USB device with 'rfswift cgroups add -c <container> -r \"c %d:* rwm\"'", usbMajor)
+}
+
+// WatchUSBDevices follows hotplug of the USB devices matching specs into a
+// running container until interrupted: a plugged device gets its node created
+// in the container and is allowed in its devices cgroup, an unplugged one is
+// removed. Without specs, the USB selectors recorded on the container (--usb,
+// usb add/rm) are used. Every change is logged. On cgroup v2 the container
+// must already allow every USB device, as its rules cannot change live.
+//
+//	in(1): context.Context ctx cancelled to stop following
+//	in(2): string containerName target container
+//	in(3): []string specs VID:PID[:serial] selectors
+//	in(4): io.Writer log receives the change log
+//	out: error if the container or selectors are invalid or uevents cannot be read
+func WatchUSBDevices(ctx context.Context, containerName string, specs []string, log io.Writer) error {
+	if GetEngine().Type() == EngineLima {
+		return fmt.Errorf("USB hotplug following is not available with the Lima engine: devices are attached to the VM")
+	}
+
+	cli, err := NewEngineClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containerJSON, err := inspectContainer(ctx, cli, containerName)
+	if err != nil {
+		return fmt.Errorf("container %s not found: %v", containerName, err)
+	}
+	if containerJSON.State == nil || !containerJSON.State.Running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	if len(specs) == 0 && containerJSON.Config != nil {
+		if label := containerJSON.Config.Labels[USBLabel]; label != "" {
+			specs = strings.Split(label, ",")
+		}
+	}
+	if len(specs) == 0 {
+		return fmt.Errorf("no USB device to follow: pass VID:PID[:serial] selectors (container %s has no --usb or usb add selectors)", containerName)
+	}
+	var selectors []USBSelector
+	for _, spec := range specs {
+		sel, err := ParseUSBSelector(spec)
+		if err != nil {
+			return err
+		}
+		selectors = append(selectors, sel)
+	}
+
+	target := &containerUSBTarget{ctx: ctx, cli: cli, id: containerJSON.ID}
+	if containerJSON.HostConfig != nil {
+		target.privileged = containerJSON.HostConfig.Privileged
+		target.rules = containerJSON.HostConfig.DeviceCgroupRules
+	}
+	if procCgroup, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", containerJSON.State.Pid)); err == nil {
+		target.cgroupDir = deviceCgroupDir(string(procCgroup), "/sys/fs/cgroup")
+	}
+	if err := target.canFollowHotplug(); err != nil {
+		return fmt.Errorf("cannot follow USB hotplug in %s: %v", containerName, err)
+	}
+
+	source, err := newUEventSource()
+	if err != nil {
+		return err
+	}
+
+	w := newUSBWatcher(selectors, source, target, log)
+	if present, err := ListUSBDevices(); err == nil {
+		w.sync(present)
+	}
+
+	names := make([]string, len(selectors))
+	for i, sel := range selectors {
+		names[i] = sel.String()
+	}
+	common.PrintInfoMessage(fmt.Sprintf("Following %s in %s (Ctrl+C to stop)", strings.Join(names, ", "), containerName))
+	return w.run(ctx)
+}
@@ -0,0 +1,62 @@
+package dock
+
+import (
+	"fmt"
+	"sync/atomic"
+	"syscall"
+)
+
+// netlinkUEventSource reads kernel uevents from a NETLINK_KOBJECT_UEVENT socket.
+type netlinkUEventSource struct {
+	fd     int
+	closed atomic.Bool
+}
+
+// newUEventSource opens a netlink socket subscribed to kernel uevents.
+//
+//	out: USBEventSource source of USB device events
+//	out: error if the socket cannot be opened
+func newUEventSource() (USBEventSource, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open uevent socket: %v", err)
+	}
+	// Group 1 carries the kernel events (group 2 is udev's rebroadcast)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot listen to uevents: %v", err)
+	}
+	// Wake up regularly so Close is noticed by a blocked Next
+	timeout := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot configure uevent socket: %v", err)
+	}
+	return &netlinkUEventSource{fd: fd}, nil
+}
+
+func (s *netlinkUEventSource) Next() (USBEvent, error) {
+	buf := make([]byte, 64*1024)
+	for {
+		if s.closed.Load() {
+			return USBEvent{}, fmt.Errorf("uevent source closed")
+		}
+		n, _, err := syscall.Recvfrom(s.fd, buf, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return USBEvent{}, fmt.Errorf("cannot read uevents: %v", err)
+		}
+		if ev, ok := parseUEvent(buf[:n]); ok {
+			return ev, nil
+		}
+	}
+}
+
+func (s *netlinkUEventSource) Close() error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	return syscall.Close(s.fd)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package dock
+
+import "fmt"
+
+// newUEventSource is only available on Linux, where the kernel reports USB
+// hotplug through netlink.
+func newUEventSource() (USBEventSource, error) {
+	return nil, fmt.Errorf("USB hotplug following needs a Linux host")
+}
@@ -0,0 +1,255 @@
+/* This code is part of RF Swift by @Penthertz
+*  Author(s): Sébastien Dudek (@FlUxIuS)
+ */
+
+package dock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeUEventSource replays a fixed list of events, then ends with io.EOF, or
+// blocks until closed when block is set.
+type fakeUEventSource struct {
+	events []USBEvent
+	block  bool
+	closed chan struct{}
+}
+
+func (s *fakeUEventSource) Next() (USBEvent, error) {
+	if len(s.events) > 0 {
+		ev := s.events[0]
+		s.events = s.events[1:]
+		return ev, nil
+	}
+	if s.block {
+		<-s.closed
+	}
+	return USBEvent{}, io.EOF
+}
+
+func (s *fakeUEventSource) Close() error {
+	close(s.closed)
+	return nil
+}
+
+// fakeUSBTarget records the changes applied to the container.
+type fakeUSBTarget struct {
+	calls     []string
+	failAdd   bool
+	failAllow bool
+}
+
+func (f *fakeUSBTarget) AddNode(d USBDevice) error {
+	if f.failAdd {
+		return fmt.Errorf("mknod: operation not permitted")
+	}
+	f.calls = append(f.calls, "mknod "+d.DevPath())
+	return nil
+}
+
+func (f *fakeUSBTarget) RemoveNode(d USBDevice) error {
+	f.calls = append(f.calls, "rm "+d.DevPath())
+	return nil
+}
+
+func (f *fakeUSBTarget) AllowDevice(d USBDevice, allow bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("allow=%v %s", allow, d.CgroupRule()))
+	if f.failAllow && allow {
+		return fmt.Errorf("cgroup v2 rules cannot change while the container runs")
+	}
+	return nil
+}
+
+func uevent(fields ...string) []byte {
+	return []byte(strings.Join(fields, "\x00") + "\x00")
+}
+
+func TestParseUEvent(t *testing.T) {
+	ev, ok := parseUEvent(uevent("add@/devices/pci0000:00/0000:00:14.0/usb1/1-3",
+		"ACTION=add", "DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-3", "SUBSYSTEM=usb",
+		"MAJOR=189", "MINOR=6", "DEVNAME=bus/usb/001/007", "DEVTYPE=usb_device",
+		"PRODUCT=1d50/6089/102", "BUSNUM=001", "DEVNUM=007"))
+	if !ok {
+		t.Fatal("parseUEvent() rejected a usb_device event")
+	}
+	want := USBDevice{Bus: 1, Device: 7, VendorID: "1d50", ProductID: "6089", Major: 189, Minor: 6, SysfsName: "1-3"}
+	if ev.Action != "add" || ev.Device != want {
+		t.Errorf("parseUEvent() = %s %+v, want add %+v", ev.Action, ev.Device, want)
+	}
+
+	ev, ok = parseUEvent(uevent("add@/devices/x/2-1", "ACTION=add", "DEVPATH=/devices/x/2-1",
+		"SUBSYSTEM=usb", "DEVTYPE=usb_device", "PRODUCT=bda/2838/100", "BUSNUM=002", "DEVNUM=003"))
+	if !ok || ev.Device.ID() != "0bda:2838" || ev.Device.CgroupRule() != "c 189:130 rwm" {
+		t.Errorf("parseUEvent() = %+v, want padded 0bda:2838 with derived minor 130", ev.Device)
+	}
+
+	rejected := [][]byte{
+		uevent("add@/devices/x/1-3/1-3:1.0", "ACTION=add", "SUBSYSTEM=usb", "DEVTYPE=usb_interface", "PRODUCT=1d50/6089/102"),
+		uevent("add@/devices/x/tty/ttyUSB0", "ACTION=add", "SUBSYSTEM=tty"),
+		uevent("libudev", "ACTION=add", "SUBSYSTEM=usb", "DEVTYPE=usb_device", "PRODUCT=1d50/6089/102"),
+		[]byte("garbage"),
+	}
+	for _, msg := range rejected {
+		if ev, ok := parseUEvent(msg); ok {
+			t.Errorf("parseUEvent(%q) = %+v, want rejected", msg, ev)
+		}
+	}
+}
+
+func TestUSBWatcher(t *testing.T) {
+	root := fakeSysfs(t)
+	writeSysfsDevice(t, root, "devices/x/1-4", map[string]string{"serial": "0000000000000000457863dc2b3f1a4b"})
+
+	hackrf := USBDevice{Bus: 1, Device: 9, VendorID: "1d50", ProductID: "6089", Major: 189, Minor: 8, SysfsName: "1-4"}
+	rtlsdr := USBDevice{Bus: 2, Device: 4, VendorID: "0bda", ProductID: "2838", Major: 189, Minor: 131, SysfsName: "2-2"}
+	source := &fakeUEventSource{
+		closed: make(chan struct{}),
+		events: []USBEvent{
+			{Action: "add", DevPath: "/bus/usb/devices/devices/x/1-4", Device: hackrf},
+			{Action: "add", DevPath: "/devices/y/2-2", Device: rtlsdr}, // not selected
+			{Action: "bind", DevPath: "/bus/usb/devices/devices/x/1-4", Device: hackrf},
+			{Action: "remove", DevPath: "/devices/y/2-2", Device: rtlsdr},
+			{Action: "remove", DevPath: "/bus/usb/devices/devices/x/1-4", Device: USBDevice{SysfsName: "1-4"}},
+		},
+	}
+	target := &fakeUSBTarget{}
+	var log bytes.Buffer
+
+	sel, _ := ParseUSBSelector("1d50:6089:0000000000000000457863dc2b3f1a4b")
+	w := newUSBWatcher([]USBSelector{sel}, source, target, &log)
+	w.sysfs = root
+	w.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	present, err := listUSBDevices(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.sync(present) // 1-3 carries the selected serial
+
+	if err := w.run(context.Background()); err != io.EOF {
+		t.Fatalf("run() error = %v, want the source error", err)
+	}
+
+	wantCalls := []string{
+		"mknod /dev/bus/usb/001/007", "allow=true c 189:6 rwm",
+		"mknod /dev/bus/usb/001/009", "allow=true c 189:8 rwm",
+		"allow=false c 189:8 rwm", "rm /dev/bus/usb/001/009",
+	}
+	if !reflect.DeepEqual(target.calls, wantCalls) {
+		t.Errorf("watcher calls = %v, want %v", target.calls, wantCalls)
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "2026-01-02T03:04:05Z added 1d50:6089 /dev/bus/usb/001/007") ||
+		!strings.Contains(lines[2], "removed 1d50:6089 /dev/bus/usb/001/009") {
+		t.Errorf("watcher log = %q", log.String())
+	}
+	if len(w.attached) != 1 {
+		t.Errorf("attached = %v, want only the device found at start", w.attached)
+	}
+}
+
+func TestUSBWatcherStop(t *testing.T) {
+	source := &fakeUEventSource{block: true, closed: make(chan struct{})}
+	w := newUSBWatcher(nil, source, &fakeUSBTarget{}, io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run() after cancel error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run() did not stop on cancel")
+	}
+}
+
+func TestUSBWatcherAddFailure(t *testing.T) {
+	target := &fakeUSBTarget{failAdd: true}
+	var log bytes.Buffer
+	sel, _ := ParseUSBSelector("1d50:6089")
+	w := newUSBWatcher([]USBSelector{sel}, nil, target, &log)
+	w.sysfs = t.TempDir()
+
+	w.handle(USBEvent{Action: "add", DevPath: "/devices/x/1-4", Device: USBDevice{Bus: 1, Device: 9, VendorID: "1d50", ProductID: "6089", Major: 189, Minor: 8, SysfsName: "1-4"}})
+	if len(w.attached) != 0 || len(target.calls) != 0 {
+		t.Errorf("failed mknod: attached = %v, calls = %v, want nothing", w.attached, target.calls)
+	}
+	if !strings.Contains(log.String(), "error: cannot create /dev/bus/usb/001/009") {
+		t.Errorf("watcher log = %q, want the mknod error", log.String())
+	}
+
+	// cgroup v2 without a wildcard rule: the node is useless, not "added"
+	target = &fakeUSBTarget{failAllow: true}
+	log.Reset()
+	w = newUSBWatcher([]USBSelector{sel}, nil, target, &log)
+	w.sysfs = t.TempDir()
+	w.handle(USBEvent{Action: "add", DevPath: "/devices/x/1-4", Device: USBDevice{Bus: 1, Device: 9, VendorID: "1d50", ProductID: "6089", Major: 189, Minor: 8, SysfsName: "1-4"}})
+	wantCalls := []string{"mknod /dev/bus/usb/001/009", "allow=true c 189:8 rwm", "rm /dev/bus/usb/001/009"}
+	if len(w.attached) != 0 || !reflect.DeepEqual(target.calls, wantCalls) {
+		t.Errorf("failed allow: attached = %v, calls = %v, want nothing attached and %v", w.attached, target.calls, wantCalls)
+	}
+	if !strings.Contains(log.String(), "error: cannot allow 1d50:6089 /dev/bus/usb/001/009") || strings.Contains(log.String(), "added") {
+		t.Errorf("watcher log = %q, want the cgroup error and no added line", log.String())
+	}
+}
+
+func TestCgroupCovers(t *testing.T) {
+	d := USBDevice{Major: 189, Minor: 8}
+	tests := []struct {
+		rules []string
+		want  bool
+	}{
+		{[]string{"c 189:* rwm"}, true},
+		{[]string{"c 189:8 rwm"}, true},
+		{[]string{"c 189:4 rwm"}, false},
+		{[]string{"a *:* rwm"}, true},
+		{[]string{"c 166:* rwm"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := cgroupCovers(tt.rules, d); got != tt.want {
+			t.Errorf("cgroupCovers(%v) = %v, want %v", tt.rules, got, tt.want)
+		}
+	}
+}
+
+func TestCanFollowHotplug(t *testing.T) {
+	tests := []struct {
+		name   string
+		target containerUSBTarget
+		ok     bool
+	}{
+		{"v2 exact rules", containerUSBTarget{rules: []string{"c 189:8 rwm"}}, false},
+		{"v2 wildcard rule", containerUSBTarget{rules: []string{"c 189:8 rwm", "c 189:* rwm"}}, true},
+		{"v2 privileged", containerUSBTarget{privileged: true}, true},
+		{"v1 exact rules", containerUSBTarget{rules: []string{"c 189:8 rwm"}, cgroupDir: "/sys/fs/cgroup/devices/docker/abc"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.target.canFollowHotplug(); (err == nil) != tt.ok {
+			t.Errorf("canFollowHotplug(%s) error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestDeviceCgroupDir(t *testing.T) {
+	v1 := "12:pids:/docker/abc\n5:devices:/docker/abc\n1:name=systemd:/docker/abc\n"
+	if got, want := deviceCgroupDir(v1, "/sys/fs/cgroup"), "/sys/fs/cgroup/devices/docker/abc"; got != want {
+		t.Errorf("deviceCgroupDir(v1) = %q, want %q", got, want)
+	}
+	if got := deviceCgroupDir("0::/system.slice/docker-abc.scope\n", "/sys/fs/cgroup"); got != "" {
+		t.Errorf("deviceCgroupDir(v2) = %q, want \"\"", got)
+	}
+}
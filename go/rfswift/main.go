@@ -11,8 +11,10 @@ import (
 )
 
 // main is the program entry point. It suppresses the ASCII banner when the
-// binary is invoked for shell-completion generation, then delegates all
-// command handling to the CLI layer via cli.Execute.
+// binary is invoked for shell-completion generation, with a json/yaml
+// --output or when a raw document is printed (build --render, profile or
+// log export to stdout), then delegates all command handling to the CLI
+// layer via cli.Execute.
 func main() {
 	isCompletion := false
 
@@ -23,7 +25,7 @@ func main() {
 		}
 	}
 
-	if isCompletion == false {
+	if isCompletion == false && !cli.MachineOutputRequested(os.Args[1:]) && !common.RawOutputRequested(os.Args[1:]) {
 		common.PrintASCII()
 	}
 
@@ -123,7 +123,8 @@ func displayEnv() (string, error) {
 // GetDisplayEnv returns a DISPLAY environment string suitable for passing to a
 // container. On macOS it resolves the en0 IP address and appends the current
 // display number; on other systems it reads the DISPLAY variable directly,
-// falling back to ":0" on error.
+// falling back to ":0" on error. The fallback notice goes to stderr: this
+// runs while flags are registered, before --output can route stdout.
 //
 //	out: string  "DISPLAY=<value>" string ready to be injected as an environment variable
 func GetDisplayEnv() string {
@@ -149,7 +150,7 @@ func GetDisplayEnv() string {
 		// Get the IP address and append the display number
 		ip, err := exec.Command("ipconfig", "getifaddr", "en0").Output()
 		if err != nil {
-			fmt.Println("Error determining IP address (using default 'DISPLAY=:0'):", err)
+			fmt.Fprintln(os.Stderr, "Error determining IP address (using default 'DISPLAY=:0'):", err)
 			return "DISPLAY=:0"
 		}
 		dispenv = "DISPLAY=" + strings.TrimSpace(string(ip)) + displayNumber
@@ -157,7 +158,7 @@ func GetDisplayEnv() string {
 		// Default behavior for other OS
 		display, err := displayEnv()
 		if err != nil {
-			fmt.Println("Error (using default 'DISPLAY=:0'):", err)
+			fmt.Fprintln(os.Stderr, "Error (using default 'DISPLAY=:0'):", err)
 			dispenv = "DISPLAY=:0"
 		} else {
 			dispenv = "DISPLAY=" + display
@@ -35,6 +35,18 @@ func PrintDoctorHeader() {
 	fmt.Printf("\n%s\n%s\n\n", header, separator)
 }
 
+// PrintStatusHeader prints the header of a container status report.
+func PrintStatusHeader(container string) {
+	header := lipgloss.NewStyle().
+		Foreground(ColorPrimary).
+		Bold(true).
+		Render("📡 Status of " + container)
+	separator := lipgloss.NewStyle().
+		Foreground(ColorPrimary).
+		Render("══════════════════════════════════════════════════════════")
+	fmt.Printf("\n%s\n%s\n\n", header, separator)
+}
+
 // PrintDoctorResult prints a single doctor check result line.
 func PrintDoctorResult(r DoctorResult) {
 	icon := DoctorStatusIcons[r.Status]
@@ -32,6 +32,7 @@ type RunWizardResult struct {
 	VPN          string // format: "type:argument"
 	GPUs         string // GPU specifier: "all" or comma-separated IDs
 	Workspace    string // "none" = disabled, "" = auto, path = custom
+	Profile      string // profile picked in the wizard ("" if none)
 	Confirmed    bool
 }
 
@@ -140,6 +141,7 @@ func RunWizard(images []string, defaults *RunWizardDefaults, existingNets []stri
 			for _, p := range defaults.Profiles {
 				if p.Name == selectedProfile {
 					profileUsed = true
+					result.Profile = p.Name
 					result.Image = p.Image
 					result.Network = p.Network
 					result.Desktop = p.Desktop